@@ -54,6 +54,7 @@ type Configuration struct {
 	FUNCTIONS_ADMIN_SERVICE_PORT string
 	INITIAL_CONFIG_FILE          string
 	WS_HOST                      string
+	FILESYSTEM_STORAGE_ROOT      string
 }
 
 func GetConfig() Configuration {
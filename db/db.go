@@ -239,18 +239,22 @@ func createTables(MetadataDbClient MetadataStorage) error {
 		ALTER TABLE schemas DROP CONSTRAINT IF EXISTS name;
 		ALTER TABLE schemas DROP CONSTRAINT IF EXISTS schemas_name_tenant_name_key;
 		ALTER TABLE schemas ADD CONSTRAINT schemas_name_tenant_name_key UNIQUE(name, tenant_name);
-		ALTER TYPE enum_type ADD VALUE 'avro';
+		ALTER TYPE enum_type ADD VALUE IF NOT EXISTS 'avro';
+		ALTER TYPE enum_type ADD VALUE IF NOT EXISTS 'xsd';
+		ALTER TYPE enum_type ADD VALUE IF NOT EXISTS 'thrift';
+		ALTER TABLE schemas ADD COLUMN IF NOT EXISTS compatibility_mode VARCHAR NOT NULL DEFAULT 'none';
 		END IF;
 	END $$;`
 
 	schemasTable := `
-	CREATE TYPE enum_type AS ENUM ('json', 'graphql', 'protobuf', 'avro');
+	CREATE TYPE enum_type AS ENUM ('json', 'graphql', 'protobuf', 'avro', 'xsd', 'thrift');
 	CREATE TABLE IF NOT EXISTS schemas(
 		id SERIAL NOT NULL,
 		name VARCHAR NOT NULL,
 		type enum_type NOT NULL DEFAULT 'protobuf',
 		created_by_username VARCHAR NOT NULL,
 		tenant_name VARCHAR NOT NULL DEFAULT '$memphis',
+		compatibility_mode VARCHAR NOT NULL DEFAULT 'none',
 		PRIMARY KEY (id),
 		CONSTRAINT fk_tenant_name_schemas
 			FOREIGN KEY(tenant_name)
@@ -374,6 +378,7 @@ func createTables(MetadataDbClient MetadataStorage) error {
 		ALTER TABLE stations ADD COLUMN IF NOT EXISTS dls_station VARCHAR NOT NULL DEFAULT '';
 		ALTER TABLE stations ADD COLUMN IF NOT EXISTS functions_lock_held BOOL NOT NULL DEFAULT false;
 		ALTER TABLE stations ADD COLUMN IF NOT EXISTS functions_locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+		ALTER TABLE stations ADD COLUMN IF NOT EXISTS schema_strict BOOL NOT NULL DEFAULT false;
 		DROP INDEX IF EXISTS unique_station_name_deleted;
 		CREATE UNIQUE INDEX unique_station_name_deleted ON stations(name, is_deleted, tenant_name) WHERE is_deleted = false;
 		END IF;
@@ -408,6 +413,7 @@ func createTables(MetadataDbClient MetadataStorage) error {
 		dls_station VARCHAR NOT NULL DEFAULT '',
 		functions_lock_held BOOL NOT NULL DEFAULT false,
 		functions_locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		schema_strict BOOL NOT NULL DEFAULT false,
 		PRIMARY KEY (id),
 		CONSTRAINT fk_tenant_name_stations
 			FOREIGN KEY(tenant_name)
@@ -585,6 +591,141 @@ func createTables(MetadataDbClient MetadataStorage) error {
 			PRIMARY KEY (id)
         );`
 
+	stationSchemaMetricsTable := `
+		CREATE TABLE IF NOT EXISTS station_schema_metrics(
+			station_id INT NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			valid_messages BIGINT NOT NULL DEFAULT 0,
+			invalid_messages BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (station_id),
+		CONSTRAINT fk_tenant_name_station_schema_metrics
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);`
+
+	dlsRetryPoliciesTable := `
+		CREATE TABLE IF NOT EXISTS dls_retry_policies(
+			station_id INT NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			max_attempts INT NOT NULL,
+			initial_delay_ms BIGINT NOT NULL,
+			backoff_multiplier FLOAT8 NOT NULL DEFAULT 2,
+			consumers_groups VARCHAR[] NOT NULL DEFAULT '{}',
+			updated_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (station_id),
+		CONSTRAINT fk_station_id_dls_retry_policies
+			FOREIGN KEY(station_id)
+			REFERENCES stations(id)
+			ON DELETE CASCADE,
+		CONSTRAINT fk_tenant_name_dls_retry_policies
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);`
+
+	dlsMessageRetriesTable := `
+		CREATE TABLE IF NOT EXISTS dls_message_retries(
+			dls_message_id INT NOT NULL,
+			cg_name VARCHAR NOT NULL,
+			station_id INT NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			status VARCHAR NOT NULL DEFAULT 'pending',
+			next_retry_at TIMESTAMPTZ NOT NULL,
+			history JSON NOT NULL DEFAULT '[]',
+			updated_at TIMESTAMPTZ NOT NULL,
+			ack_deadline TIMESTAMPTZ NOT NULL DEFAULT 'epoch',
+			PRIMARY KEY (dls_message_id, cg_name),
+		CONSTRAINT fk_dls_message_id_dls_message_retries
+			FOREIGN KEY(dls_message_id)
+			REFERENCES dls_messages(id)
+			ON DELETE CASCADE,
+		CONSTRAINT fk_tenant_name_dls_message_retries
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);
+		CREATE INDEX IF NOT EXISTS dls_message_retries_pending ON dls_message_retries (next_retry_at) WHERE status = 'pending';`
+
+	alertRulesTable := `
+		CREATE TABLE IF NOT EXISTS alert_rules(
+			id SERIAL NOT NULL,
+			name VARCHAR NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			rule_type VARCHAR NOT NULL,
+			station_id INT,
+			consumers_group VARCHAR NOT NULL DEFAULT '',
+			operator VARCHAR NOT NULL,
+			threshold FLOAT8 NOT NULL,
+			duration_sec INT NOT NULL DEFAULT 0,
+			integrations VARCHAR[] NOT NULL DEFAULT '{}',
+			enabled BOOL NOT NULL DEFAULT true,
+			state VARCHAR NOT NULL DEFAULT 'inactive',
+			last_value FLOAT8 NOT NULL DEFAULT 0,
+			last_counter BIGINT NOT NULL DEFAULT -1,
+			condition_since TIMESTAMPTZ,
+			fired_at TIMESTAMPTZ,
+			resolved_at TIMESTAMPTZ,
+			last_evaluated_at TIMESTAMPTZ,
+			next_evaluation_at TIMESTAMPTZ NOT NULL,
+			created_by INT NOT NULL,
+			created_by_username VARCHAR NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE(name, tenant_name),
+		CONSTRAINT fk_station_id_alert_rules
+			FOREIGN KEY(station_id)
+			REFERENCES stations(id)
+			ON DELETE CASCADE,
+		CONSTRAINT fk_tenant_name_alert_rules
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);
+		CREATE INDEX IF NOT EXISTS alert_rules_next_evaluation ON alert_rules (next_evaluation_at) WHERE enabled = true;`
+
+	tieredStoragePoliciesTable := `
+		CREATE TABLE IF NOT EXISTS tiered_storage_policies(
+			station_id INT NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			integration VARCHAR NOT NULL DEFAULT '',
+			key_prefix VARCHAR NOT NULL DEFAULT '',
+			max_batch_messages INT NOT NULL DEFAULT 0,
+			max_batch_bytes BIGINT NOT NULL DEFAULT 0,
+			max_upload_latency_sec INT NOT NULL DEFAULT 0,
+			redaction JSON NOT NULL DEFAULT '{}',
+			updated_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (station_id),
+		CONSTRAINT fk_station_id_tiered_storage_policies
+			FOREIGN KEY(station_id)
+			REFERENCES stations(id)
+			ON DELETE CASCADE,
+		CONSTRAINT fk_tenant_name_tiered_storage_policies
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);`
+
+	schemaReferencesTable := `
+		CREATE TABLE IF NOT EXISTS schema_references(
+			id SERIAL NOT NULL,
+			schema_version_id INT NOT NULL,
+			name VARCHAR NOT NULL,
+			referenced_schema_id INT NOT NULL,
+			referenced_version_number INT NOT NULL,
+			tenant_name VARCHAR NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE(schema_version_id, name),
+		CONSTRAINT fk_schema_version_id
+			FOREIGN KEY(schema_version_id)
+			REFERENCES schema_versions(id),
+		CONSTRAINT fk_referenced_schema_id
+			FOREIGN KEY(referenced_schema_id)
+			REFERENCES schemas(id),
+		CONSTRAINT fk_tenant_name_schema_references
+			FOREIGN KEY(tenant_name)
+			REFERENCES tenants(name)
+		);
+		CREATE INDEX IF NOT EXISTS schema_references_referenced_schema_id ON schema_references (referenced_schema_id);`
+
 	sharedLocksTable := `
 		CREATE TABLE IF NOT EXISTS shared_locks(
 			id SERIAL NOT NULL,
@@ -660,7 +801,7 @@ func createTables(MetadataDbClient MetadataStorage) error {
 	db := MetadataDbClient.Client
 	ctx := MetadataDbClient.Ctx
 
-	tables := []string{alterTenantsTable, tenantsTable, alterUsersTable, usersTable, alterAuditLogsTable, auditLogsTable, alterConfigurationsTable, configurationsTable, alterIntegrationsTable, integrationsTable, alterSchemasTable, schemasTable, alterTagsTable, tagsTable, alterStationsTable, stationsTable, alterDlsMsgsTable, dlsMessagesTable, alterConsumersTable, consumersTable, alterSchemaVerseTable, schemaVersionsTable, alterProducersTable, producersTable, alterConnectionsTable, asyncTasksTable, alterAsyncTasks, testEventsTable, functionsTable, attachedFunctionsTable, sharedLocksTable, functionsEngineWorkersTable, scheduledFunctionWorkersTable, connectorsEngineWorkersTable, connectorsConnectionsTable, connectorsTable, alterConnectorsTable, alterConnectorsConnectionsTable, rolesTable, permissionsTable}
+	tables := []string{alterTenantsTable, tenantsTable, alterUsersTable, usersTable, alterAuditLogsTable, auditLogsTable, alterConfigurationsTable, configurationsTable, alterIntegrationsTable, integrationsTable, alterSchemasTable, schemasTable, alterTagsTable, tagsTable, alterStationsTable, stationsTable, alterDlsMsgsTable, dlsMessagesTable, alterConsumersTable, consumersTable, alterSchemaVerseTable, schemaVersionsTable, alterProducersTable, producersTable, alterConnectionsTable, asyncTasksTable, alterAsyncTasks, testEventsTable, functionsTable, attachedFunctionsTable, sharedLocksTable, functionsEngineWorkersTable, scheduledFunctionWorkersTable, connectorsEngineWorkersTable, connectorsConnectionsTable, connectorsTable, alterConnectorsTable, alterConnectorsConnectionsTable, rolesTable, permissionsTable, stationSchemaMetricsTable, schemaReferencesTable, dlsRetryPoliciesTable, dlsMessageRetriesTable, alertRulesTable, tieredStoragePoliciesTable}
 
 	for _, table := range tables {
 		_, err := db.Exec(ctx, table)
@@ -1677,6 +1818,7 @@ func GetAllStationsDetailsLight(tenantName string) ([]models.ExtendedStationLigh
 			&stationRes.DlsStation,
 			&stationRes.FunctionsLockHeld,
 			&stationRes.FunctionsLockedAt,
+			&stationRes.SchemaStrict,
 			&stationRes.Activity,
 		); err != nil {
 			return []models.ExtendedStationLight{}, err
@@ -2032,10 +2174,575 @@ func getFilteredExtendedStations(stationsMap map[int]models.ExtendedStation) []m
 		station.Producers = producers
 		stations = append(stations, station)
 	}
-	return stations
+	return stations
+}
+
+func DeleteStationsByNames(stationNames []string, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `DELETE FROM stations
+	WHERE name = ANY($1)
+	AND (is_deleted = false)
+	AND tenant_name=$2`
+	stmt, err := conn.Conn().Prepare(ctx, "delete_stations_by_names", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, stationNames, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func RemoveDeletedStations() error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `DELETE FROM stations WHERE is_deleted = true`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_deleted_stations", query)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Conn().Query(ctx, stmt.Name)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func DeleteStation(name string, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `DELETE FROM stations
+	WHERE name = $1
+	AND tenant_name=$2`
+	stmt, err := conn.Conn().Prepare(ctx, "delete_station", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, name, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func AttachSchemaToStation(stationName string, schemaName string, versionNumber int, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE stations SET schema_name = $2, schema_version_number = $3
+	WHERE name = $1 AND is_deleted = false AND tenant_name=$4`
+	stmt, err := conn.Conn().Prepare(ctx, "attach_schema_to_station", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, schemaName, versionNumber, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func DetachSchemaFromStation(stationName string, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE stations SET schema_name = '', schema_version_number = 0
+	WHERE name = $1 AND is_deleted = false AND tenant_name=$2`
+	stmt, err := conn.Conn().Prepare(ctx, "detach_schema_from_station", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func UpdateStationDlsConfig(stationName string, poison bool, schemaverse bool, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE stations SET dls_configuration_poison = $2, dls_configuration_schemaverse = $3
+	WHERE name = $1 AND is_deleted = false AND tenant_name=$4`
+	stmt, err := conn.Conn().Prepare(ctx, "update_station_dls_config", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, poison, schemaverse, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func UpdateStationConfig(stationName string, retentionType string, retentionValue int, replicas int, idempotencyWindow int64, dlsConfiguration models.DlsConfiguration, tieredStorageEnabled bool, tenantName string) (models.Station, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return models.Station{}, err
+	}
+	defer conn.Release()
+	// version 0 marks stations created before partitions were introduced so it must stay as is
+	query := `UPDATE stations SET retention_type = $2, retention_value = $3, replicas = $4, idempotency_window_ms = $5, dls_configuration_poison = $6, dls_configuration_schemaverse = $7, tiered_storage_enabled = $8, updated_at = $9,
+	version = CASE WHEN version > 0 THEN version + 1 ELSE version END
+	WHERE name = $1 AND is_deleted = false AND tenant_name = $10
+	RETURNING *`
+	stmt, err := conn.Conn().Prepare(ctx, "update_station_config", query)
+	if err != nil {
+		return models.Station{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, stationName, retentionType, retentionValue, replicas, idempotencyWindow, dlsConfiguration.Poison, dlsConfiguration.Schemaverse, tieredStorageEnabled, time.Now(), tenantName)
+	if err != nil {
+		return models.Station{}, err
+	}
+	defer rows.Close()
+	stations, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Station])
+	if err != nil {
+		return models.Station{}, err
+	}
+	if len(stations) == 0 {
+		return models.Station{}, errors.New("station " + stationName + " does not exist")
+	}
+	return stations[0], nil
+}
+
+func UpdateStationPartitions(stationId int, partitionsList []int, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+
+	connection, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer connection.Release()
+
+	tx, err := connection.Conn().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+
+	query := `UPDATE stations SET partitions = $2, updated_at = $3 WHERE id = $1 AND is_deleted = false AND tenant_name = $4`
+	stmt, err := tx.Prepare(ctx, "update_station_partitions", query)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, stmt.Name, stationId, partitionsList, time.Now(), tenantName)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("station does not exist")
+	}
+
+	query = `UPDATE consumers SET partitions = $2 WHERE station_id = $1`
+	stmt, err = tx.Prepare(ctx, "update_consumers_partitions", query)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, stmt.Name, stationId, partitionsList)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE producers SET partitions = $2 WHERE station_id = $1`
+	stmt, err = tx.Prepare(ctx, "update_producers_partitions", query)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, stmt.Name, stationId, partitionsList)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func UpdateStationSchemaStrict(stationName string, schemaStrict bool, tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE stations SET schema_strict = $2 WHERE name = $1 AND is_deleted = false AND tenant_name = $3`
+	stmt, err := conn.Conn().Prepare(ctx, "update_station_schema_strict", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, schemaStrict, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func IncrementStationSchemaMetrics(stationId int, tenantName string, validMessages, invalidMessages int64) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `INSERT INTO station_schema_metrics (station_id, tenant_name, valid_messages, invalid_messages, updated_at) VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT (station_id) DO UPDATE SET valid_messages = station_schema_metrics.valid_messages + EXCLUDED.valid_messages,
+	invalid_messages = station_schema_metrics.invalid_messages + EXCLUDED.invalid_messages, updated_at = EXCLUDED.updated_at`
+	stmt, err := conn.Conn().Prepare(ctx, "increment_station_schema_metrics", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, stationId, tenantName, validMessages, invalidMessages, time.Now())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func GetStationSchemaMetrics(stationId int) (models.StationSchemaMetrics, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return models.StationSchemaMetrics{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM station_schema_metrics WHERE station_id = $1 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_station_schema_metrics", query)
+	if err != nil {
+		return models.StationSchemaMetrics{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, stationId)
+	if err != nil {
+		return models.StationSchemaMetrics{}, err
+	}
+	defer rows.Close()
+	metrics, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.StationSchemaMetrics])
+	if err != nil {
+		return models.StationSchemaMetrics{}, err
+	}
+	if len(metrics) == 0 {
+		return models.StationSchemaMetrics{StationId: stationId}, nil
+	}
+	return metrics[0], nil
+}
+
+func RemoveStationSchemaMetricsByTenant(tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	tenantName = strings.ToLower(tenantName)
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	query := `DELETE FROM station_schema_metrics WHERE tenant_name = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_station_schema_metrics_by_tenant", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Query(ctx, stmt.Name, tenantName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func UpsertDlsRetryPolicy(stationId int, tenantName string, maxAttempts int, initialDelayMs int64, backoffMultiplier float64, consumersGroups []string) (models.DlsRetryPolicy, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return models.DlsRetryPolicy{}, err
+	}
+	defer conn.Release()
+	query := `INSERT INTO dls_retry_policies (station_id, tenant_name, max_attempts, initial_delay_ms, backoff_multiplier, consumers_groups, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (station_id) DO UPDATE SET max_attempts = EXCLUDED.max_attempts, initial_delay_ms = EXCLUDED.initial_delay_ms,
+	backoff_multiplier = EXCLUDED.backoff_multiplier, consumers_groups = EXCLUDED.consumers_groups, updated_at = EXCLUDED.updated_at
+	RETURNING *`
+	stmt, err := conn.Conn().Prepare(ctx, "upsert_dls_retry_policy", query)
+	if err != nil {
+		return models.DlsRetryPolicy{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	if consumersGroups == nil {
+		consumersGroups = []string{}
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, stationId, tenantName, maxAttempts, initialDelayMs, backoffMultiplier, consumersGroups, time.Now())
+	if err != nil {
+		return models.DlsRetryPolicy{}, err
+	}
+	defer rows.Close()
+	policies, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsRetryPolicy])
+	if err != nil {
+		return models.DlsRetryPolicy{}, err
+	}
+	if len(policies) == 0 {
+		return models.DlsRetryPolicy{}, errors.New("dls retry policy was not stored")
+	}
+	return policies[0], nil
+}
+
+func GetDlsRetryPolicy(stationId int) (bool, models.DlsRetryPolicy, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.DlsRetryPolicy{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM dls_retry_policies WHERE station_id = $1 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_dls_retry_policy", query)
+	if err != nil {
+		return false, models.DlsRetryPolicy{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, stationId)
+	if err != nil {
+		return false, models.DlsRetryPolicy{}, err
+	}
+	defer rows.Close()
+	policies, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsRetryPolicy])
+	if err != nil {
+		return false, models.DlsRetryPolicy{}, err
+	}
+	if len(policies) == 0 {
+		return false, models.DlsRetryPolicy{}, nil
+	}
+	return true, policies[0], nil
+}
+
+func RemoveDlsRetryPolicy(stationId int) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `DELETE FROM dls_retry_policies WHERE station_id = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_dls_retry_policy", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, stationId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func UpsertTieredStoragePolicy(policy models.TieredStoragePolicy) (models.TieredStoragePolicy, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return models.TieredStoragePolicy{}, err
+	}
+	defer conn.Release()
+	query := `INSERT INTO tiered_storage_policies (station_id, tenant_name, integration, key_prefix, max_batch_messages, max_batch_bytes, max_upload_latency_sec, redaction, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (station_id) DO UPDATE SET integration = EXCLUDED.integration, key_prefix = EXCLUDED.key_prefix, max_batch_messages = EXCLUDED.max_batch_messages,
+	max_batch_bytes = EXCLUDED.max_batch_bytes, max_upload_latency_sec = EXCLUDED.max_upload_latency_sec, redaction = EXCLUDED.redaction, updated_at = EXCLUDED.updated_at
+	RETURNING *`
+	stmt, err := conn.Conn().Prepare(ctx, "upsert_tiered_storage_policy", query)
+	if err != nil {
+		return models.TieredStoragePolicy{}, err
+	}
+	tenantName := policy.TenantName
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, policy.StationId, tenantName, policy.Integration, policy.KeyPrefix, policy.MaxBatchMessages, policy.MaxBatchBytes, policy.MaxUploadLatencySec, policy.Redaction, time.Now())
+	if err != nil {
+		return models.TieredStoragePolicy{}, err
+	}
+	defer rows.Close()
+	policies, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.TieredStoragePolicy])
+	if err != nil {
+		return models.TieredStoragePolicy{}, err
+	}
+	if len(policies) == 0 {
+		return models.TieredStoragePolicy{}, errors.New("tiered storage policy was not stored")
+	}
+	return policies[0], nil
+}
+
+func GetTieredStoragePolicy(stationId int) (bool, models.TieredStoragePolicy, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.TieredStoragePolicy{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM tiered_storage_policies WHERE station_id = $1 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_tiered_storage_policy", query)
+	if err != nil {
+		return false, models.TieredStoragePolicy{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, stationId)
+	if err != nil {
+		return false, models.TieredStoragePolicy{}, err
+	}
+	defer rows.Close()
+	policies, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.TieredStoragePolicy])
+	if err != nil {
+		return false, models.TieredStoragePolicy{}, err
+	}
+	if len(policies) == 0 {
+		return false, models.TieredStoragePolicy{}, nil
+	}
+	return true, policies[0], nil
+}
+
+func RemoveTieredStoragePolicy(stationId int) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `DELETE FROM tiered_storage_policies WHERE station_id = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_tiered_storage_policy", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, stationId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func UpsertDlsMessageRetry(dlsMessageId int, cgName string, stationId int, tenantName string, nextRetryAt time.Time) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	// a consumer group which gets poisoned again by the same message starts a new round, attempts and history are kept
+	// so max attempts bounds the redeliveries of a message to a consumer group across rounds
+	query := `INSERT INTO dls_message_retries (dls_message_id, cg_name, station_id, tenant_name, next_retry_at, updated_at) VALUES($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (dls_message_id, cg_name) DO UPDATE SET status = 'pending', next_retry_at = EXCLUDED.next_retry_at, updated_at = EXCLUDED.updated_at
+	WHERE dls_message_retries.status <> 'pending'`
+	stmt, err := conn.Conn().Prepare(ctx, "upsert_dls_message_retry", query)
+	if err != nil {
+		return err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, dlsMessageId, cgName, stationId, tenantName, nextRetryAt, time.Now())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClaimDueDlsMessageRetries returns pending retries which are due and pushes their next_retry_at by lease,
+// so a retry is handled by a single broker even when several brokers poll concurrently
+func ClaimDueDlsMessageRetries(limit int, lease time.Duration) ([]models.DlsMessageRetry, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	defer conn.Release()
+	query := `UPDATE dls_message_retries SET next_retry_at = $2 WHERE (dls_message_id, cg_name) IN (
+		SELECT dls_message_id, cg_name FROM dls_message_retries WHERE status = 'pending' AND next_retry_at <= $1
+		ORDER BY next_retry_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+	RETURNING *`
+	stmt, err := conn.Conn().Prepare(ctx, "claim_due_dls_message_retries", query)
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	now := time.Now()
+	rows, err := conn.Conn().Query(ctx, stmt.Name, now, now.Add(lease), limit)
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	defer rows.Close()
+	retries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsMessageRetry])
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	return retries, nil
 }
 
-func DeleteStationsByNames(stationNames []string, tenantName string) error {
+func UpdateDlsMessageRetry(retry models.DlsMessageRetry) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
@@ -2043,71 +2750,115 @@ func DeleteStationsByNames(stationNames []string, tenantName string) error {
 		return err
 	}
 	defer conn.Release()
-	query := `DELETE FROM stations
-	WHERE name = ANY($1)
-	AND (is_deleted = false)
-	AND tenant_name=$2`
-	stmt, err := conn.Conn().Prepare(ctx, "delete_stations_by_names", query)
+	query := `UPDATE dls_message_retries SET attempts = $3, status = $4, next_retry_at = $5, history = $6, updated_at = $7, ack_deadline = $8 WHERE dls_message_id = $1 AND cg_name = $2`
+	stmt, err := conn.Conn().Prepare(ctx, "update_dls_message_retry", query)
 	if err != nil {
 		return err
 	}
-	if tenantName != conf.GlobalAccount {
-		tenantName = strings.ToLower(tenantName)
+	if retry.History == nil {
+		retry.History = []models.DlsRetryAttempt{}
 	}
-	_, err = conn.Conn().Exec(ctx, stmt.Name, stationNames, tenantName)
+	_, err = conn.Conn().Exec(ctx, stmt.Name, retry.DlsMessageId, retry.CgName, retry.Attempts, retry.Status, retry.NextRetryAt, retry.History, time.Now(), retry.AckDeadline)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func RemoveDeletedStations() error {
+func GetDlsMessageRetry(dlsMessageId int, cgName string) (bool, models.DlsMessageRetry, error) {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
 	if err != nil {
-		return err
+		return false, models.DlsMessageRetry{}, err
 	}
 	defer conn.Release()
-	query := `DELETE FROM stations WHERE is_deleted = true`
-	stmt, err := conn.Conn().Prepare(ctx, "remove_deleted_stations", query)
+	query := `SELECT * FROM dls_message_retries WHERE dls_message_id = $1 AND cg_name = $2 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_dls_message_retry", query)
 	if err != nil {
-		return err
+		return false, models.DlsMessageRetry{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, dlsMessageId, cgName)
+	if err != nil {
+		return false, models.DlsMessageRetry{}, err
 	}
+	defer rows.Close()
+	retries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsMessageRetry])
+	if err != nil {
+		return false, models.DlsMessageRetry{}, err
+	}
+	if len(retries) == 0 {
+		return false, models.DlsMessageRetry{}, nil
+	}
+	return true, retries[0], nil
+}
 
-	_, err = conn.Conn().Query(ctx, stmt.Name)
+func GetDlsMessageRetries(dlsMessageId int) ([]models.DlsMessageRetry, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
 	if err != nil {
-		return err
+		return []models.DlsMessageRetry{}, err
 	}
-	return nil
+	defer conn.Release()
+	query := `SELECT * FROM dls_message_retries WHERE dls_message_id = $1 ORDER BY cg_name`
+	stmt, err := conn.Conn().Prepare(ctx, "get_dls_message_retries", query)
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, dlsMessageId)
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	defer rows.Close()
+	retries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsMessageRetry])
+	if err != nil {
+		return []models.DlsMessageRetry{}, err
+	}
+	return retries, nil
 }
 
-func DeleteStation(name string, tenantName string) error {
+// Alert Rules Functions
+const alertRulesSelect = `SELECT a.*, COALESCE(s.name, '') FROM alert_rules AS a LEFT JOIN stations AS s ON a.station_id = s.id`
+
+// InsertAlertRule returns 0 rows updated when a rule with the same name already exists
+func InsertAlertRule(name, tenantName, ruleType string, stationId *int, consumersGroup, operator string, threshold float64, durationSec int, integrations []string, userId int, username string) (models.AlertRule, int64, error) {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
 	if err != nil {
-		return err
+		return models.AlertRule{}, 0, err
 	}
 	defer conn.Release()
-	query := `DELETE FROM stations
-	WHERE name = $1
-	AND tenant_name=$2`
-	stmt, err := conn.Conn().Prepare(ctx, "delete_station", query)
+	query := `INSERT INTO alert_rules (name, tenant_name, rule_type, station_id, consumers_group, operator, threshold, duration_sec, integrations, next_evaluation_at, created_by, created_by_username, created_at, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $10, $10)
+	ON CONFLICT (name, tenant_name) DO NOTHING RETURNING id`
+	stmt, err := conn.Conn().Prepare(ctx, "insert_alert_rule", query)
 	if err != nil {
-		return err
+		return models.AlertRule{}, 0, err
 	}
 	if tenantName != conf.GlobalAccount {
 		tenantName = strings.ToLower(tenantName)
 	}
-	_, err = conn.Conn().Query(ctx, stmt.Name, name, tenantName)
+	if integrations == nil {
+		integrations = []string{}
+	}
+	var id int
+	err = conn.Conn().QueryRow(ctx, stmt.Name, name, tenantName, ruleType, stationId, consumersGroup, operator, threshold, durationSec, integrations, time.Now(), userId, username).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return models.AlertRule{}, 0, nil
+	}
 	if err != nil {
-		return err
+		return models.AlertRule{}, 0, err
 	}
-	return nil
+	_, rule, err := GetAlertRuleById(id, tenantName)
+	if err != nil {
+		return models.AlertRule{}, 0, err
+	}
+	return rule, 1, nil
 }
 
-func AttachSchemaToStation(stationName string, schemaName string, versionNumber int, tenantName string) error {
+func UpdateAlertRule(id int, tenantName string, stationId *int, consumersGroup, operator string, threshold float64, durationSec int, integrations []string, enabled bool) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
@@ -2115,23 +2866,29 @@ func AttachSchemaToStation(stationName string, schemaName string, versionNumber
 		return err
 	}
 	defer conn.Release()
-	query := `UPDATE stations SET schema_name = $2, schema_version_number = $3
-	WHERE name = $1 AND is_deleted = false AND tenant_name=$4`
-	stmt, err := conn.Conn().Prepare(ctx, "attach_schema_to_station", query)
+	// a changed rule starts over from an inactive state, a firing rule gets resolved by its next evaluation
+	query := `UPDATE alert_rules SET station_id = $3, consumers_group = $4, operator = $5, threshold = $6, duration_sec = $7, integrations = $8, enabled = $9,
+	condition_since = NULL, next_evaluation_at = $10, updated_at = $10,
+	state = CASE WHEN state = 'firing' THEN state ELSE 'inactive' END
+	WHERE id = $1 AND tenant_name = $2`
+	stmt, err := conn.Conn().Prepare(ctx, "update_alert_rule", query)
 	if err != nil {
 		return err
 	}
 	if tenantName != conf.GlobalAccount {
 		tenantName = strings.ToLower(tenantName)
 	}
-	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, schemaName, versionNumber, tenantName)
+	if integrations == nil {
+		integrations = []string{}
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, id, tenantName, stationId, consumersGroup, operator, threshold, durationSec, integrations, enabled, time.Now())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DetachSchemaFromStation(stationName string, tenantName string) error {
+func RemoveAlertRule(id int, tenantName string) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
@@ -2139,23 +2896,22 @@ func DetachSchemaFromStation(stationName string, tenantName string) error {
 		return err
 	}
 	defer conn.Release()
-	query := `UPDATE stations SET schema_name = '', schema_version_number = 0
-	WHERE name = $1 AND is_deleted = false AND tenant_name=$2`
-	stmt, err := conn.Conn().Prepare(ctx, "detach_schema_from_station", query)
+	query := `DELETE FROM alert_rules WHERE id = $1 AND tenant_name = $2`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_alert_rule", query)
 	if err != nil {
 		return err
 	}
 	if tenantName != conf.GlobalAccount {
 		tenantName = strings.ToLower(tenantName)
 	}
-	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, tenantName)
+	_, err = conn.Conn().Exec(ctx, stmt.Name, id, tenantName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func UpdateStationDlsConfig(stationName string, poison bool, schemaverse bool, tenantName string) error {
+func DeleteAlertRulesByTenant(tenantName string) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
 	conn, err := MetadataDbClient.Client.Acquire(ctx)
@@ -2163,22 +2919,138 @@ func UpdateStationDlsConfig(stationName string, poison bool, schemaverse bool, t
 		return err
 	}
 	defer conn.Release()
-	query := `UPDATE stations SET dls_configuration_poison = $2, dls_configuration_schemaverse = $3
-	WHERE name = $1 AND is_deleted = false AND tenant_name=$4`
-	stmt, err := conn.Conn().Prepare(ctx, "update_station_dls_config", query)
+	query := `DELETE FROM alert_rules WHERE tenant_name = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_alert_rules_by_tenant", query)
 	if err != nil {
 		return err
 	}
 	if tenantName != conf.GlobalAccount {
 		tenantName = strings.ToLower(tenantName)
 	}
-	_, err = conn.Conn().Query(ctx, stmt.Name, stationName, poison, schemaverse, tenantName)
+	_, err = conn.Conn().Exec(ctx, stmt.Name, tenantName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func GetAlertRuleById(id int, tenantName string) (bool, models.AlertRule, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.AlertRule{}, err
+	}
+	defer conn.Release()
+	query := alertRulesSelect + ` WHERE a.id = $1 AND a.tenant_name = $2 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_alert_rule_by_id", query)
+	if err != nil {
+		return false, models.AlertRule{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, id, tenantName)
+	if err != nil {
+		return false, models.AlertRule{}, err
+	}
+	defer rows.Close()
+	rules, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.AlertRule])
+	if err != nil {
+		return false, models.AlertRule{}, err
+	}
+	if len(rules) == 0 {
+		return false, models.AlertRule{}, nil
+	}
+	return true, rules[0], nil
+}
+
+// GetAlertRules returns the alert rules of the tenant, a station id of 0 returns the rules of all the stations
+func GetAlertRules(tenantName string, stationId int) ([]models.AlertRule, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	defer conn.Release()
+	query := alertRulesSelect + ` WHERE a.tenant_name = $1 AND ($2 = 0 OR a.station_id = $2) ORDER BY a.id`
+	stmt, err := conn.Conn().Prepare(ctx, "get_alert_rules", query)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, tenantName, stationId)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	defer rows.Close()
+	rules, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.AlertRule])
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	if len(rules) == 0 {
+		return []models.AlertRule{}, nil
+	}
+	return rules, nil
+}
+
+// ClaimDueAlertRules leases the enabled rules which are due for evaluation so a rule is evaluated by a single broker at a time
+func ClaimDueAlertRules(limit int, lease time.Duration) ([]models.AlertRule, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	defer conn.Release()
+	query := `WITH claimed AS (
+		UPDATE alert_rules SET next_evaluation_at = $2 WHERE id IN (
+			SELECT id FROM alert_rules WHERE enabled = true AND next_evaluation_at <= $1
+			ORDER BY next_evaluation_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+		RETURNING *)
+	SELECT claimed.*, COALESCE(s.name, '') FROM claimed LEFT JOIN stations AS s ON claimed.station_id = s.id`
+	stmt, err := conn.Conn().Prepare(ctx, "claim_due_alert_rules", query)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	now := time.Now()
+	rows, err := conn.Conn().Query(ctx, stmt.Name, now, now.Add(lease), limit)
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	defer rows.Close()
+	rules, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.AlertRule])
+	if err != nil {
+		return []models.AlertRule{}, err
+	}
+	return rules, nil
+}
+
+// UpdateAlertRuleState stores the result of an evaluation, it does not override a rule which has been changed since it was claimed
+func UpdateAlertRuleState(rule models.AlertRule, nextEvaluationAt time.Time) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	query := `UPDATE alert_rules SET state = $2, last_value = $3, last_counter = $4, condition_since = $5, fired_at = $6, resolved_at = $7, last_evaluated_at = $8, next_evaluation_at = $9
+	WHERE id = $1 AND updated_at = $10`
+	stmt, err := conn.Conn().Prepare(ctx, "update_alert_rule_state", query)
+	if err != nil {
+		return false, err
+	}
+	res, err := conn.Conn().Exec(ctx, stmt.Name, rule.ID, rule.State, rule.LastValue, rule.LastCounter, rule.ConditionSince, rule.FiredAt, rule.ResolvedAt, rule.LastEvaluatedAt, nextEvaluationAt, rule.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
 func UpdateStationsOfDeletedUser(userId int, tenantName string) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -3672,6 +4544,37 @@ func GetSchemaByName(name string, tenantName string) (bool, models.Schema, error
 	return true, schemas[0], nil
 }
 
+func GetSchemaById(id int, tenantName string) (bool, models.Schema, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.Schema{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM schemas WHERE id = $1 AND tenant_name = $2 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_schema_by_id", query)
+	if err != nil {
+		return false, models.Schema{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, id, tenantName)
+	if err != nil {
+		return false, models.Schema{}, err
+	}
+	defer rows.Close()
+	schemas, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Schema])
+	if err != nil {
+		return false, models.Schema{}, err
+	}
+	if len(schemas) == 0 {
+		return false, models.Schema{}, nil
+	}
+	return true, schemas[0], nil
+}
+
 func GetSchemaVersionsBySchemaID(id int) ([]models.SchemaVersion, error) {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -3839,6 +4742,27 @@ func UpdateSchemaVersionsOfDeletedUser(userId int, tenantName string) error {
 	return nil
 }
 
+func RemoveSchemaReferencesByTenant(tenantName string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	query := `DELETE FROM schema_references WHERE tenant_name = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "remove_schema_references_by_tenant", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, tenantName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func RemoveSchemaVersionsByTenant(tenantName string) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -3901,6 +4825,50 @@ func GetSchemaVersionByNumberAndID(version int, schemaId int) (bool, models.Sche
 	return true, schemaVersion, nil
 }
 
+func GetSchemaVersionById(id int, tenantName string) (bool, models.SchemaVersion, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM schema_versions WHERE id = $1 AND tenant_name = $2 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_schema_version_by_id", query)
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, id, tenantName)
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	defer rows.Close()
+	schemas, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.SchemaVersionResponse])
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	if len(schemas) == 0 {
+		return false, models.SchemaVersion{}, nil
+	}
+	schemaVersion := models.SchemaVersion{
+		ID:                schemas[0].ID,
+		VersionNumber:     schemas[0].VersionNumber,
+		Active:            schemas[0].Active,
+		CreatedBy:         schemas[0].CreatedBy,
+		CreatedByUsername: schemas[0].CreatedByUsername,
+		CreatedAt:         schemas[0].CreatedAt,
+		SchemaContent:     schemas[0].SchemaContent,
+		SchemaId:          schemas[0].SchemaId,
+		MessageStructName: schemas[0].MessageStructName,
+		Descriptor:        string(schemas[0].Descriptor),
+		TenantName:        strings.ToLower(schemas[0].TenantName),
+	}
+	return true, schemaVersion, nil
+}
+
 func UpdateSchemaActiveVersion(schemaId int, versionNumber int) error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -4006,10 +4974,23 @@ func FindAndDeleteSchema(schemaIds []int) error {
 	}
 	defer conn.Release()
 
+	removeSchemaReferencesQuery := `DELETE FROM schema_references
+	WHERE schema_version_id IN (SELECT id FROM schema_versions WHERE schema_id = ANY($1))`
+
+	stmt, err := conn.Conn().Prepare(ctx, "remove_schema_references_of_schemas", removeSchemaReferencesQuery)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Conn().Exec(ctx, stmt.Name, schemaIds)
+	if err != nil {
+		return err
+	}
+
 	removeSchemaVersionsQuery := `DELETE FROM schema_versions
 	WHERE schema_id = ANY($1)`
 
-	stmt, err := conn.Conn().Prepare(ctx, "remove_schema_versions", removeSchemaVersionsQuery)
+	stmt, err = conn.Conn().Prepare(ctx, "remove_schema_versions", removeSchemaVersionsQuery)
 	if err != nil {
 		return err
 	}
@@ -4088,15 +5069,36 @@ func InsertNewSchema(schemaName string, schemaType string, createdByUsername str
 			return models.Schema{}, 0, err
 		}
 	}
-
-	rowsAffected := rows.CommandTag().RowsAffected()
-	newSchema := models.Schema{
-		ID:                schemaId,
-		Name:              schemaName,
-		Type:              schemaType,
-		CreatedByUsername: createdByUsername,
+
+	rowsAffected := rows.CommandTag().RowsAffected()
+	newSchema := models.Schema{
+		ID:                schemaId,
+		Name:              schemaName,
+		Type:              schemaType,
+		CreatedByUsername: createdByUsername,
+		CompatibilityMode: "none",
+	}
+	return newSchema, rowsAffected, nil
+}
+
+func UpdateSchemaCompatibilityMode(schemaId int, compatibilityMode string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE schemas SET compatibility_mode = $2 WHERE id = $1`
+	stmt, err := conn.Conn().Prepare(ctx, "update_schema_compatibility_mode", query)
+	if err != nil {
+		return err
 	}
-	return newSchema, rowsAffected, nil
+	_, err = conn.Conn().Exec(ctx, stmt.Name, schemaId, compatibilityMode)
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
 func InsertNewSchemaVersion(schemaVersionNumber int, userId int, username string, schemaContent string, schemaId int, messageStructName string, descriptor string, active bool, tenantName string) (models.SchemaVersion, int64, error) {
@@ -4178,6 +5180,166 @@ func InsertNewSchemaVersion(schemaVersionNumber int, userId int, username string
 	return newSchemaVersion, rowsAffected, nil
 }
 
+// InsertSchemaVersionWithReferences inserts a schema version together with its references in a single transaction,
+// the schema itself is inserted as well when schema.ID is 0, an active version deactivates the other versions of the schema,
+// inserted is false when the schema or version already exist
+func InsertSchemaVersionWithReferences(schema models.Schema, schemaVersionNumber int, userId int, username string, schemaContent string, messageStructName string, descriptor string, active bool, references []models.SchemaReference, tenantName string) (models.Schema, models.SchemaVersion, bool, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Conn().Begin(ctx)
+	if err != nil {
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+
+	if schema.ID == 0 {
+		query := `INSERT INTO schemas (name, type, created_by_username, tenant_name)
+		VALUES($1, $2, $3, $4) ON CONFLICT DO NOTHING RETURNING id`
+		stmt, err := tx.Prepare(ctx, "insert_new_schema_if_not_exists", query)
+		if err != nil {
+			return models.Schema{}, models.SchemaVersion{}, false, err
+		}
+		err = tx.QueryRow(ctx, stmt.Name, schema.Name, schema.Type, schema.CreatedByUsername, tenantName).Scan(&schema.ID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return models.Schema{}, models.SchemaVersion{}, false, nil
+			}
+			return models.Schema{}, models.SchemaVersion{}, false, err
+		}
+		schema.CompatibilityMode = "none"
+	} else if active {
+		query := `UPDATE schema_versions SET active = false WHERE schema_id = $1`
+		stmt, err := tx.Prepare(ctx, "deactivate_schema_versions", query)
+		if err != nil {
+			return models.Schema{}, models.SchemaVersion{}, false, err
+		}
+		_, err = tx.Exec(ctx, stmt.Name, schema.ID)
+		if err != nil {
+			return models.Schema{}, models.SchemaVersion{}, false, err
+		}
+	}
+
+	createdAt := time.Now()
+	newSchemaVersion := models.SchemaVersion{
+		VersionNumber:     schemaVersionNumber,
+		Active:            active,
+		CreatedBy:         userId,
+		CreatedByUsername: username,
+		CreatedAt:         createdAt,
+		SchemaContent:     schemaContent,
+		SchemaId:          schema.ID,
+		MessageStructName: messageStructName,
+		Descriptor:        descriptor,
+		References:        references,
+	}
+	query := `INSERT INTO schema_versions (version_number, active, created_by, created_by_username, created_at, schema_content, schema_id, msg_struct_name, descriptor, tenant_name)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT DO NOTHING RETURNING id`
+	stmt, err := tx.Prepare(ctx, "insert_new_schema_version_if_not_exists", query)
+	if err != nil {
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+	err = tx.QueryRow(ctx, stmt.Name, schemaVersionNumber, active, userId, username, createdAt, schemaContent, schema.ID, messageStructName, []byte(descriptor), tenantName).Scan(&newSchemaVersion.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Schema{}, models.SchemaVersion{}, false, nil
+		}
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+
+	query = `INSERT INTO schema_references (schema_version_id, name, referenced_schema_id, referenced_version_number, tenant_name)
+	SELECT $1, $2, s.id, $4, $5 FROM schemas AS s WHERE s.name = $3 AND s.tenant_name = $5`
+	stmt, err = tx.Prepare(ctx, "insert_schema_reference", query)
+	if err != nil {
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+	for _, ref := range references {
+		tag, err := tx.Exec(ctx, stmt.Name, newSchemaVersion.ID, ref.Name, ref.SchemaName, ref.VersionNumber, tenantName)
+		if err != nil {
+			return models.Schema{}, models.SchemaVersion{}, false, err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.Schema{}, models.SchemaVersion{}, false, fmt.Errorf("referenced schema %v does not exist", ref.SchemaName)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.Schema{}, models.SchemaVersion{}, false, err
+	}
+	return schema, newSchemaVersion, true, nil
+}
+
+func GetSchemaReferencesByVersionId(schemaVersionId int) ([]models.SchemaReference, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	defer conn.Release()
+	query := `SELECT r.name, s.name, r.referenced_version_number
+	          FROM schema_references AS r
+	          JOIN schemas AS s ON s.id = r.referenced_schema_id
+	          WHERE r.schema_version_id = $1
+	          ORDER BY r.id`
+	stmt, err := conn.Conn().Prepare(ctx, "get_schema_references_by_version_id", query)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, schemaVersionId)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	defer rows.Close()
+	references, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.SchemaReference])
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	return references, nil
+}
+
+// GetSchemaReferencingVersions returns the versions of other schemas which reference any version of the given schema,
+// each reference holds the referencing schema name and version number
+func GetSchemaReferencingVersions(schemaId int) ([]models.SchemaReference, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	defer conn.Release()
+	query := `SELECT r.name, s.name, sv.version_number
+	          FROM schema_references AS r
+	          JOIN schema_versions AS sv ON sv.id = r.schema_version_id
+	          JOIN schemas AS s ON s.id = sv.schema_id
+	          WHERE r.referenced_schema_id = $1 AND sv.schema_id <> $1
+	          ORDER BY s.name, sv.version_number`
+	stmt, err := conn.Conn().Prepare(ctx, "get_schema_referencing_versions", query)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, schemaId)
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	defer rows.Close()
+	references, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.SchemaReference])
+	if err != nil {
+		return []models.SchemaReference{}, err
+	}
+	return references, nil
+}
+
 func CountAllSchemasByTenant(tenantName string) (int64, error) {
 	var count int64
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
@@ -6469,6 +7631,70 @@ func GetDlsMsgsByStationAndPartition(stationId, partitionNumber int) ([]models.D
 	return dlsMsgs, nil
 }
 
+const dlsMsgsFilterCondition = `station_id = $1
+	AND ($2::varchar = '' OR message_type = $2)
+	AND ($3::varchar = '' OR $3 = ANY(poisoned_cgs))
+	AND ($4::timestamptz IS NULL OR updated_at >= $4)
+	AND ($5::timestamptz IS NULL OR updated_at <= $5)
+	AND ($6::jsonb = '{}'::jsonb OR (message_details->'headers')::jsonb @> $6::jsonb)`
+
+func dlsMsgsFilterArgs(stationId int, filter models.DlsMessagesFilter) []interface{} {
+	headers := filter.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	return []interface{}{stationId, filter.DlsMsgType, filter.ConsumersGroup, filter.FromTime, filter.ToTime, headers}
+}
+
+// CountDlsMsgsByFilter returns the number of messages matching the filter and the highest id among them
+func CountDlsMsgsByFilter(stationId int, filter models.DlsMessagesFilter) (int, int, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Release()
+	query := `SELECT COUNT(*), COALESCE(MAX(id), 0) FROM dls_messages WHERE ` + dlsMsgsFilterCondition
+	stmt, err := conn.Conn().Prepare(ctx, "count_dls_msgs_by_filter", query)
+	if err != nil {
+		return 0, 0, err
+	}
+	var count, maxId int
+	err = conn.Conn().QueryRow(ctx, stmt.Name, dlsMsgsFilterArgs(stationId, filter)...).Scan(&count, &maxId)
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, maxId, nil
+}
+
+// GetDlsMsgsBatchByFilter returns up to limit messages matching the filter whose id is in (afterId, maxId], ordered by id
+func GetDlsMsgsBatchByFilter(stationId int, filter models.DlsMessagesFilter, afterId, maxId, limit int) ([]models.DlsMessage, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return []models.DlsMessage{}, err
+	}
+	defer conn.Release()
+	query := `SELECT * FROM dls_messages WHERE ` + dlsMsgsFilterCondition + ` AND id > $7 AND id <= $8 ORDER BY id ASC LIMIT $9`
+	stmt, err := conn.Conn().Prepare(ctx, "get_dls_msgs_batch_by_filter", query)
+	if err != nil {
+		return []models.DlsMessage{}, err
+	}
+	args := append(dlsMsgsFilterArgs(stationId, filter), afterId, maxId, limit)
+	rows, err := conn.Conn().Query(ctx, stmt.Name, args...)
+	if err != nil {
+		return []models.DlsMessage{}, err
+	}
+	defer rows.Close()
+	dlsMsgs, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.DlsMessage])
+	if err != nil {
+		return []models.DlsMessage{}, err
+	}
+	return dlsMsgs, nil
+}
+
 // Tenants functions
 func UpsertTenant(name string, encryptrdInternalWSPass string) (models.Tenant, error) {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
@@ -7320,6 +8546,74 @@ func UpsertAsyncTask(task, brokerInCharge string, createdAt time.Time, tenantNam
 	return asyncTask, nil
 }
 
+// CreateAsyncTaskIfNotRunning creates the task unless the same task is already running on the station,
+// the station row is locked first so concurrent requests can not both create the task
+func CreateAsyncTaskIfNotRunning(task, brokerInCharge string, createdAt time.Time, tenantName string, stationId int, username string) (bool, models.AsyncTask, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Conn().Begin(ctx)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id FROM stations WHERE id = $1 FOR UPDATE`
+	stmt, err := tx.Prepare(ctx, "lock_station_for_async_task", query)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	var id int
+	err = tx.QueryRow(ctx, stmt.Name, stationId).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return false, models.AsyncTask{}, errors.New("station does not exist")
+	}
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+
+	query = `INSERT INTO async_tasks (name, broker_in_charge, created_at, updated_at, tenant_name, station_id, created_by, status)
+	SELECT $1::VARCHAR, $2::VARCHAR, $3::TIMESTAMPTZ, $3::TIMESTAMPTZ, $4::VARCHAR, $5::INT, $6::VARCHAR, 'running'
+	WHERE NOT EXISTS (SELECT 1 FROM async_tasks WHERE name = $1 AND tenant_name = $4 AND station_id = $5 AND status = 'running')
+	RETURNING *`
+	stmt, err = tx.Prepare(ctx, "create_async_task_if_not_running", query)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	var asyncTask models.AsyncTask
+	err = tx.QueryRow(ctx, stmt.Name, task, brokerInCharge, createdAt, tenantName, stationId, username).Scan(
+		&asyncTask.ID,
+		&asyncTask.Name,
+		&asyncTask.BrokrInCharge,
+		&asyncTask.CreatedAt,
+		&asyncTask.UpdatedAt,
+		&asyncTask.Data,
+		&asyncTask.TenantName,
+		&asyncTask.StationId,
+		&asyncTask.CreatedBy,
+		&asyncTask.Status,
+		&asyncTask.FailureReason,
+	)
+	if err == pgx.ErrNoRows {
+		return false, models.AsyncTask{}, nil
+	}
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	return true, asyncTask, nil
+}
+
 func GetAsyncTasksByName(task string) (bool, []models.AsyncTask, error) {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -7533,6 +8827,103 @@ func UpdateStatusAsyncTask(task, tenantName, status string, stationId int, failu
 	return nil
 }
 
+func GetAsyncTaskById(id int, tenantName string) (bool, models.AsyncTask, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM async_tasks WHERE id = $1 AND tenant_name = $2 LIMIT 1`
+	stmt, err := conn.Conn().Prepare(ctx, "get_async_task_by_id", query)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	rows, err := conn.Conn().Query(ctx, stmt.Name, id, tenantName)
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	defer rows.Close()
+	asyncTasks, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.AsyncTask])
+	if err != nil {
+		return false, models.AsyncTask{}, err
+	}
+	if len(asyncTasks) == 0 {
+		return false, models.AsyncTask{}, nil
+	}
+	return true, asyncTasks[0], nil
+}
+
+func UpdateAsyncTaskById(id int, updatedAt time.Time, metaData interface{}) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE async_tasks SET updated_at = $1, meta_data = $2 WHERE id = $3`
+	stmt, err := conn.Conn().Prepare(ctx, "update_async_task_by_id", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, updatedAt, metaData, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FinishAsyncTaskById sets the final status of a running task, a task which has been cancelled meanwhile keeps its status
+func FinishAsyncTaskById(id int, status, failureReason string) error {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	query := `UPDATE async_tasks SET status = $1, failure_reason = $2, updated_at = $3 WHERE id = $4 AND status = 'running'`
+	stmt, err := conn.Conn().Prepare(ctx, "finish_async_task_by_id", query)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Conn().Exec(ctx, stmt.Name, status, failureReason, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func CancelAsyncTask(id int, tenantName string) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+	defer cancelfunc()
+	conn, err := MetadataDbClient.Client.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	query := `UPDATE async_tasks SET status = 'cancelled', updated_at = $1 WHERE id = $2 AND tenant_name = $3 AND status = 'running'`
+	stmt, err := conn.Conn().Prepare(ctx, "cancel_async_task", query)
+	if err != nil {
+		return false, err
+	}
+	if tenantName != conf.GlobalAccount {
+		tenantName = strings.ToLower(tenantName)
+	}
+	tag, err := conn.Conn().Exec(ctx, stmt.Name, time.Now(), id, tenantName)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func RemoveOldAsyncTasks() error {
 	ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
 	defer cancelfunc()
@@ -0,0 +1,249 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+// setupTestTenant connects to the configured metadata db and creates a tenant which is removed with everything it owns
+// at the end of the test, the test is skipped when the metadata db is not available
+func setupTestTenant(t *testing.T) string {
+	t.Helper()
+	if _, err := InitalizeMetadataDbConnection(); err != nil {
+		t.Skipf("metadata db is not available: %v", err)
+	}
+	tenantName := fmt.Sprintf("test-%v", time.Now().UnixNano())
+	if _, err := UpsertTenant(tenantName, ""); err != nil {
+		t.Fatalf("UpsertTenant: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancelfunc := context.WithTimeout(context.Background(), DbOperationTimeout*time.Second)
+		defer cancelfunc()
+		MetadataDbClient.Client.Exec(ctx, `DELETE FROM async_tasks WHERE tenant_name = $1`, tenantName)
+		DeleteDlsMsgsByTenant(tenantName)
+		DeleteAlertRulesByTenant(tenantName)
+		RemoveProducersByTenant(tenantName)
+		RemoveConsumersByTenant(tenantName)
+		RemoveStationsByTenant(tenantName)
+		RemoveTenant(tenantName)
+	})
+	return tenantName
+}
+
+func insertTestStation(t *testing.T, tenantName string, partitionsList []int) models.Station {
+	t.Helper()
+	station, _, err := InsertNewStation("orders", 1, "root", "message_age_sec", 3600, "file", 1, "", 0, 120000, true, models.DlsConfiguration{}, false, tenantName, partitionsList, 2, "")
+	if err != nil {
+		t.Fatalf("InsertNewStation: %v", err)
+	}
+	return station
+}
+
+func TestUpdateStationPartitions(t *testing.T) {
+	tenantName := setupTestTenant(t)
+	station := insertTestStation(t, tenantName, []int{1, 2})
+	if _, err := InsertNewConsumer("consumer", station.ID, "application", "conn", "cg", 30000, 10, 1, -1, tenantName, []int{1, 2}, 2, "go", ""); err != nil {
+		t.Fatalf("InsertNewConsumer: %v", err)
+	}
+	if _, err := InsertNewProducer("producer", station.ID, "application", "conn", tenantName, []int{1, 2}, 2, "go", ""); err != nil {
+		t.Fatalf("InsertNewProducer: %v", err)
+	}
+
+	partitionsList := []int{1, 2, 3, 4}
+	if err := UpdateStationPartitions(station.ID, partitionsList, tenantName); err != nil {
+		t.Fatalf("UpdateStationPartitions: %v", err)
+	}
+
+	_, updated, err := GetStationById(station.ID, tenantName)
+	if err != nil {
+		t.Fatalf("GetStationById: %v", err)
+	}
+	if fmt.Sprint(updated.PartitionsList) != fmt.Sprint(partitionsList) {
+		t.Errorf("expected the station partitions to be %v, got %v", partitionsList, updated.PartitionsList)
+	}
+	consumers, err := GetAllConsumersByStation(station.ID)
+	if err != nil {
+		t.Fatalf("GetAllConsumersByStation: %v", err)
+	}
+	for _, consumer := range consumers {
+		if fmt.Sprint(consumer.PartitionsList) != fmt.Sprint(partitionsList) {
+			t.Errorf("expected the partitions of consumer %v to be %v, got %v", consumer.Name, partitionsList, consumer.PartitionsList)
+		}
+	}
+	_, producer, err := GetProducerByNameAndStationID("producer", station.ID)
+	if err != nil {
+		t.Fatalf("GetProducerByNameAndStationID: %v", err)
+	}
+	if fmt.Sprint(producer.PartitionsList) != fmt.Sprint(partitionsList) {
+		t.Errorf("expected the partitions of the producer to be %v, got %v", partitionsList, producer.PartitionsList)
+	}
+
+	if err := UpdateStationPartitions(station.ID+1000000, partitionsList, tenantName); err == nil {
+		t.Errorf("expected an error for a station which does not exist")
+	}
+}
+
+func TestCreateAsyncTaskIfNotRunning(t *testing.T) {
+	tenantName := setupTestTenant(t)
+	station := insertTestStation(t, tenantName, []int{1})
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var createdTasks []models.AsyncTask
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, task, err := CreateAsyncTaskIfNotRunning("add_partitions", "memphis-0", time.Now(), tenantName, station.ID, "root")
+			if err != nil {
+				t.Errorf("CreateAsyncTaskIfNotRunning: %v", err)
+				return
+			}
+			if created {
+				lock.Lock()
+				createdTasks = append(createdTasks, task)
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(createdTasks) != 1 {
+		t.Fatalf("expected exactly one concurrent request to create the task, %v did", len(createdTasks))
+	}
+	if createdTasks[0].Status != "running" || createdTasks[0].StationId != station.ID {
+		t.Errorf("unexpected task %+v", createdTasks[0])
+	}
+
+	created, _, err := CreateAsyncTaskIfNotRunning("dls_bulk_drop", "memphis-0", time.Now(), tenantName, station.ID, "root")
+	if err != nil || !created {
+		t.Errorf("expected a different task to be created on the station, got %v %v", created, err)
+	}
+
+	if err := FinishAsyncTaskById(createdTasks[0].ID, "completed", ""); err != nil {
+		t.Fatalf("FinishAsyncTaskById: %v", err)
+	}
+	created, _, err = CreateAsyncTaskIfNotRunning("add_partitions", "memphis-0", time.Now(), tenantName, station.ID, "root")
+	if err != nil || !created {
+		t.Errorf("expected the task to be created again after it completed, got %v %v", created, err)
+	}
+
+	if _, _, err := CreateAsyncTaskIfNotRunning("add_partitions", "memphis-0", time.Now(), tenantName, station.ID+1000000, "root"); err == nil {
+		t.Errorf("expected an error for a station which does not exist")
+	}
+}
+
+func TestDlsMsgsByFilter(t *testing.T) {
+	tenantName := setupTestTenant(t)
+	station := insertTestStation(t, tenantName, []int{1})
+	messages := []struct {
+		cg      string
+		headers map[string]string
+	}{
+		{cg: "billing", headers: map[string]string{"tenant": "acme"}},
+		{cg: "billing", headers: map[string]string{"tenant": "globex"}},
+		{cg: "shipping", headers: map[string]string{"tenant": "acme", "region": "eu"}},
+	}
+	for i, m := range messages {
+		payload := models.MessagePayload{TimeSent: time.Now(), Data: "data", Headers: m.headers}
+		if _, _, err := StorePoisonMsg(station.ID, i+1, m.cg, "producer", []string{m.cg}, payload, tenantName, 1, ""); err != nil {
+			t.Fatalf("StorePoisonMsg: %v", err)
+		}
+	}
+
+	future := time.Now().Add(time.Hour)
+	cases := []struct {
+		name     string
+		filter   models.DlsMessagesFilter
+		expected int
+	}{
+		{name: "no filter", filter: models.DlsMessagesFilter{}, expected: 3},
+		{name: "type", filter: models.DlsMessagesFilter{DlsMsgType: "poison"}, expected: 3},
+		{name: "other type", filter: models.DlsMessagesFilter{DlsMsgType: "schema"}, expected: 0},
+		{name: "consumer group", filter: models.DlsMessagesFilter{ConsumersGroup: "billing"}, expected: 2},
+		{name: "header", filter: models.DlsMessagesFilter{Headers: map[string]string{"tenant": "acme"}}, expected: 2},
+		{name: "headers", filter: models.DlsMessagesFilter{Headers: map[string]string{"tenant": "acme", "region": "eu"}}, expected: 1},
+		{name: "consumer group and header", filter: models.DlsMessagesFilter{ConsumersGroup: "billing", Headers: map[string]string{"tenant": "acme"}}, expected: 1},
+		{name: "from time", filter: models.DlsMessagesFilter{FromTime: &future}, expected: 0},
+		{name: "to time", filter: models.DlsMessagesFilter{ToTime: &future}, expected: 3},
+	}
+	for _, c := range cases {
+		count, maxId, err := CountDlsMsgsByFilter(station.ID, c.filter)
+		if err != nil {
+			t.Fatalf("%v: CountDlsMsgsByFilter: %v", c.name, err)
+		}
+		if count != c.expected {
+			t.Errorf("%v: expected %v messages, got %v", c.name, c.expected, count)
+		}
+
+		// walking the batches returns every counted message exactly once
+		seen := 0
+		afterId := 0
+		for {
+			batch, err := GetDlsMsgsBatchByFilter(station.ID, c.filter, afterId, maxId, 2)
+			if err != nil {
+				t.Fatalf("%v: GetDlsMsgsBatchByFilter: %v", c.name, err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			seen += len(batch)
+			afterId = batch[len(batch)-1].ID
+		}
+		if seen != c.expected {
+			t.Errorf("%v: expected the batches to return %v messages, got %v", c.name, c.expected, seen)
+		}
+	}
+}
+
+func TestDeleteAlertRulesByTenant(t *testing.T) {
+	tenantName := setupTestTenant(t)
+	station := insertTestStation(t, tenantName, []int{1})
+
+	_, _, err := InsertAlertRule("dls", tenantName, "dls_count", &station.ID, "", ">", 10, 60, []string{}, 1, "root")
+	if err != nil {
+		t.Fatalf("InsertAlertRule: %v", err)
+	}
+	// storage usage rules are not attached to a station so only the tenant references them
+	_, _, err = InsertAlertRule("storage", tenantName, "storage_usage", nil, "", ">", 80, 60, []string{}, 1, "root")
+	if err != nil {
+		t.Fatalf("InsertAlertRule: %v", err)
+	}
+
+	err = DeleteAlertRulesByTenant(tenantName)
+	if err != nil {
+		t.Fatalf("DeleteAlertRulesByTenant: %v", err)
+	}
+	rules, err := GetAlertRules(tenantName, 0)
+	if err != nil {
+		t.Fatalf("GetAlertRules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("expected all the tenant's alert rules to be deleted, got %v", len(rules))
+	}
+
+	err = RemoveStationsByTenant(tenantName)
+	if err != nil {
+		t.Fatalf("RemoveStationsByTenant: %v", err)
+	}
+	err = RemoveTenant(tenantName)
+	if err != nil {
+		t.Fatalf("expected the tenant to be removed once its alert rules are deleted: %v", err)
+	}
+}
@@ -21,4 +21,5 @@ func InitializeAsyncTasksRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	asyncTasksHandler := h.AsyncTasks
 	asyncTasksRoutes := router.Group("/asyncTasks")
 	asyncTasksRoutes.GET("/getAsyncTasks", asyncTasksHandler.GetAsyncTasks)
+	asyncTasksRoutes.POST("/cancelAsyncTask", asyncTasksHandler.CancelAsyncTask)
 }
@@ -23,6 +23,7 @@ func InitializeMonitoringRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	monitoringRoutes.GET("/getClusterInfo", monitoringHandler.GetClusterInfo)
 	monitoringRoutes.GET("/getMainOverviewData", monitoringHandler.GetMainOverviewData)
 	monitoringRoutes.GET("/getStationOverviewData", monitoringHandler.GetStationOverviewData)
+	monitoringRoutes.GET("/getCgsLagHistory", monitoringHandler.GetCgsLagHistory)
 	monitoringRoutes.GET("/getSystemLogs", monitoringHandler.GetSystemLogs)
 	monitoringRoutes.GET("/downloadSystemLogs", monitoringHandler.DownloadSystemLogs)
 	monitoringRoutes.GET("/getAvailableReplicas", monitoringHandler.GetAvailableReplicas)
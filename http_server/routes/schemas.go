@@ -27,4 +27,6 @@ func InitializeSchemasRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	schemasRoutes.POST("/createNewVersion", schemasHandler.CreateNewVersion)
 	schemasRoutes.PUT("/rollBackVersion", schemasHandler.RollBackVersion)
 	schemasRoutes.POST("/validateSchema", schemasHandler.ValidateSchema)
+	schemasRoutes.PUT("/updateCompatibilityMode", schemasHandler.UpdateCompatibilityMode)
+	schemasRoutes.POST("/checkCompatibility", schemasHandler.CheckCompatibility)
 }
@@ -32,10 +32,27 @@ func InitializeStationsRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	stationsRoutes.DELETE("/removeSchemaFromStation", stationsHandler.RemoveSchemaFromStation)
 	stationsRoutes.GET("/getUpdatesForSchemaByStation", stationsHandler.GetUpdatesForSchemaByStation)
 	stationsRoutes.PUT("/updateDlsConfig", stationsHandler.UpdateDlsConfig)
+	stationsRoutes.GET("/getDlsRetryPolicy", stationsHandler.GetDlsRetryPolicy)
+	stationsRoutes.PUT("/updateDlsRetryPolicy", stationsHandler.UpdateDlsRetryPolicy)
+	stationsRoutes.DELETE("/removeDlsRetryPolicy", stationsHandler.RemoveDlsRetryPolicy)
+	stationsRoutes.GET("/getTieredStoragePolicy", stationsHandler.GetTieredStoragePolicy)
+	stationsRoutes.PUT("/updateTieredStoragePolicy", stationsHandler.UpdateTieredStoragePolicy)
+	stationsRoutes.DELETE("/removeTieredStoragePolicy", stationsHandler.RemoveTieredStoragePolicy)
+	stationsRoutes.PUT("/updateSchemaStrictMode", stationsHandler.UpdateSchemaStrictMode)
+	stationsRoutes.PUT("/updateStation", stationsHandler.UpdateStation)
+	stationsRoutes.POST("/addPartitions", stationsHandler.AddPartitions)
 	stationsRoutes.POST("/dropDlsMessages", stationsHandler.DropDlsMessages)
+	stationsRoutes.POST("/bulkDlsOperation", stationsHandler.BulkDlsOperation)
+	stationsRoutes.GET("/downloadDlsExport", stationsHandler.DownloadDlsExport)
 	stationsRoutes.DELETE("/purgeStation", stationsHandler.PurgeStation)
 	stationsRoutes.DELETE("/removeMessages", stationsHandler.RemoveMessages)
 	stationsRoutes.POST("/produce", stationsHandler.Produce)
+	stationsRoutes.POST("/searchMessages", stationsHandler.SearchMessages)
+	stationsRoutes.POST("/exportStationMessages", stationsHandler.ExportStationMessages)
+	stationsRoutes.POST("/importStationMessages", stationsHandler.ImportStationMessages)
+	stationsRoutes.GET("/listTieredStorageObjects", stationsHandler.ListTieredStorageObjects)
+	stationsRoutes.POST("/browseTieredStorage", stationsHandler.BrowseTieredStorage)
+	stationsRoutes.POST("/rehydrateFromTieredStorage", stationsHandler.RehydrateFromTieredStorage)
 	stationsRoutes.POST("/attachDlsStation", stationsHandler.AttachDlsStation)
 	stationsRoutes.DELETE("/detachDlsStation", stationsHandler.DetachDlsStation)
 	server.InitializeCloudStationRoutes(stationsHandler, stationsRoutes)
@@ -0,0 +1,72 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package models
+
+import "time"
+
+type AlertRule struct {
+	ID                int        `json:"id"`
+	Name              string     `json:"name"`
+	TenantName        string     `json:"tenant_name"`
+	RuleType          string     `json:"rule_type"`
+	StationId         *int       `json:"station_id"`
+	ConsumersGroup    string     `json:"consumers_group"`
+	Operator          string     `json:"operator"`
+	Threshold         float64    `json:"threshold"`
+	DurationSec       int        `json:"duration_sec"`
+	Integrations      []string   `json:"integrations"`
+	Enabled           bool       `json:"enabled"`
+	State             string     `json:"state"`
+	LastValue         float64    `json:"last_value"`
+	LastCounter       int64      `json:"-"`
+	ConditionSince    *time.Time `json:"condition_since"`
+	FiredAt           *time.Time `json:"fired_at"`
+	ResolvedAt        *time.Time `json:"resolved_at"`
+	LastEvaluatedAt   *time.Time `json:"last_evaluated_at"`
+	NextEvaluationAt  time.Time  `json:"-"`
+	CreatedBy         int        `json:"created_by"`
+	CreatedByUsername string     `json:"created_by_username"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	StationName       string     `json:"station_name"`
+}
+
+type CreateAlertRuleSchema struct {
+	Name           string   `json:"name" binding:"required"`
+	RuleType       string   `json:"rule_type" binding:"required"`
+	StationName    string   `json:"station_name"`
+	ConsumersGroup string   `json:"consumers_group"`
+	Operator       string   `json:"operator" binding:"required"`
+	Threshold      float64  `json:"threshold"`
+	DurationSec    int      `json:"duration_sec" binding:"min=0"`
+	Integrations   []string `json:"integrations"`
+}
+
+type UpdateAlertRuleSchema struct {
+	ID             int      `json:"id" binding:"required"`
+	StationName    string   `json:"station_name"`
+	ConsumersGroup string   `json:"consumers_group"`
+	Operator       string   `json:"operator" binding:"required"`
+	Threshold      float64  `json:"threshold"`
+	DurationSec    int      `json:"duration_sec" binding:"min=0"`
+	Integrations   []string `json:"integrations"`
+	Enabled        bool     `json:"enabled"`
+}
+
+type RemoveAlertRuleSchema struct {
+	ID int `json:"id" binding:"required"`
+}
+
+type GetAlertRulesSchema struct {
+	StationName string `form:"station_name" json:"station_name"`
+}
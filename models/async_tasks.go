@@ -30,3 +30,7 @@ type AsyncTaskRes struct {
 type MetaData struct {
 	Offset int `json:"offset"`
 }
+
+type CancelAsyncTaskSchema struct {
+	TaskId int `json:"task_id" binding:"required"`
+}
@@ -5,4 +5,6 @@ type CacheUpdateRequest struct {
 	Operation  string   `json:"operation"`
 	Usernames  []string `json:"users"`
 	TenantName string   `json:"tenant_name"`
+	Stations   []string `json:"stations"`
+	Partitions []int    `json:"partitions,omitempty"`
 }
@@ -142,3 +142,74 @@ type ConsumerForGraph struct {
 	AppId     string `json:"app_id"`
 	Type      string `json:"type"`
 }
+
+type ResetConsumerGroupSchema struct {
+	StationName    string     `json:"station_name" binding:"required"`
+	ConsumersGroup string     `json:"consumers_group" binding:"required"`
+	ResetTo        string     `json:"reset_to" binding:"required"`
+	Sequence       uint64     `json:"sequence"`
+	Time           *time.Time `json:"time"`
+	LastMessages   int64      `json:"last_messages"`
+	DryRun         bool       `json:"dry_run"`
+}
+
+type CgPartitionReset struct {
+	PartitionNumber     int    `json:"partition_number"`
+	DeliveredSeq        uint64 `json:"delivered_seq"`
+	AckFloorSeq         uint64 `json:"ack_floor_seq"`
+	NewStartSeq         uint64 `json:"new_start_seq"`
+	RedeliveredMessages uint64 `json:"redelivered_messages"`
+	PendingMessages     uint64 `json:"pending_messages"`
+	Reset               bool   `json:"reset"`
+	Error               string `json:"error,omitempty"`
+}
+
+type ResetConsumerGroupResponse struct {
+	StationName         string             `json:"station_name"`
+	ConsumersGroup      string             `json:"consumers_group"`
+	DryRun              bool               `json:"dry_run"`
+	Partitions          []CgPartitionReset `json:"partitions"`
+	RedeliveredMessages uint64             `json:"redelivered_messages"`
+	PendingMessages     uint64             `json:"pending_messages"`
+}
+
+type CgLagSample struct {
+	CgName             string `json:"cg_name"`
+	PendingMessages    uint64 `json:"pending_messages"`
+	InProcessMessages  int    `json:"in_process_messages"`
+	OldestUnackedAgeMs int64  `json:"oldest_unacked_age_ms"`
+}
+
+type CgLagSamples struct {
+	PartitionNumber int           `json:"partition_number"`
+	Timestamp       time.Time     `json:"timestamp"`
+	Samples         []CgLagSample `json:"samples"`
+}
+
+type CgLagPoint struct {
+	Timestamp          time.Time `json:"timestamp"`
+	PendingMessages    uint64    `json:"pending_messages"`
+	InProcessMessages  int       `json:"in_process_messages"`
+	OldestUnackedAgeMs int64     `json:"oldest_unacked_age_ms"`
+}
+
+type CgLagHistory struct {
+	CgName          string       `json:"cg_name"`
+	PartitionNumber int          `json:"partition_number"`
+	History         []CgLagPoint `json:"history"`
+}
+
+type GetCgsLagHistorySchema struct {
+	StationName     string `form:"station_name" json:"station_name" binding:"required"`
+	ConsumersGroup  string `form:"consumers_group" json:"consumers_group"`
+	PartitionNumber int    `form:"partition_number" json:"partition_number"`
+	Minutes         int    `form:"minutes" json:"minutes"`
+}
+
+type GetCgsLagHistoryResponse struct {
+	StationName string         `json:"station_name"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	IntervalSec int            `json:"interval_sec"`
+	CgsLag      []CgLagHistory `json:"cgs_lag"`
+}
@@ -34,10 +34,14 @@ type MsgHeader struct {
 }
 
 type MessagePayload struct {
-	TimeSent time.Time         `json:"time_sent"`
-	Size     int               `json:"size"`
-	Data     string            `json:"data"`
-	Headers  map[string]string `json:"headers"`
+	TimeSent            time.Time         `json:"time_sent"`
+	Size                int               `json:"size"`
+	Data                string            `json:"data"`
+	Headers             map[string]string `json:"headers"`
+	RenderedData        string            `json:"rendered_data,omitempty"`
+	RenderFormat        string            `json:"render_format,omitempty"`
+	SchemaName          string            `json:"schema_name,omitempty"`
+	SchemaVersionNumber int               `json:"schema_version_number,omitempty"`
 }
 
 type MessagePayloadFunctionDls struct {
@@ -48,15 +52,17 @@ type MessagePayloadFunctionDls struct {
 }
 
 type PoisonedCg struct {
-	CgName              string     `json:"cg_name"`
-	UnprocessedMessages int        `json:"unprocessed_messages"`
-	MaxAckTimeMs        int64      `json:"max_ack_time_ms"`
-	InProcessMessages   int        `json:"in_process_messages"`
-	TotalPoisonMessages int        `json:"total_poison_messages"`
-	MaxMsgDeliveries    int        `json:"max_msg_deliveries"`
-	CgMembers           []CgMember `json:"cg_members"`
-	IsActive            bool       `json:"is_active"`
-	IsDeleted           bool       `json:"is_deleted"`
+	CgName              string            `json:"cg_name"`
+	UnprocessedMessages int               `json:"unprocessed_messages"`
+	MaxAckTimeMs        int64             `json:"max_ack_time_ms"`
+	InProcessMessages   int               `json:"in_process_messages"`
+	TotalPoisonMessages int               `json:"total_poison_messages"`
+	MaxMsgDeliveries    int               `json:"max_msg_deliveries"`
+	CgMembers           []CgMember        `json:"cg_members"`
+	IsActive            bool              `json:"is_active"`
+	IsDeleted           bool              `json:"is_deleted"`
+	RetryStatus         string            `json:"retry_status,omitempty"`
+	RetryAttempts       []DlsRetryAttempt `json:"retry_attempts"`
 }
 
 type PoisonedCgResponse struct {
@@ -119,6 +125,7 @@ type DlsMessageResponse struct {
 	Message         MessagePayload      `json:"message"`
 	UpdatedAt       time.Time           `json:"updated_at"`
 	ValidationError string              `json:"validation_error"`
+	Retries         []DlsMessageRetry   `json:"retries"`
 }
 
 type PmAckMsg struct {
@@ -153,4 +160,77 @@ type DlsMessageResponseWithFunc struct {
 	UpdatedAt       time.Time           `json:"updated_at"`
 	ValidationError string              `json:"validation_error"`
 	FunctionName    string              `json:"function_name"`
+	Retries         []DlsMessageRetry   `json:"retries"`
+}
+
+type DlsRetryPolicy struct {
+	StationId         int       `json:"station_id"`
+	TenantName        string    `json:"tenant_name"`
+	MaxAttempts       int       `json:"max_attempts"`
+	InitialDelayMs    int64     `json:"initial_delay_ms"`
+	BackoffMultiplier float64   `json:"backoff_multiplier"`
+	ConsumersGroups   []string  `json:"consumers_groups"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type DlsRetryAttempt struct {
+	Attempt     int       `json:"attempt"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	Error       string    `json:"error"`
+}
+
+type DlsMessageRetry struct {
+	DlsMessageId int               `json:"dls_message_id"`
+	CgName       string            `json:"cg_name"`
+	StationId    int               `json:"station_id"`
+	TenantName   string            `json:"tenant_name"`
+	Attempts     int               `json:"attempts"`
+	Status       string            `json:"status"`
+	NextRetryAt  time.Time         `json:"next_retry_at"`
+	History      []DlsRetryAttempt `json:"history"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	AckDeadline  time.Time         `json:"ack_deadline"`
+}
+
+type UpdateDlsRetryPolicySchema struct {
+	StationName       string   `json:"station_name" binding:"required"`
+	MaxAttempts       int      `json:"max_attempts" binding:"required,min=1"`
+	InitialDelayMs    int64    `json:"initial_delay_ms" binding:"required,min=1"`
+	BackoffMultiplier float64  `json:"backoff_multiplier"`
+	ConsumersGroups   []string `json:"consumers_groups"`
+}
+
+type GetDlsRetryPolicySchema struct {
+	StationName string `form:"station_name" json:"station_name" binding:"required"`
+}
+
+type RemoveDlsRetryPolicySchema struct {
+	StationName string `json:"station_name" binding:"required"`
+}
+
+type DlsMessagesFilter struct {
+	DlsMsgType     string            `json:"dls_type"`
+	ConsumersGroup string            `json:"consumers_group"`
+	FromTime       *time.Time        `json:"from_time"`
+	ToTime         *time.Time        `json:"to_time"`
+	Headers        map[string]string `json:"headers"`
+}
+
+type DlsBulkOperationSchema struct {
+	StationName string            `json:"station_name" binding:"required"`
+	Operation   string            `json:"operation" binding:"required"`
+	Filter      DlsMessagesFilter `json:"filter"`
+}
+
+type DlsBulkOperationMetaData struct {
+	Operation string            `json:"operation"`
+	Filter    DlsMessagesFilter `json:"filter"`
+	Total     int               `json:"total"`
+	Processed int               `json:"processed"`
+	Failed    int               `json:"failed"`
+	Offset    int               `json:"offset"`
+}
+
+type DownloadDlsExportSchema struct {
+	TaskId int `form:"task_id" json:"task_id" binding:"required"`
 }
@@ -33,6 +33,26 @@ type SlackIntegration struct {
 	Client     *slack.Client     `json:"client"`
 }
 
+type WebhookIntegration struct {
+	Name       string            `json:"name"`
+	Keys       map[string]string `json:"keys"`
+	Headers    map[string]string `json:"headers"`
+	Properties map[string]bool   `json:"properties"`
+}
+
+type IncomingWebhookIntegration struct {
+	Name       string            `json:"name"`
+	Keys       map[string]string `json:"keys"`
+	Properties map[string]bool   `json:"properties"`
+}
+
+type SmtpIntegration struct {
+	Name       string            `json:"name"`
+	Keys       map[string]string `json:"keys"`
+	Recipients []string          `json:"recipients"`
+	Properties map[string]bool   `json:"properties"`
+}
+
 type CreateIntegrationSchema struct {
 	Name       string                 `json:"name"`
 	Keys       map[string]interface{} `json:"keys"`
@@ -21,20 +21,22 @@ type Schema struct {
 	Type              string `json:"type"`
 	CreatedByUsername string `json:"created_by_username"`
 	TenantName        string `json:"tenant_name"`
+	CompatibilityMode string `json:"compatibility_mode"`
 }
 
 type SchemaVersion struct {
-	ID                int       `json:"id" `
-	VersionNumber     int       `json:"version_number"`
-	Active            bool      `json:"active"`
-	CreatedBy         int       `json:"created_by"`
-	CreatedByUsername string    `json:"created_by_username"`
-	CreatedAt         time.Time `json:"created_at"`
-	SchemaContent     string    `json:"schema_content"`
-	SchemaId          int       `json:"schema_id"`
-	MessageStructName string    `json:"message_struct_name"`
-	Descriptor        string    `json:"descriptor"`
-	TenantName        string    `json:"tenant_name"`
+	ID                int               `json:"id" `
+	VersionNumber     int               `json:"version_number"`
+	Active            bool              `json:"active"`
+	CreatedBy         int               `json:"created_by"`
+	CreatedByUsername string            `json:"created_by_username"`
+	CreatedAt         time.Time         `json:"created_at"`
+	SchemaContent     string            `json:"schema_content"`
+	SchemaId          int               `json:"schema_id"`
+	MessageStructName string            `json:"message_struct_name"`
+	Descriptor        string            `json:"descriptor"`
+	TenantName        string            `json:"tenant_name"`
+	References        []SchemaReference `json:"references"`
 }
 
 type SchemaVersionResponse struct {
@@ -52,11 +54,12 @@ type SchemaVersionResponse struct {
 }
 
 type CreateNewSchema struct {
-	Name              string      `json:"name" binding:"required,min=1,max=32"`
-	Type              string      `json:"type"`
-	SchemaContent     string      `json:"schema_content"`
-	Tags              []CreateTag `json:"tags"`
-	MessageStructName string      `json:"message_struct_name"`
+	Name              string            `json:"name" binding:"required,min=1,max=32"`
+	Type              string            `json:"type"`
+	SchemaContent     string            `json:"schema_content"`
+	Tags              []CreateTag       `json:"tags"`
+	MessageStructName string            `json:"message_struct_name"`
+	References        []SchemaReference `json:"references"`
 }
 
 type ExtendedSchema struct {
@@ -79,6 +82,7 @@ type ExtendedSchemaDetails struct {
 	UsedStations      []string        `json:"used_stations"`
 	Tags              []CreateTag     `json:"tags"`
 	CreatedByUsername string          `json:"created_by_username"`
+	CompatibilityMode string          `json:"compatibility_mode"`
 }
 
 type SchemaUpdateType int
@@ -115,9 +119,10 @@ type RemoveSchema struct {
 }
 
 type CreateNewVersion struct {
-	SchemaName        string `json:"schema_name"`
-	SchemaContent     string `json:"schema_content"`
-	MessageStructName string `json:"message_struct_name"`
+	SchemaName        string            `json:"schema_name"`
+	SchemaContent     string            `json:"schema_content"`
+	MessageStructName string            `json:"message_struct_name"`
+	References        []SchemaReference `json:"references"`
 }
 
 type RollBackVersion struct {
@@ -129,3 +134,40 @@ type ValidateSchema struct {
 	SchemaType    string `json:"schema_type"`
 	SchemaContent string `json:"schema_content"`
 }
+
+type UpdateSchemaCompatibilityMode struct {
+	SchemaName        string `json:"schema_name" binding:"required"`
+	CompatibilityMode string `json:"compatibility_mode" binding:"required"`
+}
+
+type CheckSchemaCompatibility struct {
+	SchemaName        string            `json:"schema_name" binding:"required"`
+	SchemaContent     string            `json:"schema_content"`
+	MessageStructName string            `json:"message_struct_name"`
+	CompatibilityMode string            `json:"compatibility_mode"`
+	References        []SchemaReference `json:"references"`
+}
+
+type SchemaRegistryReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+type SchemaRegistrySchema struct {
+	Schema     string                    `json:"schema"`
+	SchemaType string                    `json:"schemaType"`
+	References []SchemaRegistryReference `json:"references"`
+}
+
+type SchemaRegistryCompatibility struct {
+	Compatibility string `json:"compatibility"`
+}
+
+// SchemaReference points to a version of another schema, the name is the import path of a protobuf file
+// or the full name of an avro named type
+type SchemaReference struct {
+	Name          string `json:"name"`
+	SchemaName    string `json:"schema_name"`
+	VersionNumber int    `json:"version_number"`
+}
@@ -38,6 +38,11 @@ type MessageDetails struct {
 	Size         int               `json:"size"`
 	Headers      map[string]string `json:"headers"`
 	Partition    int               `json:"partition"`
+	// rendering preview of the payload decoded with the station's schema
+	RenderedData        string `json:"rendered_data,omitempty"`
+	RenderFormat        string `json:"render_format,omitempty"`
+	SchemaName          string `json:"schema_name,omitempty"`
+	SchemaVersionNumber int    `json:"schema_version_number,omitempty"`
 }
 
 type Station struct {
@@ -66,6 +71,7 @@ type Station struct {
 	DlsStation                  string    `json:"dls_station"`
 	FunctionsLockHeld           bool      `json:"functions_lock_held"`
 	FunctionsLockedAt           time.Time `json:"functions_locked_at,omitempty"`
+	SchemaStrict                bool      `json:"schema_strict"`
 }
 
 type GetStationResponseSchema struct {
@@ -149,6 +155,7 @@ type ExtendedStationLight struct {
 	DlsStation                  string      `json:"dls_station"`
 	FunctionsLockHeld           bool        `json:"functions_lock_held"`
 	FunctionsLockedAt           time.Time   `json:"functions_locked_at"`
+	SchemaStrict                bool        `json:"schema_strict"`
 }
 
 type StationLight struct {
@@ -210,6 +217,49 @@ type UpdateDlsConfigSchema struct {
 	Schemaverse bool   `json:"schemaverse"`
 }
 
+// UpdateStationSchema holds a partial update of a station, fields which are not set keep their current values
+type UpdateStationSchema struct {
+	StationName          string                 `json:"station_name" binding:"required"`
+	RetentionType        string                 `json:"retention_type"`
+	RetentionValue       *int                   `json:"retention_value"`
+	Replicas             *int                   `json:"replicas"`
+	IdempotencyWindow    *int64                 `json:"idempotency_window_in_ms"`
+	DlsConfiguration     UpdateDlsConfiguration `json:"dls_configuration"`
+	TieredStorageEnabled *bool                  `json:"tiered_storage_enabled"`
+}
+
+type UpdateDlsConfiguration struct {
+	Poison      *bool `json:"poison"`
+	Schemaverse *bool `json:"schemaverse"`
+}
+
+type AddPartitionsSchema struct {
+	StationName      string `json:"station_name" binding:"required"`
+	PartitionsNumber int    `json:"partitions_number" binding:"required"`
+}
+
+type UpdateSchemaStrictSchema struct {
+	StationName  string `json:"station_name" binding:"required"`
+	SchemaStrict bool   `json:"schema_strict"`
+}
+
+type StationSchemaMetrics struct {
+	StationId       int       `json:"station_id"`
+	TenantName      string    `json:"tenant_name"`
+	ValidMessages   int64     `json:"valid_messages"`
+	InvalidMessages int64     `json:"invalid_messages"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type StationConfigUpdate struct {
+	RetentionType        string `json:"retention_type"`
+	RetentionValue       int    `json:"retention_value"`
+	Replicas             int    `json:"replicas"`
+	IdempotencyWindow    int64  `json:"idempotency_window_in_ms"`
+	TieredStorageEnabled bool   `json:"tiered_storage_enabled"`
+	Version              int    `json:"version"`
+}
+
 type DropDlsMessagesSchema struct {
 	DlsMsgType    string `json:"dls_type" binding:"required"`
 	DlsMessageIds []int  `json:"dls_message_ids" binding:"required"`
@@ -255,6 +305,24 @@ type GetMessageDetailsSchema struct {
 	PartitionNumber int    `form:"partition_number" json:"partition_number" binding:"required"`
 }
 
+type SearchMessagesSchema struct {
+	StationName     string            `json:"station_name" binding:"required"`
+	Headers         map[string]string `json:"headers"`
+	PayloadContains string            `json:"payload_contains"`
+	JsonPath        string            `json:"json_path"`
+	ProducerName    string            `json:"producer_name"`
+	FromTime        *time.Time        `json:"from_time"`
+	ToTime          *time.Time        `json:"to_time"`
+	Limit           int               `json:"limit"`
+	Cursor          string            `json:"cursor"`
+}
+
+type SearchMessagesResponse struct {
+	Messages        []MessageDetails `json:"messages"`
+	Cursor          string           `json:"cursor"`
+	ScannedMessages int              `json:"scanned_messages"`
+}
+
 type UseSchema struct {
 	StationNames []string `json:"station_names" binding:"required"`
 	SchemaName   string   `json:"schema_name" binding:"required"`
@@ -283,3 +351,64 @@ type GetUpdatesForSchema struct {
 type PartitionsUpdate struct {
 	PartitionsList []int `json:"partitions_list"`
 }
+
+type ExportStationPartitionRange struct {
+	PartitionNumber int    `json:"partition_number"`
+	FromSeq         uint64 `json:"from_seq"`
+	ToSeq           uint64 `json:"to_seq"`
+}
+
+type ExportStationMessagesSchema struct {
+	StationName string                        `json:"station_name" binding:"required"`
+	Partitions  []ExportStationPartitionRange `json:"partitions"`
+}
+
+type ImportStationMessagesSchema struct {
+	StationName string `form:"station_name" json:"station_name"`
+}
+
+type StationArchiveConfig struct {
+	Name                 string `json:"name"`
+	RetentionType        string `json:"retention_type"`
+	RetentionValue       int    `json:"retention_value"`
+	StorageType          string `json:"storage_type"`
+	Replicas             int    `json:"replicas"`
+	IdempotencyWindow    int64  `json:"idempotency_window_in_ms"`
+	DlsPoison            bool   `json:"dls_poison"`
+	DlsSchemaverse       bool   `json:"dls_schemaverse"`
+	TieredStorageEnabled bool   `json:"tiered_storage_enabled"`
+	SchemaStrict         bool   `json:"schema_strict"`
+	PartitionsList       []int  `json:"partitions_list"`
+}
+
+type StationArchiveSchema struct {
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	VersionNumber     int    `json:"version_number"`
+	SchemaContent     string `json:"schema_content"`
+	MessageStructName string `json:"message_struct_name"`
+}
+
+type StationArchiveManifest struct {
+	Format        string                        `json:"format"`
+	FormatVersion int                           `json:"format_version"`
+	ExportedAt    time.Time                     `json:"exported_at"`
+	Station       StationArchiveConfig          `json:"station"`
+	Schema        *StationArchiveSchema         `json:"schema,omitempty"`
+	Partitions    []ExportStationPartitionRange `json:"partitions"`
+}
+
+type StationArchiveMessage struct {
+	Partition int               `json:"partition"`
+	Sequence  uint64            `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Headers   map[string]string `json:"headers"`
+	Data      []byte            `json:"data"`
+}
+
+type ImportStationMessagesResponse struct {
+	StationName      string   `json:"station_name"`
+	StationCreated   bool     `json:"station_created"`
+	ImportedMessages int      `json:"imported_messages"`
+	Warnings         []string `json:"warnings"`
+}
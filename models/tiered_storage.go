@@ -0,0 +1,101 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package models
+
+import "time"
+
+type TieredStorageObject struct {
+	Key        string    `json:"key"`
+	Partition  int       `json:"partition"`
+	Messages   int       `json:"messages"`
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type TieredStorageMessage struct {
+	MessageDetails
+	ObjectKey string `json:"object_key"`
+}
+
+type ListTieredStorageObjectsSchema struct {
+	StationName string    `form:"station_name" json:"station_name" binding:"required"`
+	From        time.Time `form:"from" json:"from"`
+	To          time.Time `form:"to" json:"to"`
+}
+
+type BrowseTieredStorageSchema struct {
+	StationName string    `json:"station_name" binding:"required"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Partition   int       `json:"partition"`
+	Limit       int       `json:"limit"`
+}
+
+type BrowseTieredStorageResponse struct {
+	Messages  []TieredStorageMessage `json:"messages"`
+	Truncated bool                   `json:"truncated"`
+}
+
+type RehydrateFromTieredStorageSchema struct {
+	StationName       string    `json:"station_name" binding:"required"`
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	TargetStationName string    `json:"target_station_name"`
+}
+
+type TieredStorageRehydrationMetaData struct {
+	SourceStation    string    `json:"source_station"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	TotalObjects     int       `json:"total_objects"`
+	ProcessedObjects int       `json:"processed_objects"`
+	Replayed         int       `json:"replayed"`
+	Failed           int       `json:"failed"`
+}
+
+type TieredStorageRedaction struct {
+	Type        string   `json:"type,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Fields      []string `json:"fields,omitempty"`
+	Replacement string   `json:"replacement,omitempty"`
+}
+
+type TieredStoragePolicy struct {
+	StationId           int                    `json:"station_id"`
+	TenantName          string                 `json:"tenant_name"`
+	Integration         string                 `json:"integration"`
+	KeyPrefix           string                 `json:"key_prefix"`
+	MaxBatchMessages    int                    `json:"max_batch_messages"`
+	MaxBatchBytes       int64                  `json:"max_batch_bytes"`
+	MaxUploadLatencySec int                    `json:"max_upload_latency_sec"`
+	Redaction           TieredStorageRedaction `json:"redaction"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+}
+
+type UpdateTieredStoragePolicySchema struct {
+	StationName         string                 `json:"station_name" binding:"required"`
+	Integration         string                 `json:"integration"`
+	KeyPrefix           string                 `json:"key_prefix"`
+	MaxBatchMessages    int                    `json:"max_batch_messages" binding:"min=0"`
+	MaxBatchBytes       int64                  `json:"max_batch_bytes" binding:"min=0"`
+	MaxUploadLatencySec int                    `json:"max_upload_latency_sec" binding:"min=0"`
+	Redaction           TieredStorageRedaction `json:"redaction"`
+}
+
+type GetTieredStoragePolicySchema struct {
+	StationName string `form:"station_name" json:"station_name" binding:"required"`
+}
+
+type RemoveTieredStoragePolicySchema struct {
+	StationName string `json:"station_name" binding:"required"`
+}
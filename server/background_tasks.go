@@ -51,8 +51,6 @@ const FUNCTION_TASKS_CONSUMER = "$memphis_function_tasks_consumer"
 
 var LastReadThroughputMap map[string]models.Throughput
 var LastWriteThroughputMap map[string]models.Throughput
-var tieredStorageMsgsMap *concurrentMap[map[string][]StoredMsg]
-var tieredStorageMapLock sync.Mutex
 
 func (s *Server) ListenForZombieConnCheckRequests() error {
 	_, err := s.subscribeOnAcc(s.MemphisGlobalAccount(), CONN_STATUS_SUBJ, CONN_STATUS_SUBJ+"_sid", func(_ *client, subject, reply string, msg []byte) {
@@ -108,6 +106,10 @@ func (s *Server) ListenForCacheUpdates() error {
 						return
 					}
 				}
+			case stationSchemaCacheType:
+				refreshStationSchemaEnforcementCache(cache_req.TenantName, cache_req.Stations)
+			case stationPartitionsCacheType:
+				applyStationPartitionsCacheUpdate(cache_req)
 			}
 
 		}(copyBytes(msg))
@@ -133,8 +135,18 @@ func (s *Server) ListenForIntegrationsUpdateEvents() error {
 					EditClusterCompHost("ui_host", integrationUpdate.UIUrl)
 				}
 				CacheDetails("slack", integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
+			case "smtp":
+				CacheDetails("smtp", integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
+			case "teams", "discord":
+				CacheDetails(strings.ToLower(integrationUpdate.Name), integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
+			case "webhook":
+				CacheDetails("webhook", integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
 			case "s3":
 				CacheDetails("s3", integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
+			case filesystemIntegrationName:
+				CacheDetails(filesystemIntegrationName, integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
+			case gcsIntegrationName, azureBlobIntegrationName:
+				CacheDetails(strings.ToLower(integrationUpdate.Name), integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
 			case "github":
 				CacheDetails("github", integrationUpdate.Keys, integrationUpdate.Properties, integrationUpdate.TenantName)
 			default:
@@ -272,6 +284,7 @@ func (s *Server) InitializeThroughputSampling() {
 		}
 	}
 	go s.CalculateSelfThroughput()
+	go s.SampleCgsLag()
 }
 
 func (s *Server) CalculateSelfThroughput() {
@@ -352,6 +365,16 @@ func (s *Server) StartBackgroundTasks() error {
 		return errors.New("Failed to subscribing for cloud cache updates" + err.Error())
 	}
 
+	err = s.ListenForScheduledStreamsUpdates()
+	if err != nil {
+		return errors.New("Failed to subscribing for scheduled streams updates" + err.Error())
+	}
+
+	err = s.ListenForCgsLagSamples()
+	if err != nil {
+		return errors.New("Failed to subscribing for consumer groups lag samples" + err.Error())
+	}
+
 	err = s.ListenToFunctionsCounterUpdates()
 	if err != nil {
 		return errors.New("Failed to subscribing for functions counter updates" + err.Error())
@@ -377,18 +400,22 @@ func (s *Server) StartBackgroundTasks() error {
 	go s.ScaleFunctionWorkers()
 	go s.ConnectorsDeadPodsRescheduler()
 	go s.removeOldAsyncTasks()
+	go s.FlushStationSchemaMetrics()
+	go s.ReleaseScheduledMessages()
+	go s.RetryDlsMessages()
+	go s.EvaluateAlertRules()
 
 	return nil
 }
 
 func (s *Server) uploadMsgsToTier2Storage() {
 	currentTimeFrame := s.opts.TieredStorageUploadIntervalSec
-	ticker := time.NewTicker(time.Duration(currentTimeFrame) * time.Second)
+	ticker := time.NewTicker(tieredStorageSchedulerInterval)
 	defer ticker.Stop()
+	lastProgress := time.Now()
 	for range ticker.C {
 		if s.opts.TieredStorageUploadIntervalSec != currentTimeFrame {
 			currentTimeFrame = s.opts.TieredStorageUploadIntervalSec
-			ticker.Reset(time.Duration(currentTimeFrame) * time.Second)
 			// update consumer when TIERED_STORAGE_TIME_FRAME_SEC configuration was changed
 			cc := ConsumerConfig{
 				DeliverPolicy: DeliverAll,
@@ -406,26 +433,13 @@ func (s *Server) uploadMsgsToTier2Storage() {
 			}
 			TIERED_STORAGE_CONSUMER_CREATED = true
 		}
-		tieredStorageMapLock.Lock()
-		err := flushMapToTier2Storage()
-		if err != nil {
-			serv.Errorf("Failed upload messages to tiered 2 storage: %v", err.Error())
-			tieredStorageMapLock.Unlock()
-			continue
-		}
-		// ack all messages uploaded to tiered 2 storage or when there is no s3 integaration to tenant
-		for t, tenant := range tieredStorageMsgsMap.m {
-			for i, msgs := range tenant {
-				for _, msg := range msgs {
-					reply := msg.ReplySubject
-					s.sendInternalAccountMsg(s.MemphisGlobalAccount(), reply, []byte(_EMPTY_))
-				}
-				delete(tenant, i)
-			}
-			tieredStorageMsgsMap.Delete(t)
+		// buffered messages are kept in progress well within the ack wait of the consumer
+		now := time.Now()
+		sendProgress := now.Sub(lastProgress) >= time.Duration(2)*time.Duration(currentTimeFrame)*time.Second/3
+		if sendProgress {
+			lastProgress = now
 		}
-
-		tieredStorageMapLock.Unlock()
+		s.scheduleTieredStorageBuffers(now, time.Duration(currentTimeFrame)*time.Second, sendProgress)
 	}
 }
 
@@ -500,7 +514,6 @@ func (s *Server) ConsumeTieredStorageMsgs() {
 		ReplySubject string
 	}
 
-	tieredStorageMsgsMap = NewConcurrentMap[map[string][]StoredMsg]()
 	amount := 1000
 	req := []byte(strconv.FormatUint(uint64(amount), 10))
 	for {
@@ -784,6 +797,67 @@ func (s *Server) CheckBrokenConnectedIntegrations() error {
 						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
 					}
 				}
+			case "webhook":
+				if _, ok := integration.Keys["url"].(string); !ok {
+					integration.Keys["url"] = _EMPTY_
+				}
+				err := testWebhookIntegration(integration.Keys["url"].(string))
+				if err != nil {
+					serv.Warnf("[tenant: %s]CheckBrokenConnectedIntegrations at testWebhookIntegration: %v", integration.TenantName, err.Error())
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, false)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				} else {
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, true)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				}
+			case "teams", "discord":
+				if _, ok := integration.Keys["webhook_url"].(string); !ok {
+					integration.Keys["webhook_url"] = _EMPTY_
+				}
+				webhookUrl, err := DecryptAES(getAESKey(), integration.Keys["webhook_url"].(string))
+				if err != nil {
+					serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at DecryptAES: %v", integration.TenantName, err.Error())
+				}
+				err = testIncomingWebhookIntegration(integration.Name, webhookUrl)
+				if err != nil {
+					serv.Warnf("[tenant: %s]CheckBrokenConnectedIntegrations at testIncomingWebhookIntegration: %v", integration.TenantName, err.Error())
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, false)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				} else {
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, true)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				}
+			case "smtp":
+				key := getAESKey()
+				keys := GetKeysAsStringMap(integration.Keys)
+				if keys["password"] != _EMPTY_ {
+					password, err := DecryptAES(key, keys["password"])
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at DecryptAES: %v", integration.TenantName, err.Error())
+					}
+					keys["password"] = password
+				}
+				err := testSmtpIntegration(keys)
+				if err != nil {
+					serv.Warnf("[tenant: %s]CheckBrokenConnectedIntegrations at testSmtpIntegration: %v", integration.TenantName, err.Error())
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, false)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				} else {
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, true)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				}
 			case "s3":
 				key := getAESKey()
 				if _, ok := integration.Keys["access_key"].(string); !ok {
@@ -850,6 +924,50 @@ func (s *Server) CheckBrokenConnectedIntegrations() error {
 						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
 					}
 				}
+			case gcsIntegrationName, azureBlobIntegrationName:
+				key := getAESKey()
+				keys := GetKeysAsStringMap(integration.Keys)
+				if keys["secret_key"] != _EMPTY_ {
+					secretKey, err := DecryptAES(key, keys["secret_key"])
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at DecryptAES: %v", integration.TenantName, err.Error())
+					}
+					keys["secret_key"] = secretKey
+				}
+				testIntegration := testGcsIntegrationKeys
+				if integration.Name == azureBlobIntegrationName {
+					testIntegration = testAzureBlobIntegrationKeys
+				}
+				_, err := testIntegration(keys)
+				if err != nil {
+					serv.Warnf("[tenant: %s]CheckBrokenConnectedIntegrations at test %v integration: %v", integration.TenantName, integration.Name, err.Error())
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, false)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				} else {
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, true)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				}
+			case filesystemIntegrationName:
+				if _, ok := integration.Keys["path"].(string); !ok {
+					integration.Keys["path"] = _EMPTY_
+				}
+				err := testFilesystemIntegration(integration.Keys["path"].(string))
+				if err != nil {
+					serv.Warnf("[tenant: %s]CheckBrokenConnectedIntegrations at testFilesystemIntegration: %v", integration.TenantName, err.Error())
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, false)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				} else {
+					err = db.UpdateIsValidIntegration(integration.TenantName, integration.Name, true)
+					if err != nil {
+						serv.Errorf("[tenant: %s]CheckBrokenConnectedIntegrations at UpdateIsValidIntegration: %v", integration.TenantName, err.Error())
+					}
+				}
 			}
 		}
 	}
@@ -865,24 +983,24 @@ func (s *Server) ConsumeNotificationsBufferMessages() error {
 			continue
 		}
 
-		msgs, err := fetchMessages[slackMsg](s,
+		msgs, err := fetchMessages[notificationBufferMsg](s,
 			NOTIFICATIONS_BUFFER_CONSUMER,
 			notificationsStreamName,
 			mAmount,
 			3*time.Second,
-			createSlackMsg)
+			createNotificationBufferMsg)
 
 		if err != nil {
 			s.Errorf("Failed to fetch notifications: %v", err.Error())
 			continue
 		}
 
-		sendSlackNotifications(s, msgs)
+		sendBufferedNotifications(s, msgs)
 	}
 }
 
-func createSlackMsg(msg []byte, reply string) slackMsg {
-	return slackMsg{
+func createNotificationBufferMsg(msg []byte, reply string) notificationBufferMsg {
+	return notificationBufferMsg{
 		Msg:          msg,
 		ReplySubject: reply,
 	}
@@ -3820,6 +3820,15 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 		hdrBytes, msgBytes := c.msgParts(msg)
 		IncrementEventCounter(accName, "produced_event", 0, 1, subj, msgBytes, hdrBytes)
 	}
+	if !c.routeByPartitionKey(accName, msg) {
+		return false, false
+	}
+	if !c.enforceStationSchema(accName, msg) {
+		return false, false
+	}
+	if !c.scheduleDelayedMsg(accName, msg) {
+		return false, false
+	}
 	// added by Memphis ***
 
 	// Check that client (could be here with SYSTEM) is not publishing on reserved "$GNR" prefix.
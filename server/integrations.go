@@ -19,21 +19,29 @@ import (
 )
 
 var IntegrationsConcurrentCache *concurrentMap[map[string]interface{}]
-var NotificationFunctionsMap map[string]interface{}
+var NotificationFunctionsMap map[string]notifier
 var StorageFunctionsMap map[string]interface{}
 var SourceCodeManagementFunctionsMap map[string]map[string]interface{}
 
 const PoisonMAlert = "poison_message_alert"
 const SchemaVAlert = "schema_validation_fail_alert"
 const DisconEAlert = "disconnection_events_alert"
+const AlertRuleAlert = "alert_rule_alert"
 
 func InitializeIntegrations() error {
 	IntegrationsConcurrentCache = NewConcurrentMap[map[string]interface{}]()
-	NotificationFunctionsMap = make(map[string]interface{})
+	NotificationFunctionsMap = make(map[string]notifier)
 	StorageFunctionsMap = make(map[string]interface{})
 	SourceCodeManagementFunctionsMap = make(map[string]map[string]interface{})
-	NotificationFunctionsMap["slack"] = sendMessageToSlackChannel
+	NotificationFunctionsMap["slack"] = slackNotifier{}
+	NotificationFunctionsMap["webhook"] = webhookNotifier{}
+	NotificationFunctionsMap["smtp"] = smtpNotifier{}
+	NotificationFunctionsMap["teams"] = teamsNotifier
+	NotificationFunctionsMap["discord"] = discordNotifier
 	StorageFunctionsMap["s3"] = serv.uploadToS3Storage
+	StorageFunctionsMap[filesystemIntegrationName] = serv.uploadToFilesystemStorage
+	StorageFunctionsMap[gcsIntegrationName] = serv.uploadToGcsStorage
+	StorageFunctionsMap[azureBlobIntegrationName] = serv.uploadToAzureBlobStorage
 	SourceCodeManagementFunctionsMap["github"] = make(map[string]interface{})
 	SourceCodeManagementFunctionsMap["github"]["get_all_repos"] = serv.getGithubRepositories
 	SourceCodeManagementFunctionsMap["github"]["get_all_branches"] = serv.getGithubBranches
@@ -67,6 +75,31 @@ func InitializeConnections() error {
 				return err
 			}
 			integration.Keys["auth_token"] = decryptedValue
+		} else if value, ok := integration.Keys["signing_secret"].(string); ok && value != _EMPTY_ {
+			decryptedValue, err := DecryptAES(key, value)
+			if err != nil {
+				return err
+			}
+			integration.Keys["signing_secret"] = decryptedValue
+		} else if value, ok := integration.Keys["password"].(string); ok && value != _EMPTY_ {
+			decryptedValue, err := DecryptAES(key, value)
+			if err != nil {
+				return err
+			}
+			integration.Keys["password"] = decryptedValue
+		} else if value, ok := integration.Keys["webhook_url"].(string); ok && value != _EMPTY_ {
+			decryptedValue, err := DecryptAES(key, value)
+			if err != nil {
+				return err
+			}
+			integration.Keys["webhook_url"] = decryptedValue
+		}
+		if headers, ok := integration.Keys["headers"].(map[string]interface{}); ok && integration.Name == webhookIntegrationName {
+			decryptedHeaders, err := decryptWebhookHeaders(key, headers)
+			if err != nil {
+				return err
+			}
+			integration.Keys["headers"] = decryptedHeaders
 		}
 		CacheDetails(integration.Name, integration.Keys, integration.Properties, integration.TenantName)
 	}
@@ -77,8 +110,20 @@ func CacheDetails(integrationType string, keys map[string]interface{}, propertie
 	switch integrationType {
 	case "slack":
 		cacheDetailsSlack(keys, properties, tenantName)
+	case "webhook":
+		cacheDetailsWebhook(keys, properties, tenantName)
+	case "smtp":
+		cacheDetailsSmtp(keys, properties, tenantName)
+	case "teams", "discord":
+		cacheDetailsIncomingWebhook(integrationType, keys, properties, tenantName)
 	case "s3":
 		cacheDetailsS3(keys, properties, tenantName)
+	case filesystemIntegrationName:
+		cacheDetailsFilesystem(keys, properties, tenantName)
+	case gcsIntegrationName:
+		cacheDetailsGcs(keys, properties, tenantName)
+	case azureBlobIntegrationName:
+		cacheDetailsAzureBlob(keys, properties, tenantName)
 	case "github":
 		cacheDetailsGithub(keys, properties, tenantName)
 	}
@@ -95,6 +140,16 @@ func EncryptOldUnencryptedValues() error {
 			return err
 		}
 
+		err = encryptUnencryptedKeysByIntegrationType(gcsIntegrationName, "secret_key", tenant.Name)
+		if err != nil {
+			return err
+		}
+
+		err = encryptUnencryptedKeysByIntegrationType(azureBlobIntegrationName, "secret_key", tenant.Name)
+		if err != nil {
+			return err
+		}
+
 		err = encryptUnencryptedKeysByIntegrationType("slack", "auth_token", tenant.Name)
 		if err != nil {
 			return err
@@ -1358,6 +1358,8 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits) erro
 			DLS_FUNCTIONS_STREAM_CREATED = true
 		case connectorsLogsStream:
 			CONNECTORS_LOGS_STREAM_CREATED = true
+		case cgLagStreamName:
+			CG_LAG_STREAM_CREATED = true
 		}
 		// added by Memphis ***
 
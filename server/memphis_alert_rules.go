@@ -0,0 +1,561 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	alertRuleTypeCgLag        = "cg_lag"
+	alertRuleTypeIngestRate   = "station_ingest_rate"
+	alertRuleTypeDlsCount     = "dls_count"
+	alertRuleTypeStorageUsage = "storage_usage"
+
+	alertRuleStateInactive = "inactive"
+	alertRuleStatePending  = "pending"
+	alertRuleStateFiring   = "firing"
+	alertRuleStateResolved = "resolved"
+
+	alertRulesCheckInterval      = 5 * time.Second
+	alertRulesEvaluationInterval = 30 * time.Second
+	alertRulesEvaluationLease    = time.Minute
+	alertRulesBatchSize          = 100
+)
+
+var alertRuleOperators = []string{">", ">=", "<", "<=", "==", "!="}
+
+type AlertRulesHandler struct{ S *Server }
+
+func validateAlertRule(ruleType, operator, stationName, consumersGroup string, threshold float64, integrations []string) error {
+	switch ruleType {
+	case alertRuleTypeCgLag:
+		if stationName == _EMPTY_ || consumersGroup == _EMPTY_ {
+			return errors.New("cg_lag rules require a station name and a consumers group")
+		}
+	case alertRuleTypeIngestRate, alertRuleTypeDlsCount:
+		if stationName == _EMPTY_ {
+			return fmt.Errorf("%v rules require a station name", ruleType)
+		}
+		if consumersGroup != _EMPTY_ {
+			return fmt.Errorf("%v rules do not support a consumers group", ruleType)
+		}
+	case alertRuleTypeStorageUsage:
+		if stationName != _EMPTY_ || consumersGroup != _EMPTY_ {
+			return errors.New("storage_usage rules do not support a station name or a consumers group")
+		}
+		if threshold > 100 {
+			return errors.New("the threshold of storage_usage rules is a percentage between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("rule type can be one of the following %v/%v/%v/%v", alertRuleTypeCgLag, alertRuleTypeIngestRate, alertRuleTypeDlsCount, alertRuleTypeStorageUsage)
+	}
+
+	valid := false
+	for _, op := range alertRuleOperators {
+		if op == operator {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("operator can be one of the following %v", strings.Join(alertRuleOperators, "/"))
+	}
+	if threshold < 0 {
+		return errors.New("threshold can not be negative")
+	}
+	for _, integration := range integrations {
+		if _, ok := NotificationFunctionsMap[integration]; !ok {
+			return fmt.Errorf("%v is not a notifications integration", integration)
+		}
+	}
+	return nil
+}
+
+func compareAlertRuleValue(operator string, value, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// nextAlertRuleState moves the rule between states, a rule fires once its condition holds for the rule duration
+// and the returned state is not empty only when the transition should be notified
+func nextAlertRuleState(rule *models.AlertRule, conditionMet bool, now time.Time) string {
+	if conditionMet {
+		if rule.ConditionSince == nil {
+			rule.ConditionSince = &now
+		}
+		if rule.State == alertRuleStateFiring {
+			return _EMPTY_
+		}
+		if now.Sub(*rule.ConditionSince) >= time.Duration(rule.DurationSec)*time.Second {
+			rule.State = alertRuleStateFiring
+			rule.FiredAt = &now
+			return alertRuleStateFiring
+		}
+		rule.State = alertRuleStatePending
+		return _EMPTY_
+	}
+
+	rule.ConditionSince = nil
+	switch rule.State {
+	case alertRuleStateFiring:
+		rule.State = alertRuleStateResolved
+		rule.ResolvedAt = &now
+		return alertRuleStateResolved
+	case alertRuleStatePending:
+		rule.State = alertRuleStateInactive
+	}
+	return _EMPTY_
+}
+
+func describeAlertRule(rule models.AlertRule) string {
+	var metric string
+	switch rule.RuleType {
+	case alertRuleTypeCgLag:
+		metric = fmt.Sprintf("the lag of consumers group %v in station %v", rule.ConsumersGroup, rule.StationName)
+	case alertRuleTypeIngestRate:
+		metric = fmt.Sprintf("the ingest rate (messages/sec) of station %v", rule.StationName)
+	case alertRuleTypeDlsCount:
+		metric = fmt.Sprintf("the amount of dead-letter messages in station %v", rule.StationName)
+	case alertRuleTypeStorageUsage:
+		metric = "the storage usage (%)"
+	}
+	description := fmt.Sprintf("%v %v %v", metric, rule.Operator, rule.Threshold)
+	if rule.DurationSec > 0 {
+		description += fmt.Sprintf(" for %v", time.Duration(rule.DurationSec)*time.Second)
+	}
+	return description
+}
+
+// measureAlertRule returns the current value of the rule metric, false is returned when there is no value to evaluate
+func (s *Server) measureAlertRule(rule *models.AlertRule, now time.Time) (float64, bool, error) {
+	if rule.RuleType == alertRuleTypeStorageUsage {
+		acc, err := s.lookupAccount(rule.TenantName)
+		if err != nil {
+			return 0, false, err
+		}
+		usage := acc.JetStreamUsage()
+		limit := usage.Limits.MaxStore
+		if limit <= 0 {
+			if config := s.JetStreamConfig(); config != nil {
+				limit = config.MaxStore
+			}
+		}
+		if limit <= 0 {
+			return 0, false, nil
+		}
+		return float64(usage.Store) / float64(limit) * 100, true, nil
+	}
+
+	if rule.StationId == nil {
+		return 0, false, nil
+	}
+	exist, station, err := db.GetStationById(*rule.StationId, rule.TenantName)
+	if err != nil {
+		return 0, false, err
+	}
+	if !exist {
+		return 0, false, nil
+	}
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch rule.RuleType {
+	case alertRuleTypeCgLag:
+		cgInfo, err := s.GetCgInfo(station.TenantName, sn, rule.ConsumersGroup, station.PartitionsList)
+		if err != nil {
+			if IsNatsErr(err, JSConsumerNotFoundErr) {
+				return 0, false, nil
+			}
+			return 0, false, err
+		}
+		return float64(cgInfo.NumPending) + float64(cgInfo.NumAckPending), true, nil
+	case alertRuleTypeIngestRate:
+		streams := []string{sn.Intern()}
+		if len(station.PartitionsList) > 0 {
+			streams = streams[:0]
+			for _, p := range station.PartitionsList {
+				streams = append(streams, fmt.Sprintf("%v$%v", sn.Intern(), p))
+			}
+		}
+		var counter int64
+		for _, streamName := range streams {
+			streamInfo, err := s.memphisStreamInfo(station.TenantName, streamName)
+			if err != nil {
+				return 0, false, err
+			}
+			counter += int64(streamInfo.State.LastSeq)
+		}
+		prevCounter, prevEvaluation := rule.LastCounter, rule.LastEvaluatedAt
+		rule.LastCounter = counter
+		if prevCounter < 0 || prevEvaluation == nil || counter < prevCounter || !now.After(*prevEvaluation) {
+			// the rate is known only from the second evaluation
+			return 0, false, nil
+		}
+		return float64(counter-prevCounter) / now.Sub(*prevEvaluation).Seconds(), true, nil
+	case alertRuleTypeDlsCount:
+		count, _, err := db.CountDlsMsgsByFilter(station.ID, models.DlsMessagesFilter{})
+		if err != nil {
+			return 0, false, err
+		}
+		return float64(count), true, nil
+	}
+	return 0, false, nil
+}
+
+// EvaluateAlertRules evaluates the due alert rules, every rule is leased by a single broker so it is notified once per transition
+func (s *Server) EvaluateAlertRules() {
+	ticker := time.NewTicker(alertRulesCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rules, err := db.ClaimDueAlertRules(alertRulesBatchSize, alertRulesEvaluationLease)
+		if err != nil {
+			s.Errorf("EvaluateAlertRules at ClaimDueAlertRules: %v", err.Error())
+			continue
+		}
+		for _, rule := range rules {
+			s.evaluateAlertRule(rule)
+		}
+	}
+}
+
+func (s *Server) evaluateAlertRule(rule models.AlertRule) {
+	now := time.Now()
+	value, ok, err := s.measureAlertRule(&rule, now)
+	if err != nil {
+		s.Errorf("[tenant: %v]evaluateAlertRule at measureAlertRule: rule %v: %v", rule.TenantName, rule.Name, err.Error())
+	}
+
+	transition := _EMPTY_
+	if ok {
+		rule.LastValue = value
+		transition = nextAlertRuleState(&rule, compareAlertRuleValue(rule.Operator, value, rule.Threshold), now)
+	} else if err == nil && rule.RuleType != alertRuleTypeIngestRate {
+		// the measured entity does not exist anymore
+		transition = nextAlertRuleState(&rule, false, now)
+	}
+	rule.LastEvaluatedAt = &now
+
+	updated, err := db.UpdateAlertRuleState(rule, now.Add(alertRulesEvaluationInterval))
+	if err != nil {
+		s.Errorf("[tenant: %v]evaluateAlertRule at UpdateAlertRuleState: rule %v: %v", rule.TenantName, rule.Name, err.Error())
+		return
+	}
+	if !updated || transition == _EMPTY_ {
+		// the rule has been changed while it was evaluated so its state is evaluated again
+		return
+	}
+
+	var title, message string
+	if transition == alertRuleStateFiring {
+		title = fmt.Sprintf("Alert rule %v is firing", rule.Name)
+		message = fmt.Sprintf("Alert rule %v is firing: %v, current value: %.2f", rule.Name, describeAlertRule(rule), value)
+	} else {
+		title = fmt.Sprintf("Alert rule %v has been resolved", rule.Name)
+		message = fmt.Sprintf("Alert rule %v has been resolved: %v no longer holds, current value: %.2f", rule.Name, describeAlertRule(rule), rule.LastValue)
+	}
+	err = s.sendNotificationToIntegrations(rule.TenantName, title, message, AlertRuleAlert, rule.Integrations)
+	if err != nil {
+		s.Errorf("[tenant: %v]evaluateAlertRule at sendNotificationToIntegrations: rule %v: %v", rule.TenantName, rule.Name, err.Error())
+	}
+}
+
+// alertRuleStation returns the station of a station scoped rule after the user permissions have been validated
+func alertRuleStation(user models.User, stationName, action string) (*int, string, int, error) {
+	if stationName == _EMPTY_ {
+		return nil, _EMPTY_, 0, nil
+	}
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	allowed, _, err := ValidateStationPermissions(user.Roles, sn.Ext(), user.TenantName, action)
+	if err != nil {
+		return nil, _EMPTY_, 500, err
+	}
+	if !allowed {
+		return nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("user %v is not allowed to %v station %v", user.Username, action, sn.Ext())
+	}
+	exist, station, err := db.GetStationByName(sn.Ext(), user.TenantName)
+	if err != nil {
+		return nil, _EMPTY_, 500, err
+	}
+	if !exist {
+		return nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("Station %v does not exist", sn.Ext())
+	}
+	return &station.ID, station.Name, 0, nil
+}
+
+func abortAlertRuleRequest(c *gin.Context, user models.User, funcName string, status int, err error) {
+	if status == SHOWABLE_ERROR_STATUS_CODE {
+		serv.Warnf("[tenant: %v][user: %v]%v: %v", user.TenantName, user.Username, funcName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	serv.Errorf("[tenant: %v][user: %v]%v: %v", user.TenantName, user.Username, funcName, err.Error())
+	c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+}
+
+func createAlertRuleAuditLog(user models.User, stationName, message string) {
+	if stationName == _EMPTY_ {
+		return
+	}
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName,
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err := CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]createAlertRuleAuditLog at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, stationName, err.Error())
+	}
+}
+
+func (ah AlertRulesHandler) CreateAlertRule(c *gin.Context) {
+	var body models.CreateAlertRuleSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateAlertRule at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	name := strings.TrimSpace(body.Name)
+	ruleType := strings.ToLower(body.RuleType)
+	err = validateAlertRule(ruleType, body.Operator, body.StationName, body.ConsumersGroup, body.Threshold, body.Integrations)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "CreateAlertRule at validateAlertRule", SHOWABLE_ERROR_STATUS_CODE, err)
+		return
+	}
+
+	stationId, stationName, status, err := alertRuleStation(user, body.StationName, "write")
+	if err != nil {
+		abortAlertRuleRequest(c, user, "CreateAlertRule at alertRuleStation", status, err)
+		return
+	}
+
+	rule, rowsUpdated, err := db.InsertAlertRule(name, user.TenantName, ruleType, stationId, body.ConsumersGroup, body.Operator, body.Threshold, body.DurationSec, body.Integrations, user.ID, user.Username)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "CreateAlertRule at InsertAlertRule", 500, err)
+		return
+	}
+	if rowsUpdated == 0 {
+		abortAlertRuleRequest(c, user, "CreateAlertRule", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("Alert rule %v already exists", name))
+		return
+	}
+
+	message := fmt.Sprintf("Alert rule %v has been created by user %v", name, user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	createAlertRuleAuditLog(user, stationName, message)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"rule-type": ruleType}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-create-alert-rule")
+	}
+
+	c.IndentedJSON(200, rule)
+}
+
+func (ah AlertRulesHandler) UpdateAlertRule(c *gin.Context) {
+	var body models.UpdateAlertRuleSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateAlertRule at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	exist, rule, err := db.GetAlertRuleById(body.ID, user.TenantName)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at GetAlertRuleById", 500, err)
+		return
+	}
+	if !exist {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("Alert rule %v does not exist", body.ID))
+		return
+	}
+	if _, _, status, err := alertRuleStation(user, rule.StationName, "write"); err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at alertRuleStation", status, err)
+		return
+	}
+
+	err = validateAlertRule(rule.RuleType, body.Operator, body.StationName, body.ConsumersGroup, body.Threshold, body.Integrations)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at validateAlertRule", SHOWABLE_ERROR_STATUS_CODE, err)
+		return
+	}
+	stationId, stationName, status, err := alertRuleStation(user, body.StationName, "write")
+	if err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at alertRuleStation", status, err)
+		return
+	}
+
+	err = db.UpdateAlertRule(rule.ID, user.TenantName, stationId, body.ConsumersGroup, body.Operator, body.Threshold, body.DurationSec, body.Integrations, body.Enabled)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at db.UpdateAlertRule", 500, err)
+		return
+	}
+	_, rule, err = db.GetAlertRuleById(rule.ID, user.TenantName)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "UpdateAlertRule at GetAlertRuleById", 500, err)
+		return
+	}
+
+	message := fmt.Sprintf("Alert rule %v has been updated by user %v", rule.Name, user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	createAlertRuleAuditLog(user, stationName, message)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"rule-type": rule.RuleType, "enabled": body.Enabled}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-update-alert-rule")
+	}
+
+	c.IndentedJSON(200, rule)
+}
+
+func (ah AlertRulesHandler) RemoveAlertRule(c *gin.Context) {
+	var body models.RemoveAlertRuleSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveAlertRule at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	exist, rule, err := db.GetAlertRuleById(body.ID, user.TenantName)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "RemoveAlertRule at GetAlertRuleById", 500, err)
+		return
+	}
+	if !exist {
+		abortAlertRuleRequest(c, user, "RemoveAlertRule", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("Alert rule %v does not exist", body.ID))
+		return
+	}
+	if _, _, status, err := alertRuleStation(user, rule.StationName, "write"); err != nil {
+		abortAlertRuleRequest(c, user, "RemoveAlertRule at alertRuleStation", status, err)
+		return
+	}
+
+	err = db.RemoveAlertRule(rule.ID, user.TenantName)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "RemoveAlertRule at db.RemoveAlertRule", 500, err)
+		return
+	}
+
+	message := fmt.Sprintf("Alert rule %v has been removed by user %v", rule.Name, user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	createAlertRuleAuditLog(user, rule.StationName, message)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"rule-type": rule.RuleType}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-remove-alert-rule")
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (ah AlertRulesHandler) GetAlertRules(c *gin.Context) {
+	var body models.GetAlertRulesSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetAlertRules at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationId := 0
+	if body.StationName != _EMPTY_ {
+		id, _, status, err := alertRuleStation(user, body.StationName, "read")
+		if err != nil {
+			abortAlertRuleRequest(c, user, "GetAlertRules at alertRuleStation", status, err)
+			return
+		}
+		stationId = *id
+	}
+
+	rules, err := db.GetAlertRules(user.TenantName, stationId)
+	if err != nil {
+		abortAlertRuleRequest(c, user, "GetAlertRules at db.GetAlertRules", 500, err)
+		return
+	}
+
+	allowedRules := make([]models.AlertRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.StationName != _EMPTY_ && stationId == 0 {
+			allowed, _, err := ValidateStationPermissions(user.Roles, rule.StationName, user.TenantName, "read")
+			if err != nil {
+				abortAlertRuleRequest(c, user, "GetAlertRules at ValidateStationPermissions", 500, err)
+				return
+			}
+			if !allowed {
+				continue
+			}
+		}
+		allowedRules = append(allowedRules, rule)
+	}
+
+	c.IndentedJSON(200, allowedRules)
+}
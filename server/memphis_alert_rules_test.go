@@ -0,0 +1,50 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestAlertRuleState(t *testing.T) {
+	if !compareAlertRuleValue(">=", 10, 10) || compareAlertRuleValue(">", 10, 10) || !compareAlertRuleValue("!=", 1, 2) {
+		t.Errorf("unexpected comparison result")
+	}
+	if err := validateAlertRule(alertRuleTypeStorageUsage, ">", "orders", "", 80, nil); err == nil {
+		t.Errorf("expected an error for a station scoped storage rule")
+	}
+	if err := validateAlertRule(alertRuleTypeCgLag, "=>", "orders", "cg1", 10, nil); err == nil {
+		t.Errorf("expected an error for an unknown operator")
+	}
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rule := models.AlertRule{State: alertRuleStateInactive, DurationSec: 60}
+	if transition := nextAlertRuleState(&rule, true, now); transition != "" || rule.State != alertRuleStatePending {
+		t.Errorf("expected a pending rule, got %v/%v", transition, rule.State)
+	}
+	if transition := nextAlertRuleState(&rule, true, now.Add(time.Minute)); transition != alertRuleStateFiring || rule.State != alertRuleStateFiring {
+		t.Errorf("expected a firing rule, got %v/%v", transition, rule.State)
+	}
+	if transition := nextAlertRuleState(&rule, true, now.Add(2*time.Minute)); transition != "" {
+		t.Errorf("expected a single firing notification, got %v", transition)
+	}
+	if transition := nextAlertRuleState(&rule, false, now.Add(3*time.Minute)); transition != alertRuleStateResolved || rule.ConditionSince != nil {
+		t.Errorf("expected a resolved rule, got %v/%v", transition, rule.State)
+	}
+	if transition := nextAlertRuleState(&rule, false, now.Add(4*time.Minute)); transition != "" || rule.State != alertRuleStateResolved {
+		t.Errorf("expected the rule to stay resolved, got %v/%v", transition, rule.State)
+	}
+}
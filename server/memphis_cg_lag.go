@@ -0,0 +1,410 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cgLagSamplingInterval      = time.Second
+	cgLagHistoryRetention      = 6 * time.Hour
+	cgLagHistoryDefaultMinutes = 60
+	cgLagHistoryMaxPoints      = 300
+	cgLagHistoryBatchSize      = 1000
+	cgLagHistoryMaxScanned     = 200000
+	cgLagHistoryBatchTimeout   = 2 * time.Second
+	cgLagOverviewWindow        = 15 * time.Minute
+)
+
+// cgLagOverviews keeps the lag samples of the last cgLagOverviewWindow of every station in memory, downsampled to the
+// interval of the overview when they are added, so the station overview does not read the lag history stream on every push
+var cgLagOverviews = NewConcurrentMap[[]models.CgLagSamples]()
+
+func getCgLagSubject(tenantName, stationIntern string, partition int) string {
+	return fmt.Sprintf("%v.%v.%v.%v", cgLagStreamName, tenantName, stationIntern, partition)
+}
+
+// splitStationStreamName returns the station and the partition of a station stream, streams of stations without partitions return partition 0
+func splitStationStreamName(streamName string) (string, int) {
+	idx := strings.LastIndex(streamName, "$")
+	if idx > 0 {
+		partition, err := strconv.Atoi(streamName[idx+1:])
+		if err == nil {
+			return streamName[:idx], partition
+		}
+	}
+	return streamName, 0
+}
+
+// SampleCgsLag samples the lag of the consumer groups this broker leads and keeps it in the lag history stream
+func (s *Server) SampleCgsLag() {
+	lastEviction := time.Now()
+	for range time.Tick(cgLagSamplingInterval) {
+		if !CG_LAG_STREAM_CREATED {
+			continue
+		}
+		now := time.Now()
+		if now.Sub(lastEviction) > cgLagOverviewWindow {
+			evictCgLagOverviews(now)
+			lastEviction = now
+		}
+		s.accounts.Range(func(_, v interface{}) bool {
+			acc := v.(*Account)
+			for _, mset := range acc.streams() {
+				if strings.HasPrefix(mset.name(), "$") { // internal streams
+					continue
+				}
+				samples := sampleStreamCgsLag(mset, now)
+				if len(samples) == 0 {
+					continue
+				}
+				stationIntern, partition := splitStationStreamName(mset.name())
+				// echoed so the overview of this broker receives its own samples as well
+				s.sendInternalAccountMsgWithReply(s.MemphisGlobalAccount(), getCgLagSubject(acc.GetName(), stationIntern, partition), _EMPTY_, nil, models.CgLagSamples{
+					PartitionNumber: partition,
+					Timestamp:       now,
+					Samples:         samples,
+				}, true)
+			}
+			return true
+		})
+	}
+}
+
+func sampleStreamCgsLag(mset *stream, now time.Time) []models.CgLagSample {
+	var samples []models.CgLagSample
+	for _, o := range mset.getConsumers() {
+		if !o.IsLeader() {
+			continue
+		}
+		info := o.info()
+		if info == nil || info.Config == nil || info.Config.Durable == _EMPTY_ || strings.HasPrefix(info.Name, "$memphis") {
+			continue
+		}
+		sample := models.CgLagSample{
+			CgName:            revertDelimiters(info.Name),
+			PendingMessages:   info.NumPending,
+			InProcessMessages: info.NumAckPending,
+		}
+		if info.NumPending > 0 || info.NumAckPending > 0 {
+			sample.OldestUnackedAgeMs = oldestUnackedMsgAge(mset, info, now).Milliseconds()
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// oldestUnackedMsgAge returns the age of the first message after the ack floor of the consumer
+func oldestUnackedMsgAge(mset *stream, info *ConsumerInfo, now time.Time) time.Duration {
+	filter, wc := info.Config.FilterSubject, true
+	if filter == _EMPTY_ {
+		filter = fwcs
+	} else {
+		wc = subjectHasWildcard(filter)
+	}
+	sm, _, err := mset.Store().LoadNextMsg(filter, wc, info.AckFloor.Stream+1, nil)
+	if err != nil || sm == nil {
+		return 0
+	}
+	age := now.Sub(time.Unix(0, sm.ts))
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// ListenForCgsLagSamples keeps the samples of all the brokers in the in-memory lag overview
+func (s *Server) ListenForCgsLagSamples() error {
+	_, err := s.subscribeOnAcc(s.MemphisGlobalAccount(), cgLagStreamName+".>", cgLagStreamName+"_overview_sid", func(_ *client, subject, reply string, msg []byte) {
+		go func(subject string, msg []byte) {
+			// the subject is the stream name followed by the tenant, the station and the partition
+			tokens := strings.Split(subject, ".")
+			if len(tokens) != 4 {
+				return
+			}
+			var batch models.CgLagSamples
+			err := json.Unmarshal(msg, &batch)
+			if err != nil {
+				s.Errorf("ListenForCgsLagSamples at Unmarshal: %v", err.Error())
+				return
+			}
+			addCgLagOverviewSamples(tokens[1]+":"+tokens[2], batch, time.Now())
+		}(strings.Clone(subject), copyBytes(msg))
+	})
+	return err
+}
+
+// addCgLagOverviewSamples adds the batch to the overview of its station, a batch which falls in the same interval as the
+// latest batch of its partition replaces it since the overview keeps the latest sample of every interval
+func addCgLagOverviewSamples(key string, batch models.CgLagSamples, now time.Time) {
+	interval := cgLagHistoryInterval(cgLagOverviewWindow)
+	from := now.Add(-cgLagOverviewWindow)
+	if batch.Timestamp.Before(from) {
+		return
+	}
+	cgLagOverviews.Lock()
+	defer cgLagOverviews.Unlock()
+	batches := cgLagOverviews.m[key]
+	expired := 0
+	for expired < len(batches) && batches[expired].Timestamp.Before(from) {
+		expired++
+	}
+	batches = batches[expired:]
+	for i := len(batches) - 1; i >= 0; i-- {
+		if batches[i].PartitionNumber != batch.PartitionNumber {
+			continue
+		}
+		if batches[i].Timestamp.Truncate(interval).Equal(batch.Timestamp.Truncate(interval)) {
+			if batch.Timestamp.After(batches[i].Timestamp) {
+				batches[i] = batch
+			}
+			cgLagOverviews.m[key] = batches
+			return
+		}
+		break
+	}
+	cgLagOverviews.m[key] = append(batches, batch)
+}
+
+// evictCgLagOverviews removes the overviews of stations which have not been sampled during the last window
+func evictCgLagOverviews(now time.Time) {
+	from := now.Add(-cgLagOverviewWindow)
+	cgLagOverviews.Lock()
+	defer cgLagOverviews.Unlock()
+	for key, batches := range cgLagOverviews.m {
+		if len(batches) == 0 || batches[len(batches)-1].Timestamp.Before(from) {
+			delete(cgLagOverviews.m, key)
+		}
+	}
+}
+
+// getCgsLagOverview returns the lag of the last cgLagOverviewWindow of the station from memory, partition 0 or below returns all the partitions
+func getCgsLagOverview(tenantName, stationIntern string, partition int, now time.Time) []models.CgLagHistory {
+	var batches []models.CgLagSamples
+	cgLagOverviews.Lock()
+	for _, batch := range cgLagOverviews.m[tenantName+":"+stationIntern] {
+		if partition <= 0 || batch.PartitionNumber == partition {
+			batches = append(batches, batch)
+		}
+	}
+	cgLagOverviews.Unlock()
+	return buildCgsLagHistory(batches, _EMPTY_, now.Add(-cgLagOverviewWindow), cgLagHistoryInterval(cgLagOverviewWindow))
+}
+
+// cgLagHistoryInterval returns the interval between the points of a history so it does not exceed cgLagHistoryMaxPoints
+func cgLagHistoryInterval(window time.Duration) time.Duration {
+	interval := (window / cgLagHistoryMaxPoints).Truncate(time.Second)
+	if interval < cgLagSamplingInterval {
+		return cgLagSamplingInterval
+	}
+	return interval
+}
+
+// buildCgsLagHistory downsamples the lag samples into points of the given interval, the latest sample of every interval is kept
+func buildCgsLagHistory(batches []models.CgLagSamples, cgName string, from time.Time, interval time.Duration) []models.CgLagHistory {
+	type historyKey struct {
+		cgName    string
+		partition int
+	}
+	buckets := map[historyKey]map[int64]models.CgLagPoint{}
+	for _, batch := range batches {
+		if batch.Timestamp.Before(from) {
+			continue
+		}
+		bucket := int64(batch.Timestamp.Sub(from) / interval)
+		for _, sample := range batch.Samples {
+			if cgName != _EMPTY_ && sample.CgName != cgName {
+				continue
+			}
+			key := historyKey{cgName: sample.CgName, partition: batch.PartitionNumber}
+			if buckets[key] == nil {
+				buckets[key] = map[int64]models.CgLagPoint{}
+			}
+			if point, ok := buckets[key][bucket]; ok && point.Timestamp.After(batch.Timestamp) {
+				continue
+			}
+			buckets[key][bucket] = models.CgLagPoint{
+				Timestamp:          batch.Timestamp,
+				PendingMessages:    sample.PendingMessages,
+				InProcessMessages:  sample.InProcessMessages,
+				OldestUnackedAgeMs: sample.OldestUnackedAgeMs,
+			}
+		}
+	}
+
+	history := make([]models.CgLagHistory, 0, len(buckets))
+	for key, points := range buckets {
+		cgHistory := models.CgLagHistory{CgName: key.cgName, PartitionNumber: key.partition, History: make([]models.CgLagPoint, 0, len(points))}
+		for _, point := range points {
+			cgHistory.History = append(cgHistory.History, point)
+		}
+		sort.Slice(cgHistory.History, func(i, j int) bool {
+			return cgHistory.History[i].Timestamp.Before(cgHistory.History[j].Timestamp)
+		})
+		history = append(history, cgHistory)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].CgName != history[j].CgName {
+			return history[i].CgName < history[j].CgName
+		}
+		return history[i].PartitionNumber < history[j].PartitionNumber
+	})
+	return history
+}
+
+// getCgsLagHistory reads the lag samples of the station since the given time, partition 0 or below returns all the partitions
+func (s *Server) getCgsLagHistory(station models.Station, cgName string, partition int, from time.Time, interval time.Duration) ([]models.CgLagHistory, error) {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return nil, err
+	}
+	filterSubj := fmt.Sprintf("%v.%v.%v.>", cgLagStreamName, station.TenantName, sn.Intern())
+	if partition > 0 {
+		filterSubj = getCgLagSubject(station.TenantName, sn.Intern(), partition)
+	}
+
+	streamInfo, err := s.memphisStreamInfo(s.MemphisGlobalAccountString(), cgLagStreamName)
+	if err != nil {
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			return []models.CgLagHistory{}, nil
+		}
+		return nil, err
+	}
+	if streamInfo.State.Msgs == 0 {
+		return []models.CgLagHistory{}, nil
+	}
+
+	lastSeq := streamInfo.State.LastSeq
+	startSeq := s.findFirstSeqByTime(s.MemphisGlobalAccountString(), cgLagStreamName, filterSubj, streamInfo.State.FirstSeq, lastSeq, from)
+	var batches []models.CgLagSamples
+	scanned := 0
+	for startSeq <= lastSeq && scanned < cgLagHistoryMaxScanned {
+		amount := cgLagHistoryBatchSize
+		if remaining := lastSeq - startSeq + 1; remaining < uint64(amount) {
+			amount = int(remaining)
+		}
+		msgs, err := s.memphisGetMsgs(s.MemphisGlobalAccountString(), filterSubj, cgLagStreamName, startSeq, amount, cgLagHistoryBatchTimeout, false, false, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			if msg.Sequence >= startSeq {
+				startSeq = msg.Sequence + 1
+			}
+			var batch models.CgLagSamples
+			err = json.Unmarshal(msg.Data, &batch)
+			if err != nil {
+				continue
+			}
+			batches = append(batches, batch)
+		}
+		scanned += len(msgs)
+		if len(msgs) < amount {
+			break
+		}
+	}
+
+	return buildCgsLagHistory(batches, cgName, from, interval), nil
+}
+
+func (mh MonitoringHandler) GetCgsLagHistory(c *gin.Context) {
+	var body models.GetCgsLagHistorySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetCgsLagHistory at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]GetCgsLagHistory at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "read")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetCgsLagHistory at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to read station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]GetCgsLagHistory: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	minutes := body.Minutes
+	if minutes <= 0 {
+		minutes = cgLagHistoryDefaultMinutes
+	}
+	if time.Duration(minutes)*time.Minute > cgLagHistoryRetention {
+		errMsg := fmt.Sprintf("the lag history is kept for %v minutes", int(cgLagHistoryRetention.Minutes()))
+		serv.Warnf("[tenant: %v][user: %v]GetCgsLagHistory: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetCgsLagHistory at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]GetCgsLagHistory: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Duration(minutes) * time.Minute)
+	interval := cgLagHistoryInterval(to.Sub(from))
+	cgsLag, err := mh.S.getCgsLagHistory(station, body.ConsumersGroup, body.PartitionNumber, from, interval)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetCgsLagHistory at getCgsLagHistory: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, models.GetCgsLagHistoryResponse{
+		StationName: stationName.Ext(),
+		From:        from,
+		To:          to,
+		IntervalSec: int(interval.Seconds()),
+		CgsLag:      cgsLag,
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestBuildCgsLagHistory(t *testing.T) {
+	if station, partition := splitStationStreamName("orders$3"); station != "orders" || partition != 3 {
+		t.Errorf("expected orders and 3, got %v and %v", station, partition)
+	}
+	if station, partition := splitStationStreamName("orders"); station != "orders" || partition != 0 {
+		t.Errorf("expected orders and 0, got %v and %v", station, partition)
+	}
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	batches := []models.CgLagSamples{
+		{PartitionNumber: 1, Timestamp: from.Add(-time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 100}}},
+		{PartitionNumber: 1, Timestamp: from.Add(time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 10}, {CgName: "cg2", PendingMessages: 5}}},
+		{PartitionNumber: 1, Timestamp: from.Add(3 * time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 8}}},
+		{PartitionNumber: 1, Timestamp: from.Add(2 * time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 9}}},
+		{PartitionNumber: 2, Timestamp: from.Add(6 * time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 1}}},
+	}
+
+	history := buildCgsLagHistory(batches, "cg1", from, 5*time.Second)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 histories, got %v", len(history))
+	}
+	if history[0].PartitionNumber != 1 || len(history[0].History) != 1 || history[0].History[0].PendingMessages != 8 {
+		t.Errorf("expected the latest sample of the interval, got %+v", history[0])
+	}
+	if history[1].PartitionNumber != 2 || len(history[1].History) != 1 || history[1].History[0].PendingMessages != 1 {
+		t.Errorf("unexpected history of partition 2: %+v", history[1])
+	}
+
+	if interval := cgLagHistoryInterval(time.Minute); interval != time.Second {
+		t.Errorf("expected an interval of a second, got %v", interval)
+	}
+	if interval := cgLagHistoryInterval(5 * time.Hour); interval != time.Minute {
+		t.Errorf("expected an interval of a minute, got %v", interval)
+	}
+}
+
+func TestCgsLagOverview(t *testing.T) {
+	now := time.Now().Truncate(cgLagHistoryInterval(cgLagOverviewWindow))
+	key := "acme:orders"
+	defer cgLagOverviews.Delete(key)
+	addCgLagOverviewSamples(key, models.CgLagSamples{PartitionNumber: 1, Timestamp: now.Add(-cgLagOverviewWindow - time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 100}}}, now)
+	for i, pending := range []uint64{10, 9, 8} {
+		addCgLagOverviewSamples(key, models.CgLagSamples{PartitionNumber: 1, Timestamp: now.Add(time.Duration(i) * time.Second), Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: pending}}}, now)
+	}
+	addCgLagOverviewSamples(key, models.CgLagSamples{PartitionNumber: 2, Timestamp: now, Samples: []models.CgLagSample{{CgName: "cg1", PendingMessages: 1}}}, now)
+
+	if batches, _ := cgLagOverviews.Load(key); len(batches) != 2 {
+		t.Fatalf("expected the samples to be downsampled when added, got %v batches", len(batches))
+	}
+	overview := getCgsLagOverview("acme", "orders", 1, now)
+	if len(overview) != 1 || len(overview[0].History) != 1 || overview[0].History[0].PendingMessages != 8 {
+		t.Errorf("expected the latest sample of the interval, got %+v", overview)
+	}
+	if overview := getCgsLagOverview("acme", "orders", 0, now); len(overview) != 2 {
+		t.Errorf("expected the overview of all the partitions, got %+v", overview)
+	}
+
+	evictCgLagOverviews(now.Add(2 * cgLagOverviewWindow))
+	if _, ok := cgLagOverviews.Load(key); ok {
+		t.Errorf("expected the overview of a station which is no longer sampled to be evicted")
+	}
+}
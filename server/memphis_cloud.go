@@ -2212,6 +2212,21 @@ func (sh StationsHandler) Produce(c *gin.Context) {
 		return
 	}
 
+	schemaVersion := 0
+	if !body.BypassSchema {
+		schemaVersion, err = validateProducedMessage(station, []byte(body.MsgPayload))
+		if err != nil {
+			if errors.Is(err, ErrSchemaValidation) {
+				serv.Warnf("[tenant: %v][user: %v]Produce at validateProducedMessage: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+				c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+				return
+			}
+			serv.Errorf("[tenant: %v][user: %v]Produce at validateProducedMessage: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+	}
+
 	subject := _EMPTY_
 	shouldRoundRobin := false
 	if station.Version == 0 {
@@ -2232,11 +2247,34 @@ func (sh StationsHandler) Produce(c *gin.Context) {
 	}
 	body.MsgHdrs["$memphis_producedBy"] = "UI"
 	body.MsgHdrs["$memphis_connectionId"] = "UI"
+	if schemaVersion != 0 {
+		body.MsgHdrs[schemaVersionHeader] = strconv.Itoa(schemaVersion)
+	} else {
+		delete(body.MsgHdrs, schemaVersionHeader)
+	}
 	if shouldRoundRobin {
 		rand.Seed(time.Now().UnixNano())
 		randomIndex := rand.Intn(len(station.PartitionsList))
 		subject = fmt.Sprintf("%s$%v.final", stationName.Intern(), station.PartitionsList[randomIndex])
 	}
+
+	now := time.Now()
+	deliverAt, scheduled, err := scheduledDeliveryTime([]byte(body.MsgHdrs[deliverAtHeader]), []byte(body.MsgHdrs[delayHeader]), now)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]Produce at scheduledDeliveryTime: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	if scheduled && deliverAt.After(now) {
+		err = serv.scheduleMessage(user.TenantName, subject, _EMPTY_, body.MsgHdrs, []byte(body.MsgPayload))
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]Produce at scheduleMessage: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		c.IndentedJSON(200, gin.H{})
+		return
+	}
 	serv.sendInternalAccountMsgWithHeadersWithEcho(account, subject, body.MsgPayload, body.MsgHdrs)
 
 	c.IndentedJSON(200, gin.H{})
@@ -2456,6 +2494,7 @@ func (pmh PoisonMessagesHandler) GetDlsMessageDetails(messageId int, dlsType str
 		UpdatedAt:       dlsMsg.UpdatedAt,
 		ValidationError: dlsMsg.ValidationError,
 		FunctionName:    _EMPTY_,
+		Retries:         dlsMsg.Retries,
 	}
 
 	return dlsMsgResponse, nil
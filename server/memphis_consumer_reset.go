@@ -0,0 +1,532 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/memphis_cache"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cgResetToSequence     = "sequence"
+	cgResetToTime         = "time"
+	cgResetToLastMessages = "last_messages"
+	// the holder keeps the messages of interest based stations while the consumer group is recreated
+	cgResetHolderSuffix            = "$reset"
+	cgResetHolderInactiveThreshold = time.Minute
+)
+
+// errCgResetRejected is returned when one of the partitions can not be reset, in that case none of them is changed
+var errCgResetRejected = errors.New("consumer group can not be reset")
+
+type cgPartitionResetPlan struct {
+	streamName string
+	info       ConsumerInfo
+	retention  RetentionPolicy
+	report     models.CgPartitionReset
+}
+
+func validateResetConsumerGroupRequest(body models.ResetConsumerGroupSchema) error {
+	switch body.ResetTo {
+	case cgResetToSequence:
+		if body.Sequence == 0 {
+			return errors.New("sequence has to be a positive number")
+		}
+	case cgResetToTime:
+		if body.Time == nil || body.Time.IsZero() {
+			return errors.New("time is required when resetting to a time")
+		}
+	case cgResetToLastMessages:
+		if body.LastMessages <= 0 {
+			return errors.New("last_messages has to be a positive number")
+		}
+	default:
+		return fmt.Errorf("reset_to has to be one of %v, %v or %v", cgResetToSequence, cgResetToTime, cgResetToLastMessages)
+	}
+	return nil
+}
+
+// validateConsumerGroupResettable returns a showable error when the consumer group can not be reset,
+// a consumer group has to be inactive during a reset since its pending deliveries are dropped
+func validateConsumerGroupResettable(station models.Station, cgName string, dryRun bool) (string, error) {
+	if !station.IsNative {
+		return fmt.Sprintf("Station %v is not a Memphis station", station.Name), nil
+	}
+	members, err := db.GetConsumerGroupMembers(cgName, station.ID)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	if len(members) == 0 {
+		return fmt.Sprintf("Consumer group %v does not exist at station %v", cgName, station.Name), nil
+	}
+	if dryRun {
+		return _EMPTY_, nil
+	}
+	activeCount, err := db.CountActiveConsumersInCG(cgName, station.ID)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	if activeCount > 0 {
+		return fmt.Sprintf("Consumer group %v has %v active consumers, stop them before resetting it", cgName, activeCount), nil
+	}
+	return _EMPTY_, nil
+}
+
+// planConsumerGroupReset calculates the new start sequence of the consumer group on every partition,
+// a partition which can not be reset is reported with an error and the rest are still planned so all the problems are returned at once
+func (s *Server) planConsumerGroupReset(station models.Station, cgName string, body models.ResetConsumerGroupSchema) ([]cgPartitionResetPlan, error) {
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return nil, err
+	}
+	partitions := []int{0}
+	if len(station.PartitionsList) > 0 {
+		partitions = append([]int{}, station.PartitionsList...)
+		sort.Ints(partitions)
+	}
+
+	plans := make([]cgPartitionResetPlan, 0, len(partitions))
+	for _, partition := range partitions {
+		streamName := stationName.Intern()
+		var partitionsList []int
+		if len(station.PartitionsList) > 0 {
+			streamName = fmt.Sprintf("%v$%v", stationName.Intern(), partition)
+			partitionsList = []int{partition}
+		}
+		streamInfo, err := s.memphisStreamInfo(station.TenantName, streamName)
+		if err != nil {
+			return nil, err
+		}
+		cgInfo, err := s.GetCgInfo(station.TenantName, stationName, cgName, partitionsList)
+		if err != nil {
+			if !IsNatsErr(err, JSConsumerNotFoundErr) {
+				return nil, err
+			}
+			plans = append(plans, cgPartitionResetPlan{streamName: streamName, report: models.CgPartitionReset{
+				PartitionNumber: partition,
+				Error:           fmt.Sprintf("consumer group %v does not exist at partition %v", cgName, partition),
+			}})
+			continue
+		}
+		if cgInfo.Config == nil {
+			plans = append(plans, cgPartitionResetPlan{streamName: streamName, report: models.CgPartitionReset{
+				PartitionNumber: partition,
+				Error:           fmt.Sprintf("consumer group %v has no configuration at partition %v", cgName, partition),
+			}})
+			continue
+		}
+
+		firstSeq, lastSeq := streamInfo.State.FirstSeq, streamInfo.State.LastSeq
+		var newStartSeq uint64
+		switch body.ResetTo {
+		case cgResetToSequence:
+			newStartSeq = body.Sequence
+		case cgResetToTime:
+			newStartSeq = lastSeq + 1
+			if streamInfo.State.Msgs > 0 {
+				newStartSeq = s.findFirstSeqByTime(station.TenantName, streamName, cgInfo.Config.FilterSubject, firstSeq, lastSeq, *body.Time)
+			}
+		case cgResetToLastMessages:
+			newStartSeq = 1
+			if lastSeq > uint64(body.LastMessages) {
+				newStartSeq = lastSeq - uint64(body.LastMessages) + 1
+			}
+		}
+		if newStartSeq < firstSeq {
+			newStartSeq = firstSeq
+		}
+
+		report := models.CgPartitionReset{
+			PartitionNumber: partition,
+			DeliveredSeq:    cgInfo.Delivered.Stream,
+			AckFloorSeq:     cgInfo.AckFloor.Stream,
+			NewStartSeq:     newStartSeq,
+		}
+		if cgInfo.Delivered.Stream >= newStartSeq {
+			report.RedeliveredMessages = cgInfo.Delivered.Stream - newStartSeq + 1
+		}
+		if lastSeq >= newStartSeq {
+			report.PendingMessages = lastSeq - newStartSeq + 1
+		}
+		if body.ResetTo == cgResetToSequence && body.Sequence > lastSeq+1 {
+			report.Error = fmt.Sprintf("sequence %v is beyond the last sequence %v of partition %v", body.Sequence, lastSeq, partition)
+		}
+		plans = append(plans, cgPartitionResetPlan{
+			streamName: streamName,
+			info:       cgInfo,
+			retention:  streamInfo.Config.Retention,
+			report:     report,
+		})
+	}
+	return plans, nil
+}
+
+// applyConsumerGroupReset recreates the consumer group with the same configuration and a new start position,
+// the deliver policy of a consumer can not be updated in place
+func (s *Server) applyConsumerGroupReset(tenantName string, plan cgPartitionResetPlan, body models.ResetConsumerGroupSchema) error {
+	consumerConfig := *plan.info.Config
+	consumerName := consumerConfig.Durable
+	if consumerName == _EMPTY_ {
+		consumerName = plan.info.Name
+	}
+	consumerConfig.Durable = consumerName
+	consumerConfig.OptStartSeq = 0
+	consumerConfig.OptStartTime = nil
+	if body.ResetTo == cgResetToTime {
+		startTime := body.Time.UTC()
+		consumerConfig.DeliverPolicy = DeliverByStartTime
+		consumerConfig.OptStartTime = &startTime
+	} else {
+		consumerConfig.DeliverPolicy = DeliverByStartSequence
+		consumerConfig.OptStartSeq = plan.report.NewStartSeq
+	}
+
+	if plan.retention == InterestPolicy {
+		holderStartSeq := plan.report.NewStartSeq
+		if plan.info.AckFloor.Stream+1 < holderStartSeq {
+			holderStartSeq = plan.info.AckFloor.Stream + 1
+		}
+		holderConfig := &ConsumerConfig{
+			Durable:           consumerName + cgResetHolderSuffix,
+			DeliverPolicy:     DeliverByStartSequence,
+			OptStartSeq:       holderStartSeq,
+			AckPolicy:         AckExplicit,
+			FilterSubject:     consumerConfig.FilterSubject,
+			ReplayPolicy:      ReplayInstant,
+			MaxAckPending:     -1,
+			InactiveThreshold: cgResetHolderInactiveThreshold,
+		}
+		err := s.memphisAddConsumer(tenantName, plan.streamName, holderConfig)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := s.memphisRemoveConsumer(tenantName, plan.streamName, holderConfig.Durable)
+			if err != nil && !IsNatsErr(err, JSConsumerNotFoundErr) {
+				s.Warnf("[tenant: %v]applyConsumerGroupReset at memphisRemoveConsumer: stream %v: %v", tenantName, plan.streamName, err.Error())
+			}
+		}()
+	}
+
+	err := s.memphisRemoveConsumer(tenantName, plan.streamName, consumerName)
+	if err != nil && !IsNatsErr(err, JSConsumerNotFoundErr) {
+		return err
+	}
+	return s.memphisAddConsumer(tenantName, plan.streamName, &consumerConfig)
+}
+
+func (s *Server) resetConsumerGroup(station models.Station, body models.ResetConsumerGroupSchema) (models.ResetConsumerGroupResponse, error) {
+	plans, err := s.planConsumerGroupReset(station, body.ConsumersGroup, body)
+	if err != nil {
+		return newResetConsumerGroupResponse(station, body), err
+	}
+	return runConsumerGroupReset(station, body, plans, func(plan cgPartitionResetPlan) error {
+		return s.applyConsumerGroupReset(station.TenantName, plan, body)
+	})
+}
+
+func newResetConsumerGroupResponse(station models.Station, body models.ResetConsumerGroupSchema) models.ResetConsumerGroupResponse {
+	return models.ResetConsumerGroupResponse{
+		StationName:    station.Name,
+		ConsumersGroup: body.ConsumersGroup,
+		DryRun:         body.DryRun,
+		Partitions:     []models.CgPartitionReset{},
+	}
+}
+
+// runConsumerGroupReset applies the plans only once all the partitions were validated,
+// every partition is reported with its own result so a partial failure shows which partitions were reset
+func runConsumerGroupReset(station models.Station, body models.ResetConsumerGroupSchema, plans []cgPartitionResetPlan, apply func(plan cgPartitionResetPlan) error) (models.ResetConsumerGroupResponse, error) {
+	response := newResetConsumerGroupResponse(station, body)
+	var invalid []string
+	for _, plan := range plans {
+		if plan.report.Error != _EMPTY_ {
+			invalid = append(invalid, plan.report.Error)
+		}
+	}
+	if len(invalid) > 0 && !body.DryRun {
+		for _, plan := range plans {
+			response.Partitions = append(response.Partitions, plan.report)
+		}
+		return response, fmt.Errorf("%w: %v", errCgResetRejected, strings.Join(invalid, ", "))
+	}
+
+	failed := 0
+	for _, plan := range plans {
+		report := plan.report
+		if !body.DryRun {
+			err := apply(plan)
+			if err != nil {
+				report.Error = err.Error()
+				failed++
+			} else {
+				report.Reset = true
+			}
+		}
+		response.Partitions = append(response.Partitions, report)
+		response.RedeliveredMessages += report.RedeliveredMessages
+		response.PendingMessages += report.PendingMessages
+	}
+	if failed > 0 {
+		return response, fmt.Errorf("consumer group %v has been reset on %v out of %v partitions", body.ConsumersGroup, len(plans)-failed, len(plans))
+	}
+	return response, nil
+}
+
+func consumerGroupResetAuditMessage(body models.ResetConsumerGroupSchema, stationName, username string) string {
+	var position string
+	switch body.ResetTo {
+	case cgResetToSequence:
+		position = fmt.Sprintf("sequence %v", body.Sequence)
+	case cgResetToTime:
+		position = fmt.Sprintf("time %v", body.Time.UTC().Format(time.RFC3339))
+	case cgResetToLastMessages:
+		position = fmt.Sprintf("the last %v messages", body.LastMessages)
+	}
+	return fmt.Sprintf("Consumer group %v of station %v has been reset to %v by user %v", body.ConsumersGroup, stationName, position, username)
+}
+
+func (ch ConsumersHandler) ResetConsumerGroup(c *gin.Context) {
+	var body models.ResetConsumerGroupSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ResetConsumerGroup at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	body.ConsumersGroup = strings.ToLower(body.ConsumersGroup)
+	err = validateResetConsumerGroupRequest(body)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup at validateResetConsumerGroupRequest: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ResetConsumerGroup at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to reset consumer groups of station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ResetConsumerGroup at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	errMsg, err := validateConsumerGroupResettable(station, body.ConsumersGroup, body.DryRun)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ResetConsumerGroup at validateConsumerGroupResettable: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if errMsg != _EMPTY_ {
+		serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	response, err := ch.S.resetConsumerGroup(station, body)
+	if err != nil {
+		if errors.Is(err, errCgResetRejected) {
+			serv.Warnf("[tenant: %v][user: %v]ResetConsumerGroup at resetConsumerGroup: At station %v: consumer group %v: %v", user.TenantName, user.Username, body.StationName, body.ConsumersGroup, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error(), "partitions": response.Partitions})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]ResetConsumerGroup at resetConsumerGroup: At station %v: consumer group %v: %v", user.TenantName, user.Username, body.StationName, body.ConsumersGroup, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error", "partitions": response.Partitions})
+		return
+	}
+
+	if !body.DryRun {
+		message := consumerGroupResetAuditMessage(body, stationName.Ext(), user.Username)
+		serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+		var auditLogs []interface{}
+		newAuditLog := models.AuditLog{
+			StationName:       stationName.Ext(),
+			Message:           message,
+			CreatedBy:         user.ID,
+			CreatedByUsername: user.Username,
+			CreatedAt:         time.Now(),
+			TenantName:        user.TenantName,
+		}
+		auditLogs = append(auditLogs, newAuditLog)
+		err = CreateAuditLogs(auditLogs)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]ResetConsumerGroup at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		}
+
+		shouldSendAnalytics, _ := shouldSendAnalytics()
+		if shouldSendAnalytics {
+			analyticsParams := map[string]interface{}{"reset-to": body.ResetTo}
+			analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-reset-consumer-group")
+		}
+	}
+
+	c.IndentedJSON(200, response)
+}
+
+func (s *Server) resetConsumerGroupDirect(c *client, reply string, msg []byte) {
+	var rcgr resetConsumerGroupRequest
+	var resp resetConsumerGroupResponse
+	tenantName, message, err := s.getTenantNameAndMessage(msg)
+	if err != nil {
+		s.Errorf("resetConsumerGroupDirect at getTenantNameAndMessage: %v", err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	if err := json.Unmarshal([]byte(message), &rcgr); err != nil {
+		s.Errorf("[tenant: %v]resetConsumerGroupDirect at json.Unmarshal: %v", tenantName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	rcgr.TenantName = tenantName
+	body := models.ResetConsumerGroupSchema{
+		StationName:    rcgr.StationName,
+		ConsumersGroup: strings.ToLower(rcgr.ConsumersGroup),
+		ResetTo:        rcgr.ResetTo,
+		Sequence:       rcgr.Sequence,
+		Time:           rcgr.Time,
+		LastMessages:   rcgr.LastMessages,
+		DryRun:         rcgr.DryRun,
+	}
+	err = validateResetConsumerGroupRequest(body)
+	if err != nil {
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect at validateResetConsumerGroupRequest: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+
+	stationName, err := StationNameFromStr(rcgr.StationName)
+	if err != nil {
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect at StationNameFromStr: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+
+	exist, user, err := memphis_cache.GetUser(rcgr.Username, tenantName, false)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at memphis_cache.GetUser: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("user %v does not exist", rcgr.Username)
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect: %v", tenantName, rcgr.Username, errMsg)
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg), &resp)
+		return
+	}
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), tenantName, "write")
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at ValidateStationPermissions: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to reset consumer groups of station %v", rcgr.Username, stationName.Ext())
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect: %v", tenantName, rcgr.Username, errMsg)
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg), &resp)
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), tenantName)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at GetStationByName: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", rcgr.StationName)
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect: %v", tenantName, rcgr.Username, errMsg)
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg), &resp)
+		return
+	}
+
+	errMsg, err := validateConsumerGroupResettable(station, body.ConsumersGroup, body.DryRun)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at validateConsumerGroupResettable: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+	if errMsg != _EMPTY_ {
+		s.Warnf("[tenant: %v][user: %v]resetConsumerGroupDirect: %v", tenantName, rcgr.Username, errMsg)
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg), &resp)
+		return
+	}
+
+	resp.ResetConsumerGroupResponse, err = s.resetConsumerGroup(station, body)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at resetConsumerGroup: Station %v: consumer group %v: %v", tenantName, rcgr.Username, rcgr.StationName, body.ConsumersGroup, err.Error())
+		respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+		return
+	}
+
+	if !body.DryRun {
+		message := consumerGroupResetAuditMessage(body, stationName.Ext(), user.Username)
+		s.Noticef("[tenant: %v][user: %v]: %v", tenantName, user.Username, message)
+		var auditLogs []interface{}
+		newAuditLog := models.AuditLog{
+			StationName:       stationName.Ext(),
+			Message:           message,
+			CreatedBy:         user.ID,
+			CreatedByUsername: user.Username,
+			CreatedAt:         time.Now(),
+			TenantName:        tenantName,
+		}
+		auditLogs = append(auditLogs, newAuditLog)
+		err = CreateAuditLogs(auditLogs)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]resetConsumerGroupDirect at CreateAuditLogs: Station %v: %v", tenantName, rcgr.Username, rcgr.StationName, err.Error())
+		}
+	}
+
+	respondWithResp(s.MemphisGlobalAccountString(), s, reply, &resp)
+}
@@ -0,0 +1,118 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestValidateResetConsumerGroupRequest(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name  string
+		body  models.ResetConsumerGroupSchema
+		valid bool
+	}{
+		{name: "sequence", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToSequence, Sequence: 10}, valid: true},
+		{name: "zero sequence", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToSequence}},
+		{name: "time", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToTime, Time: &now}, valid: true},
+		{name: "missing time", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToTime}},
+		{name: "last messages", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToLastMessages, LastMessages: 5}, valid: true},
+		{name: "negative last messages", body: models.ResetConsumerGroupSchema{ResetTo: cgResetToLastMessages, LastMessages: -1}},
+		{name: "unknown reset", body: models.ResetConsumerGroupSchema{ResetTo: "beginning"}},
+	}
+	for _, tc := range cases {
+		err := validateResetConsumerGroupRequest(tc.body)
+		if (err == nil) != tc.valid {
+			t.Errorf("%v: expected valid %v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestRunConsumerGroupReset(t *testing.T) {
+	station := models.Station{Name: "orders", PartitionsList: []int{1, 2, 3}}
+	newPlans := func(invalidPartition int) []cgPartitionResetPlan {
+		var plans []cgPartitionResetPlan
+		for _, p := range station.PartitionsList {
+			report := models.CgPartitionReset{PartitionNumber: p, NewStartSeq: 5, RedeliveredMessages: 2, PendingMessages: 10}
+			if p == invalidPartition {
+				report = models.CgPartitionReset{PartitionNumber: p, Error: "consumer group cg does not exist at partition 2"}
+			}
+			plans = append(plans, cgPartitionResetPlan{streamName: fmt.Sprintf("orders$%v", p), report: report})
+		}
+		return plans
+	}
+	body := models.ResetConsumerGroupSchema{ConsumersGroup: "cg", ResetTo: cgResetToSequence, Sequence: 5}
+
+	t.Run("all partitions are validated before any is changed", func(t *testing.T) {
+		applied := 0
+		response, err := runConsumerGroupReset(station, body, newPlans(2), func(plan cgPartitionResetPlan) error {
+			applied++
+			return nil
+		})
+		if !errors.Is(err, errCgResetRejected) {
+			t.Fatalf("expected the reset to be rejected, got %v", err)
+		}
+		if applied != 0 {
+			t.Fatalf("expected no partition to be changed, %v were", applied)
+		}
+		if len(response.Partitions) != 3 || response.Partitions[1].Error == "" || response.Partitions[0].Reset {
+			t.Fatalf("expected every partition to be reported without being reset, got %+v", response.Partitions)
+		}
+	})
+
+	t.Run("dry run reports the invalid partitions", func(t *testing.T) {
+		dryRun := body
+		dryRun.DryRun = true
+		response, err := runConsumerGroupReset(station, dryRun, newPlans(2), func(plan cgPartitionResetPlan) error {
+			t.Fatalf("a dry run must not change partition %v", plan.report.PartitionNumber)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(response.Partitions) != 3 || response.Partitions[1].Error == "" || response.PendingMessages != 20 {
+			t.Fatalf("unexpected dry run response %+v", response)
+		}
+	})
+
+	t.Run("per partition results", func(t *testing.T) {
+		response, err := runConsumerGroupReset(station, body, newPlans(0), func(plan cgPartitionResetPlan) error {
+			if plan.report.PartitionNumber == 2 {
+				return errors.New("consumer create failed")
+			}
+			return nil
+		})
+		if err == nil || errors.Is(err, errCgResetRejected) {
+			t.Fatalf("expected a partial failure, got %v", err)
+		}
+		expected := []struct {
+			reset bool
+			err   string
+		}{{reset: true}, {err: "consumer create failed"}, {reset: true}}
+		for i, e := range expected {
+			p := response.Partitions[i]
+			if p.Reset != e.reset || p.Error != e.err {
+				t.Errorf("partition %v: expected reset %v and error %q, got %v and %q", p.PartitionNumber, e.reset, e.err, p.Reset, p.Error)
+			}
+		}
+		if response.RedeliveredMessages != 6 || response.PendingMessages != 30 {
+			t.Errorf("expected the totals of all the partitions, got %v redelivered and %v pending", response.RedeliveredMessages, response.PendingMessages)
+		}
+	})
+}
@@ -0,0 +1,442 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+)
+
+const (
+	dlsBulkResendTaskName  = "dls_bulk_resend"
+	dlsBulkDropTaskName    = "dls_bulk_drop"
+	dlsBulkExportTaskName  = "dls_bulk_export"
+	dlsBulkOperationResend = "resend"
+	dlsBulkOperationDrop   = "drop"
+	dlsBulkOperationExport = "export"
+	dlsBulkBatchSize       = 500
+	dlsExportsStreamName   = "$memphis_dls_exports"
+	dlsExportsMaxAge       = 24 * time.Hour
+)
+
+var dlsBulkOperationsTaskNames = map[string]string{
+	dlsBulkOperationResend: dlsBulkResendTaskName,
+	dlsBulkOperationDrop:   dlsBulkDropTaskName,
+	dlsBulkOperationExport: dlsBulkExportTaskName,
+}
+
+func isDlsBulkOperationTask(taskName string) bool {
+	for _, name := range dlsBulkOperationsTaskNames {
+		if name == taskName {
+			return true
+		}
+	}
+	return false
+}
+
+func dlsExportSubject(taskId int) string {
+	return dlsExportsStreamName + "." + strconv.Itoa(taskId)
+}
+
+func validateDlsMessagesFilter(filter models.DlsMessagesFilter) error {
+	if filter.DlsMsgType != _EMPTY_ && filter.DlsMsgType != "poison" && filter.DlsMsgType != "schema" {
+		return fmt.Errorf("DLS type %v is not supported, use poison or schema", filter.DlsMsgType)
+	}
+	if filter.FromTime != nil && filter.ToTime != nil && filter.FromTime.After(*filter.ToTime) {
+		return errors.New("from_time must not be later than to_time")
+	}
+	return nil
+}
+
+func (s *Server) ensureDlsExportsStream(tenantName string) error {
+	replicas := 1
+	if s.JetStreamIsClustered() {
+		replicas = 3
+	}
+	err := s.memphisAddStream(tenantName, &StreamConfig{
+		Name:         dlsExportsStreamName,
+		Subjects:     []string{dlsExportsStreamName + ".>"},
+		Retention:    LimitsPolicy,
+		MaxAge:       dlsExportsMaxAge,
+		MaxConsumers: -1,
+		MaxMsgs:      -1,
+		MaxBytes:     -1,
+		MaxMsgsPer:   -1,
+		Discard:      DiscardOld,
+		Storage:      FileStorage,
+		Replicas:     replicas,
+		NoAck:        false,
+	})
+	if err != nil && !IsNatsErr(err, JSStreamNameExistErr) {
+		return err
+	}
+	return nil
+}
+
+// applyDlsBulkOperation applies the operation to a batch of messages and returns the number of messages which failed
+func (s *Server) applyDlsBulkOperation(task models.AsyncTask, station models.Station, operation string, filter models.DlsMessagesFilter, dlsMsgs []models.DlsMessage, user models.User) int {
+	failed := 0
+	switch operation {
+	case dlsBulkOperationResend:
+		for _, dlsMsg := range dlsMsgs {
+			if filter.ConsumersGroup != _EMPTY_ {
+				dlsMsg.PoisonedCgs = []string{filter.ConsumersGroup}
+			}
+			_, err := s.ResendUnackedMsg(dlsMsg, user, station.Name)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]applyDlsBulkOperation at ResendUnackedMsg: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+				failed++
+			}
+		}
+	case dlsBulkOperationDrop:
+		// dropping by consumer group only releases the message from that group, the message is removed once no group is left
+		if filter.ConsumersGroup != _EMPTY_ {
+			for _, dlsMsg := range dlsMsgs {
+				err := db.RemoveCgFromDlsMsg(dlsMsg.ID, filter.ConsumersGroup, station.TenantName)
+				if err != nil {
+					s.Errorf("[tenant: %v][user: %v]applyDlsBulkOperation at RemoveCgFromDlsMsg: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+					failed++
+				}
+			}
+			break
+		}
+		ids := make([]int, 0, len(dlsMsgs))
+		for _, dlsMsg := range dlsMsgs {
+			ids = append(ids, dlsMsg.ID)
+		}
+		err := db.DropDlsMessages(ids)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]applyDlsBulkOperation at DropDlsMessages: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+			failed += len(ids)
+		}
+	case dlsBulkOperationExport:
+		for _, dlsMsg := range dlsMsgs {
+			line, err := json.Marshal(dlsMsg)
+			if err == nil {
+				err = s.memphisPublishWithAck(station.TenantName, dlsExportSubject(task.ID), nil, line)
+			}
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]applyDlsBulkOperation at export: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// RunDlsBulkOperation walks over the DLS messages of the station which match the filter in batches, the progress is kept in the
+// task's meta data and the task status is checked between batches so a cancelled task stops before its next batch
+func (s *Server) RunDlsBulkOperation(task models.AsyncTask, station models.Station, operation string, filter models.DlsMessagesFilter, user models.User) {
+	go func() {
+		tenantName := station.TenantName
+		total, maxId, err := db.CountDlsMsgsByFilter(station.ID, filter)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at CountDlsMsgsByFilter: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+			s.handleDlsBulkOperationFailure(task, station, operation, user, err.Error())
+			return
+		}
+		metaData := models.DlsBulkOperationMetaData{
+			Operation: operation,
+			Filter:    filter,
+			Total:     total,
+		}
+		err = db.UpdateAsyncTaskById(task.ID, time.Now(), metaData)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at UpdateAsyncTaskById: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+		}
+		if operation == dlsBulkOperationExport {
+			err = s.ensureDlsExportsStream(tenantName)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at ensureDlsExportsStream: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+				s.handleDlsBulkOperationFailure(task, station, operation, user, err.Error())
+				return
+			}
+		}
+
+		for {
+			exist, currentTask, err := db.GetAsyncTaskById(task.ID, tenantName)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at GetAsyncTaskById: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+				s.handleDlsBulkOperationFailure(task, station, operation, user, err.Error())
+				return
+			}
+			if !exist || currentTask.Status != "running" {
+				s.Noticef("[tenant: %v][user: %v]RunDlsBulkOperation: %v of DLS messages at station %v has been stopped after %v of %v messages", tenantName, user.Username, operation, station.Name, metaData.Processed, metaData.Total)
+				return
+			}
+
+			dlsMsgs, err := db.GetDlsMsgsBatchByFilter(station.ID, filter, metaData.Offset, maxId, dlsBulkBatchSize)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at GetDlsMsgsBatchByFilter: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+				s.handleDlsBulkOperationFailure(task, station, operation, user, err.Error())
+				return
+			}
+			if len(dlsMsgs) == 0 {
+				break
+			}
+
+			metaData.Failed += s.applyDlsBulkOperation(task, station, operation, filter, dlsMsgs, user)
+			metaData.Processed += len(dlsMsgs)
+			metaData.Offset = dlsMsgs[len(dlsMsgs)-1].ID
+			err = db.UpdateAsyncTaskById(task.ID, time.Now(), metaData)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at UpdateAsyncTaskById: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+			}
+		}
+
+		err = db.FinishAsyncTaskById(task.ID, "completed", _EMPTY_)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at FinishAsyncTaskById: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+			return
+		}
+
+		systemMessage := SystemMessage{
+			MessageType:    "info",
+			MessagePayload: fmt.Sprintf("Bulk %v of DLS messages at station %s, triggered by user %s has been completed (%v messages, %v failed)", operation, station.Name, user.Username, metaData.Processed, metaData.Failed),
+		}
+		err = s.sendSystemMessageOnWS(user, systemMessage)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunDlsBulkOperation at sendSystemMessageOnWS: station %v: %v", tenantName, user.Username, station.Name, err.Error())
+		}
+	}()
+}
+
+func (s *Server) handleDlsBulkOperationFailure(task models.AsyncTask, station models.Station, operation string, user models.User, errMsg string) {
+	err := db.FinishAsyncTaskById(task.ID, "failed", errMsg)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleDlsBulkOperationFailure at FinishAsyncTaskById: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+	}
+	systemMessage := SystemMessage{
+		MessageType:    "error",
+		MessagePayload: fmt.Sprintf("Bulk %v of DLS messages at station %s, triggered by user %s has failed due to an internal error", operation, station.Name, user.Username),
+	}
+	err = s.sendSystemMessageOnWS(user, systemMessage)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleDlsBulkOperationFailure at sendSystemMessageOnWS: station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+	}
+}
+
+func (sh StationsHandler) BulkDlsOperation(c *gin.Context) {
+	var body models.DlsBulkOperationSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("BulkDlsOperation at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	taskName, ok := dlsBulkOperationsTaskNames[body.Operation]
+	if !ok {
+		errMsg := fmt.Sprintf("Operation %v is not supported, use resend, drop or export", body.Operation)
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	filter := body.Filter
+	filter.ConsumersGroup = strings.ToLower(strings.TrimSpace(filter.ConsumersGroup))
+	if body.Operation == dlsBulkOperationResend {
+		// only poison messages have consumer groups to be resent to
+		filter.DlsMsgType = "poison"
+	}
+	err = validateDlsMessagesFilter(filter)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation at validateDlsMessagesFilter: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	permission := "write"
+	if body.Operation == dlsBulkOperationExport {
+		permission = "read"
+	}
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, permission)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]BulkDlsOperation at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to %v DLS messages of station %v", user.Username, body.Operation, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if body.Operation == dlsBulkOperationResend && IsStorageLimitExceeded(user.TenantName) {
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation at IsStorageLimitExceeded: %s", user.TenantName, user.Username, ErrUpgradePlan.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": ErrUpgradePlan.Error()})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]BulkDlsOperation at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	created, task, err := db.CreateAsyncTaskIfNotRunning(taskName, sh.S.opts.ServerName, time.Now(), user.TenantName, station.ID, user.Username)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]BulkDlsOperation at CreateAsyncTaskIfNotRunning: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !created {
+		errMsg := fmt.Sprintf("A bulk %v of DLS messages is already running on station %v", body.Operation, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]BulkDlsOperation: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	sh.S.RunDlsBulkOperation(task, station, body.Operation, filter, user)
+
+	message := fmt.Sprintf("Bulk %v of DLS messages at station %v has been triggered by user %v", body.Operation, stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]BulkDlsOperation at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"operation": body.Operation}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-bulk-dls-operation")
+	}
+
+	c.IndentedJSON(200, gin.H{"async_task_id": task.ID})
+}
+
+func (sh StationsHandler) DownloadDlsExport(c *gin.Context) {
+	var body models.DownloadDlsExportSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("DownloadDlsExport at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	exist, task, err := db.GetAsyncTaskById(body.TaskId, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DownloadDlsExport at GetAsyncTaskById: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist || task.Name != dlsBulkExportTaskName {
+		errMsg := fmt.Sprintf("Export %v does not exist", body.TaskId)
+		serv.Warnf("[tenant: %v][user: %v]DownloadDlsExport: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if task.Status == "running" {
+		errMsg := fmt.Sprintf("Export %v is still running", body.TaskId)
+		serv.Warnf("[tenant: %v][user: %v]DownloadDlsExport: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationById(task.StationId, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DownloadDlsExport at GetStationById: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if exist {
+		allowed, _, err := ValidateStationPermissions(user.Roles, station.Name, user.TenantName, "read")
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]DownloadDlsExport at ValidateStationPermissions: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !allowed {
+			errMsg := fmt.Sprintf("user %v is not allowed to read station %v", user.Username, station.Name)
+			serv.Warnf("[tenant: %v][user: %v]DownloadDlsExport: %v", user.TenantName, user.Username, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+	}
+
+	subject := dlsExportSubject(task.ID)
+	msg, err := sh.S.memphisGetNextMessage(user.TenantName, dlsExportsStreamName, subject, 1)
+	if err != nil {
+		if IsNatsErr(err, JSNoMessageFoundErr) || IsNatsErr(err, JSStreamNotFoundErr) {
+			errMsg := fmt.Sprintf("Export %v is empty or has expired", body.TaskId)
+			serv.Warnf("[tenant: %v][user: %v]DownloadDlsExport: %v", user.TenantName, user.Username, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]DownloadDlsExport at memphisGetNextMessage: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dls-export-%v.ndjson", task.ID))
+	c.Status(200)
+	for {
+		_, err = c.Writer.Write(append(msg.Data, '\n'))
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]DownloadDlsExport at Write: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+			return
+		}
+		msg, err = sh.S.memphisGetNextMessage(user.TenantName, dlsExportsStreamName, subject, msg.Sequence+1)
+		if err != nil {
+			if !IsNatsErr(err, JSNoMessageFoundErr) {
+				serv.Errorf("[tenant: %v][user: %v]DownloadDlsExport at memphisGetNextMessage: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateDlsMessagesFilter(t *testing.T) {
+	from := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	to := from.Add(30 * time.Minute)
+	cases := []struct {
+		name   string
+		filter models.DlsMessagesFilter
+		valid  bool
+	}{
+		{name: "empty", filter: models.DlsMessagesFilter{}, valid: true},
+		{name: "poison", filter: models.DlsMessagesFilter{DlsMsgType: "poison", ConsumersGroup: "billing"}, valid: true},
+		{name: "schema", filter: models.DlsMessagesFilter{DlsMsgType: "schema"}, valid: true},
+		{name: "unknown type", filter: models.DlsMessagesFilter{DlsMsgType: "functions"}, valid: false},
+		{name: "time range", filter: models.DlsMessagesFilter{FromTime: &from, ToTime: &to}, valid: true},
+		{name: "single point in time", filter: models.DlsMessagesFilter{FromTime: &from, ToTime: &from}, valid: true},
+		{name: "reversed time range", filter: models.DlsMessagesFilter{FromTime: &to, ToTime: &from}, valid: false},
+		{name: "open time range", filter: models.DlsMessagesFilter{ToTime: &from, Headers: map[string]string{"tenant": "acme"}}, valid: true},
+	}
+	for _, c := range cases {
+		err := validateDlsMessagesFilter(c.filter)
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid to be %v, got %v", c.name, c.valid, err)
+		}
+	}
+}
+
+func TestDlsBulkOperationTasks(t *testing.T) {
+	for operation, taskName := range dlsBulkOperationsTaskNames {
+		if !isDlsBulkOperationTask(taskName) {
+			t.Errorf("expected the task of %v to be a bulk operation task", operation)
+		}
+	}
+	for _, taskName := range []string{"resend_all_dls_msgs", addPartitionsTaskName, ""} {
+		if isDlsBulkOperationTask(taskName) {
+			t.Errorf("expected %q not to be a bulk operation task", taskName)
+		}
+	}
+	if subject := dlsExportSubject(42); subject != "$memphis_dls_exports.42" {
+		t.Errorf("unexpected export subject %v", subject)
+	}
+}
+
+func TestBulkDlsOperationHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	originalServ := serv
+	serv = &Server{}
+	defer func() { serv = originalServ }()
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{name: "missing operation", body: `{"station_name": "orders"}`, expected: 400},
+		{name: "invalid station name", body: `{"station_name": "orders!", "operation": "drop"}`, expected: SHOWABLE_ERROR_STATUS_CODE},
+		{name: "unsupported operation", body: `{"station_name": "orders", "operation": "archive"}`, expected: SHOWABLE_ERROR_STATUS_CODE, message: "not supported"},
+		{name: "unsupported dls type", body: `{"station_name": "orders", "operation": "drop", "filter": {"dls_type": "functions"}}`, expected: SHOWABLE_ERROR_STATUS_CODE, message: "DLS type"},
+		{name: "reversed time range", body: `{"station_name": "orders", "operation": "export", "filter": {"from_time": "2023-05-01T10:30:00Z", "to_time": "2023-05-01T10:00:00Z"}}`, expected: SHOWABLE_ERROR_STATUS_CODE, message: "from_time"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/stations/bulkDlsOperation", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", models.User{Username: "root", TenantName: "acme"})
+
+		StationsHandler{S: serv}.BulkDlsOperation(c)
+		if w.Code != tc.expected {
+			t.Errorf("%v: expected status %v, got %v: %v", tc.name, tc.expected, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tc.message) {
+			t.Errorf("%v: expected the response to contain %q, got %v", tc.name, tc.message, w.Body.String())
+		}
+	}
+}
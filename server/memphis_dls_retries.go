@@ -0,0 +1,438 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	dlsRetryStatusPending            = "pending"
+	dlsRetryStatusSucceeded          = "succeeded"
+	dlsRetryStatusExhausted          = "exhausted"
+	dlsRetryStatusCancelled          = "cancelled"
+	dlsRetryDefaultBackoffMultiplier = 2
+	dlsRetryMaxBackoff               = 24 * time.Hour
+	dlsRetryInterval                 = 5 * time.Second
+	dlsRetryLease                    = time.Minute
+	dlsRetryBatchSize                = 100
+)
+
+// dlsRetryBackoff returns the delay before the next attempt given the number of attempts already made
+func dlsRetryBackoff(policy models.DlsRetryPolicy, attempts int) time.Duration {
+	multiplier := policy.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delayMs := float64(policy.InitialDelayMs) * math.Pow(multiplier, float64(attempts))
+	if delayMs > float64(dlsRetryMaxBackoff.Milliseconds()) || math.IsInf(delayMs, 0) || math.IsNaN(delayMs) {
+		return dlsRetryMaxBackoff
+	}
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+func dlsRetryPolicyCoversCg(policy models.DlsRetryPolicy, cgName string) bool {
+	return len(policy.ConsumersGroups) == 0 || slices.Contains(policy.ConsumersGroups, cgName)
+}
+
+// dlsRetryAckDeadline returns the time by which a redelivered message is either acked or poisoned again by the consumer group
+func dlsRetryAckDeadline(attemptedAt time.Time, maxAckTimeMs int64, maxMsgDeliveries int) time.Time {
+	if maxMsgDeliveries < 1 {
+		maxMsgDeliveries = 1
+	}
+	return attemptedAt.Add(time.Duration(maxAckTimeMs*int64(maxMsgDeliveries)) * time.Millisecond)
+}
+
+// dlsRetrySucceeded reports whether the last attempt of a retry succeeded, which is only known once its ack deadline passed
+// without the consumer group being poisoned again, otherwise the time to check again is returned
+func dlsRetrySucceeded(retry models.DlsMessageRetry, poisoned bool, now time.Time) (bool, time.Time) {
+	if poisoned {
+		return false, time.Time{}
+	}
+	if now.Before(retry.AckDeadline) {
+		return false, retry.AckDeadline
+	}
+	return true, time.Time{}
+}
+
+// attachDlsRetriesToPoisonedCgs adds the redelivery attempts of every consumer group to the message journey
+func attachDlsRetriesToPoisonedCgs(poisonedCgs []models.PoisonedCg, retries []models.DlsMessageRetry) {
+	for i := range poisonedCgs {
+		poisonedCgs[i].RetryAttempts = []models.DlsRetryAttempt{}
+		for _, retry := range retries {
+			if retry.CgName == poisonedCgs[i].CgName {
+				poisonedCgs[i].RetryStatus = retry.Status
+				poisonedCgs[i].RetryAttempts = retry.History
+				break
+			}
+		}
+	}
+}
+
+func (s *Server) scheduleDlsMessageRetry(station models.Station, dlsMsgId int, cgName string) {
+	exist, policy, err := db.GetDlsRetryPolicy(station.ID)
+	if err != nil {
+		s.Errorf("[tenant: %v]scheduleDlsMessageRetry at GetDlsRetryPolicy: station %v: %v", station.TenantName, station.Name, err.Error())
+		return
+	}
+	if !exist || !dlsRetryPolicyCoversCg(policy, cgName) {
+		return
+	}
+	exist, retry, err := db.GetDlsMessageRetry(dlsMsgId, cgName)
+	if err != nil {
+		s.Errorf("[tenant: %v]scheduleDlsMessageRetry at GetDlsMessageRetry: station %v: %v", station.TenantName, station.Name, err.Error())
+		return
+	}
+	if exist && retry.Status == dlsRetryStatusExhausted && retry.Attempts >= policy.MaxAttempts {
+		return
+	}
+	// the backoff continues from the attempts of previous rounds
+	err = db.UpsertDlsMessageRetry(dlsMsgId, cgName, station.ID, station.TenantName, time.Now().Add(dlsRetryBackoff(policy, retry.Attempts)))
+	if err != nil {
+		s.Errorf("[tenant: %v]scheduleDlsMessageRetry at UpsertDlsMessageRetry: station %v: %v", station.TenantName, station.Name, err.Error())
+	}
+}
+
+func (s *Server) RetryDlsMessages() {
+	ticker := time.NewTicker(dlsRetryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		retries, err := db.ClaimDueDlsMessageRetries(dlsRetryBatchSize, dlsRetryLease)
+		if err != nil {
+			s.Errorf("RetryDlsMessages at ClaimDueDlsMessageRetries: %v", err.Error())
+			continue
+		}
+		for _, retry := range retries {
+			err = s.retryDlsMessage(retry)
+			if err != nil {
+				s.Errorf("[tenant: %v]RetryDlsMessages at retryDlsMessage: dls message %v, consumer group %v: %v", retry.TenantName, retry.DlsMessageId, retry.CgName, err.Error())
+			}
+		}
+	}
+}
+
+// retryDlsMessage handles a claimed retry, on error the retry is picked up again once its lease expires
+func (s *Server) retryDlsMessage(retry models.DlsMessageRetry) error {
+	exist, dlsMsg, err := db.GetDlsMessageById(retry.DlsMessageId)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	succeeded, recheckAt := dlsRetrySucceeded(retry, slices.Contains(dlsMsg.PoisonedCgs, retry.CgName), time.Now())
+	if succeeded {
+		retry.Status = dlsRetryStatusSucceeded
+		return db.UpdateDlsMessageRetry(retry)
+	}
+	if !recheckAt.IsZero() {
+		retry.NextRetryAt = recheckAt
+		return db.UpdateDlsMessageRetry(retry)
+	}
+
+	exist, station, err := db.GetStationById(retry.StationId, retry.TenantName)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	exist, policy, err := db.GetDlsRetryPolicy(station.ID)
+	if err != nil {
+		return err
+	}
+	if !exist || !dlsRetryPolicyCoversCg(policy, retry.CgName) {
+		retry.Status = dlsRetryStatusCancelled
+		return db.UpdateDlsMessageRetry(retry)
+	}
+
+	if retry.Attempts >= policy.MaxAttempts {
+		retry.Status = dlsRetryStatusExhausted
+		err = db.UpdateDlsMessageRetry(retry)
+		if err != nil {
+			return err
+		}
+		msgUrl := s.opts.UiHost + "/stations/" + station.Name + "/" + strconv.Itoa(dlsMsg.ID)
+		message := fmt.Sprintf("Automatic redelivery of a poison message to consumer group %v gave up after %v attempts, for more details head to: %v", retry.CgName, retry.Attempts, msgUrl)
+		err = s.SendNotification(station.TenantName, PoisonMessageTitle, message, PoisonMAlert)
+		if err != nil {
+			s.Warnf("[tenant: %v]retryDlsMessage at SendNotification: Error while sending a poison message notification: %v", station.TenantName, err.Error())
+		}
+		return nil
+	}
+
+	cgMembers, err := GetConsumerGroupMembers(retry.CgName, station)
+	if err != nil {
+		return err
+	}
+	if len(cgMembers) == 0 {
+		retry.Status = dlsRetryStatusCancelled
+		return db.UpdateDlsMessageRetry(retry)
+	}
+
+	dlsMsg.PoisonedCgs = []string{retry.CgName}
+	attempt := models.DlsRetryAttempt{
+		Attempt:     retry.Attempts + 1,
+		AttemptedAt: time.Now(),
+	}
+	_, err = s.ResendUnackedMsg(dlsMsg, models.User{TenantName: station.TenantName}, station.Name)
+	if err != nil {
+		attempt.Error = err.Error()
+		s.Warnf("[tenant: %v]retryDlsMessage at ResendUnackedMsg: station %v: %v", station.TenantName, station.Name, err.Error())
+	}
+	retry.Attempts++
+	retry.History = append(retry.History, attempt)
+	retry.AckDeadline = dlsRetryAckDeadline(attempt.AttemptedAt, cgMembers[0].MaxAckTimeMs, cgMembers[0].MaxMsgDeliveries)
+	retry.NextRetryAt = attempt.AttemptedAt.Add(dlsRetryBackoff(policy, retry.Attempts))
+	if retry.NextRetryAt.Before(retry.AckDeadline) {
+		retry.NextRetryAt = retry.AckDeadline
+	}
+	return db.UpdateDlsMessageRetry(retry)
+}
+
+func (sh StationsHandler) UpdateDlsRetryPolicy(c *gin.Context) {
+	var body models.UpdateDlsRetryPolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateDlsRetryPolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateDlsRetryPolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	if body.BackoffMultiplier == 0 {
+		body.BackoffMultiplier = dlsRetryDefaultBackoffMultiplier
+	}
+	if body.BackoffMultiplier < 1 {
+		errMsg := "Backoff multiplier must be at least 1"
+		serv.Warnf("[tenant: %v][user: %v]UpdateDlsRetryPolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	consumersGroups := []string{}
+	for _, cg := range body.ConsumersGroups {
+		cg = strings.ToLower(strings.TrimSpace(cg))
+		if cg != _EMPTY_ && !slices.Contains(consumersGroups, cg) {
+			consumersGroups = append(consumersGroups, cg)
+		}
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateDlsRetryPolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]UpdateDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateDlsRetryPolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]UpdateDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	policy, err := db.UpsertDlsRetryPolicy(station.ID, station.TenantName, body.MaxAttempts, body.InitialDelayMs, body.BackoffMultiplier, consumersGroups)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateDlsRetryPolicy at UpsertDlsRetryPolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	message := fmt.Sprintf("DLS retry policy of station %v has been updated by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateDlsRetryPolicy at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	c.IndentedJSON(200, policy)
+}
+
+func (sh StationsHandler) GetDlsRetryPolicy(c *gin.Context) {
+	var body models.GetDlsRetryPolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetDlsRetryPolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]GetDlsRetryPolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "read")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetDlsRetryPolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to read station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]GetDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetDlsRetryPolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]GetDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, policy, err := db.GetDlsRetryPolicy(station.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetDlsRetryPolicy at db.GetDlsRetryPolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		c.IndentedJSON(200, gin.H{})
+		return
+	}
+
+	c.IndentedJSON(200, policy)
+}
+
+func (sh StationsHandler) RemoveDlsRetryPolicy(c *gin.Context) {
+	var body models.RemoveDlsRetryPolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveDlsRetryPolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]RemoveDlsRetryPolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveDlsRetryPolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]RemoveDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveDlsRetryPolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]RemoveDlsRetryPolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	// pending retries of the station are cancelled by the retry task once they are due
+	err = db.RemoveDlsRetryPolicy(station.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveDlsRetryPolicy at db.RemoveDlsRetryPolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	message := fmt.Sprintf("DLS retry policy of station %v has been removed by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveDlsRetryPolicy at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
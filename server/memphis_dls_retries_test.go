@@ -0,0 +1,114 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestDlsRetryBackoff(t *testing.T) {
+	policy := models.DlsRetryPolicy{InitialDelayMs: 1000, BackoffMultiplier: 2}
+	cases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 0, expected: time.Second},
+		{attempts: 1, expected: 2 * time.Second},
+		{attempts: 3, expected: 8 * time.Second},
+		{attempts: 100, expected: dlsRetryMaxBackoff},
+	}
+
+	for _, c := range cases {
+		delay := dlsRetryBackoff(policy, c.attempts)
+		if delay != c.expected {
+			t.Errorf("attempt %v: expected %v, got %v", c.attempts, c.expected, delay)
+		}
+	}
+
+	policy.BackoffMultiplier = 0
+	if delay := dlsRetryBackoff(policy, 5); delay != time.Second {
+		t.Errorf("expected a constant delay for a multiplier below 1, got %v", delay)
+	}
+}
+
+func TestDlsRetryAckDeadline(t *testing.T) {
+	attemptedAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	if deadline := dlsRetryAckDeadline(attemptedAt, 30000, 3); !deadline.Equal(attemptedAt.Add(90 * time.Second)) {
+		t.Errorf("expected the deadline to cover every delivery, got %v", deadline)
+	}
+	if deadline := dlsRetryAckDeadline(attemptedAt, 30000, 0); !deadline.Equal(attemptedAt.Add(30 * time.Second)) {
+		t.Errorf("expected at least a single delivery, got %v", deadline)
+	}
+}
+
+func TestDlsRetrySucceeded(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		retry     models.DlsMessageRetry
+		poisoned  bool
+		succeeded bool
+		recheckAt time.Time
+	}{
+		{
+			name:     "poisoned again",
+			retry:    models.DlsMessageRetry{Attempts: 1, AckDeadline: now.Add(-time.Minute)},
+			poisoned: true,
+		},
+		{
+			name:      "not poisoned before the ack deadline",
+			retry:     models.DlsMessageRetry{Attempts: 1, AckDeadline: now.Add(time.Minute)},
+			recheckAt: now.Add(time.Minute),
+		},
+		{
+			name:      "not poisoned after the ack deadline",
+			retry:     models.DlsMessageRetry{Attempts: 1, AckDeadline: now.Add(-time.Minute)},
+			succeeded: true,
+		},
+		{
+			name:      "acked before any attempt",
+			retry:     models.DlsMessageRetry{},
+			succeeded: true,
+		},
+	}
+
+	for _, c := range cases {
+		succeeded, recheckAt := dlsRetrySucceeded(c.retry, c.poisoned, now)
+		if succeeded != c.succeeded || !recheckAt.Equal(c.recheckAt) {
+			t.Errorf("%v: expected %v and recheck at %v, got %v and %v", c.name, c.succeeded, c.recheckAt, succeeded, recheckAt)
+		}
+	}
+}
+
+func TestAttachDlsRetriesToPoisonedCgs(t *testing.T) {
+	attemptedAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	poisonedCgs := []models.PoisonedCg{{CgName: "billing"}, {CgName: "audit"}}
+	retries := []models.DlsMessageRetry{
+		{CgName: "billing", Status: dlsRetryStatusPending, Attempts: 2, History: []models.DlsRetryAttempt{
+			{Attempt: 1, AttemptedAt: attemptedAt},
+			{Attempt: 2, AttemptedAt: attemptedAt.Add(time.Minute), Error: "stream not found"},
+		}},
+		{CgName: "shipping", Status: dlsRetryStatusSucceeded, Attempts: 1, History: []models.DlsRetryAttempt{{Attempt: 1, AttemptedAt: attemptedAt}}},
+	}
+
+	attachDlsRetriesToPoisonedCgs(poisonedCgs, retries)
+	if poisonedCgs[0].RetryStatus != dlsRetryStatusPending || len(poisonedCgs[0].RetryAttempts) != 2 || poisonedCgs[0].RetryAttempts[1].Error != "stream not found" {
+		t.Errorf("expected the attempts of billing in its journey, got %+v", poisonedCgs[0])
+	}
+	if poisonedCgs[1].RetryStatus != _EMPTY_ || poisonedCgs[1].RetryAttempts == nil || len(poisonedCgs[1].RetryAttempts) != 0 {
+		t.Errorf("expected no attempts for audit, got %+v", poisonedCgs[1])
+	}
+}
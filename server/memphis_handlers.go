@@ -42,6 +42,7 @@ type Handlers struct {
 	userMgmt       UserMgmtHandler
 	AsyncTasks     AsyncTasksHandler
 	Functions      FunctionsHandler
+	AlertRules     AlertRulesHandler
 }
 
 var serv *Server
@@ -148,6 +149,7 @@ func CreateDefaultStation(tenantName string, s *Server, sn StationName, user mod
 	if rowsUpdated == 0 {
 		return models.Station{}, false, nil
 	}
+	sendStationPartitionsCacheUpdate(newStation.TenantName, sn.Intern(), newStation.PartitionsList)
 
 	err = CreateDefaultTags("station", newStation.ID, tenantName)
 	if err != nil {
@@ -119,6 +119,14 @@ func (ash AsyncTasksHandler) GetAllAsyncTasks(tenantName string) ([]models.Async
 		switch task.Name {
 		case "resend_all_dls_msgs":
 			task.Name = "Resend All DLS Messages"
+		case addPartitionsTaskName:
+			task.Name = "Add Partitions"
+		case dlsBulkResendTaskName:
+			task.Name = "Resend DLS Messages"
+		case dlsBulkDropTaskName:
+			task.Name = "Drop DLS Messages"
+		case dlsBulkExportTaskName:
+			task.Name = "Export DLS Messages"
 		case "clone_repo":
 			task.Name = "Add GitHub Repo"
 		case "install_function":
@@ -128,3 +136,90 @@ func (ash AsyncTasksHandler) GetAllAsyncTasks(tenantName string) ([]models.Async
 	}
 	return asyncTasks, nil
 }
+
+// CancelAsyncTask marks a running task as cancelled, the task stops once its runner notices the status change
+func (ash AsyncTasksHandler) CancelAsyncTask(c *gin.Context) {
+	var body models.CancelAsyncTaskSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CancelAsyncTask at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	exist, task, err := db.GetAsyncTaskById(body.TaskId, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CancelAsyncTask at GetAsyncTaskById: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Task %v does not exist", body.TaskId)
+		serv.Warnf("[tenant: %v][user: %v]CancelAsyncTask: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if !isDlsBulkOperationTask(task.Name) && task.Name != tieredStorageRehydrationTaskName {
+		errMsg := fmt.Sprintf("Task %v can not be cancelled", body.TaskId)
+		serv.Warnf("[tenant: %v][user: %v]CancelAsyncTask: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationById(task.StationId, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CancelAsyncTask at GetStationById: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if exist {
+		allowed, _, err := ValidateStationPermissions(user.Roles, station.Name, user.TenantName, "write")
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]CancelAsyncTask at ValidateStationPermissions: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !allowed {
+			errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, station.Name)
+			serv.Warnf("[tenant: %v][user: %v]CancelAsyncTask: %v", user.TenantName, user.Username, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+	}
+
+	cancelled, err := db.CancelAsyncTask(task.ID, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CancelAsyncTask at db.CancelAsyncTask: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !cancelled {
+		errMsg := fmt.Sprintf("Task %v is not running", body.TaskId)
+		serv.Warnf("[tenant: %v][user: %v]CancelAsyncTask: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	message := fmt.Sprintf("Task %v has been cancelled by user %v", body.TaskId, user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       station.Name,
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CancelAsyncTask at CreateAuditLogs: task %v: %v", user.TenantName, user.Username, body.TaskId, err.Error())
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
@@ -36,13 +36,13 @@ const (
 func updateNewClientWithConfig(c *client, connId string) {
 	// TODO more configurations logic here
 
-	slackEnabled, err := IsSlackEnabled(c.acc.GetName())
+	notificationsEnabled, err := IsNotificationsEnabled(c.acc.GetName())
 	if err != nil {
 		c.Errorf("updateNewClientWithConfig: %v", err.Error())
 	}
 
 	config := models.GlobalConfigurationsUpdate{
-		Notifications: slackEnabled,
+		Notifications: notificationsEnabled,
 	}
 
 	sendConnectUpdate(c, config, connId)
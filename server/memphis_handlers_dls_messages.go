@@ -144,6 +144,9 @@ func (s *Server) handleNewUnackedMsg(msg []byte) error {
 	if dlsMsgId == 0 { // nothing to do
 		return nil
 	}
+	if station.IsNative {
+		s.scheduleDlsMessageRetry(station, dlsMsgId, cgName)
+	}
 
 	idForUrl := strconv.Itoa(dlsMsgId)
 	var msgUrl = s.opts.UiHost + "/stations/" + stationName.Ext() + "/" + idForUrl
@@ -179,20 +182,25 @@ func (s *Server) handleSchemaverseDlsMsg(msg []byte) error {
 		return nil
 	}
 
+	return s.storeSchemaverseDlsMsg(station, message)
+}
+
+func (s *Server) storeSchemaverseDlsMsg(station models.Station, message models.SchemaVerseDlsMessageSdk) error {
+	tenantName := station.TenantName
 	message.Message.TimeSent = time.Now()
-	_, err = db.InsertSchemaverseDlsMsg(station.ID, 0, message.Producer.Name, []string{}, models.MessagePayload(message.Message), message.ValidationError, tenantName, message.PartitionNumber)
+	_, err := db.InsertSchemaverseDlsMsg(station.ID, 0, message.Producer.Name, []string{}, models.MessagePayload(message.Message), message.ValidationError, tenantName, message.PartitionNumber)
 	if err != nil {
-		serv.Errorf("[tenant: %v]handleSchemaverseDlsMsg: %v", tenantName, err.Error())
+		serv.Errorf("[tenant: %v]storeSchemaverseDlsMsg: %v", tenantName, err.Error())
 		return err
 	}
 	data, err := hex.DecodeString(message.Message.Data)
 	if err != nil {
-		serv.Errorf("[tenant: %v]handleSchemaverseDlsMsg at DecodeString: %v", tenantName, err.Error())
+		serv.Errorf("[tenant: %v]storeSchemaverseDlsMsg at DecodeString: %v", tenantName, err.Error())
 		return err
 	}
 	err = s.sendToDlsStation(station, data, message.Message.Headers, "failed_schema", _EMPTY_)
 	if err != nil {
-		serv.Errorf("[tenant: %v]handleSchemaverseDlsMsg at sendToDlsStation: station: %v, Error while getting notified about a poison message: %v", tenantName, station.DlsStation, err.Error())
+		serv.Errorf("[tenant: %v]storeSchemaverseDlsMsg at sendToDlsStation: station: %v, Error while getting notified about a poison message: %v", tenantName, station.DlsStation, err.Error())
 		return err
 	}
 
@@ -286,6 +294,7 @@ func (s *Server) handleNackedDlsMsg(msg []byte) error {
 	if dlsMsgId == 0 { // nothing to do
 		return nil
 	}
+	s.scheduleDlsMessageRetry(station, dlsMsgId, message.CgName)
 
 	idForUrl := strconv.Itoa(dlsMsgId)
 	var msgUrl = s.opts.UiHost + "/stations/" + stationName.Ext() + "/" + idForUrl
@@ -398,6 +407,7 @@ func (pmh PoisonMessagesHandler) GetDlsMessageDetailsById(messageId int, dlsType
 	poisonedCgs := []models.PoisonedCg{}
 	isActive := false
 
+	schemaVersion := messageSchemaVersion(dlsMessage.MessageDetails.Headers)
 	msgDetails := models.MessagePayload{
 		TimeSent: dlsMessage.MessageDetails.TimeSent,
 		Size:     dlsMessage.MessageDetails.Size,
@@ -479,6 +489,18 @@ func (pmh PoisonMessagesHandler) GetDlsMessageDetailsById(messageId int, dlsType
 		}
 	}
 
+	decoder, err := loadStationSchemaDecoder(station)
+	if err != nil {
+		serv.Warnf("[tenant: %v]GetDlsMessageDetailsById at loadStationSchemaDecoder: station %v: %v", station.TenantName, station.Name, err.Error())
+	}
+	decoder.renderMessagePayload(&dlsMsg.MessageDetails, decodeDlsMessageData(dlsMsg.MessageDetails.Data), schemaVersion)
+
+	retries, err := db.GetDlsMessageRetries(dlsMsg.ID)
+	if err != nil {
+		return models.DlsMessageResponse{}, err
+	}
+	attachDlsRetriesToPoisonedCgs(poisonedCgs, retries)
+
 	result := models.DlsMessageResponse{
 		ID:          dlsMsg.ID,
 		StationName: station.Name,
@@ -492,6 +514,7 @@ func (pmh PoisonMessagesHandler) GetDlsMessageDetailsById(messageId int, dlsType
 		UpdatedAt:       dlsMsg.UpdatedAt,
 		PoisonedCgs:     poisonedCgs,
 		ValidationError: dlsMsg.ValidationError,
+		Retries:         retries,
 	}
 
 	return result, nil
@@ -33,9 +33,16 @@ const INTEGRATIONS_AUDIT_LOGS_CONSUMER = "$memphis_integrations_audit_logs_consu
 type IntegrationsHandler struct{ S *Server }
 
 var integrationsAuditLogLabelToSubjectMap = map[string]string{
-	"slack":  integrationsAuditLogsStream + ".%s.slack",
-	"s3":     integrationsAuditLogsStream + ".%s.s3",
-	"github": integrationsAuditLogsStream + ".%s.github",
+	"slack":      integrationsAuditLogsStream + ".%s.slack",
+	"webhook":    integrationsAuditLogsStream + ".%s.webhook",
+	"smtp":       integrationsAuditLogsStream + ".%s.smtp",
+	"teams":      integrationsAuditLogsStream + ".%s.teams",
+	"discord":    integrationsAuditLogsStream + ".%s.discord",
+	"s3":         integrationsAuditLogsStream + ".%s.s3",
+	"filesystem": integrationsAuditLogsStream + ".%s.filesystem",
+	"gcs":        integrationsAuditLogsStream + ".%s.gcs",
+	"azure_blob": integrationsAuditLogsStream + ".%s.azure_blob",
+	"github":     integrationsAuditLogsStream + ".%s.github",
 }
 
 func (it IntegrationsHandler) CreateIntegration(c *gin.Context) {
@@ -88,6 +95,69 @@ func (it IntegrationsHandler) CreateIntegration(c *gin.Context) {
 			return
 		}
 		integration = slackIntegration
+	case "webhook":
+		if !ValidataAccessToFeature(user.TenantName, "feature-integration-webhook") {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-integration-webhook")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		webhookIntegration, errorCode, err := it.handleCreateWebhookIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateWebhookIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateWebhookIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Webhook: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = webhookIntegration
+	case "smtp":
+		if !ValidataAccessToFeature(user.TenantName, "feature-integration-smtp") {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-integration-smtp")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		smtpIntegration, errorCode, err := it.handleCreateSmtpIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateSmtpIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateSmtpIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with SMTP: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = smtpIntegration
+	case "teams", "discord":
+		if !ValidataAccessToFeature(user.TenantName, "feature-integration-"+integrationType) {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-integration-"+integrationType)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		incomingWebhookIntegration, errorCode, err := it.handleCreateIncomingWebhookIntegration(integrationType, user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateIncomingWebhookIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateIncomingWebhookIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with %v: %v", integrationType, message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = incomingWebhookIntegration
 	case "s3":
 		if !ValidataAccessToFeature(user.TenantName, "feature-storage-tiering") {
 			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-storage-tiering")
@@ -109,6 +179,69 @@ func (it IntegrationsHandler) CreateIntegration(c *gin.Context) {
 			return
 		}
 		integration = s3Integration
+	case filesystemIntegrationName:
+		if !ValidataAccessToFeature(user.TenantName, "feature-storage-tiering") {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-storage-tiering")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		filesystemIntegration, errorCode, err := it.handleCreateFilesystemIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateFilesystemIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateFilesystemIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with the filesystem: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = filesystemIntegration
+	case gcsIntegrationName:
+		if !ValidataAccessToFeature(user.TenantName, "feature-storage-tiering") {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-storage-tiering")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		gcsIntegration, errorCode, err := it.handleCreateGcsIntegration(user.TenantName, body.Keys)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateGcsIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateGcsIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Google Cloud Storage: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = gcsIntegration
+	case azureBlobIntegrationName:
+		if !ValidataAccessToFeature(user.TenantName, "feature-storage-tiering") {
+			serv.Warnf("[tenant: %v][user: %v]CreateIntegration at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-storage-tiering")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "This feature is not available on your current pricing plan, in order to enjoy it you will have to upgrade your plan"})
+			return
+		}
+		azureBlobIntegration, errorCode, err := it.handleCreateAzureBlobIntegration(user.TenantName, body.Keys)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]CreateIntegration at handleCreateAzureBlobIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]CreateIntegration at handleCreateAzureBlobIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Azure Blob Storage: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = azureBlobIntegration
 	case "github":
 		githubIntegration, errorCode, err := it.handleCreateGithubIntegration(user.TenantName, body.Keys)
 		if err != nil {
@@ -185,6 +318,54 @@ func (it IntegrationsHandler) UpdateIntegration(c *gin.Context) {
 			return
 		}
 		integration = slackIntegration
+	case "webhook":
+		webhookIntegration, errorCode, err := it.handleUpdateWebhookIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateWebhookIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateWebhookIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Webhook: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = webhookIntegration
+	case "smtp":
+		smtpIntegration, errorCode, err := it.handleUpdateSmtpIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateSmtpIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateSmtpIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with SMTP: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = smtpIntegration
+	case "teams", "discord":
+		incomingWebhookIntegration, errorCode, err := it.handleUpdateIncomingWebhookIntegration(integrationType, user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateIncomingWebhookIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateIncomingWebhookIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with %v: %v", integrationType, message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = incomingWebhookIntegration
 	case "s3":
 		s3Integration, errorCode, err := it.handleUpdateS3Integration(user.TenantName, body)
 		if err != nil {
@@ -201,6 +382,54 @@ func (it IntegrationsHandler) UpdateIntegration(c *gin.Context) {
 			return
 		}
 		integration = s3Integration
+	case filesystemIntegrationName:
+		filesystemIntegration, errorCode, err := it.handleUpdateFilesystemIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateFilesystemIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateFilesystemIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with the filesystem: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = filesystemIntegration
+	case gcsIntegrationName:
+		gcsIntegration, errorCode, err := it.handleUpdateGcsIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateGcsIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateGcsIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Google Cloud Storage: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = gcsIntegration
+	case azureBlobIntegrationName:
+		azureBlobIntegration, errorCode, err := it.handleUpdateAzureBlobIntegration(user.TenantName, body)
+		if err != nil {
+			if errorCode == 500 {
+				serv.Errorf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateAzureBlobIntegration: %v", user.TenantName, user.Username, err.Error())
+				message = "Server error"
+			} else {
+				message = err.Error()
+				serv.Warnf("[tenant: %v][user: %v]UpdateIntegration at handleUpdateAzureBlobIntegration: %v", user.TenantName, user.Username, message)
+				auditLog := fmt.Sprintf("Error while trying to connect with Azure Blob Storage: %v", message)
+				it.Errorf(integrationType, user.TenantName, auditLog)
+			}
+			c.AbortWithStatusJSON(errorCode, gin.H{"message": message})
+			return
+		}
+		integration = azureBlobIntegration
 	case "github":
 		_, locked, _, err := db.GetAndLockSharedLock("functions", user.TenantName)
 		if err != nil {
@@ -464,10 +693,25 @@ func (it IntegrationsHandler) GetIntegrationDetails(c *gin.Context) {
 		integration.Keys["auth_token"] = "xoxb-****"
 	}
 
-	if integration.Name == "s3" && integration.Keys["secret_key"] != _EMPTY_ {
+	if (integration.Name == "s3" || integration.Name == gcsIntegrationName || integration.Name == azureBlobIntegrationName) && integration.Keys["secret_key"] != _EMPTY_ {
 		integration.Keys["secret_key"] = hideIntegrationSecretKey(integration.Keys["secret_key"].(string))
 	}
 
+	if integration.Name == "webhook" && integration.Keys["signing_secret"] != _EMPTY_ {
+		integration.Keys["signing_secret"] = hideIntegrationSecretKey(integration.Keys["signing_secret"].(string))
+	}
+	if headers, ok := integration.Keys["headers"].(map[string]interface{}); ok && integration.Name == "webhook" {
+		integration.Keys["headers"] = hideWebhookHeaders(headers)
+	}
+
+	if integration.Name == "smtp" && integration.Keys["password"] != _EMPTY_ {
+		integration.Keys["password"] = hideSmtpPassword(integration.Keys["password"].(string))
+	}
+
+	if (integration.Name == "teams" || integration.Name == "discord") && integration.Keys["webhook_url"] != _EMPTY_ {
+		integration.Keys["webhook_url"] = "****"
+	}
+
 	sourceCodeIntegration, branchesMap, err := getSourceCodeDetails(user.TenantName, body, "get_all_repos")
 	if err != nil {
 		serv.Errorf("[tenant: %v][user: %v]GetIntegrationDetails at getSourceCodeDetails: Integration %v: %v", user.TenantName, user.Username, body.Name, err.Error())
@@ -523,9 +767,21 @@ func (it IntegrationsHandler) GetAllIntegrations(c *gin.Context) {
 		if integrations[i].Name == "slack" && integrations[i].Keys["auth_token"] != _EMPTY_ {
 			integrations[i].Keys["auth_token"] = "xoxb-****"
 		}
-		if integrations[i].Name == "s3" && integrations[i].Keys["secret_key"] != _EMPTY_ {
+		if (integrations[i].Name == "s3" || integrations[i].Name == gcsIntegrationName || integrations[i].Name == azureBlobIntegrationName) && integrations[i].Keys["secret_key"] != _EMPTY_ {
 			integrations[i].Keys["secret_key"] = hideIntegrationSecretKey(integrations[i].Keys["secret_key"].(string))
 		}
+		if integrations[i].Name == "webhook" && integrations[i].Keys["signing_secret"] != _EMPTY_ {
+			integrations[i].Keys["signing_secret"] = hideIntegrationSecretKey(integrations[i].Keys["signing_secret"].(string))
+		}
+		if headers, ok := integrations[i].Keys["headers"].(map[string]interface{}); ok && integrations[i].Name == "webhook" {
+			integrations[i].Keys["headers"] = hideWebhookHeaders(headers)
+		}
+		if integrations[i].Name == "smtp" && integrations[i].Keys["password"] != _EMPTY_ {
+			integrations[i].Keys["password"] = hideSmtpPassword(integrations[i].Keys["password"].(string))
+		}
+		if (integrations[i].Name == "teams" || integrations[i].Name == "discord") && integrations[i].Keys["webhook_url"] != _EMPTY_ {
+			integrations[i].Keys["webhook_url"] = "****"
+		}
 		if integrations[i].Name == "github" && integrations[i].Keys["installation_id"] != _EMPTY_ {
 			memphisFuncs, err := db.GetMemphisFunctionsByMemphis()
 			if err != nil {
@@ -687,7 +687,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 	if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(user.TenantName); !ok {
 		station.TieredStorageEnabled = false
 	} else {
-		_, ok = tenantInetgrations["s3"].(models.Integration)
+		ok = hasTier2StorageIntegration(tenantInetgrations)
 		if !ok {
 			station.TieredStorageEnabled = false
 		} else if station.TieredStorageEnabled {
@@ -838,6 +838,23 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 		}
 	}
 
+	schemaMetrics, err := db.GetStationSchemaMetrics(station.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetStationOverviewData at GetStationSchemaMetrics: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	response["schema_strict"] = station.SchemaStrict
+	response["schema_validation_metrics"] = gin.H{"valid_messages": schemaMetrics.ValidMessages, "invalid_messages": schemaMetrics.InvalidMessages}
+
+	scheduledMessages, err := mh.S.getScheduledMessagesCount(station.TenantName, stationName, body.PartitionNumber)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetStationOverviewData at getScheduledMessagesCount: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	response["scheduled_messages"] = scheduledMessages
+
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
 		analyticsParams := make(map[string]interface{})
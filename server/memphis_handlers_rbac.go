@@ -145,6 +145,7 @@ func GetAllMemphisAndNatsInternalSubjects() []string {
 	subjects = append(subjects, memphisSchemaCreations)
 	subjects = append(subjects, memphisStationCreations)
 	subjects = append(subjects, memphisStationDestructions)
+	subjects = append(subjects, memphisStationUpdates)
 
 	// Nats subjects
 	subjects = append(subjects, inboxSubject)
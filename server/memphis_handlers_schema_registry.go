@@ -0,0 +1,778 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The schema registry API follows the Confluent Schema Registry semantics over schemaverse with these differences:
+//   - subjects are schema names, so a subject which is not a valid lowercase schema name is rejected on registration
+//     instead of being stored as is, subjects are matched exactly as in Confluent
+//   - a registered version becomes the active version stations validate against, rolling back to an older version
+//     through schemaverse keeps latest pointing to the newest version while stations validate against the older one
+//   - graphql, xsd and thrift schemas are not exposed as subjects
+
+// Error codes of the Confluent Schema Registry REST API
+const (
+	schemaRegistryUnauthorized             = 40101
+	schemaRegistrySubjectNotFound          = 40401
+	schemaRegistryVersionNotFound          = 40402
+	schemaRegistrySchemaNotFound           = 40403
+	schemaRegistryIncompatibleSchema       = 409
+	schemaRegistryInvalidSchema            = 42201
+	schemaRegistryInvalidVersion           = 42202
+	schemaRegistryInvalidCompatibilityMode = 42203
+	schemaRegistryReferenceExists          = 42206
+	schemaRegistryInvalidSubject           = 42208
+	schemaRegistryServerError              = 50001
+)
+
+func schemaRegistryError(c *gin.Context, status int, errorCode int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error_code": errorCode, "message": message})
+}
+
+func schemaRegistryServerErr(c *gin.Context) {
+	schemaRegistryError(c, 500, schemaRegistryServerError, "Server error")
+}
+
+// schemaRegistryAuthenticator is replaced in tests
+var schemaRegistryAuthenticator = authenticateUser
+
+// SchemaRegistryAuthenticate authenticates schema registry clients with HTTP basic auth as Confluent clients do,
+// the credentials are the ones of a management user
+func SchemaRegistryAuthenticate(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok || username == _EMPTY_ {
+		c.Header("WWW-Authenticate", `Basic realm="schemaRegistry"`)
+		schemaRegistryError(c, 401, schemaRegistryUnauthorized, "Unauthorized")
+		return
+	}
+	authenticated, user, err := schemaRegistryAuthenticator(strings.ToLower(username), password)
+	if err != nil {
+		serv.Errorf("SchemaRegistryAuthenticate at authenticateUser: User %v: %v", username, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !authenticated || user.UserType == "application" {
+		c.Header("WWW-Authenticate", `Basic realm="schemaRegistry"`)
+		schemaRegistryError(c, 401, schemaRegistryUnauthorized, "Unauthorized")
+		return
+	}
+	if user.TenantName != DEFAULT_GLOBAL_ACCOUNT {
+		user.TenantName = strings.ToLower(user.TenantName)
+	}
+	user.Password = _EMPTY_
+	c.Set("user", user)
+	c.Next()
+}
+
+// schemaRegistryTypeToSchemaType maps a registry schema type to the schemaverse type, the registry default is AVRO
+func schemaRegistryTypeToSchemaType(registryType string) (string, error) {
+	switch strings.ToUpper(registryType) {
+	case _EMPTY_, "AVRO":
+		return "avro", nil
+	case "PROTOBUF":
+		return "protobuf", nil
+	case "JSON":
+		return "json", nil
+	default:
+		return _EMPTY_, fmt.Errorf("unsupported schema type %v", registryType)
+	}
+}
+
+// isSchemaRegistryType returns whether the schema type can be expressed by the registry, graphql, xsd and thrift schemas are not exposed
+func isSchemaRegistryType(schemaType string) bool {
+	return schemaType == "avro" || schemaType == "protobuf" || schemaType == "json"
+}
+
+func schemaTypeToSchemaRegistryType(schemaType string) string {
+	return strings.ToUpper(schemaType)
+}
+
+func schemaRegistryReferencesToSchemaReferences(references []models.SchemaRegistryReference) []models.SchemaReference {
+	schemaReferences := []models.SchemaReference{}
+	for _, ref := range references {
+		schemaReferences = append(schemaReferences, models.SchemaReference{Name: ref.Name, SchemaName: ref.Subject, VersionNumber: ref.Version})
+	}
+	return schemaReferences
+}
+
+func schemaReferencesToSchemaRegistryReferences(references []models.SchemaReference) []models.SchemaRegistryReference {
+	registryReferences := []models.SchemaRegistryReference{}
+	for _, ref := range references {
+		registryReferences = append(registryReferences, models.SchemaRegistryReference{Name: ref.Name, Subject: ref.SchemaName, Version: ref.VersionNumber})
+	}
+	return registryReferences
+}
+
+func schemaRegistryVersionResponse(schema models.Schema, version models.SchemaVersion) gin.H {
+	response := gin.H{
+		"subject": schema.Name,
+		"id":      version.ID,
+		"version": version.VersionNumber,
+		"schema":  version.SchemaContent,
+	}
+	if schema.Type != "avro" {
+		response["schemaType"] = schemaTypeToSchemaRegistryType(schema.Type)
+	}
+	if len(version.References) > 0 {
+		response["references"] = schemaReferencesToSchemaRegistryReferences(version.References)
+	}
+	return response
+}
+
+// getSchemaRegistrySubject returns the schema a subject is mapped to, schemas of types the registry can not express are not exposed
+func getSchemaRegistrySubject(subject, tenantName string) (bool, models.Schema, error) {
+	exist, schema, err := db.GetSchemaByName(subject, tenantName)
+	if err != nil {
+		return false, models.Schema{}, err
+	}
+	if !exist || !isSchemaRegistryType(schema.Type) {
+		return false, models.Schema{}, nil
+	}
+	return true, schema, nil
+}
+
+// findSchemaRegistryVersion returns the index of a registry version which is either a version number or latest (-1),
+// latest is the newest version
+func findSchemaRegistryVersion(versions []models.SchemaVersion, version string) (int, error) {
+	if version == "latest" || version == "-1" {
+		found := -1
+		for i, v := range versions {
+			if found == -1 || v.VersionNumber > versions[found].VersionNumber {
+				found = i
+			}
+		}
+		return found, nil
+	}
+	versionNumber, err := strconv.Atoi(version)
+	if err != nil {
+		return -1, err
+	}
+	for i, v := range versions {
+		if v.VersionNumber == versionNumber {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// getSchemaRegistryVersion resolves a registry version which is either a version number or latest (-1)
+func getSchemaRegistryVersion(schemaId int, version string) (bool, models.SchemaVersion, error) {
+	versions, err := getSchemaVersionsBySchemaId(schemaId)
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	found, err := findSchemaRegistryVersion(versions, version)
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	if found == -1 {
+		return false, models.SchemaVersion{}, nil
+	}
+	err = loadSchemaVersionsReferences(versions[found : found+1])
+	if err != nil {
+		return false, models.SchemaVersion{}, err
+	}
+	return true, versions[found], nil
+}
+
+func (sh SchemasHandler) GetSchemaRegistryTypes(c *gin.Context) {
+	c.IndentedJSON(200, []string{"AVRO", "JSON", "PROTOBUF"})
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySchemaById(c *gin.Context) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetSchemaRegistrySchemaById at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		schemaRegistryError(c, 404, schemaRegistrySchemaNotFound, "Schema not found")
+		return
+	}
+	exist, version, err := db.GetSchemaVersionById(id, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySchemaById at GetSchemaVersionById: Schema id %v: %v", user.TenantName, user.Username, id, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySchemaNotFound, "Schema not found")
+		return
+	}
+	exist, schema, err := db.GetSchemaById(version.SchemaId, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySchemaById at GetSchemaById: Schema id %v: %v", user.TenantName, user.Username, id, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist || !isSchemaRegistryType(schema.Type) {
+		schemaRegistryError(c, 404, schemaRegistrySchemaNotFound, "Schema not found")
+		return
+	}
+
+	references, err := db.GetSchemaReferencesByVersionId(version.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySchemaById at GetSchemaReferencesByVersionId: Schema id %v: %v", user.TenantName, user.Username, id, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+
+	response := gin.H{"schema": version.SchemaContent}
+	if schema.Type != "avro" {
+		response["schemaType"] = schemaTypeToSchemaRegistryType(schema.Type)
+	}
+	if len(references) > 0 {
+		response["references"] = schemaReferencesToSchemaRegistryReferences(references)
+	}
+	c.IndentedJSON(200, response)
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySubjects(c *gin.Context) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetSchemaRegistrySubjects at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	schemas, err := db.GetAllSchemasDetails(user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySubjects at GetAllSchemasDetails: %v", user.TenantName, user.Username, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subjects := []string{}
+	for _, schema := range schemas {
+		if isSchemaRegistryType(schema.Type) {
+			subjects = append(subjects, schema.Name)
+		}
+	}
+	sort.Strings(subjects)
+	c.IndentedJSON(200, subjects)
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySubjectVersions(c *gin.Context) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetSchemaRegistrySubjectVersions at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySubjectVersions at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	versions, err := getSchemaVersionsBySchemaId(schema.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySubjectVersions at getSchemaVersionsBySchemaId: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	versionNumbers := []int{}
+	for _, v := range versions {
+		versionNumbers = append(versionNumbers, v.VersionNumber)
+	}
+	sort.Ints(versionNumbers)
+	c.IndentedJSON(200, versionNumbers)
+}
+
+func (sh SchemasHandler) getSchemaRegistrySubjectVersion(c *gin.Context, funcName string) (models.Schema, models.SchemaVersion, bool) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("%v at getUserDetailsFromMiddleware: %v", funcName, err.Error())
+		schemaRegistryServerErr(c)
+		return models.Schema{}, models.SchemaVersion{}, false
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]%v at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, funcName, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return models.Schema{}, models.SchemaVersion{}, false
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return models.Schema{}, models.SchemaVersion{}, false
+	}
+	exist, version, err := getSchemaRegistryVersion(schema.ID, c.Param("version"))
+	if err != nil {
+		if _, ok := err.(*strconv.NumError); ok {
+			schemaRegistryError(c, 422, schemaRegistryInvalidVersion, fmt.Sprintf("The specified version '%v' is not a valid version id. Allowed values are between [1, 2^31-1] and the string \"latest\"", c.Param("version")))
+			return models.Schema{}, models.SchemaVersion{}, false
+		}
+		serv.Errorf("[tenant: %v][user: %v]%v at getSchemaRegistryVersion: Subject %v: %v", user.TenantName, user.Username, funcName, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return models.Schema{}, models.SchemaVersion{}, false
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistryVersionNotFound, "Version not found.")
+		return models.Schema{}, models.SchemaVersion{}, false
+	}
+	return schema, version, true
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySubjectVersion(c *gin.Context) {
+	schema, version, ok := sh.getSchemaRegistrySubjectVersion(c, "GetSchemaRegistrySubjectVersion")
+	if !ok {
+		return
+	}
+	c.IndentedJSON(200, schemaRegistryVersionResponse(schema, version))
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySubjectVersionSchema(c *gin.Context) {
+	_, version, ok := sh.getSchemaRegistrySubjectVersion(c, "GetSchemaRegistrySubjectVersionSchema")
+	if !ok {
+		return
+	}
+	c.String(200, version.SchemaContent)
+}
+
+func (sh SchemasHandler) LookupSchemaRegistrySubjectSchema(c *gin.Context) {
+	var body models.SchemaRegistrySchema
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("LookupSchemaRegistrySubjectSchema at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]LookupSchemaRegistrySubjectSchema at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	versions, err := getSchemaVersionsBySchemaId(schema.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]LookupSchemaRegistrySubjectSchema at getSchemaVersionsBySchemaId: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	for i, v := range versions {
+		if v.SchemaContent == body.Schema {
+			err = loadSchemaVersionsReferences(versions[i : i+1])
+			if err != nil {
+				serv.Errorf("[tenant: %v][user: %v]LookupSchemaRegistrySubjectSchema at loadSchemaVersionsReferences: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+				schemaRegistryServerErr(c)
+				return
+			}
+			c.IndentedJSON(200, schemaRegistryVersionResponse(schema, versions[i]))
+			return
+		}
+	}
+	schemaRegistryError(c, 404, schemaRegistrySchemaNotFound, "Schema not found")
+}
+
+func (sh SchemasHandler) RegisterSchemaRegistrySubjectVersion(c *gin.Context) {
+	var body models.SchemaRegistrySchema
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RegisterSchemaRegistrySubjectVersion at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	tenantName := user.TenantName
+	subject := c.Param("subject")
+	err = validateSchemaName(subject)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at validateSchemaName: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryError(c, 422, schemaRegistryInvalidSubject, fmt.Sprintf("Invalid subject '%v': %v", subject, err.Error()))
+		return
+	}
+	schemaType, err := schemaRegistryTypeToSchemaType(body.SchemaType)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at schemaRegistryTypeToSchemaType: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	schemaReferences := schemaRegistryReferencesToSchemaReferences(body.References)
+	references, err := resolveSchemaReferences(schemaType, tenantName, schemaReferences)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchemaReference) {
+			serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at resolveSchemaReferences: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at resolveSchemaReferences: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	err = validateSchemaContentWithReferences(body.Schema, schemaType, references)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at validateSchemaContent: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	messageStructName := _EMPTY_
+	if schemaType == "protobuf" {
+		messageStructName, err = getProtoMessageStructName(body.Schema)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at getProtoMessageStructName: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+	}
+
+	exist, schema, err := db.GetSchemaByName(subject, tenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at GetSchemaByName: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+
+	versionNumber := 1
+	if exist {
+		if schema.Type != schemaType {
+			errMsg := fmt.Sprintf("Schema being registered is incompatible with an earlier schema for subject \"%v\": schema type %v does not match %v", subject, schemaTypeToSchemaRegistryType(schemaType), schemaTypeToSchemaRegistryType(schema.Type))
+			schemaRegistryError(c, 409, schemaRegistryIncompatibleSchema, errMsg)
+			return
+		}
+		versions, err := getSchemaVersionsBySchemaId(schema.ID)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at getSchemaVersionsBySchemaId: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryServerErr(c)
+			return
+		}
+		for _, v := range versions {
+			if v.SchemaContent == body.Schema {
+				c.IndentedJSON(200, gin.H{"id": v.ID})
+				return
+			}
+			if v.VersionNumber >= versionNumber {
+				versionNumber = v.VersionNumber + 1
+			}
+		}
+		if schema.CompatibilityMode != schemaCompatibilityNone {
+			err = loadSchemaVersionsReferences(versions)
+			if err != nil {
+				serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at loadSchemaVersionsReferences: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+				schemaRegistryServerErr(c)
+				return
+			}
+		}
+		violations, err := checkSchemaCompatibility(schema.CompatibilityMode, schema.Type, schemaCandidate{Content: body.Schema, MessageStructName: messageStructName, References: references}, versions)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at checkSchemaCompatibility: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+		if len(violations) > 0 {
+			errMsg := fmt.Sprintf("Schema being registered is incompatible with an earlier schema for subject \"%v\": %v", subject, strings.Join(violations, "; "))
+			schemaRegistryError(c, 409, schemaRegistryIncompatibleSchema, errMsg)
+			return
+		}
+	}
+
+	descriptor := _EMPTY_
+	if schemaType == "protobuf" {
+		descriptor, err = generateSchemaDescriptorWithReferences(subject, versionNumber, body.Schema, schemaType, references)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at generateSchemaDescriptor: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+	}
+
+	if !exist {
+		schema = models.Schema{Name: subject, Type: schemaType, CreatedByUsername: user.Username}
+	}
+	// as in Confluent the registered version is the one in use, so stations validate against latest
+	schema, newVersion, inserted, err := db.InsertSchemaVersionWithReferences(schema, versionNumber, user.ID, user.Username, body.Schema, messageStructName, descriptor, true, schemaReferences, tenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at InsertSchemaVersionWithReferences: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !inserted {
+		schemaRegistryError(c, 409, schemaRegistryIncompatibleSchema, fmt.Sprintf("Version %v of subject %v has been registered concurrently", versionNumber, subject))
+		return
+	}
+	if !exist {
+		err = CreateDefaultTags("schema", schema.ID, tenantName)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]RegisterSchemaRegistrySubjectVersion at CreateDefaultTags: Subject %v: %v", tenantName, user.Username, subject, err.Error())
+			schemaRegistryServerErr(c)
+			return
+		}
+	} else {
+		sendStationSchemaCacheUpdate(tenantName, nil)
+	}
+	serv.Noticef("[tenant: %v][user: %v]Schema %v version %v has been registered through the schema registry API by %v", tenantName, user.Username, subject, versionNumber, user.Username)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"schema-name": subject}
+		analytics.SendEvent(tenantName, user.Username, analyticsParams, "user-register-schema-registry-version")
+	}
+
+	c.IndentedJSON(200, gin.H{"id": newVersion.ID})
+}
+
+func (sh SchemasHandler) DeleteSchemaRegistrySubject(c *gin.Context) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("DeleteSchemaRegistrySubject at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DeleteSchemaRegistrySubject at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	versions, err := getSchemaVersionsBySchemaId(schema.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DeleteSchemaRegistrySubject at getSchemaVersionsBySchemaId: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+
+	referencedMsg, err := getSchemaReferencedByMessage(schema, []string{})
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DeleteSchemaRegistrySubject at getSchemaReferencedByMessage: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if referencedMsg != _EMPTY_ {
+		schemaRegistryError(c, 422, schemaRegistryReferenceExists, referencedMsg)
+		return
+	}
+
+	DeleteTagsFromSchema(schema.ID)
+	err = deleteSchemaFromStations(sh.S, schema.Name, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DeleteSchemaRegistrySubject at deleteSchemaFromStations: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	err = db.FindAndDeleteSchema([]int{schema.ID})
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]DeleteSchemaRegistrySubject at FindAndDeleteSchema: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	serv.Noticef("[tenant: %v][user: %v]Schema %v has been deleted through the schema registry API", user.TenantName, user.Username, schema.Name)
+
+	versionNumbers := []int{}
+	for _, v := range versions {
+		versionNumbers = append(versionNumbers, v.VersionNumber)
+	}
+	sort.Ints(versionNumbers)
+	c.IndentedJSON(200, versionNumbers)
+}
+
+func (sh SchemasHandler) GetSchemaRegistryGlobalConfig(c *gin.Context) {
+	c.IndentedJSON(200, gin.H{"compatibilityLevel": strings.ToUpper(schemaCompatibilityNone)})
+}
+
+func (sh SchemasHandler) GetSchemaRegistrySubjectConfig(c *gin.Context) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetSchemaRegistrySubjectConfig at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetSchemaRegistrySubjectConfig at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	c.IndentedJSON(200, gin.H{"compatibilityLevel": strings.ToUpper(schema.CompatibilityMode)})
+}
+
+func (sh SchemasHandler) UpdateSchemaRegistrySubjectConfig(c *gin.Context) {
+	var body models.SchemaRegistryCompatibility
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidCompatibilityMode, err.Error())
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateSchemaRegistrySubjectConfig at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateSchemaRegistrySubjectConfig at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	compatibilityMode := strings.ToLower(body.Compatibility)
+	err = validateSchemaCompatibilityMode(compatibilityMode, schema.Type)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidCompatibilityMode, err.Error())
+		return
+	}
+	err = db.UpdateSchemaCompatibilityMode(schema.ID, compatibilityMode)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateSchemaRegistrySubjectConfig at UpdateSchemaCompatibilityMode: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	serv.Noticef("[tenant: %v][user: %v]Compatibility mode of schema %v has been set to %v by %v", user.TenantName, user.Username, schema.Name, compatibilityMode, user.Username)
+	c.IndentedJSON(200, gin.H{"compatibility": strings.ToUpper(compatibilityMode)})
+}
+
+func (sh SchemasHandler) CheckSchemaRegistryCompatibility(c *gin.Context) {
+	var body models.SchemaRegistrySchema
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CheckSchemaRegistryCompatibility at getUserDetailsFromMiddleware: %v", err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	subject := c.Param("subject")
+	exist, schema, err := getSchemaRegistrySubject(subject, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CheckSchemaRegistryCompatibility at getSchemaRegistrySubject: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	if !exist {
+		schemaRegistryError(c, 404, schemaRegistrySubjectNotFound, fmt.Sprintf("Subject '%v' not found.", subject))
+		return
+	}
+	schemaType, err := schemaRegistryTypeToSchemaType(body.SchemaType)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	if schemaType != schema.Type {
+		c.IndentedJSON(200, gin.H{"is_compatible": false, "messages": []string{fmt.Sprintf("schema type %v does not match %v", schemaTypeToSchemaRegistryType(schemaType), schemaTypeToSchemaRegistryType(schema.Type))}})
+		return
+	}
+	references, err := resolveSchemaReferences(schemaType, user.TenantName, schemaRegistryReferencesToSchemaReferences(body.References))
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchemaReference) {
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]CheckSchemaRegistryCompatibility at resolveSchemaReferences: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+		schemaRegistryServerErr(c)
+		return
+	}
+	err = validateSchemaContentWithReferences(body.Schema, schemaType, references)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	messageStructName := _EMPTY_
+	if schemaType == "protobuf" {
+		messageStructName, err = getProtoMessageStructName(body.Schema)
+		if err != nil {
+			schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+			return
+		}
+	}
+
+	// a specific version is checked on its own while a check without a version follows the subject mode
+	mode := schema.CompatibilityMode
+	var versions []models.SchemaVersion
+	if c.Param("version") != _EMPTY_ {
+		exist, version, err := getSchemaRegistryVersion(schema.ID, c.Param("version"))
+		if err != nil {
+			if _, ok := err.(*strconv.NumError); ok {
+				schemaRegistryError(c, 422, schemaRegistryInvalidVersion, fmt.Sprintf("The specified version '%v' is not a valid version id", c.Param("version")))
+				return
+			}
+			serv.Errorf("[tenant: %v][user: %v]CheckSchemaRegistryCompatibility at getSchemaRegistryVersion: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+			schemaRegistryServerErr(c)
+			return
+		}
+		if !exist {
+			schemaRegistryError(c, 404, schemaRegistryVersionNotFound, "Version not found.")
+			return
+		}
+		versions = []models.SchemaVersion{version}
+		mode = strings.TrimSuffix(mode, "_transitive")
+	} else {
+		versions, err = getSchemaVersionsBySchemaId(schema.ID)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]CheckSchemaRegistryCompatibility at getSchemaVersionsBySchemaId: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+			schemaRegistryServerErr(c)
+			return
+		}
+		err = loadSchemaVersionsReferences(versions)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]CheckSchemaRegistryCompatibility at loadSchemaVersionsReferences: Subject %v: %v", user.TenantName, user.Username, subject, err.Error())
+			schemaRegistryServerErr(c)
+			return
+		}
+	}
+
+	violations, err := checkSchemaCompatibility(mode, schema.Type, schemaCandidate{Content: body.Schema, MessageStructName: messageStructName, References: references}, versions)
+	if err != nil {
+		schemaRegistryError(c, 422, schemaRegistryInvalidSchema, err.Error())
+		return
+	}
+	c.IndentedJSON(200, gin.H{"is_compatible": len(violations) == 0, "messages": violations})
+}
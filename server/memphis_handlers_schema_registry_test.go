@@ -0,0 +1,184 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSchemaRegistryAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := map[string]models.User{
+		"root":   {ID: 1, Username: "root", Password: "secret", UserType: "root", TenantName: "Tenant"},
+		"worker": {ID: 2, Username: "worker", Password: "secret", UserType: "application", TenantName: "tenant"},
+	}
+	orig := schemaRegistryAuthenticator
+	defer func() { schemaRegistryAuthenticator = orig }()
+	schemaRegistryAuthenticator = func(username, password string) (bool, models.User, error) {
+		user, ok := users[username]
+		if !ok || user.Password != password {
+			return false, models.User{}, nil
+		}
+		return true, user, nil
+	}
+
+	router := gin.New()
+	routes := router.Group("/api/schemaRegistry")
+	routes.Use(SchemaRegistryAuthenticate)
+	routes.GET("/subjects", func(c *gin.Context) {
+		user, err := getUserDetailsFromMiddleware(c)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c.JSON(200, user)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		username string
+		password string
+		bearer   bool
+		status   int
+	}{
+		{name: "no credentials", status: 401},
+		{name: "memphis jwt", bearer: true, status: 401},
+		{name: "wrong password", username: "root", password: "wrong", status: 401},
+		{name: "unknown user", username: "nobody", password: "secret", status: 401},
+		{name: "application user", username: "worker", password: "secret", status: 401},
+		{name: "management user", username: "ROOT", password: "secret", status: 200},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/schemaRegistry/subjects", nil)
+			if tc.bearer {
+				req.Header.Set("Authorization", "Bearer token")
+			} else if tc.username != _EMPTY_ {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+			router.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %v, got %v: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status == 401 {
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if body["error_code"] != float64(schemaRegistryUnauthorized) {
+					t.Fatalf("Expected error code %v, got %v", schemaRegistryUnauthorized, body["error_code"])
+				}
+				if w.Header().Get("WWW-Authenticate") == _EMPTY_ {
+					t.Fatalf("Expected a WWW-Authenticate header")
+				}
+				return
+			}
+			var user models.User
+			if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if user.Username != "root" || user.TenantName != "tenant" || user.Password != _EMPTY_ {
+				t.Fatalf("Unexpected user in context: %+v", user)
+			}
+		})
+	}
+}
+
+func TestFindSchemaRegistryVersion(t *testing.T) {
+	activeSecond := []models.SchemaVersion{
+		{VersionNumber: 1},
+		{VersionNumber: 3},
+		{VersionNumber: 2, Active: true},
+	}
+	noneActive := []models.SchemaVersion{
+		{VersionNumber: 2},
+		{VersionNumber: 3},
+		{VersionNumber: 1},
+	}
+	for _, tc := range []struct {
+		name     string
+		versions []models.SchemaVersion
+		version  string
+		expected int
+	}{
+		{name: "latest is the newest version", versions: activeSecond, version: "latest", expected: 3},
+		{name: "-1 is the newest version", versions: activeSecond, version: "-1", expected: 3},
+		{name: "latest without an active version", versions: noneActive, version: "latest", expected: 3},
+		{name: "version number", versions: activeSecond, version: "3", expected: 3},
+		{name: "missing version", versions: activeSecond, version: "4", expected: -1},
+		{name: "no versions", versions: nil, version: "latest", expected: -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := findSchemaRegistryVersion(tc.versions, tc.version)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expected == -1 {
+				if found != -1 {
+					t.Fatalf("Expected no version, got %v", tc.versions[found].VersionNumber)
+				}
+				return
+			}
+			if found == -1 || tc.versions[found].VersionNumber != tc.expected {
+				t.Fatalf("Expected version %v, got index %v", tc.expected, found)
+			}
+		})
+	}
+
+	_, err := findSchemaRegistryVersion(activeSecond, "first")
+	if _, ok := err.(*strconv.NumError); !ok {
+		t.Fatalf("Expected a version parsing error, got %v", err)
+	}
+}
+
+func TestRegisterSchemaRegistrySubjectVersionInvalidSubject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	originalServ := serv
+	serv = &Server{}
+	defer func() { serv = originalServ }()
+	router := gin.New()
+	router.POST("/subjects/:subject/versions", func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1, Username: "root", UserType: "root", TenantName: "tenant"})
+		c.Next()
+	}, SchemasHandler{}.RegisterSchemaRegistrySubjectVersion)
+
+	for _, subject := range []string{"Orders-value", "orders_value_"} {
+		t.Run(subject, func(t *testing.T) {
+			body := `{"schema":"{\"type\":\"string\"}"}`
+			req := httptest.NewRequest("POST", "/subjects/"+subject+"/versions", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != 422 {
+				t.Fatalf("Expected status 422, got %v: %v", w.Code, w.Body.String())
+			}
+			var resp struct {
+				ErrorCode int `json:"error_code"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("Unexpected response %v: %v", w.Body.String(), err)
+			}
+			if resp.ErrorCode != schemaRegistryInvalidSubject {
+				t.Fatalf("Expected error code %v, got %v", schemaRegistryInvalidSubject, resp.ErrorCode)
+			}
+		})
+	}
+}
@@ -44,7 +44,9 @@ const (
 )
 
 var (
-	ErrNoSchema = errors.New("no schemas found")
+	ErrNoSchema               = errors.New("no schemas found")
+	ErrInvalidSchemaReference = errors.New("invalid schema reference")
+	ErrSchemaValidation       = errors.New("schema validation has failed")
 )
 
 func validateProtobufContent(schemaContent string) error {
@@ -125,7 +127,7 @@ func validateSchemaType(schemaType string) error {
 	invalidTypeErrStr := "unsupported schema type"
 	invalidTypeErr := errors.New(invalidTypeErrStr)
 
-	if schemaType == "protobuf" || schemaType == "json" || schemaType == "graphql" || schemaType == "avro" {
+	if schemaType == "protobuf" || schemaType == "json" || schemaType == "graphql" || schemaType == "avro" || schemaType == "xsd" || schemaType == "thrift" {
 		return nil
 	} else {
 		return invalidTypeErr
@@ -158,6 +160,16 @@ func validateSchemaContent(schemaContent, schemaType string) error {
 		if err != nil {
 			return err
 		}
+	case "xsd":
+		err := validateXsdSchemaContent(schemaContent)
+		if err != nil {
+			return err
+		}
+	case "thrift":
+		err := validateThriftSchemaContent(schemaContent)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -167,20 +179,37 @@ func generateSchemaDescriptor(schemaName string, schemaVersionNum int, schemaCon
 		return _EMPTY_, errors.New("attempt to generate schema descriptor with empty schema")
 	}
 
-	if schemaType != "protobuf" {
-		return _EMPTY_, errors.New("descriptor generation with schema type: " + schemaType + ", while protobuf is expected")
+	var descriptor []byte
+	var err error
+	switch schemaType {
+	case "protobuf":
+		descriptor, err = generateProtobufDescriptor(schemaName, schemaVersionNum, schemaContent)
+	case "xsd":
+		descriptor, err = generateXsdDescriptor(schemaContent)
+	case "thrift":
+		descriptor, err = generateThriftDescriptor(schemaContent)
+	default:
+		return _EMPTY_, errors.New("descriptor generation with schema type: " + schemaType + ", while protobuf, xsd or thrift is expected")
 	}
-
-	descriptor, err := generateProtobufDescriptor(schemaName, schemaVersionNum, schemaContent)
 	if err != nil {
 		return _EMPTY_, err
 	}
 	return base64.StdEncoding.EncodeToString(descriptor), nil
 }
 
+// schemaTypeHasDescriptor returns whether versions of the schema type are stored with a generated descriptor
+func schemaTypeHasDescriptor(schemaType string) bool {
+	return schemaType == "protobuf" || schemaType == "xsd" || schemaType == "thrift"
+}
+
+// schemaTypeHasMessageStruct returns whether the schema type defines several structs and the message struct has to be chosen
+func schemaTypeHasMessageStruct(schemaType string) bool {
+	return schemaType == "protobuf" || schemaType == "thrift"
+}
+
 func validateMessageStructName(messageStructName string) error {
 	if messageStructName == _EMPTY_ {
-		return errors.New("message struct name is required when schema type is Protobuf")
+		return errors.New("message struct name is required when schema type is Protobuf or Thrift")
 	}
 	return nil
 }
@@ -220,6 +249,8 @@ func (s *Server) updateStationProducersOfSchemaChange(tenantName string, sn Stat
 		return
 	}
 
+	sendStationSchemaCacheUpdate(tenantName, []string{sn.Intern()})
+
 	account, err := s.lookupAccount(tenantName)
 	if err != nil {
 		s.Errorf("[tenant: %v]updateStationProducersOfSchemaChange at lookupAccount: %v", tenantName, err.Error())
@@ -321,6 +352,7 @@ func (sh SchemasHandler) getExtendedSchemaDetailsUpdateAvailable(schemaVersion i
 		UsedStations:      stations,
 		Tags:              tags,
 		CreatedByUsername: schema.CreatedByUsername,
+		CompatibilityMode: schema.CompatibilityMode,
 	}
 
 	return extedndedSchemaDetails, nil
@@ -331,6 +363,10 @@ func (sh SchemasHandler) getExtendedSchemaDetails(schema models.Schema, tenantNa
 	if err != nil {
 		return models.ExtendedSchemaDetails{}, err
 	}
+	err = loadSchemaVersionsReferences(schemaVersions)
+	if err != nil {
+		return models.ExtendedSchemaDetails{}, err
+	}
 
 	var extedndedSchemaDetails models.ExtendedSchemaDetails
 	stations, err := db.GetStationNamesUsingSchema(schema.Name, tenantName)
@@ -352,6 +388,7 @@ func (sh SchemasHandler) getExtendedSchemaDetails(schema models.Schema, tenantNa
 		UsedStations:      stations,
 		Tags:              tags,
 		CreatedByUsername: schema.CreatedByUsername,
+		CompatibilityMode: schema.CompatibilityMode,
 	}
 
 	return extedndedSchemaDetails, nil
@@ -430,7 +467,7 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 		return
 	}
 	messageStructName := body.MessageStructName
-	if schemaType == "protobuf" {
+	if schemaTypeHasMessageStruct(schemaType) {
 		err := validateMessageStructName(messageStructName)
 		if err != nil {
 			serv.Warnf("[tenant: %v][user: %v]CreateNewSchema at validateMessageStructName: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
@@ -439,17 +476,37 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 		}
 	}
 
+	references, err := resolveSchemaReferences(schemaType, tenantName, body.References)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchemaReference) {
+			serv.Warnf("[tenant: %v][user: %v]CreateNewSchema at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]CreateNewSchema at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
 	schemaContent := body.SchemaContent
-	err = validateSchemaContent(schemaContent, schemaType)
+	err = validateSchemaContentWithReferences(schemaContent, schemaType, references)
 	if err != nil {
 		serv.Warnf("[tenant: %v][user: %v]CreateNewSchema at validateSchemaContent: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
 		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
 		return
 	}
+	if schemaType == "thrift" {
+		err = validateThriftMessageStructName(schemaContent, messageStructName)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]CreateNewSchema at validateThriftMessageStructName: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
+			c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+	}
 	schemaVersionNumber := 1
 	descriptor := _EMPTY_
-	if schemaType == "protobuf" {
-		descriptor, err = generateSchemaDescriptor(schemaName, schemaVersionNumber, schemaContent, schemaType)
+	if schemaTypeHasDescriptor(schemaType) {
+		descriptor, err = generateSchemaDescriptorWithReferences(schemaName, schemaVersionNumber, schemaContent, schemaType, references)
 		if err != nil {
 			serv.Warnf("[tenant: %v][user: %v]CreateNewSchema at generateSchemaDescriptor: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
 			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
@@ -457,20 +514,15 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 		}
 	}
 
-	newSchema, rowsUpdated, err := db.InsertNewSchema(schemaName, schemaType, user.Username, tenantName)
+	schemaToInsert := models.Schema{Name: schemaName, Type: schemaType, CreatedByUsername: user.Username}
+	newSchema, _, inserted, err := db.InsertSchemaVersionWithReferences(schemaToInsert, schemaVersionNumber, user.ID, user.Username, schemaContent, messageStructName, descriptor, true, body.References, tenantName)
 	if err != nil {
-		serv.Errorf("[tenant: %v][user: %v]CreateNewSchema at InsertNewSchema: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
+		serv.Errorf("[tenant: %v][user: %v]CreateNewSchema at InsertSchemaVersionWithReferences: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
 
-	if rowsUpdated == 1 {
-		_, _, err = db.InsertNewSchemaVersion(schemaVersionNumber, user.ID, user.Username, schemaContent, newSchema.ID, messageStructName, descriptor, true, tenantName)
-		if err != nil {
-			serv.Errorf("[tenant: %v][user: %v]CreateNewSchema at InsertNewSchemaVersion: %v", user.TenantName, user.Username, err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
-		}
+	if inserted {
 		message := fmt.Sprintf("[tenant: %v][user: %v]Schema %v has been created by %v", user.TenantName, user.Username, schemaName, user.Username)
 		serv.Noticef(message)
 	} else {
@@ -608,6 +660,8 @@ func (sh SchemasHandler) RemoveSchema(c *gin.Context) {
 	}
 
 	tenantName := user.TenantName
+	schemas := []models.Schema{}
+	schemaNames := []string{}
 	for _, name := range body.SchemaNames {
 		schemaName := strings.ToLower(name)
 		exist, schema, err := db.GetSchemaByName(schemaName, tenantName)
@@ -617,18 +671,37 @@ func (sh SchemasHandler) RemoveSchema(c *gin.Context) {
 			return
 		}
 		if exist {
-			DeleteTagsFromSchema(schema.ID)
-			err := deleteSchemaFromStations(sh.S, schema.Name, tenantName)
-			if err != nil {
-				serv.Errorf("[tenant: %v][user: %v]RemoveSchema at deleteSchemaFromStations: Schema %v: %v", user.TenantName, user.Username, schemaName, err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
-			}
+			schemas = append(schemas, schema)
+			schemaNames = append(schemaNames, schema.Name)
+		}
+	}
 
-			schemaIds = append(schemaIds, schema.ID)
+	for _, schema := range schemas {
+		referencedMsg, err := getSchemaReferencedByMessage(schema, schemaNames)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]RemoveSchema at getSchemaReferencedByMessage: Schema %v: %v", user.TenantName, user.Username, schema.Name, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if referencedMsg != _EMPTY_ {
+			serv.Warnf("[tenant: %v][user: %v]RemoveSchema: %v", user.TenantName, user.Username, referencedMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": referencedMsg})
+			return
 		}
 	}
 
+	for _, schema := range schemas {
+		DeleteTagsFromSchema(schema.ID)
+		err := deleteSchemaFromStations(sh.S, schema.Name, tenantName)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]RemoveSchema at deleteSchemaFromStations: Schema %v: %v", user.TenantName, user.Username, schema.Name, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+
+		schemaIds = append(schemaIds, schema.ID)
+	}
+
 	if len(schemaIds) > 0 {
 		err := db.FindAndDeleteSchema(schemaIds)
 		if err != nil {
@@ -678,7 +751,7 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 	}
 
 	messageStructName := body.MessageStructName
-	if schema.Type == "protobuf" {
+	if schemaTypeHasMessageStruct(schema.Type) {
 		err := validateMessageStructName(messageStructName)
 		if err != nil {
 			serv.Errorf("[tenant: %v][user: %v]CreateNewVersion at validateMessageStructName: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
@@ -686,13 +759,45 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 			return
 		}
 	}
+	references, err := resolveSchemaReferences(schema.Type, user.TenantName, body.References)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchemaReference) {
+			serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]CreateNewVersion at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
 	schemaContent := body.SchemaContent
-	err = validateSchemaContent(schemaContent, schema.Type)
+	err = validateSchemaContentWithReferences(schemaContent, schema.Type, references)
 	if err != nil {
 		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at validateSchemaContent: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
 		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
 		return
 	}
+	if schema.Type == "thrift" {
+		err = validateThriftMessageStructName(schemaContent, messageStructName)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at validateThriftMessageStructName: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+			c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	violations, err := validateNewSchemaVersionCompatibility(schema, schemaCandidate{Content: schemaContent, MessageStructName: messageStructName, References: references})
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at validateNewSchemaVersionCompatibility: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	if len(violations) > 0 {
+		errMsg := schemaCompatibilityErrorMessage(schema.CompatibilityMode, violations)
+		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, errMsg)
+		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": errMsg, "violations": violations})
+		return
+	}
 
 	countVersions, err := db.GetShcemaVersionsCount(schema.ID, user.TenantName)
 	if err != nil {
@@ -703,24 +808,24 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 
 	versionNumber := countVersions + 1
 	descriptor := _EMPTY_
-	if schema.Type == "protobuf" {
-		descriptor, err = generateSchemaDescriptor(schemaName, versionNumber, schemaContent, schema.Type)
+	if schemaTypeHasDescriptor(schema.Type) {
+		descriptor, err = generateSchemaDescriptorWithReferences(schemaName, versionNumber, schemaContent, schema.Type, references)
 		if err != nil {
 			serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at generateSchemaDescriptor: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
 			c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
 			return
 		}
 	}
-	newSchemaVersion, rowsUpdated, err := db.InsertNewSchemaVersion(versionNumber, user.ID, user.Username, schemaContent, schema.ID, messageStructName, descriptor, false, user.TenantName)
+	_, newSchemaVersion, inserted, err := db.InsertSchemaVersionWithReferences(schema, versionNumber, user.ID, user.Username, schemaContent, messageStructName, descriptor, false, body.References, user.TenantName)
 	if err != nil {
-		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at InsertNewSchemaVersion: %v", user.TenantName, user.Username, err.Error())
+		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion at InsertSchemaVersionWithReferences: %v", user.TenantName, user.Username, err.Error())
 		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
 		return
 	}
-	if rowsUpdated == 1 {
+	if inserted {
 		serv.Noticef("[tenant: %v][user: %v]Schema Version %v has been created by %v", user.TenantName, user.Username, strconv.Itoa(newSchemaVersion.VersionNumber), user.Username)
 	} else {
-		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion: Schema %v: Version %v already exists", user.TenantName, user.Username, body.SchemaName, strconv.Itoa(newSchemaVersion.VersionNumber))
+		serv.Warnf("[tenant: %v][user: %v]CreateNewVersion: Schema %v: Version %v already exists", user.TenantName, user.Username, body.SchemaName, strconv.Itoa(versionNumber))
 		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Version already exists"})
 		return
 	}
@@ -797,6 +902,7 @@ func (sh SchemasHandler) RollBackVersion(c *gin.Context) {
 			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
 			return
 		}
+		sendStationSchemaCacheUpdate(user.TenantName, nil)
 	}
 	extedndedSchemaDetails, err = sh.getExtendedSchemaDetails(schema, user.TenantName)
 	if err != nil {
@@ -849,6 +955,141 @@ func (sh SchemasHandler) ValidateSchema(c *gin.Context) {
 	})
 }
 
+func (sh SchemasHandler) UpdateCompatibilityMode(c *gin.Context) {
+	var body models.UpdateSchemaCompatibilityMode
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateCompatibilityMode at getUserDetailsFromMiddleware: Schema %v: %v", body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	schemaName := strings.ToLower(body.SchemaName)
+	exist, schema, err := db.GetSchemaByName(schemaName, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateCompatibilityMode at GetSchemaByName: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Schema %v does not exist", body.SchemaName)
+		serv.Warnf("[tenant: %v][user: %v]UpdateCompatibilityMode: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	compatibilityMode := strings.ToLower(body.CompatibilityMode)
+	err = validateSchemaCompatibilityMode(compatibilityMode, schema.Type)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateCompatibilityMode at validateSchemaCompatibilityMode: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = db.UpdateSchemaCompatibilityMode(schema.ID, compatibilityMode)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateCompatibilityMode at UpdateSchemaCompatibilityMode: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	serv.Noticef("[tenant: %v][user: %v]Compatibility mode of schema %v has been set to %v by %v", user.TenantName, user.Username, schemaName, compatibilityMode, user.Username)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"schema-name": schemaName, "compatibility-mode": compatibilityMode}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-update-schema-compatibility-mode")
+	}
+
+	c.IndentedJSON(200, gin.H{"schema_name": schemaName, "compatibility_mode": compatibilityMode})
+}
+
+func (sh SchemasHandler) CheckCompatibility(c *gin.Context) {
+	var body models.CheckSchemaCompatibility
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CheckCompatibility at getUserDetailsFromMiddleware: Schema %v: %v", body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	schemaName := strings.ToLower(body.SchemaName)
+	exist, schema, err := db.GetSchemaByName(schemaName, user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]CheckCompatibility at GetSchemaByName: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Schema %v does not exist", body.SchemaName)
+		serv.Warnf("[tenant: %v][user: %v]CheckCompatibility: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if body.CompatibilityMode != _EMPTY_ {
+		schema.CompatibilityMode = strings.ToLower(body.CompatibilityMode)
+		err = validateSchemaCompatibilityMode(schema.CompatibilityMode, schema.Type)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]CheckCompatibility at validateSchemaCompatibilityMode: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if schemaTypeHasMessageStruct(schema.Type) {
+		err := validateMessageStructName(body.MessageStructName)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]CheckCompatibility at validateMessageStructName: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	references, err := resolveSchemaReferences(schema.Type, user.TenantName, body.References)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchemaReference) {
+			serv.Warnf("[tenant: %v][user: %v]CheckCompatibility at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]CheckCompatibility at resolveSchemaReferences: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = validateSchemaContentWithReferences(body.SchemaContent, schema.Type, references)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]CheckCompatibility at validateSchemaContent: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	violations, err := validateNewSchemaVersionCompatibility(schema, schemaCandidate{Content: body.SchemaContent, MessageStructName: body.MessageStructName, References: references})
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]CheckCompatibility at validateNewSchemaVersionCompatibility: Schema %v: %v", user.TenantName, user.Username, body.SchemaName, err.Error())
+		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"schema-name": schemaName}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-check-schema-compatibility")
+	}
+
+	c.IndentedJSON(200, gin.H{
+		"is_compatible":      len(violations) == 0,
+		"compatibility_mode": schema.CompatibilityMode,
+		"violations":         violations,
+	})
+}
+
 func (s *Server) createSchemaDirect(c *client, reply string, msg []byte) {
 	var csr CreateSchemaReq
 	var resp SchemaResponse
@@ -880,7 +1121,16 @@ func (s *Server) createSchemaDirect(c *client, reply string, msg []byte) {
 		}
 	}
 
-	if csr.Type == "protobuf" {
+	if csr.Type == "thrift" && csr.MessageStructName == _EMPTY_ {
+		csr.MessageStructName, err = getThriftMessageStructName(csr.SchemaContent)
+		if err != nil {
+			s.Errorf("[tenant: %v]createSchemaDirect at getThriftMessageStructName- failed creating Schema: %v : %v", tenantName, csr.Name, err.Error())
+			respondWithRespErr(s.MemphisGlobalAccountString(), s, reply, err, &resp)
+			return
+		}
+	}
+
+	if schemaTypeHasMessageStruct(csr.Type) {
 		err := validateMessageStructName(csr.MessageStructName)
 		if err != nil {
 			s.Warnf("[tenant: %v]createSchemaDirect at validateMessageStructName- failed creating Schema: %v : %v", tenantName, csr.Name, err.Error())
@@ -898,7 +1148,7 @@ func (s *Server) createSchemaDirect(c *client, reply string, msg []byte) {
 
 	if exist {
 		if existedSchema.Type == csr.Type {
-			err = s.updateSchemaVersion(existedSchema.ID, tenantName, csr)
+			err = s.updateSchemaVersion(existedSchema, tenantName, csr)
 			if err != nil {
 				if !strings.Contains(err.Error(), "already exist") {
 					s.Errorf("[tenant: %v]createSchemaDirect at updateSchemaVersion - failed creating Schema: %v : %v", tenantName, csr.Name, err.Error())
@@ -927,7 +1177,8 @@ func (s *Server) createSchemaDirect(c *client, reply string, msg []byte) {
 
 }
 
-func (s *Server) updateSchemaVersion(schemaID int, tenantName string, newSchemaReq CreateSchemaReq) error {
+func (s *Server) updateSchemaVersion(schema models.Schema, tenantName string, newSchemaReq CreateSchemaReq) error {
+	schemaID := schema.ID
 	_, user, err := memphis_cache.GetUser(newSchemaReq.CreatedByUsername, tenantName, false)
 	if err != nil {
 		s.Errorf("[tenant: %v]updateSchemaVersion at memphis_cache.GetUser: Schema %v: %v", tenantName, newSchemaReq.Name, err.Error())
@@ -951,10 +1202,21 @@ func (s *Server) updateSchemaVersion(schemaID int, tenantName string, newSchemaR
 		return errors.New(alreadyExistInDB)
 	}
 
+	violations, err := validateNewSchemaVersionCompatibility(schema, schemaCandidate{Content: newSchemaReq.SchemaContent, MessageStructName: newSchemaReq.MessageStructName})
+	if err != nil {
+		s.Warnf("[tenant: %v][user: %v]updateSchemaVersion at validateNewSchemaVersionCompatibility: Schema %v: %v", tenantName, user.Username, newSchemaReq.Name, err.Error())
+		return err
+	}
+	if len(violations) > 0 {
+		errMsg := schemaCompatibilityErrorMessage(schema.CompatibilityMode, violations)
+		s.Warnf("[tenant: %v][user: %v]updateSchemaVersion: Schema %v: %v", tenantName, user.Username, newSchemaReq.Name, errMsg)
+		return errors.New(errMsg)
+	}
+
 	versionNumber := countVersions + 1
 
 	descriptor := _EMPTY_
-	if newSchemaReq.Type == "protobuf" {
+	if schemaTypeHasDescriptor(newSchemaReq.Type) {
 		descriptor, err = generateSchemaDescriptor(newSchemaReq.Name, 1, newSchemaReq.SchemaContent, newSchemaReq.Type)
 		if err != nil {
 			s.Errorf("[tenant: %v][user: %v]CreateNewSchemaDirectn: could not create proto descriptor for %v: %v", tenantName, user.Username, newSchemaReq.Name, err.Error())
@@ -988,7 +1250,7 @@ func (s *Server) createNewSchema(newSchemaReq CreateSchemaReq, tenantName string
 	}
 
 	descriptor := _EMPTY_
-	if newSchemaReq.Type == "protobuf" {
+	if schemaTypeHasDescriptor(newSchemaReq.Type) {
 		descriptor, err = generateSchemaDescriptor(newSchemaReq.Name, 1, newSchemaReq.SchemaContent, newSchemaReq.Type)
 		if err != nil {
 			s.Errorf("[tenant: %v][user: %v]CreateNewSchema at generateSchemaDescriptor: Schema %v: %v", tenantName, user.Username, newSchemaReq.Name, err.Error())
@@ -36,6 +36,9 @@ const (
 	stationObjectName       = "Station"
 	schemaToDlsUpdateType   = "schemaverse_to_dls"
 	removeStationUpdateType = "remove_station"
+	stationUpdateType       = "station_update"
+	partitionsUpdateType    = "partitions_update"
+	addPartitionsTaskName   = "add_partitions"
 )
 
 type StationName struct {
@@ -148,9 +151,15 @@ func removeStationResources(s *Server, station models.Station, shouldDeleteStrea
 				}
 			}
 		}
+		err = s.RemoveStream(station.TenantName, scheduledMessagesStreamName(stationName))
+		if err != nil && !IsNatsErr(err, JSStreamNotFoundErr) {
+			s.Errorf("[tenant: %v]removeStationResources at RemoveStream: scheduled messages of station %v: %v", station.TenantName, station.Name, err.Error())
+		}
 	}
 
 	DeleteTagsFromStation(station.ID)
+	sendStationSchemaCacheUpdate(station.TenantName, []string{stationName.Intern()})
+	sendStationPartitionsCacheUpdate(station.TenantName, stationName.Intern(), nil)
 
 	err = db.DeleteDLSMessagesByStationID(station.ID)
 	if err != nil {
@@ -558,6 +567,7 @@ func (s *Server) createStationDirectIntern(c *client,
 		return
 	}
 	if rowsUpdated > 0 {
+		sendStationPartitionsCacheUpdate(newStation.TenantName, stationName.Intern(), newStation.PartitionsList)
 		err = CreateDefaultTags("station", newStation.ID, user.TenantName)
 		if err != nil {
 			serv.Errorf("[tenant: %v][user: %v]createStationDirect at CreateDefaultTags: %v", user.TenantName, user.Username, err.Error())
@@ -634,7 +644,7 @@ func (sh StationsHandler) GetStation(c *gin.Context) {
 	if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(user.TenantName); !ok {
 		station.TieredStorageEnabled = false
 	} else {
-		_, ok = tenantInetgrations["s3"].(models.Integration)
+		ok = hasTier2StorageIntegration(tenantInetgrations)
 		if !ok {
 			station.TieredStorageEnabled = false
 		} else if station.TieredStorageEnabled {
@@ -736,7 +746,7 @@ func (sh StationsHandler) GetStationsDetails(tenantName string) ([]models.Extend
 			if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
 				station.TieredStorageEnabled = false
 			} else {
-				_, ok = tenantInetgrations["s3"].(models.Integration)
+				ok = hasTier2StorageIntegration(tenantInetgrations)
 				if !ok {
 					station.TieredStorageEnabled = false
 				} else if station.TieredStorageEnabled {
@@ -837,7 +847,7 @@ func (sh StationsHandler) GetAllStationsDetailsLight(shouldExtend bool, tenantNa
 				if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
 					stations[i].TieredStorageEnabled = false
 				} else {
-					_, ok = tenantInetgrations["s3"].(models.Integration)
+					ok = hasTier2StorageIntegration(tenantInetgrations)
 					if !ok {
 						stations[i].TieredStorageEnabled = false
 					} else if stations[i].TieredStorageEnabled {
@@ -1208,6 +1218,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
 		return
 	}
+	sendStationPartitionsCacheUpdate(newStation.TenantName, stationName.Intern(), newStation.PartitionsList)
 
 	if len(body.Tags) > 0 {
 		err = AddTagsToEntity(body.Tags, "station", newStation.ID, newStation.TenantName, _EMPTY_)
@@ -1919,6 +1930,7 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 
 	connectionIdHeader := headersJson["$memphis_connectionId"]
 	producedByHeader := strings.ToLower(headersJson["$memphis_producedBy"])
+	schemaVersion := messageSchemaVersion(headersJson)
 
 	for header := range headersJson {
 		if strings.HasPrefix(header, MEMPHIS_GLOBAL_ACCOUNT) {
@@ -1966,6 +1978,11 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 		},
 		PoisonedCgs: poisonedCgs,
 	}
+	decoder, err := loadStationSchemaDecoder(station)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]GetMessageDetails at loadStationSchemaDecoder: Message ID: %v: %v", user.TenantName, user.Username, strconv.Itoa(msgId), err.Error())
+	}
+	decoder.renderMessagePayload(&msg.Message, sm.Data, schemaVersion)
 	c.IndentedJSON(200, msg)
 }
 
@@ -2431,10 +2448,733 @@ func (sh StationsHandler) UpdateDlsConfig(c *gin.Context) {
 		Update:      station.DlsConfigurationSchemaverse,
 	}
 	serv.SendUpdateToClients(configUpdate)
+	if schemaverseConfigChanged {
+		sendStationSchemaCacheUpdate(station.TenantName, []string{stationName.Intern()})
+	}
 
 	c.IndentedJSON(200, gin.H{"poison": body.Poison, "schemaverse": body.Schemaverse})
 }
 
+func (sh StationsHandler) UpdateSchemaStrictMode(c *gin.Context) {
+	var body models.UpdateSchemaStrictSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateSchemaStrictMode at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateSchemaStrictMode at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateSchemaStrictMode at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]UpdateSchemaStrictMode: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateSchemaStrictMode at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]UpdateSchemaStrictMode: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if body.SchemaStrict {
+		if station.SchemaName == _EMPTY_ {
+			errMsg := fmt.Sprintf("Station %v has no schema attached, strict mode requires an enforced schema", stationName.Ext())
+			serv.Warnf("[tenant: %v][user: %v]UpdateSchemaStrictMode: %v", user.TenantName, user.Username, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+		if !ValidataAccessToFeature(user.TenantName, "feature-schemaverse-enforcement") {
+			serv.Warnf("[tenant: %v][user: %v]UpdateSchemaStrictMode at ValidataAccessToFeature: %v", user.TenantName, user.Username, "feature-schemaverse-enforcement")
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Schema enforcement is not supported in your pricing plan"})
+			return
+		}
+	}
+
+	if station.SchemaStrict != body.SchemaStrict {
+		err = db.UpdateStationSchemaStrict(station.Name, body.SchemaStrict, station.TenantName)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]UpdateSchemaStrictMode at UpdateStationSchemaStrict: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		sendStationSchemaCacheUpdate(station.TenantName, []string{stationName.Intern()})
+
+		mode := "disabled"
+		if body.SchemaStrict {
+			mode = "enabled"
+		}
+		message := fmt.Sprintf("Schema strict mode has been %v for station %v by user %v", mode, stationName.Ext(), user.Username)
+		serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+		var auditLogs []interface{}
+		newAuditLog := models.AuditLog{
+			StationName:       stationName.Ext(),
+			Message:           message,
+			CreatedBy:         user.ID,
+			CreatedByUsername: user.Username,
+			CreatedAt:         time.Now(),
+			TenantName:        user.TenantName,
+		}
+		auditLogs = append(auditLogs, newAuditLog)
+		err = CreateAuditLogs(auditLogs)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]UpdateSchemaStrictMode: At station %v - create audit logs error: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		}
+
+		shouldSendAnalytics, _ := shouldSendAnalytics()
+		if shouldSendAnalytics {
+			analyticsParams := map[string]interface{}{"station-name": stationName.Ext(), "schema-strict": body.SchemaStrict}
+			analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-update-schema-strict-mode")
+		}
+	}
+
+	c.IndentedJSON(200, gin.H{"schema_strict": body.SchemaStrict})
+}
+
+// validateStationUpdate merges the update into the current station, every field which has not been set keeps its current value,
+// and validates the result, the returned error is meant to be shown to the user
+func validateStationUpdate(station models.Station, update models.UpdateStationSchema) (models.Station, error) {
+	updated := station
+	if update.RetentionType != _EMPTY_ {
+		updated.RetentionType = strings.ToLower(update.RetentionType)
+		err := validateRetentionType(updated.RetentionType)
+		if err != nil {
+			return models.Station{}, err
+		}
+		updated.RetentionValue = 0
+		if update.RetentionValue != nil {
+			updated.RetentionValue = *update.RetentionValue
+		}
+		if updated.RetentionValue <= 0 && updated.RetentionType != "ack_based" {
+			updated.RetentionType = "message_age_sec"
+			updated.RetentionValue = 3600 // 1 hour
+		}
+	} else if update.RetentionValue != nil && *update.RetentionValue > 0 {
+		updated.RetentionValue = *update.RetentionValue
+	}
+	if !validateRetentionPolicyUsage(station.TenantName, updated.RetentionType, updated.RetentionValue) {
+		return models.Station{}, errors.New("this retention type or value is not supported in your pricing plan")
+	}
+	err := validateRetentionPolicy(getRetentionPolicy(updated.RetentionType))
+	if err != nil {
+		return models.Station{}, err
+	}
+
+	if update.Replicas != nil && *update.Replicas > 0 {
+		updated.Replicas = GetStationReplicas(*update.Replicas)
+	}
+	err = validateReplicas(updated.Replicas)
+	if err != nil {
+		return models.Station{}, err
+	}
+
+	if update.IdempotencyWindow != nil && *update.IdempotencyWindow > 0 {
+		updated.IdempotencyWindow = *update.IdempotencyWindow
+		if updated.IdempotencyWindow < 100 {
+			updated.IdempotencyWindow = 100 // minimum is 100 millis
+		}
+	}
+	err = validateIdempotencyWindow(updated.RetentionType, updated.RetentionValue, updated.IdempotencyWindow)
+	if err != nil {
+		return models.Station{}, err
+	}
+
+	if update.DlsConfiguration.Poison != nil {
+		updated.DlsConfigurationPoison = *update.DlsConfiguration.Poison
+	}
+	if update.DlsConfiguration.Schemaverse != nil {
+		updated.DlsConfigurationSchemaverse = *update.DlsConfiguration.Schemaverse
+	}
+	if update.TieredStorageEnabled != nil {
+		updated.TieredStorageEnabled = *update.TieredStorageEnabled
+	}
+	return updated, nil
+}
+
+func applyStationConfigToStream(cfg *StreamConfig, station models.Station) {
+	cfg.MaxMsgs = -1
+	if station.RetentionType == "messages" && station.RetentionValue > 0 {
+		cfg.MaxMsgs = int64(station.RetentionValue)
+	}
+	cfg.MaxBytes = -1
+	if station.RetentionType == "bytes" && station.RetentionValue > 0 {
+		cfg.MaxBytes = int64(station.RetentionValue)
+	}
+	cfg.MaxAge = GetStationMaxAge(station.RetentionType, station.TenantName, station.RetentionValue)
+	cfg.Retention = getRetentionPolicy(station.RetentionType)
+	cfg.Replicas = station.Replicas
+	cfg.Duplicates = time.Duration(station.IdempotencyWindow) * time.Millisecond
+	cfg.TieredStorageEnabled = station.TieredStorageEnabled
+}
+
+// updateStationStreams applies the updated station config on all the partitions of the station and returns their previous
+// configs, in case one of the partitions fails the already updated ones are reverted
+func (s *Server) updateStationStreams(station models.Station, stationName StationName, updated models.Station) ([]StreamConfig, error) {
+	streamNames := []string{stationName.Intern()}
+	if len(station.PartitionsList) > 0 {
+		streamNames = make([]string, 0, len(station.PartitionsList))
+		for _, p := range station.PartitionsList {
+			streamNames = append(streamNames, fmt.Sprintf("%v$%v", stationName.Intern(), p))
+		}
+	}
+
+	updatedConfigs := make([]StreamConfig, 0, len(streamNames))
+	for _, streamName := range streamNames {
+		streamInfo, err := s.memphisStreamInfo(station.TenantName, streamName)
+		if err != nil {
+			s.revertStationStreams(station.TenantName, updatedConfigs)
+			return nil, err
+		}
+		newConfig := streamInfo.Config
+		applyStationConfigToStream(&newConfig, updated)
+		err = s.memphisUpdateStream(station.TenantName, &newConfig)
+		if err != nil {
+			s.revertStationStreams(station.TenantName, updatedConfigs)
+			return nil, err
+		}
+		updatedConfigs = append(updatedConfigs, streamInfo.Config)
+	}
+
+	return updatedConfigs, nil
+}
+
+func (s *Server) revertStationStreams(tenantName string, oldConfigs []StreamConfig) {
+	for _, cfg := range oldConfigs {
+		oldConfig := cfg
+		err := s.memphisUpdateStream(tenantName, &oldConfig)
+		if err != nil {
+			s.Errorf("[tenant: %v]revertStationStreams at memphisUpdateStream: stream %v: %v", tenantName, cfg.Name, err.Error())
+		}
+	}
+}
+
+// updateStation applies a station merged by validateStationUpdate
+func (s *Server) updateStation(station models.Station, updated models.Station) (models.Station, error) {
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return models.Station{}, err
+	}
+
+	previousConfigs, err := s.updateStationStreams(station, stationName, updated)
+	if err != nil {
+		return models.Station{}, err
+	}
+
+	dlsConfiguration := models.DlsConfiguration{Poison: updated.DlsConfigurationPoison, Schemaverse: updated.DlsConfigurationSchemaverse}
+	updatedStation, err := db.UpdateStationConfig(station.Name, updated.RetentionType, updated.RetentionValue, updated.Replicas, updated.IdempotencyWindow, dlsConfiguration, updated.TieredStorageEnabled, station.TenantName)
+	if err != nil {
+		// the streams are restored so they keep matching the station config which is still stored
+		s.revertStationStreams(station.TenantName, previousConfigs)
+		return models.Station{}, err
+	}
+
+	configUpdate := models.SdkClientsUpdates{
+		StationName: stationName.Intern(),
+		Type:        stationUpdateType,
+		Update: models.StationConfigUpdate{
+			RetentionType:        updatedStation.RetentionType,
+			RetentionValue:       updatedStation.RetentionValue,
+			Replicas:             updatedStation.Replicas,
+			IdempotencyWindow:    updatedStation.IdempotencyWindow,
+			TieredStorageEnabled: updatedStation.TieredStorageEnabled,
+			Version:              updatedStation.Version,
+		},
+	}
+	s.SendUpdateToClients(configUpdate)
+
+	if station.DlsConfigurationSchemaverse != updatedStation.DlsConfigurationSchemaverse {
+		dlsUpdate := models.SdkClientsUpdates{
+			StationName: stationName.Intern(),
+			Type:        schemaToDlsUpdateType,
+			Update:      updatedStation.DlsConfigurationSchemaverse,
+		}
+		s.SendUpdateToClients(dlsUpdate)
+		sendStationSchemaCacheUpdate(station.TenantName, []string{stationName.Intern()})
+	}
+
+	return updatedStation, nil
+}
+
+func (sh StationsHandler) UpdateStation(c *gin.Context) {
+	var body models.UpdateStationSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateStation at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateStation at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateStation at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]UpdateStation: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateStation at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]UpdateStation: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	updated, err := validateStationUpdate(station, body)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateStation at validateStationUpdate: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	updatedStation, err := sh.S.updateStation(station, updated)
+	if err != nil {
+		if IsNatsErr(err, JSStreamReplicasNotSupportedErr) || IsNatsErr(err, JSInsufficientResourcesErr) {
+			serv.Warnf("[tenant: %v][user: %v]UpdateStation: Station %v: Station can not be updated, probably since replicas count is larger than the cluster size", user.TenantName, user.Username, body.StationName)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Station can not be updated, probably since replicas count is larger than the cluster size"})
+			return
+		}
+		serv.Errorf("[tenant: %v][user: %v]UpdateStation at updateStation: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	message := fmt.Sprintf("Station %v has been updated by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateStation: At station %v - create audit logs error: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": stationName.Ext()}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-update-station")
+	}
+
+	storageType := updatedStation.StorageType
+	if storageType == "file" {
+		storageType = "disk"
+	}
+	c.IndentedJSON(200, gin.H{
+		"id":                            updatedStation.ID,
+		"name":                          updatedStation.Name,
+		"retention_type":                updatedStation.RetentionType,
+		"retention_value":               updatedStation.RetentionValue,
+		"storage_type":                  storageType,
+		"replicas":                      updatedStation.Replicas,
+		"last_update":                   updatedStation.UpdatedAt,
+		"idempotency_window_in_ms":      updatedStation.IdempotencyWindow,
+		"dls_configuration_poison":      updatedStation.DlsConfigurationPoison,
+		"dls_configuration_schemaverse": updatedStation.DlsConfigurationSchemaverse,
+		"tiered_storage_enabled":        updatedStation.TieredStorageEnabled,
+		"version":                       updatedStation.Version,
+	})
+}
+
+func (s *Server) updateStationDirect(c *client, reply string, msg []byte) {
+	var usr updateStationRequest
+	tenantName, message, err := s.getTenantNameAndMessage(msg)
+	if err != nil {
+		s.Errorf("updateStationDirect at getTenantNameAndMessage: %v", err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+	if err := json.Unmarshal([]byte(message), &usr); err != nil {
+		s.Errorf("[tenant: %v]updateStationDirect at json.Unmarshal: failed updating station: %v", tenantName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+	usr.TenantName = tenantName
+
+	stationName, err := StationNameFromStr(usr.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]updateStationDirect at StationNameFromStr: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+
+	exist, user, err := memphis_cache.GetUser(usr.Username, usr.TenantName, false)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]updateStationDirect at memphis_cache.GetUser: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("user %v does not exist", usr.Username)
+		serv.Warnf("[tenant: %v][user: %v]updateStationDirect: %v", usr.TenantName, usr.Username, errMsg)
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg))
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), usr.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]updateStationDirect at ValidateStationPermissions: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", usr.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]updateStationDirect: %v", usr.TenantName, usr.Username, errMsg)
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg))
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), usr.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]updateStationDirect at GetStationByName: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", usr.StationName)
+		serv.Warnf("[tenant: %v][user: %v]updateStationDirect: %v", usr.TenantName, usr.Username, errMsg)
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, errors.New(errMsg))
+		return
+	}
+
+	update := models.UpdateStationSchema{
+		StationName:          usr.StationName,
+		RetentionType:        usr.RetentionType,
+		RetentionValue:       usr.RetentionValue,
+		Replicas:             usr.Replicas,
+		IdempotencyWindow:    usr.IdempotencyWindow,
+		DlsConfiguration:     usr.DlsConfiguration,
+		TieredStorageEnabled: usr.TieredStorageEnabled,
+	}
+	updated, err := validateStationUpdate(station, update)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]updateStationDirect at validateStationUpdate: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+
+	_, err = s.updateStation(station, updated)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]updateStationDirect at updateStation: Station %v: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+		respondWithErr(s.MemphisGlobalAccountString(), s, reply, err)
+		return
+	}
+
+	message = fmt.Sprintf("Station %v has been updated by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]updateStationDirect: Station %v - create audit logs error: %v", usr.TenantName, usr.Username, usr.StationName, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": stationName.Ext()}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-update-station-sdk")
+	}
+
+	respondWithErr(s.MemphisGlobalAccountString(), s, reply, nil)
+}
+
+func (sh StationsHandler) AddPartitions(c *gin.Context) {
+	var body models.AddPartitionsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("AddPartitions at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]AddPartitions at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to add partitions to station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]AddPartitions at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if len(station.PartitionsList) == 0 {
+		errMsg := fmt.Sprintf("Station %v was created before partitions were supported, partitions can not be added to it", stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if body.PartitionsNumber <= len(station.PartitionsList) {
+		errMsg := fmt.Sprintf("Station %v already has %v partitions, the new partitions number has to be larger", stationName.Ext(), len(station.PartitionsList))
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	canCreate, partitionLimit := ValidataUsageLimitOfFeature(user.TenantName, "feature-partitions-per-station", body.PartitionsNumber)
+	if !canCreate {
+		errMsg := fmt.Sprintf("this amount of partitions you are trying to create for a single station is not supported on your pricing plan (max: %v)", partitionLimit)
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	created, task, err := db.CreateAsyncTaskIfNotRunning(addPartitionsTaskName, sh.S.opts.ServerName, time.Now(), user.TenantName, station.ID, user.Username)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]AddPartitions at CreateAsyncTaskIfNotRunning: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !created {
+		errMsg := fmt.Sprintf("Partitions are already being added to station %v", stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]AddPartitions: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	sh.S.AddStationPartitions(station, stationName, body.PartitionsNumber, user)
+
+	message := fmt.Sprintf("Adding partitions to station %v (%v -> %v) has been triggered by user %v", stationName.Ext(), len(station.PartitionsList), body.PartitionsNumber, user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]AddPartitions: At station %v - create audit logs error: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": stationName.Ext(), "partitions-number": body.PartitionsNumber}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-add-partitions")
+	}
+
+	c.IndentedJSON(200, gin.H{"async_task_id": task.ID})
+}
+
+// AddStationPartitions creates the streams of the new partitions and a consumer on each of them for every consumer group of the station,
+// the station is updated only after all the partitions were created so a failure leaves it as it was
+func (s *Server) AddStationPartitions(station models.Station, stationName StationName, partitionsNumber int, user models.User) {
+	go func() {
+		tenantName := station.TenantName
+		username := user.Username
+		newPartitions := newStationPartitions(station.PartitionsList, partitionsNumber)
+
+		for _, p := range newPartitions {
+			err := s.CreateStream(tenantName, stationName, station.RetentionType, station.RetentionValue, station.StorageType, station.IdempotencyWindow, station.Replicas, station.TieredStorageEnabled, p, station.Version >= 2)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]AddStationPartitions at CreateStream at station %v partition %v: %v", tenantName, username, station.Name, p, err.Error())
+				s.handleAddPartitionsFailure(user, station, stationName, newPartitions, err.Error())
+				return
+			}
+			err = db.UpdateAsyncTask(addPartitionsTaskName, tenantName, time.Now(), models.MetaData{Offset: p}, station.ID)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]AddStationPartitions at UpdateAsyncTask at station %v: %v", tenantName, username, station.Name, err.Error())
+			}
+		}
+
+		partitionsList := append(append([]int{}, station.PartitionsList...), newPartitions...)
+		updatedStation := station
+		updatedStation.PartitionsList = partitionsList
+
+		consumers, err := db.GetAllConsumersByStation(station.ID)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]AddStationPartitions at GetAllConsumersByStation at station %v: %v", tenantName, username, station.Name, err.Error())
+			s.handleAddPartitionsFailure(user, station, stationName, newPartitions, err.Error())
+			return
+		}
+		createdCgs := make(map[string]bool)
+		for _, consumer := range consumers {
+			if createdCgs[consumer.ConsumersGroup] {
+				continue
+			}
+			// the new partitions are empty so the consumers start from their beginning
+			newConsumer := models.Consumer{
+				Name:                consumer.Name,
+				ConsumersGroup:      consumer.ConsumersGroup,
+				MaxAckTimeMs:        consumer.MaxAckTimeMs,
+				MaxMsgDeliveries:    consumer.MaxMsgDeliveries,
+				StartConsumeFromSeq: 1,
+				LastMessages:        -1,
+				TenantName:          tenantName,
+			}
+			err = s.CreateConsumer(tenantName, newConsumer, updatedStation, newPartitions)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]AddStationPartitions at CreateConsumer at station %v consumer group %v: %v", tenantName, username, station.Name, consumer.ConsumersGroup, err.Error())
+				s.handleAddPartitionsFailure(user, station, stationName, newPartitions, err.Error())
+				return
+			}
+			createdCgs[consumer.ConsumersGroup] = true
+		}
+
+		err = db.UpdateStationPartitions(station.ID, partitionsList, tenantName)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]AddStationPartitions at UpdateStationPartitions at station %v: %v", tenantName, username, station.Name, err.Error())
+			s.handleAddPartitionsFailure(user, station, stationName, newPartitions, err.Error())
+			return
+		}
+
+		update := models.SdkClientsUpdates{
+			StationName: stationName.Intern(),
+			Type:        partitionsUpdateType,
+			Update:      models.PartitionsUpdate{PartitionsList: partitionsList},
+		}
+		s.SendUpdateToClients(update)
+		sendStationPartitionsCacheUpdate(tenantName, stationName.Intern(), partitionsList)
+
+		err = db.UpdateStatusAsyncTask(addPartitionsTaskName, tenantName, "completed", station.ID, "", "")
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]AddStationPartitions at UpdateStatusAsyncTask at station %v: %v", tenantName, username, station.Name, err.Error())
+		}
+
+		systemMessage := SystemMessage{
+			MessageType:    "info",
+			MessagePayload: fmt.Sprintf("Adding partitions to station %s, triggered by user %s has been completed successfully", stationName.Ext(), username),
+		}
+		err = s.sendSystemMessageOnWS(user, systemMessage)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]AddStationPartitions at sendSystemMessageOnWS at station %v: %v", tenantName, username, station.Name, err.Error())
+		}
+	}()
+}
+
+// newStationPartitions returns the partitions which have to be added for the station to have partitionsNumber partitions,
+// they are numbered after the highest existing partition
+func newStationPartitions(partitionsList []int, partitionsNumber int) []int {
+	lastPartition := 0
+	for _, p := range partitionsList {
+		if p > lastPartition {
+			lastPartition = p
+		}
+	}
+	var newPartitions []int
+	for i := 1; i <= partitionsNumber-len(partitionsList); i++ {
+		newPartitions = append(newPartitions, lastPartition+i)
+	}
+	return newPartitions
+}
+
+func (s *Server) handleAddPartitionsFailure(user models.User, station models.Station, stationName StationName, newPartitions []int, errMsg string) {
+	for _, p := range newPartitions {
+		streamName := fmt.Sprintf("%v$%v", stationName.Intern(), p)
+		err := s.RemoveStream(station.TenantName, streamName)
+		if err != nil && !IsNatsErr(err, JSStreamNotFoundErr) {
+			s.Errorf("[tenant: %v][user: %v]handleAddPartitionsFailure at RemoveStream at station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+		}
+	}
+
+	err := db.UpdateStatusAsyncTask(addPartitionsTaskName, station.TenantName, "failed", station.ID, errMsg, "")
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleAddPartitionsFailure at UpdateStatusAsyncTask at station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+	}
+
+	systemMessage := SystemMessage{
+		MessageType:    "error",
+		MessagePayload: fmt.Sprintf("Adding partitions to station %s, triggered by user %s has failed due to an internal error", stationName.Ext(), user.Username),
+	}
+	err = s.sendSystemMessageOnWS(user, systemMessage)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleAddPartitionsFailure at sendSystemMessageOnWS at station %v: %v", station.TenantName, user.Username, station.Name, err.Error())
+	}
+}
+
 func (sh StationsHandler) PurgeStation(c *gin.Context) {
 	var body models.PurgeStationSchema
 	ok := utils.Validate(c, &body, false, nil)
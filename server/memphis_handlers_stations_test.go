@@ -0,0 +1,183 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateStationUpdate(t *testing.T) {
+	station := models.Station{
+		Name:                        "orders",
+		TenantName:                  "acme",
+		RetentionType:               "message_age_sec",
+		RetentionValue:              7200,
+		Replicas:                    1,
+		IdempotencyWindow:           120000,
+		DlsConfigurationPoison:      true,
+		DlsConfigurationSchemaverse: true,
+		TieredStorageEnabled:        true,
+	}
+
+	cases := []struct {
+		name     string
+		body     string
+		expected func(s models.Station) models.Station
+		valid    bool
+	}{
+		{
+			name:     "empty update keeps everything",
+			body:     `{"station_name": "orders"}`,
+			expected: func(s models.Station) models.Station { return s },
+			valid:    true,
+		},
+		{
+			name: "replicas only keeps the dls and tiered storage flags",
+			body: `{"station_name": "orders", "replicas": 2}`,
+			expected: func(s models.Station) models.Station {
+				s.Replicas = 3
+				return s
+			},
+			valid: true,
+		},
+		{
+			name: "turning off one dls flag keeps the other",
+			body: `{"station_name": "orders", "dls_configuration": {"schemaverse": false}}`,
+			expected: func(s models.Station) models.Station {
+				s.DlsConfigurationSchemaverse = false
+				return s
+			},
+			valid: true,
+		},
+		{
+			name: "turning off tiered storage",
+			body: `{"station_name": "orders", "tiered_storage_enabled": false}`,
+			expected: func(s models.Station) models.Station {
+				s.TieredStorageEnabled = false
+				return s
+			},
+			valid: true,
+		},
+		{
+			name: "retention value only keeps the retention type",
+			body: `{"station_name": "orders", "retention_value": 600}`,
+			expected: func(s models.Station) models.Station {
+				s.RetentionValue = 600
+				s.IdempotencyWindow = 120000
+				return s
+			},
+			valid: true,
+		},
+		{
+			name: "retention type without a value falls back to an hour",
+			body: `{"station_name": "orders", "retention_type": "Messages"}`,
+			expected: func(s models.Station) models.Station {
+				s.RetentionType = "message_age_sec"
+				s.RetentionValue = 3600
+				return s
+			},
+			valid: true,
+		},
+		{
+			name: "retention type and value",
+			body: `{"station_name": "orders", "retention_type": "bytes", "retention_value": 1024, "idempotency_window_in_ms": 50}`,
+			expected: func(s models.Station) models.Station {
+				s.RetentionType = "bytes"
+				s.RetentionValue = 1024
+				s.IdempotencyWindow = 100
+				return s
+			},
+			valid: true,
+		},
+		{name: "invalid retention type", body: `{"station_name": "orders", "retention_type": "forever"}`},
+		{name: "idempotency window above the retention", body: `{"station_name": "orders", "retention_value": 60}`},
+		{name: "idempotency window above a day", body: `{"station_name": "orders", "retention_type": "messages", "retention_value": 10, "idempotency_window_in_ms": 90000000}`},
+	}
+
+	for _, tc := range cases {
+		var update models.UpdateStationSchema
+		err := json.Unmarshal([]byte(tc.body), &update)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		updated, err := validateStationUpdate(station, update)
+		if (err == nil) != tc.valid {
+			t.Errorf("%v: expected valid %v, got %v", tc.name, tc.valid, err)
+			continue
+		}
+		if !tc.valid {
+			continue
+		}
+		if expected := tc.expected(station); !reflect.DeepEqual(updated, expected) {
+			t.Errorf("%v: expected %+v, got %+v", tc.name, expected, updated)
+		}
+	}
+}
+
+func TestUpdateStationHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	originalServ := serv
+	serv = &Server{}
+	defer func() { serv = originalServ }()
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{name: "missing station name", body: `{"replicas": 3}`, expected: 400},
+		{name: "not json", body: `replicas=3`, expected: 400},
+		{name: "wrong field type", body: `{"station_name": "orders", "tiered_storage_enabled": "yes"}`, expected: 400},
+		{name: "invalid station name", body: `{"station_name": "orders!", "replicas": 3}`, expected: SHOWABLE_ERROR_STATUS_CODE},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("PUT", "/stations/updateStation", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", models.User{Username: "root", TenantName: "acme"})
+
+		StationsHandler{S: serv}.UpdateStation(c)
+		if w.Code != tc.expected {
+			t.Errorf("%v: expected status %v, got %v: %v", tc.name, tc.expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNewStationPartitions(t *testing.T) {
+	cases := []struct {
+		partitionsList   []int
+		partitionsNumber int
+		expected         []int
+	}{
+		{partitionsList: []int{1}, partitionsNumber: 3, expected: []int{2, 3}},
+		{partitionsList: []int{1, 2, 3}, partitionsNumber: 4, expected: []int{4}},
+		{partitionsList: []int{3, 1, 2}, partitionsNumber: 5, expected: []int{4, 5}},
+		{partitionsList: []int{1, 4}, partitionsNumber: 3, expected: []int{5}},
+		{partitionsList: []int{1, 2}, partitionsNumber: 2, expected: nil},
+	}
+	for _, c := range cases {
+		newPartitions := newStationPartitions(c.partitionsList, c.partitionsNumber)
+		if !reflect.DeepEqual(newPartitions, c.expected) {
+			t.Errorf("partitions %v to %v: expected %v, got %v", c.partitionsList, c.partitionsNumber, c.expected, newPartitions)
+		}
+	}
+}
@@ -126,6 +126,11 @@ func removeTenantResources(tenantName string, user models.User) error {
 		return err
 	}
 
+	err = db.RemoveSchemaReferencesByTenant(tenantName)
+	if err != nil {
+		return err
+	}
+
 	err = db.RemoveSchemaVersionsByTenant(tenantName)
 	if err != nil {
 		return err
@@ -186,6 +191,16 @@ func removeTenantResources(tenantName string, user models.User) error {
 		return err
 	}
 
+	err = db.RemoveStationSchemaMetricsByTenant(tenantName)
+	if err != nil {
+		return err
+	}
+
+	err = db.DeleteAlertRulesByTenant(tenantName)
+	if err != nil {
+		return err
+	}
+
 	err = db.RemoveStationsByTenant(tenantName)
 	if err != nil {
 		return err
@@ -827,7 +842,7 @@ func (umh UserMgmtHandler) GetFilterDetails(c *gin.Context) {
 			return
 		}
 
-		schemaType := []string{"protobuf", "json", "graphql", "avro"}
+		schemaType := []string{"protobuf", "json", "graphql", "avro", "xsd", "thrift"}
 		usage := []string{"used", "not used"}
 		c.IndentedJSON(200, gin.H{"tags": tags, "users": users, "type": schemaType, "usage": usage})
 		return
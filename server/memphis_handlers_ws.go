@@ -364,6 +364,11 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string,
 		}
 	}
 
+	cgsLag := make([]models.CgLagHistory, 0)
+	if station.IsNative {
+		cgsLag = getCgsLagOverview(station.TenantName, sn.Intern(), partitionNumber, time.Now())
+	}
+
 	tags, err := h.Tags.GetTagsByEntityWithID("station", station.ID)
 	if err != nil {
 		return map[string]any{}, err
@@ -417,6 +422,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string,
 				"connected_cgs":                   cc,
 				"disconnected_cgs":                disconnectedCgs,
 				"deleted_cgs":                     dc,
+				"cgs_lag":                         cgsLag,
 				"total_messages":                  totalMessages,
 				"average_message_size":            avgMsgSize,
 				"audit_logs":                      auditLogs,
@@ -449,6 +455,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string,
 				"connected_cgs":                   connectedCgs,
 				"disconnected_cgs":                disconnectedCgs,
 				"deleted_cgs":                     deletedCgs,
+				"cgs_lag":                         cgsLag,
 				"total_messages":                  totalMessages,
 				"average_message_size":            avgMsgSize,
 				"audit_logs":                      auditLogs,
@@ -500,6 +507,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string,
 		"connected_cgs":                   connectedCgs,
 		"disconnected_cgs":                disconnectedCgs,
 		"deleted_cgs":                     deletedCgs,
+		"cgs_lag":                         cgsLag,
 		"total_messages":                  totalMessages,
 		"average_message_size":            avgMsgSize,
 		"audit_logs":                      auditLogs,
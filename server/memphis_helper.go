@@ -61,6 +61,7 @@ const (
 	tieredStorageStream         = "$memphis_tiered_storage"
 	throughputStreamName        = "$memphis-throughput"
 	throughputStreamNameV1      = "$memphis-throughput-v1"
+	cgLagStreamName             = "$memphis_cg_lag"
 	MEMPHIS_GLOBAL_ACCOUNT      = "$memphis"
 	integrationsAuditLogsStream = "$memphis_integrations_audit_logs"
 	notificationsStreamName     = "$memphis_notifications_buffer"
@@ -75,6 +76,7 @@ const (
 	memphisSchemaCreations      = "$memphis_schema_creations"
 	memphisStationCreations     = "$memphis_station_creations"
 	memphisStationDestructions  = "$memphis_station_destructions"
+	memphisStationUpdates       = "$memphis_station_updates"
 )
 
 var noLimit = -1
@@ -85,6 +87,7 @@ var memphisReplaceImportString = "replaceImports"
 var memphisExportString = `[
 	{service: "$memphis_station_creations"},
 	{service: "$memphis_station_destructions"},
+	{service: "$memphis_station_updates"},
 	{service: "$memphis_producer_creations"},
 	{service: "$memphis_producer_destructions"},
 	{service: "$memphis_consumer_creations"},
@@ -106,6 +109,7 @@ var memphisExportString = `[
 var memphisImportString = `[
 	{service: {account: "$memphis", subject: "$memphis_station_creations"}},
 	{service: {account: "$memphis", subject: "$memphis_station_destructions"}},
+	{service: {account: "$memphis", subject: "$memphis_station_updates"}},
 	{service: {account: "$memphis", subject: "$memphis_producer_creations"}},
 	{service: {account: "$memphis", subject: "$memphis_producer_destructions"}},
 	{service: {account: "$memphis", subject: "$memphis_consumer_creations"}},
@@ -163,6 +167,7 @@ var (
 	SYSTEM_TASKS_STREAM_CREATED            bool
 	FUNCTIONS_TASKS_CONSUMER_CREATED       bool
 	CONNECTORS_LOGS_STREAM_CREATED         bool
+	CG_LAG_STREAM_CREATED                  bool
 )
 
 type Messages []models.MessageDetails
@@ -682,6 +687,25 @@ func tryCreateInternalJetStreamResources(s *Server, retentionDur time.Duration,
 		CONNECTORS_LOGS_STREAM_CREATED = true
 	}
 
+	// create consumer groups lag history stream
+	if !CG_LAG_STREAM_CREATED {
+		err = s.memphisAddStream(s.MemphisGlobalAccountString(), &StreamConfig{
+			Name:         cgLagStreamName,
+			Subjects:     []string{cgLagStreamName + ".>"},
+			Retention:    LimitsPolicy,
+			MaxAge:       cgLagHistoryRetention,
+			MaxConsumers: -1,
+			Discard:      DiscardOld,
+			Storage:      FileStorage,
+			Replicas:     replicas,
+		})
+		if err != nil && !IsNatsErr(err, JSStreamNameExistErr) {
+			successCh <- err
+			return
+		}
+		CG_LAG_STREAM_CREATED = true
+	}
+
 	successCh <- nil
 }
 
@@ -1272,6 +1296,13 @@ func (s *Server) GetMessagesFromPartition(station models.Station, streamName str
 	var messages []models.MessageDetails
 
 	stationIsNative := station.IsNative
+	var decoder *schemaPayloadDecoder
+	if stationIsNative {
+		decoder, err = loadStationSchemaDecoder(station)
+		if err != nil {
+			s.Warnf("[tenant: %v]GetMessagesFromPartition at loadStationSchemaDecoder: station %v: %v", station.TenantName, station.Name, err.Error())
+		}
+	}
 
 	for _, msg := range msgs {
 		messageDetails := models.MessageDetails{
@@ -1296,6 +1327,7 @@ func (s *Server) GetMessagesFromPartition(station models.Station, streamName str
 			}
 			connectionIdHeader := headersJson["$memphis_connectionId"]
 			producedByHeader := strings.ToLower(headersJson["$memphis_producedBy"])
+			schemaVersion := messageSchemaVersion(headersJson)
 
 			for header := range headersJson {
 				if strings.HasPrefix(header, MEMPHIS_GLOBAL_ACCOUNT) {
@@ -1310,6 +1342,7 @@ func (s *Server) GetMessagesFromPartition(station models.Station, streamName str
 			messageDetails.ConnectionId = connectionIdHeader
 			messageDetails.Headers = headersJson
 			messageDetails.Partition = partition
+			decoder.renderMessagePreview(&messageDetails, msg.Data, schemaVersion)
 		}
 
 		messages = append(messages, messageDetails)
@@ -1489,6 +1522,63 @@ func (s *Server) memphisGetMessage(tenantName, streamName string, msgSeq uint64)
 	return resp.Message, nil
 }
 
+// memphisGetNextMessage returns the first message on the subject whose sequence is equal or greater than startSeq
+func (s *Server) memphisGetNextMessage(tenantName, streamName, subject string, startSeq uint64) (*StoredMsg, error) {
+	requestSubject := fmt.Sprintf(JSApiMsgGetT, streamName)
+	request := JSApiMsgGetRequest{Seq: startSeq, NextFor: subject}
+	rawRequest, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp JSApiMsgGetResponse
+	err = jsApiRequest(tenantName, s, requestSubject, kindGetMsg, rawRequest, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resp.ToError()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Message, nil
+}
+
+// memphisPublishWithAck publishes a message to a stream subject and waits for the stream to acknowledge it
+func (s *Server) memphisPublishWithAck(tenantName, subject string, hdr map[string]string, msg []byte) error {
+	account, err := s.lookupAccount(tenantName)
+	if err != nil {
+		return err
+	}
+	reply := s.getJsApiReplySubject()
+
+	timeout := time.After(10 * time.Second)
+	respCh := make(chan []byte)
+	sub, err := s.subscribeOnAcc(account, reply, reply+"_sid", createReplyHandler(s, respCh))
+	if err != nil {
+		return err
+	}
+
+	s.sendInternalAccountMsgWithReply(account, subject, reply, hdr, msg, true)
+
+	var rawResp []byte
+	select {
+	case rawResp = <-respCh:
+		s.unsubscribeOnAcc(account, sub)
+	case <-timeout:
+		s.unsubscribeOnAcc(account, sub)
+		return fmt.Errorf("[tenant name: %v]publish ack timeout on %q", tenantName, subject)
+	}
+
+	var resp JSPubAckResponse
+	err = json.Unmarshal(rawResp, &resp)
+	if err != nil {
+		return err
+	}
+	return resp.ToError()
+}
+
 func (s *Server) queueSubscribe(tenantName string, subj, queueGroupName string, cb simplifiedMsgHandler) error {
 	acc, err := s.lookupAccount(tenantName)
 	if err != nil {
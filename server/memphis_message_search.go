@@ -0,0 +1,486 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	searchMessagesDefaultLimit = 50
+	searchMessagesMaxLimit     = 1000
+	searchMessagesBatchSize    = 1000
+	searchMessagesMaxScanned   = 100000
+	searchMessagesTimeLimit    = 10 * time.Second
+	searchMessagesBatchTimeout = 2 * time.Second
+)
+
+// messageSearchCursor holds the next sequence to scan of every partition which was not fully scanned yet
+type messageSearchCursor map[int]uint64
+
+func encodeMessageSearchCursor(cursor messageSearchCursor) (string, error) {
+	if len(cursor) == 0 {
+		return _EMPTY_, nil
+	}
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
+
+func decodeMessageSearchCursor(cursor string) (messageSearchCursor, error) {
+	if cursor == _EMPTY_ {
+		return nil, nil
+	}
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var decoded messageSearchCursor
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return decoded, nil
+}
+
+type messageSearchFilter struct {
+	headers         map[string]string
+	payloadContains []byte
+	jsonPath        *jsonpath.JSONPath
+	producerName    string
+	fromTime        *time.Time
+	toTime          *time.Time
+}
+
+func newMessageSearchFilter(body models.SearchMessagesSchema) (*messageSearchFilter, error) {
+	filter := &messageSearchFilter{
+		headers:      body.Headers,
+		producerName: strings.ToLower(body.ProducerName),
+		fromTime:     body.FromTime,
+		toTime:       body.ToTime,
+	}
+	if body.PayloadContains != _EMPTY_ {
+		filter.payloadContains = []byte(body.PayloadContains)
+	}
+	if body.FromTime != nil && body.ToTime != nil && body.ToTime.Before(*body.FromTime) {
+		return nil, errors.New("to_time has to be after from_time")
+	}
+	if body.JsonPath != _EMPTY_ {
+		// both $.a.b and the kubernetes {.a.b} template forms are accepted
+		expression := body.JsonPath
+		if !strings.HasPrefix(expression, "{") {
+			expression = "{" + expression + "}"
+		}
+		filter.jsonPath = jsonpath.New("search").AllowMissingKeys(true)
+		err := filter.jsonPath.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json path %v: %v", body.JsonPath, err.Error())
+		}
+	}
+	return filter, nil
+}
+
+func (f *messageSearchFilter) match(msg *StoredMsg, headers map[string]string, decoder *schemaPayloadDecoder) bool {
+	if f.fromTime != nil && msg.Time.Before(*f.fromTime) {
+		return false
+	}
+	if f.toTime != nil && msg.Time.After(*f.toTime) {
+		return false
+	}
+	if f.producerName != _EMPTY_ && strings.ToLower(headers["$memphis_producedBy"]) != f.producerName {
+		return false
+	}
+	for key, value := range f.headers {
+		if headerValue, ok := headers[key]; !ok || headerValue != value {
+			return false
+		}
+	}
+	if f.payloadContains == nil && f.jsonPath == nil {
+		return true
+	}
+
+	// payloads which are not readable as they are (for example protobuf) are also searched in their schema rendering
+	rendering, renderedOk := decoder.render(msg.Data, messageSchemaVersion(headers), false)
+	if f.payloadContains != nil && !bytes.Contains(msg.Data, f.payloadContains) &&
+		!(renderedOk && strings.Contains(rendering.data, string(f.payloadContains))) {
+		return false
+	}
+	if f.jsonPath != nil {
+		payload := msg.Data
+		if !json.Valid(payload) {
+			if !renderedOk || rendering.format != "json" {
+				return false
+			}
+			payload = []byte(rendering.data)
+		}
+		var value interface{}
+		if json.Unmarshal(payload, &value) != nil {
+			return false
+		}
+		results, err := f.jsonPath.FindResults(value)
+		if err != nil {
+			return false
+		}
+		found := false
+		for _, result := range results {
+			if len(result) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func searchResultMessageDetails(msg *StoredMsg, headers map[string]string, partition int, decoder *schemaPayloadDecoder) models.MessageDetails {
+	data := hex.EncodeToString(msg.Data)
+	if len(data) > messagePreviewLength {
+		data = data[0:messagePreviewLength]
+	}
+	userHeaders := make(map[string]string, len(headers))
+	for header, value := range headers {
+		if !strings.HasPrefix(header, MEMPHIS_GLOBAL_ACCOUNT) {
+			userHeaders[header] = value
+		}
+	}
+	messageDetails := models.MessageDetails{
+		MessageSeq:   int(msg.Sequence),
+		ProducedBy:   strings.ToLower(headers["$memphis_producedBy"]),
+		Data:         data,
+		TimeSent:     msg.Time,
+		ConnectionId: headers["$memphis_connectionId"],
+		Size:         len(msg.Subject) + len(msg.Data) + len(msg.Header),
+		Headers:      userHeaders,
+		Partition:    partition,
+	}
+	decoder.renderMessagePreview(&messageDetails, msg.Data, messageSchemaVersion(headers))
+	return messageDetails
+}
+
+// findFirstSeqByTime returns a sequence to start scanning from so that all the messages before it were stored before the given time,
+// it is found by a binary search over the message times which are monotonic within a stream
+func (s *Server) findFirstSeqByTime(tenantName, streamName, subject string, firstSeq, lastSeq uint64, t time.Time) uint64 {
+	lo, hi := firstSeq, lastSeq+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		msg, err := s.memphisGetNextMessage(tenantName, streamName, subject, mid)
+		if err != nil {
+			hi = mid
+			continue
+		}
+		if msg.Time.Before(t) {
+			lo = msg.Sequence + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// scannableSearchBatch sorts a fetched batch and returns the messages which can be scanned without skipping a sequence,
+// and whether the partition has no messages after them. The messages of a fetch are received concurrently so when the fetch
+// times out before the whole batch arrived the missing messages may be in the middle of the batch, every gap between the
+// received sequences is looked up with nextSeq and the batch is cut at the first sequence which exists but was not received
+func scannableSearchBatch(msgs []StoredMsg, startSeq uint64, amount int, nextSeq func(seq uint64) (uint64, bool, error)) ([]StoredMsg, bool, error) {
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Sequence < msgs[j].Sequence
+	})
+	if len(msgs) >= amount {
+		return msgs, false, nil
+	}
+
+	expected := startSeq
+	for i, msg := range msgs {
+		if msg.Sequence != expected {
+			seq, found, err := nextSeq(expected)
+			if err != nil {
+				return nil, false, err
+			}
+			if !found {
+				return msgs[:i], true, nil
+			}
+			if seq != msg.Sequence {
+				return msgs[:i], false, nil
+			}
+		}
+		expected = msg.Sequence + 1
+	}
+	_, found, err := nextSeq(expected)
+	if err != nil {
+		return nil, false, err
+	}
+	return msgs, !found, nil
+}
+
+// searchStationMessages scans the partitions of the station in order, it stops when enough results were found
+// or the scanning budget (messages and time) is exhausted and returns a cursor to resume the search from
+func (s *Server) searchStationMessages(station models.Station, filter *messageSearchFilter, cursor messageSearchCursor, limit int) (models.SearchMessagesResponse, error) {
+	response := models.SearchMessagesResponse{Messages: []models.MessageDetails{}}
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return response, err
+	}
+
+	partitions := []int{0}
+	if len(station.PartitionsList) > 0 {
+		partitions = append([]int{}, station.PartitionsList...)
+		sort.Ints(partitions)
+	}
+
+	var decoder *schemaPayloadDecoder
+	if station.IsNative {
+		decoder, err = loadStationSchemaDecoder(station)
+		if err != nil {
+			s.Warnf("[tenant: %v]searchStationMessages at loadStationSchemaDecoder: station %v: %v", station.TenantName, station.Name, err.Error())
+		}
+	}
+
+	deadline := time.Now().Add(searchMessagesTimeLimit)
+	nextCursor := messageSearchCursor{}
+	exhausted := false
+	for _, partition := range partitions {
+		startSeq, inCursor := cursor[partition]
+		if cursor != nil && !inCursor {
+			// the partition was fully scanned by a previous page
+			continue
+		}
+		if exhausted {
+			nextCursor[partition] = startSeq
+			continue
+		}
+
+		streamName := stationName.Intern()
+		if len(station.PartitionsList) > 0 {
+			streamName = fmt.Sprintf("%v$%v", stationName.Intern(), partition)
+		}
+		streamInfo, err := s.memphisStreamInfo(station.TenantName, streamName)
+		if err != nil {
+			return response, err
+		}
+		filterSubj := streamName + ".final"
+		if !station.IsNative {
+			filterSubj = _EMPTY_
+		}
+		replicas := 1
+		if streamInfo.Config.Retention == InterestPolicy {
+			replicas = streamInfo.Config.Replicas
+		}
+		lastSeq := streamInfo.State.LastSeq
+		nextSubj := filterSubj
+		if nextSubj == _EMPTY_ {
+			nextSubj = streamName + ".>"
+		}
+		if startSeq < streamInfo.State.FirstSeq {
+			startSeq = streamInfo.State.FirstSeq
+			if filter.fromTime != nil && streamInfo.State.Msgs > 0 {
+				startSeq = s.findFirstSeqByTime(station.TenantName, streamName, nextSubj, startSeq, lastSeq, *filter.fromTime)
+			}
+		}
+		nextSeq := func(seq uint64) (uint64, bool, error) {
+			if seq > lastSeq {
+				return 0, false, nil
+			}
+			msg, err := s.memphisGetNextMessage(station.TenantName, streamName, nextSubj, seq)
+			if err != nil {
+				if IsNatsErr(err, JSNoMessageFoundErr) {
+					return 0, false, nil
+				}
+				return 0, false, err
+			}
+			if msg.Sequence > lastSeq {
+				return 0, false, nil
+			}
+			return msg.Sequence, true, nil
+		}
+
+	scan:
+		for startSeq <= lastSeq && streamInfo.State.Msgs > 0 {
+			if len(response.Messages) >= limit || response.ScannedMessages >= searchMessagesMaxScanned || time.Now().After(deadline) {
+				exhausted = true
+				break
+			}
+			amount := searchMessagesBatchSize
+			if remaining := lastSeq - startSeq + 1; remaining < uint64(amount) {
+				amount = int(remaining)
+			}
+			timeout := time.Until(deadline)
+			if timeout > searchMessagesBatchTimeout {
+				timeout = searchMessagesBatchTimeout
+			}
+			msgs, err := s.memphisGetMsgs(station.TenantName, filterSubj, streamName, startSeq, amount, timeout, true, station.RetentionType == "ack_based", replicas)
+			if err != nil {
+				return response, err
+			}
+			msgs, reachedEnd, err := scannableSearchBatch(msgs, startSeq, amount, nextSeq)
+			if err != nil {
+				return response, err
+			}
+
+			for i := range msgs {
+				msg := &msgs[i]
+				if len(response.Messages) >= limit {
+					startSeq = msg.Sequence
+					exhausted = true
+					break scan
+				}
+				response.ScannedMessages++
+				startSeq = msg.Sequence + 1
+				if filter.toTime != nil && msg.Time.After(*filter.toTime) {
+					// messages are stored in time order so the rest of the partition is out of range
+					startSeq = lastSeq + 1
+					break scan
+				}
+
+				headers := map[string]string{}
+				if len(msg.Header) > 0 {
+					headers, err = DecodeHeader(msg.Header)
+					if err != nil {
+						return response, err
+					}
+				}
+				if headers["$memphis_producedBy"] == "$memphis_dls" { // skip poison messages which have been resent
+					continue
+				}
+				if filter.match(msg, headers, decoder) {
+					response.Messages = append(response.Messages, searchResultMessageDetails(msg, headers, partition, decoder))
+				}
+			}
+			if reachedEnd {
+				// the rest of the sequences up to the last one were deleted
+				startSeq = lastSeq + 1
+				break
+			}
+			if len(msgs) == 0 {
+				// nothing could be scanned before the timeout, the cursor resumes from the same sequence
+				break
+			}
+		}
+		if startSeq <= lastSeq && streamInfo.State.Msgs > 0 {
+			nextCursor[partition] = startSeq
+		}
+	}
+
+	response.Cursor, err = encodeMessageSearchCursor(nextCursor)
+	if err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+func (sh StationsHandler) SearchMessages(c *gin.Context) {
+	var body models.SearchMessagesSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("SearchMessages at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "read")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]SearchMessages at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to read from station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	limit := body.Limit
+	if limit <= 0 {
+		limit = searchMessagesDefaultLimit
+	}
+	if limit > searchMessagesMaxLimit {
+		errMsg := fmt.Sprintf("limit can not be larger than %v", searchMessagesMaxLimit)
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	filter, err := newMessageSearchFilter(body)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages at newMessageSearchFilter: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	cursor, err := decodeMessageSearchCursor(body.Cursor)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages at decodeMessageSearchCursor: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]SearchMessages at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]SearchMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	response, err := sh.S.searchStationMessages(station, filter, cursor, limit)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]SearchMessages at searchStationMessages: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := make(map[string]interface{})
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-search-messages")
+	}
+
+	c.IndentedJSON(200, response)
+}
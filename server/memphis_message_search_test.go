@@ -0,0 +1,188 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestMessageSearchCursor(t *testing.T) {
+	cursor := messageSearchCursor{1: 120, 3: 7}
+	encoded, err := encodeMessageSearchCursor(cursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := decodeMessageSearchCursor(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cursor) {
+		t.Errorf("expected %v, got %v", cursor, decoded)
+	}
+
+	if encoded, err := encodeMessageSearchCursor(messageSearchCursor{}); err != nil || encoded != "" {
+		t.Errorf("expected a fully scanned search to have no cursor, got %q %v", encoded, err)
+	}
+	if decoded, err := decodeMessageSearchCursor(""); err != nil || decoded != nil {
+		t.Errorf("expected an empty cursor to start a new search, got %v %v", decoded, err)
+	}
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24="} {
+		if _, err := decodeMessageSearchCursor(invalid); err == nil {
+			t.Errorf("%v: expected an invalid cursor error", invalid)
+		}
+	}
+}
+
+func TestNewMessageSearchFilterErrors(t *testing.T) {
+	from := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	to := from.Add(-time.Minute)
+	if _, err := newMessageSearchFilter(models.SearchMessagesSchema{FromTime: &from, ToTime: &to}); err == nil {
+		t.Errorf("expected an error for a time range which ends before it starts")
+	}
+	if _, err := newMessageSearchFilter(models.SearchMessagesSchema{JsonPath: "$.items[?(@.sku"}); err == nil {
+		t.Errorf("expected an error for an invalid json path")
+	}
+}
+
+func TestMessageSearchFilterMatch(t *testing.T) {
+	sent := time.Date(2023, 5, 1, 10, 15, 0, 0, time.UTC)
+	msg := &StoredMsg{
+		Sequence: 5,
+		Data:     []byte(`{"customer": {"id": 7, "tier": "gold"}, "items": [{"sku": "a"}, {"sku": "b"}]}`),
+		Time:     sent,
+	}
+	headers := map[string]string{"$memphis_producedBy": "Orders-Service", "tenant": "acme"}
+	before := sent.Add(-15 * time.Minute)
+	after := sent.Add(15 * time.Minute)
+
+	cases := []struct {
+		name    string
+		body    models.SearchMessagesSchema
+		msg     *StoredMsg
+		matches bool
+	}{
+		{name: "no filters", matches: true},
+		{name: "in time range", body: models.SearchMessagesSchema{FromTime: &before, ToTime: &after}, matches: true},
+		{name: "before time range", body: models.SearchMessagesSchema{FromTime: &after}, matches: false},
+		{name: "after time range", body: models.SearchMessagesSchema{ToTime: &before}, matches: false},
+		{name: "producer name is case insensitive", body: models.SearchMessagesSchema{ProducerName: "orders-service"}, matches: true},
+		{name: "other producer", body: models.SearchMessagesSchema{ProducerName: "billing"}, matches: false},
+		{name: "header", body: models.SearchMessagesSchema{Headers: map[string]string{"tenant": "acme"}}, matches: true},
+		{name: "header value mismatch", body: models.SearchMessagesSchema{Headers: map[string]string{"tenant": "globex"}}, matches: false},
+		{name: "missing header", body: models.SearchMessagesSchema{Headers: map[string]string{"region": "eu"}}, matches: false},
+		{name: "payload contains", body: models.SearchMessagesSchema{PayloadContains: `"tier": "gold"`}, matches: true},
+		{name: "payload does not contain", body: models.SearchMessagesSchema{PayloadContains: "silver"}, matches: false},
+		{name: "json path", body: models.SearchMessagesSchema{JsonPath: "$.customer.tier"}, matches: true},
+		{name: "json path template form", body: models.SearchMessagesSchema{JsonPath: "{.customer.id}"}, matches: true},
+		{name: "json path missing key", body: models.SearchMessagesSchema{JsonPath: "$.customer.email"}, matches: false},
+		{name: "json path filter", body: models.SearchMessagesSchema{JsonPath: `$.items[?(@.sku=="b")]`}, matches: true},
+		{name: "json path filter mismatch", body: models.SearchMessagesSchema{JsonPath: `$.items[?(@.sku=="c")]`}, matches: false},
+		{name: "json path on a non json payload", body: models.SearchMessagesSchema{JsonPath: "$.customer"}, msg: &StoredMsg{Data: []byte("customer"), Time: sent}, matches: false},
+		{name: "all filters", body: models.SearchMessagesSchema{ProducerName: "orders-service", Headers: map[string]string{"tenant": "acme"}, PayloadContains: "sku", JsonPath: "$.customer.id", FromTime: &before}, matches: true},
+	}
+	for _, c := range cases {
+		filter, err := newMessageSearchFilter(c.body)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		m := msg
+		if c.msg != nil {
+			m = c.msg
+		}
+		if matches := filter.match(m, headers, nil); matches != c.matches {
+			t.Errorf("%v: expected match to be %v, got %v", c.name, c.matches, matches)
+		}
+	}
+}
+
+func TestSearchResultMessageDetails(t *testing.T) {
+	data := make([]byte, 100)
+	msg := &StoredMsg{Subject: "orders$2.final", Sequence: 9, Data: data, Time: time.Now()}
+	headers := map[string]string{"$memphis_producedBy": "Orders", "$memphis_connectionId": "conn-1", "tenant": "acme"}
+	details := searchResultMessageDetails(msg, headers, 2, nil)
+	if details.MessageSeq != 9 || details.Partition != 2 || details.ProducedBy != "orders" || details.ConnectionId != "conn-1" {
+		t.Errorf("unexpected message details %+v", details)
+	}
+	if !reflect.DeepEqual(details.Headers, map[string]string{"tenant": "acme"}) {
+		t.Errorf("expected only the user headers, got %v", details.Headers)
+	}
+	if details.Data != hex.EncodeToString(data)[:messagePreviewLength] {
+		t.Errorf("expected the data preview to be truncated to %v characters, got %v", messagePreviewLength, len(details.Data))
+	}
+	if details.Size != len(msg.Subject)+len(data) {
+		t.Errorf("expected size %v, got %v", len(msg.Subject)+len(data), details.Size)
+	}
+}
+
+func TestScannableSearchBatch(t *testing.T) {
+	batch := func(seqs ...uint64) []StoredMsg {
+		msgs := []StoredMsg{}
+		for _, seq := range seqs {
+			msgs = append(msgs, StoredMsg{Sequence: seq})
+		}
+		return msgs
+	}
+	sequences := func(msgs []StoredMsg) []uint64 {
+		seqs := []uint64{}
+		for _, msg := range msgs {
+			seqs = append(seqs, msg.Sequence)
+		}
+		return seqs
+	}
+	// the stream holds the given sequences, the others were deleted
+	stream := func(stored ...uint64) func(uint64) (uint64, bool, error) {
+		return func(seq uint64) (uint64, bool, error) {
+			for _, s := range stored {
+				if s >= seq {
+					return s, true, nil
+				}
+			}
+			return 0, false, nil
+		}
+	}
+
+	cases := []struct {
+		name       string
+		msgs       []StoredMsg
+		amount     int
+		stored     []uint64
+		expected   []uint64
+		reachedEnd bool
+	}{
+		{name: "complete batch", msgs: batch(3, 1, 2), amount: 3, stored: []uint64{1, 2, 3, 4}, expected: []uint64{1, 2, 3}},
+		{name: "timed out at the end of the partition", msgs: batch(2, 1), amount: 3, stored: []uint64{1, 2}, expected: []uint64{1, 2}, reachedEnd: true},
+		{name: "timed out before the rest arrived", msgs: batch(2, 1), amount: 3, stored: []uint64{1, 2, 3}, expected: []uint64{1, 2}},
+		{name: "missing in the middle", msgs: batch(1, 4, 2), amount: 5, stored: []uint64{1, 2, 3, 4, 5}, expected: []uint64{1, 2}},
+		{name: "deleted in the middle", msgs: batch(1, 4, 2), amount: 5, stored: []uint64{1, 2, 4, 6}, expected: []uint64{1, 2, 4}},
+		{name: "deleted before the first", msgs: batch(5), amount: 2, stored: []uint64{5}, expected: []uint64{5}, reachedEnd: true},
+		{name: "nothing arrived", msgs: batch(), amount: 2, stored: []uint64{1}, expected: []uint64{}},
+		{name: "nothing left", msgs: batch(), amount: 2, stored: []uint64{}, expected: []uint64{}, reachedEnd: true},
+	}
+	for _, c := range cases {
+		msgs, reachedEnd, err := scannableSearchBatch(c.msgs, 1, c.amount, stream(c.stored...))
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		if seqs := sequences(msgs); !reflect.DeepEqual(seqs, c.expected) {
+			t.Errorf("%v: expected sequences %v, got %v", c.name, c.expected, seqs)
+		}
+		if reachedEnd != c.reachedEnd {
+			t.Errorf("%v: expected reached end %v, got %v", c.name, c.reachedEnd, reachedEnd)
+		}
+	}
+}
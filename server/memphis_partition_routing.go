@@ -0,0 +1,240 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+// Producers which want the broker to pick the partition publish to the station's root subject (<station>.final)
+// with the partition key header, the key is hashed with FNV-1a (32 bit) modulo the number of partitions,
+// the same hash used by the partition() subject mapping function, and the result is an index into the sorted partitions list
+const partitionKeyHeader = "$memphis_partition_key"
+
+const stationPartitionsCacheType = "station_partitions"
+
+const (
+	notStationsCacheTTL     = time.Minute
+	notStationsCacheMaxSize = 10000
+)
+
+// tenant:station -> sorted partitions list, the cache is filled with all the stations before the broker accepts client
+// connections and kept up to date by the station cache updates
+var stationPartitionsCache = NewConcurrentMap[[]int]()
+
+// tenant:subject -> expiration of an entry which marks a subject which is not a station, the entries expire so a station
+// created while its update was missed is picked up, and the cache is capped since the subjects are picked by the clients
+var notStationsCache = NewConcurrentMap[time.Time]()
+
+// tenant:station -> a load of the station's partitions is in progress
+var stationPartitionsLoading = NewConcurrentMap[bool]()
+
+func getPartitionByKey(key []byte, partitionsList []int) int {
+	var tr subjectTransform
+	index, _ := strconv.Atoi(tr.getHashPartition(key, len(partitionsList)))
+	return partitionsList[index]
+}
+
+func sortedPartitionsList(partitionsList []int) []int {
+	sorted := append([]int{}, partitionsList...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func stationPartitionsCacheKey(tenantName, stationIntern string) string {
+	return tenantName + ":" + stationIntern
+}
+
+func setStationPartitionsCache(cacheKey string, partitionsList []int) {
+	notStationsCache.Delete(cacheKey)
+	stationPartitionsCache.Lock()
+	stationPartitionsCache.m[cacheKey] = sortedPartitionsList(partitionsList)
+	stationPartitionsCache.Unlock()
+}
+
+func setNotStationCache(cacheKey string, now time.Time) {
+	stationPartitionsCache.Delete(cacheKey)
+	notStationsCache.Lock()
+	defer notStationsCache.Unlock()
+	if len(notStationsCache.m) >= notStationsCacheMaxSize {
+		for key, expiration := range notStationsCache.m {
+			if !now.Before(expiration) {
+				delete(notStationsCache.m, key)
+			}
+		}
+	}
+	// the map iteration order is random so a full cache evicts a random entry
+	for key := range notStationsCache.m {
+		if len(notStationsCache.m) < notStationsCacheMaxSize {
+			break
+		}
+		delete(notStationsCache.m, key)
+	}
+	notStationsCache.m[cacheKey] = now.Add(notStationsCacheTTL)
+}
+
+func isNotStationCached(cacheKey string, now time.Time) bool {
+	expiration, ok := notStationsCache.Load(cacheKey)
+	return ok && now.Before(expiration)
+}
+
+// getStationPartitionsForRouting returns the sorted partitions of the station out of the cache, an empty list marks a subject
+// which is not a station, on a miss the station is loaded in the background and ok is false so the client read loop never waits for the db
+func getStationPartitionsForRouting(tenantName string, stationName StationName) ([]int, bool) {
+	cacheKey := stationPartitionsCacheKey(tenantName, stationName.Intern())
+	if partitionsList, ok := stationPartitionsCache.Load(cacheKey); ok {
+		return partitionsList, true
+	}
+	if isNotStationCached(cacheKey, time.Now()) {
+		return []int{}, true
+	}
+	if stationPartitionsLoading.Add(cacheKey, true) {
+		go func() {
+			defer stationPartitionsLoading.Delete(cacheKey)
+			_, err := loadStationPartitions(tenantName, stationName)
+			if err != nil {
+				serv.Errorf("[tenant: %v]getStationPartitionsForRouting at loadStationPartitions: station %v: %v", tenantName, stationName.Ext(), err.Error())
+			}
+		}()
+	}
+	return nil, false
+}
+
+// loadStationPartitions returns the sorted partitions of the station and loads them into the cache on a miss,
+// it queries the db so it must not be called on the publish path
+func loadStationPartitions(tenantName string, stationName StationName) ([]int, error) {
+	cacheKey := stationPartitionsCacheKey(tenantName, stationName.Intern())
+	if partitionsList, ok := stationPartitionsCache.Load(cacheKey); ok {
+		return partitionsList, nil
+	}
+	if isNotStationCached(cacheKey, time.Now()) {
+		return []int{}, nil
+	}
+	exist, station, err := db.GetStationByName(stationName.Ext(), tenantName)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		setNotStationCache(cacheKey, time.Now())
+		return []int{}, nil
+	}
+	partitionsList := sortedPartitionsList(station.PartitionsList)
+	// an update which arrived during the load is newer than the loaded station
+	stationPartitionsCache.Add(cacheKey, partitionsList)
+	return partitionsList, nil
+}
+
+// LoadStationPartitionsCache fills the partitions cache with all the stations, it runs before the broker accepts
+// client connections so keyed messages published right after a restart are routed
+func (s *Server) LoadStationPartitionsCache() error {
+	stations, err := db.GetActiveStations()
+	if err != nil {
+		return err
+	}
+	for _, station := range stations {
+		stationName, err := StationNameFromStr(station.Name)
+		if err != nil {
+			continue
+		}
+		setStationPartitionsCache(stationPartitionsCacheKey(station.TenantName, stationName.Intern()), station.PartitionsList)
+	}
+	return nil
+}
+
+// applyStationPartitionsCacheUpdate stores the partitions of the updated stations, a delete marks them as not being stations
+func applyStationPartitionsCacheUpdate(cacheUpdate models.CacheUpdateRequest) {
+	for _, stationIntern := range cacheUpdate.Stations {
+		cacheKey := stationPartitionsCacheKey(cacheUpdate.TenantName, stationIntern)
+		if cacheUpdate.Operation == "delete" {
+			setNotStationCache(cacheKey, time.Now())
+			continue
+		}
+		setStationPartitionsCache(cacheKey, cacheUpdate.Partitions)
+	}
+}
+
+// sendStationPartitionsCacheUpdate notifies all the brokers about the partitions of a created or scaled station,
+// nil partitions mark a removed station
+func sendStationPartitionsCacheUpdate(tenantName, stationIntern string, partitionsList []int) {
+	operation := "update"
+	if partitionsList == nil {
+		operation = "delete"
+	}
+	cacheUpdate := models.CacheUpdateRequest{
+		CacheType:  stationPartitionsCacheType,
+		Operation:  operation,
+		TenantName: tenantName,
+		Stations:   []string{stationIntern},
+		Partitions: partitionsList,
+	}
+
+	msg, err := json.Marshal(cacheUpdate)
+	if err != nil {
+		serv.Errorf("[tenant: %v]sendStationPartitionsCacheUpdate at json.Marshal: %v", tenantName, err.Error())
+		return
+	}
+
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), CACHE_UDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		serv.Errorf("[tenant: %v]sendStationPartitionsCacheUpdate at sendInternalAccountMsgWithReply: %v", tenantName, err.Error())
+	}
+}
+
+// routeByPartitionKey replaces the subject of a message published to a partitioned station's root subject
+// with the subject of the partition its key is mapped to, messages without the key header are left untouched,
+// a message of a station whose partitions are not loaded yet is rejected since its root subject has no stream
+func (c *client) routeByPartitionKey(tenantName string, msg []byte) bool {
+	if c.kind != CLIENT || c.pa.hdr <= 0 {
+		return true
+	}
+	subj := string(c.pa.subject)
+	if strings.HasPrefix(subj, "$") || !strings.HasSuffix(subj, ".final") {
+		return true
+	}
+	stationIntern := strings.TrimSuffix(subj, ".final")
+	if strings.ContainsAny(stationIntern, "$.") {
+		return true
+	}
+	hdr, _ := c.msgParts(msg)
+	key := getHeader(partitionKeyHeader, hdr)
+	if len(key) == 0 {
+		return true
+	}
+
+	stationName := StationNameFromStreamName(stationIntern)
+	partitionsList, ok := getStationPartitionsForRouting(tenantName, stationName)
+	if !ok {
+		// a publisher without a reply subject is not notified, an -ERR would close its connection
+		if reply := string(c.pa.reply); reply != _EMPTY_ {
+			loadingErr := fmt.Sprintf("The partitions of station %v are being loaded, please retry", stationName.Ext())
+			resp := JSPubAckResponse{Error: &ApiError{Code: 503, Description: loadingErr}}
+			c.srv.sendInternalAccountMsg(c.acc, reply, resp)
+		}
+		return false
+	}
+	if len(partitionsList) == 0 {
+		return true
+	}
+
+	partition := getPartitionByKey(key, partitionsList)
+	c.pa.subject = []byte(stationIntern + "$" + strconv.Itoa(partition) + ".final")
+	return true
+}
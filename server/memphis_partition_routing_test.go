@@ -0,0 +1,143 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestGetPartitionByKey(t *testing.T) {
+	cases := []struct {
+		key            string
+		partitionsList []int
+		expected       int
+	}{
+		{key: "user-1", partitionsList: []int{1, 2, 3}, expected: 2},
+		{key: "user-2", partitionsList: []int{1, 2, 3}, expected: 2},
+		{key: "order-42", partitionsList: []int{1, 2, 3}, expected: 3},
+		{key: "user-1", partitionsList: []int{1, 2, 3, 4, 5}, expected: 1},
+		{key: "user-2", partitionsList: []int{1, 2, 3, 4, 5}, expected: 3},
+		{key: "a", partitionsList: []int{1}, expected: 1},
+	}
+
+	for _, c := range cases {
+		partition := getPartitionByKey([]byte(c.key), c.partitionsList)
+		if partition != c.expected {
+			t.Errorf("key %v with partitions %v: expected partition %v, got %v", c.key, c.partitionsList, c.expected, partition)
+		}
+	}
+}
+
+func TestRouteByPartitionKey(t *testing.T) {
+	applyStationPartitionsCacheUpdate(models.CacheUpdateRequest{TenantName: "acme", Operation: "update", Stations: []string{"orders"}, Partitions: []int{3, 1, 2}})
+	applyStationPartitionsCacheUpdate(models.CacheUpdateRequest{TenantName: "acme", Operation: "delete", Stations: []string{"events"}})
+	defer stationPartitionsCache.Delete(stationPartitionsCacheKey("acme", "orders"))
+	defer notStationsCache.Delete(stationPartitionsCacheKey("acme", "events"))
+
+	route := func(subject, key string) string {
+		hdr := "NATS/1.0\r\n"
+		if key != "" {
+			hdr += partitionKeyHeader + ": " + key + "\r\n"
+		}
+		hdr += "\r\n"
+		c := &client{kind: CLIENT}
+		c.pa.subject = []byte(subject)
+		c.pa.hdr = len(hdr)
+		if !c.routeByPartitionKey("acme", []byte(hdr+"payload\r\n")) {
+			return "rejected"
+		}
+		return string(c.pa.subject)
+	}
+
+	cases := []struct {
+		subject  string
+		key      string
+		expected string
+	}{
+		// the partitions are stored sorted so the key is mapped the same way getPartitionByKey maps it on [1, 2, 3]
+		{subject: "orders.final", key: "user-1", expected: "orders$2.final"},
+		{subject: "orders.final", key: "order-42", expected: "orders$3.final"},
+		{subject: "orders.final", key: "", expected: "orders.final"},
+		{subject: "orders$1.final", key: "user-1", expected: "orders$1.final"},
+		{subject: "events.final", key: "user-1", expected: "events.final"},
+		{subject: "$memphis_internal.final", key: "user-1", expected: "$memphis_internal.final"},
+	}
+	for _, c := range cases {
+		if subject := route(c.subject, c.key); subject != c.expected {
+			t.Errorf("subject %v with key %q: expected %v, got %v", c.subject, c.key, c.expected, subject)
+		}
+	}
+
+	// scaling the station up is applied by the next cache update without touching the db
+	applyStationPartitionsCacheUpdate(models.CacheUpdateRequest{TenantName: "acme", Operation: "update", Stations: []string{"orders"}, Partitions: []int{5, 4, 3, 2, 1}})
+	if subject := route("orders.final", "user-2"); subject != "orders$3.final" {
+		t.Errorf("expected the key to be routed by the updated partitions, got %v", subject)
+	}
+	if partitionsList, ok := getStationPartitionsForRouting("acme", StationNameFromStreamName("events")); !ok || len(partitionsList) != 0 {
+		t.Errorf("expected a subject which is not a station to be cached without partitions, got %v", partitionsList)
+	}
+
+	// a station which is not loaded yet has no stream on its root subject so the message is rejected,
+	// the load is marked as running so the test does not query the db
+	missKey := stationPartitionsCacheKey("acme", "payments")
+	stationPartitionsLoading.Add(missKey, true)
+	defer stationPartitionsLoading.Delete(missKey)
+	if subject := route("payments.final", "user-1"); subject != "rejected" {
+		t.Errorf("expected a keyed message of a station which is not loaded to be rejected, got %v", subject)
+	}
+	if subject := route("payments.final", ""); subject != "payments.final" {
+		t.Errorf("expected a message without a key to be left untouched, got %v", subject)
+	}
+}
+
+func TestNotStationsCache(t *testing.T) {
+	defer func() {
+		notStationsCache.Lock()
+		notStationsCache.m = map[string]time.Time{}
+		notStationsCache.Unlock()
+	}()
+
+	now := time.Now()
+	setNotStationCache("acme:expired", now.Add(-notStationsCacheTTL))
+	if isNotStationCached("acme:expired", now) {
+		t.Errorf("expected an entry older than the ttl to be expired")
+	}
+	setNotStationCache("acme:fresh", now)
+	if !isNotStationCached("acme:fresh", now) {
+		t.Errorf("expected a fresh entry to be cached")
+	}
+
+	for i := 0; i < notStationsCacheMaxSize+100; i++ {
+		setNotStationCache("acme:subject-"+strconv.Itoa(i), now)
+	}
+	notStationsCache.Lock()
+	size := len(notStationsCache.m)
+	notStationsCache.Unlock()
+	if size > notStationsCacheMaxSize {
+		t.Errorf("expected the cache to be capped at %v entries, got %v", notStationsCacheMaxSize, size)
+	}
+	if !isNotStationCached("acme:subject-"+strconv.Itoa(notStationsCacheMaxSize+99), now) {
+		t.Errorf("expected the newest entry to be cached")
+	}
+
+	// a station created after its subject was cached replaces the entry
+	setStationPartitionsCache("acme:subject-1", []int{1})
+	defer stationPartitionsCache.Delete("acme:subject-1")
+	if isNotStationCached("acme:subject-1", now) {
+		t.Errorf("expected a station update to remove the entry")
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+// Producers schedule a message by setting one of these headers, the deliver at header is an RFC 3339 time
+// or a unix time in milliseconds and the delay header is a duration (e.g. 30s) or a number of milliseconds
+const (
+	deliverAtHeader = "$memphis_deliver_at"
+	delayHeader     = "$memphis_delay"
+)
+
+// Scheduled messages are held in a stream per station, in the station's account, until they are due.
+// The stream's subject is <stream name>.<partition number> where 0 stands for a station without partitions
+const (
+	scheduledMessagesStreamPrefix      = "$memphis_scheduled_"
+	scheduledMessagesReleaseInterval   = time.Second
+	scheduledMessagesRetryInterval     = 5 * time.Second
+	scheduledMessagesDiscoveryInterval = 30 * time.Second
+	scheduledMessagesReleaseWorkers    = 32
+)
+
+// tenant:station -> true once the station's scheduled messages stream was created by this broker
+var scheduledStreamsCache = NewConcurrentMap[bool]()
+
+// set when a scheduled messages stream is created so the release loop looks for it without waiting for the next discovery
+var scheduledStreamsChanged int32
+
+func scheduledMessagesStreamName(stationName StationName) string {
+	return scheduledMessagesStreamPrefix + stationName.Intern()
+}
+
+// scheduledMessageTarget returns the station subject a scheduled message is released to
+func scheduledMessageTarget(streamName, subject string) string {
+	stationIntern := strings.TrimPrefix(streamName, scheduledMessagesStreamPrefix)
+	partition := strings.TrimPrefix(subject, streamName+".")
+	if partition == "0" {
+		return stationIntern + ".final"
+	}
+	return stationIntern + "$" + partition + ".final"
+}
+
+// parseStationSubject splits a station subject (<station>.final or <station>$<partition>.final)
+func parseStationSubject(subject string) (string, int, bool) {
+	if strings.HasPrefix(subject, "$") || !strings.HasSuffix(subject, ".final") {
+		return _EMPTY_, 0, false
+	}
+	streamName := strings.TrimSuffix(subject, ".final")
+	if strings.Contains(streamName, ".") {
+		return _EMPTY_, 0, false
+	}
+	stationIntern, partition, _ := strings.Cut(streamName, "$")
+	if partition == _EMPTY_ {
+		return stationIntern, 0, true
+	}
+	partitionNumber, err := strconv.Atoi(partition)
+	if err != nil || partitionNumber <= 0 {
+		return _EMPTY_, 0, false
+	}
+	return stationIntern, partitionNumber, true
+}
+
+// scheduledDeliveryTime returns the time a message has to be delivered at according to its scheduling headers,
+// the delay is relative to the time the message was published, the second value reports whether the message is scheduled at all
+func scheduledDeliveryTime(deliverAt, delay []byte, publishedAt time.Time) (time.Time, bool, error) {
+	if len(deliverAt) > 0 {
+		if ms, err := strconv.ParseInt(string(deliverAt), 10, 64); err == nil {
+			return time.UnixMilli(ms), true, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, string(deliverAt))
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("%v has to be an RFC 3339 time or a unix time in milliseconds", deliverAtHeader)
+		}
+		return t, true, nil
+	}
+	if len(delay) > 0 {
+		d, err := time.ParseDuration(string(delay))
+		if err != nil {
+			ms, err := strconv.ParseInt(string(delay), 10, 64)
+			if err != nil {
+				return time.Time{}, true, fmt.Errorf("%v has to be a duration (e.g. 30s) or a number of milliseconds", delayHeader)
+			}
+			d = time.Duration(ms) * time.Millisecond
+		}
+		if d < 0 {
+			return time.Time{}, true, fmt.Errorf("%v can not be negative", delayHeader)
+		}
+		return publishedAt.Add(d), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+func invalidateScheduledStreamsCache(stationIntern string) {
+	keys, _ := scheduledStreamsCache.Array()
+	for _, key := range keys {
+		if strings.HasSuffix(key, ":"+stationIntern) {
+			scheduledStreamsCache.Delete(key)
+		}
+	}
+}
+
+func (s *Server) ListenForScheduledStreamsUpdates() error {
+	_, err := s.subscribeOnAcc(s.MemphisGlobalAccount(), sdkClientsUpdatesSubject, sdkClientsUpdatesSubject+"_scheduled_sid", func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			var update models.SdkClientsUpdates
+			err := json.Unmarshal(msg, &update)
+			if err != nil {
+				s.Errorf("ListenForScheduledStreamsUpdates at Unmarshal: %v", err.Error())
+				return
+			}
+			if update.Type == removeStationUpdateType {
+				invalidateScheduledStreamsCache(update.StationName)
+			}
+		}(copyBytes(msg))
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureScheduledMessagesStream creates the scheduled messages stream of a station with the station's storage and replication
+func (s *Server) ensureScheduledMessagesStream(tenantName string, stationName StationName) (string, error) {
+	streamName := scheduledMessagesStreamName(stationName)
+	cacheKey := tenantName + ":" + stationName.Intern()
+	if _, ok := scheduledStreamsCache.Load(cacheKey); ok {
+		return streamName, nil
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), tenantName)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	if !exist {
+		return _EMPTY_, fmt.Errorf("Station %v does not exist", stationName.Ext())
+	}
+	storage := FileStorage
+	if station.StorageType == "memory" {
+		storage = MemoryStorage
+	}
+	replicas := station.Replicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+	err = s.memphisAddStream(tenantName, &StreamConfig{
+		Name:         streamName,
+		Subjects:     []string{streamName + ".>"},
+		Retention:    LimitsPolicy,
+		MaxConsumers: -1,
+		MaxMsgs:      -1,
+		MaxBytes:     -1,
+		MaxMsgsPer:   -1,
+		Discard:      DiscardOld,
+		Storage:      storage,
+		Replicas:     replicas,
+		NoAck:        false,
+	})
+	if err != nil && !IsNatsErr(err, JSStreamNameExistErr) {
+		return _EMPTY_, err
+	}
+	scheduledStreamsCache.Add(cacheKey, true)
+	atomic.StoreInt32(&scheduledStreamsChanged, 1)
+	return streamName, nil
+}
+
+// scheduleMessage stores a message which was published to a station subject in the station's scheduled messages stream,
+// the stream acknowledges the message to the reply subject the same way the station would
+func (s *Server) scheduleMessage(tenantName, subject, reply string, headers map[string]string, payload []byte) error {
+	stationIntern, partition, ok := parseStationSubject(subject)
+	if !ok {
+		return fmt.Errorf("subject %v is not a station subject", subject)
+	}
+	stationName := StationNameFromStreamName(stationIntern)
+	streamName, err := s.ensureScheduledMessagesStream(tenantName, stationName)
+	if err != nil {
+		return err
+	}
+	partitionsList, err := loadStationPartitions(tenantName, stationName)
+	if err != nil {
+		return err
+	}
+	validPartition := partition == 0
+	if len(partitionsList) > 0 {
+		validPartition = false
+		for _, p := range partitionsList {
+			if p == partition {
+				validPartition = true
+				break
+			}
+		}
+	}
+	if !validPartition {
+		return fmt.Errorf("partition %v does not exist in station %v", partition, stationName.Ext())
+	}
+
+	account, err := s.lookupAccount(tenantName)
+	if err != nil {
+		return err
+	}
+	return s.sendInternalAccountMsgWithReply(account, fmt.Sprintf("%v.%v", streamName, partition), reply, headers, payload, true)
+}
+
+// scheduleDelayedMsg diverts messages published with a future delivery time to the station's scheduled messages stream,
+// messages which are already due are left untouched
+func (c *client) scheduleDelayedMsg(tenantName string, msg []byte) bool {
+	if c.kind != CLIENT || c.pa.hdr <= 0 {
+		return true
+	}
+	subj := string(c.pa.subject)
+	if _, _, ok := parseStationSubject(subj); !ok {
+		return true
+	}
+	hdr, payload := c.msgParts(msg)
+	now := time.Now()
+	deliverAt, scheduled, err := scheduledDeliveryTime(getHeader(deliverAtHeader, hdr), getHeader(delayHeader, hdr), now)
+	if !scheduled {
+		return true
+	}
+	// let the regular flow reject publishes the client is not allowed to make
+	if !c.pubAllowed(subj) {
+		return true
+	}
+
+	reply := string(c.pa.reply)
+	if err == nil && !deliverAt.After(now) {
+		return true
+	}
+	if err == nil {
+		var headers map[string]string
+		headers, err = DecodeHeader(hdr)
+		if err == nil {
+			// the wire message ends with CR_LF which is not part of the payload
+			if len(payload) >= LEN_CR_LF {
+				payload = payload[:len(payload)-LEN_CR_LF]
+			}
+			go func(payload []byte) {
+				err := c.srv.scheduleMessage(tenantName, subj, reply, headers, payload)
+				if err != nil {
+					c.Warnf("[tenant: %v]scheduleDelayedMsg at scheduleMessage: subject %v: %v", tenantName, subj, err.Error())
+					c.srv.respondScheduledMessageError(c.acc, reply, err)
+				}
+			}(copyBytes(payload))
+			return false
+		}
+	}
+
+	// an -ERR would close the connection of the publisher, a publisher without a reply subject is only logged
+	if reply != _EMPTY_ {
+		c.srv.respondScheduledMessageError(c.acc, reply, err)
+	} else {
+		c.Warnf("[tenant: %v]scheduleDelayedMsg: a message to %v was dropped: %v", tenantName, subj, err.Error())
+	}
+	return false
+}
+
+func (s *Server) respondScheduledMessageError(acc *Account, reply string, err error) {
+	if reply == _EMPTY_ {
+		return
+	}
+	resp := JSPubAckResponse{Error: &ApiError{Code: 400, Description: err.Error()}}
+	s.sendInternalAccountMsg(acc, reply, resp)
+}
+
+type scheduledMsgRef struct {
+	seq       uint64
+	deliverAt time.Time
+}
+
+// scheduledMsgsHeap orders the pending messages of a scheduled messages stream by their delivery time
+type scheduledMsgsHeap []scheduledMsgRef
+
+func (h scheduledMsgsHeap) Len() int { return len(h) }
+func (h scheduledMsgsHeap) Less(i, j int) bool {
+	if h[i].deliverAt.Equal(h[j].deliverAt) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].deliverAt.Before(h[j].deliverAt)
+}
+func (h scheduledMsgsHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scheduledMsgsHeap) Push(x interface{}) { *h = append(*h, x.(scheduledMsgRef)) }
+func (h *scheduledMsgsHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ref := old[n-1]
+	*h = old[:n-1]
+	return ref
+}
+
+type scheduledStreamIndex struct {
+	acc     *Account
+	mset    *stream
+	lastSeq uint64
+	pending scheduledMsgsHeap
+	// set while a worker releases the stream's messages, the worker owns the index until it is cleared
+	releasing int32
+}
+
+// hasWork reports whether the stream got new messages or has due messages, it is called by the index owner only
+func (index *scheduledStreamIndex) hasWork(now time.Time) bool {
+	if index.pending.Len() > 0 && !index.pending[0].deliverAt.After(now) {
+		return true
+	}
+	return index.mset.lastSeq() > index.lastSeq
+}
+
+// discoverScheduledStreams returns the scheduled messages streams this broker leads
+func (s *Server) discoverScheduledStreams() map[*stream]*Account {
+	streams := map[*stream]*Account{}
+	s.accounts.Range(func(_, v interface{}) bool {
+		acc := v.(*Account)
+		for _, mset := range acc.streams() {
+			if strings.HasPrefix(mset.name(), scheduledMessagesStreamPrefix) && mset.isLeader() {
+				streams[mset] = acc
+			}
+		}
+		return true
+	})
+	return streams
+}
+
+// ReleaseScheduledMessages releases due messages of the scheduled messages streams this broker leads,
+// the pending messages are indexed in memory and the index is rebuilt from the stream after a restart or a leader change.
+// Every stream is released by its own worker so a slow station does not hold back the others,
+// the streams are rediscovered periodically and whenever this broker creates one
+func (s *Server) ReleaseScheduledMessages() {
+	indexes := map[*stream]*scheduledStreamIndex{}
+	workers := make(chan struct{}, scheduledMessagesReleaseWorkers)
+	ticker := time.NewTicker(scheduledMessagesReleaseInterval)
+	defer ticker.Stop()
+	var lastDiscovery time.Time
+	for range ticker.C {
+		now := time.Now()
+		if atomic.SwapInt32(&scheduledStreamsChanged, 0) == 1 || now.Sub(lastDiscovery) >= scheduledMessagesDiscoveryInterval {
+			lastDiscovery = now
+			streams := s.discoverScheduledStreams()
+			for mset, acc := range streams {
+				if _, ok := indexes[mset]; !ok {
+					indexes[mset] = &scheduledStreamIndex{acc: acc, mset: mset}
+				}
+			}
+			for mset := range indexes {
+				if _, ok := streams[mset]; !ok {
+					delete(indexes, mset)
+				}
+			}
+		}
+
+		for mset, index := range indexes {
+			if !mset.isLeader() {
+				// a worker which is still running keeps its own reference to the index
+				delete(indexes, mset)
+				continue
+			}
+			if !atomic.CompareAndSwapInt32(&index.releasing, 0, 1) {
+				continue
+			}
+			if !index.hasWork(now) {
+				atomic.StoreInt32(&index.releasing, 0)
+				continue
+			}
+			select {
+			case workers <- struct{}{}:
+			default:
+				// all the workers are busy, the stream is picked up on one of the next ticks
+				atomic.StoreInt32(&index.releasing, 0)
+				continue
+			}
+			go func(index *scheduledStreamIndex) {
+				defer func() {
+					<-workers
+					atomic.StoreInt32(&index.releasing, 0)
+				}()
+				s.releaseDueScheduledMessages(index)
+			}(index)
+		}
+	}
+}
+
+func (s *Server) releaseDueScheduledMessages(index *scheduledStreamIndex) {
+	mset := index.mset
+	state := mset.state()
+	startSeq := index.lastSeq + 1
+	if startSeq < state.FirstSeq {
+		startSeq = state.FirstSeq
+	}
+	for seq := startSeq; seq <= state.LastSeq; seq++ {
+		sm, err := mset.getMsg(seq)
+		if err != nil {
+			continue
+		}
+		deliverAt, _, err := scheduledDeliveryTime(getHeader(deliverAtHeader, sm.Header), getHeader(delayHeader, sm.Header), sm.Time)
+		if err != nil {
+			deliverAt = sm.Time
+		}
+		heap.Push(&index.pending, scheduledMsgRef{seq: seq, deliverAt: deliverAt})
+	}
+	if state.LastSeq > index.lastSeq {
+		index.lastSeq = state.LastSeq
+	}
+
+	tenantName := index.acc.GetName()
+	streamName := mset.name()
+	now := time.Now()
+	for index.pending.Len() > 0 && !index.pending[0].deliverAt.After(now) {
+		ref := heap.Pop(&index.pending).(scheduledMsgRef)
+		sm, err := mset.getMsg(ref.seq)
+		if err != nil {
+			// already released or removed
+			continue
+		}
+		err = s.releaseScheduledMessage(tenantName, streamName, sm)
+		if err != nil {
+			s.Warnf("[tenant: %v]releaseDueScheduledMessages at releaseScheduledMessage: stream %v: sequence %v: %v", tenantName, streamName, ref.seq, err.Error())
+			ref.deliverAt = now.Add(scheduledMessagesRetryInterval)
+			heap.Push(&index.pending, ref)
+			break
+		}
+	}
+}
+
+// releaseScheduledMessage publishes a scheduled message to its station and removes it from the scheduled messages stream,
+// the message id makes the station drop the message if it is released again before the removal was applied
+func (s *Server) releaseScheduledMessage(tenantName, streamName string, sm *StoredMsg) error {
+	headers := map[string]string{}
+	if len(sm.Header) > 0 {
+		var err error
+		headers, err = DecodeHeader(sm.Header)
+		if err != nil {
+			return err
+		}
+	}
+	if headers[JSMsgId] == _EMPTY_ {
+		headers[JSMsgId] = fmt.Sprintf("%v:%v", streamName, sm.Sequence)
+	}
+	err := s.memphisPublishWithAck(tenantName, scheduledMessageTarget(streamName, sm.Subject), headers, sm.Data)
+	if err != nil {
+		return err
+	}
+
+	requestSubject := fmt.Sprintf(JSApiMsgDeleteT, streamName)
+	var resp JSApiMsgDeleteResponse
+	req, _ := json.Marshal(JSApiMsgDeleteRequest{Seq: sm.Sequence})
+	err = jsApiRequest(tenantName, s, requestSubject, kindDeleteMessage, req, &resp)
+	if err != nil {
+		return err
+	}
+	return resp.ToError()
+}
+
+func (s *Server) getScheduledMessagesCount(tenantName string, stationName StationName, partitionNumber int) (int, error) {
+	streamName := scheduledMessagesStreamName(stationName)
+	streamInfo, err := s.memphisStreamInfo(tenantName, streamName)
+	if err != nil {
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if partitionNumber == -1 {
+		return int(streamInfo.State.Msgs), nil
+	}
+	return int(streamInfo.State.Subjects[fmt.Sprintf("%v.%v", streamName, partitionNumber)]), nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"container/heap"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScheduledDeliveryTime(t *testing.T) {
+	publishedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		deliverAt string
+		delay     string
+		expected  time.Time
+		scheduled bool
+		valid     bool
+	}{
+		{name: "not scheduled", expected: time.Time{}, scheduled: false, valid: true},
+		{name: "deliver at rfc3339", deliverAt: "2023-01-02T00:00:00Z", expected: publishedAt.Add(24 * time.Hour), scheduled: true, valid: true},
+		{name: "deliver at millis", deliverAt: "1672531260000", expected: publishedAt.Add(time.Minute), scheduled: true, valid: true},
+		{name: "delay duration", delay: "90s", expected: publishedAt.Add(90 * time.Second), scheduled: true, valid: true},
+		{name: "delay millis", delay: "1500", expected: publishedAt.Add(1500 * time.Millisecond), scheduled: true, valid: true},
+		{name: "deliver at wins", deliverAt: "1672531260000", delay: "1h", expected: publishedAt.Add(time.Minute), scheduled: true, valid: true},
+		{name: "invalid deliver at", deliverAt: "tomorrow", scheduled: true, valid: false},
+		{name: "negative delay", delay: "-5s", scheduled: true, valid: false},
+	}
+
+	for _, c := range cases {
+		deliverAt, scheduled, err := scheduledDeliveryTime([]byte(c.deliverAt), []byte(c.delay), publishedAt)
+		if scheduled != c.scheduled || (err == nil) != c.valid {
+			t.Errorf("%v: expected scheduled %v and valid %v, got %v and %v", c.name, c.scheduled, c.valid, scheduled, err)
+			continue
+		}
+		if c.valid && !deliverAt.Equal(c.expected) {
+			t.Errorf("%v: expected %v, got %v", c.name, c.expected, deliverAt)
+		}
+	}
+
+	if target := scheduledMessageTarget("$memphis_scheduled_orders", "$memphis_scheduled_orders.0"); target != "orders.final" {
+		t.Errorf("expected orders.final, got %v", target)
+	}
+	if target := scheduledMessageTarget("$memphis_scheduled_orders", "$memphis_scheduled_orders.3"); target != "orders$3.final" {
+		t.Errorf("expected orders$3.final, got %v", target)
+	}
+}
+
+func TestScheduleDelayedMsg(t *testing.T) {
+	publish := func(subject, reply, header string) (*client, bool) {
+		hdr := "NATS/1.0\r\n"
+		if header != "" {
+			hdr += header + "\r\n"
+		}
+		hdr += "\r\n"
+		c := &client{kind: CLIENT, srv: &Server{}}
+		c.pa.subject = []byte(subject)
+		c.pa.reply = []byte(reply)
+		c.pa.hdr = len(hdr)
+		return c, c.scheduleDelayedMsg("acme", []byte(hdr+"payload\r\n"))
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10)
+	cases := []struct {
+		name     string
+		subject  string
+		reply    string
+		header   string
+		expected bool
+	}{
+		{name: "not scheduled", subject: "orders.final", expected: true},
+		{name: "already due", subject: "orders$2.final", header: deliverAtHeader + ": " + past, expected: true},
+		{name: "zero delay", subject: "orders.final", header: delayHeader + ": 0s", expected: true},
+		{name: "not a station subject", subject: "orders.events", header: delayHeader + ": bad", expected: true},
+		{name: "internal subject", subject: "$memphis_orders.final", header: delayHeader + ": bad", expected: true},
+		{name: "invalid delay with reply", subject: "orders.final", reply: "_INBOX.1", header: delayHeader + ": bad", expected: false},
+		{name: "invalid delay without reply", subject: "orders.final", header: delayHeader + ": -5s", expected: false},
+		{name: "invalid deliver at without reply", subject: "orders$1.final", header: deliverAtHeader + ": tomorrow", expected: false},
+	}
+	for _, tc := range cases {
+		c, ok := publish(tc.subject, tc.reply, tc.header)
+		if ok != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expected, ok)
+		}
+		// the publisher is never sent an -ERR since it would close its connection
+		if c.out.pb != 0 {
+			t.Errorf("%v: expected nothing to be written to the client, got %v bytes", tc.name, c.out.pb)
+		}
+	}
+}
+
+func TestScheduledMsgsHeap(t *testing.T) {
+	now := time.Now()
+	var pending scheduledMsgsHeap
+	for _, ref := range []scheduledMsgRef{
+		{seq: 1, deliverAt: now.Add(time.Hour)},
+		{seq: 2, deliverAt: now.Add(time.Minute)},
+		{seq: 4, deliverAt: now.Add(time.Second)},
+		{seq: 3, deliverAt: now.Add(time.Second)},
+	} {
+		heap.Push(&pending, ref)
+	}
+
+	expected := []uint64{3, 4, 2, 1}
+	for i, seq := range expected {
+		ref := heap.Pop(&pending).(scheduledMsgRef)
+		if ref.seq != seq {
+			t.Fatalf("position %v: expected sequence %v, got %v", i, seq, ref.seq)
+		}
+	}
+}
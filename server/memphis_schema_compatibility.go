@@ -0,0 +1,410 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/hamba/avro/v2"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	schemaCompatibilityNone               = "none"
+	schemaCompatibilityBackward           = "backward"
+	schemaCompatibilityBackwardTransitive = "backward_transitive"
+	schemaCompatibilityForward            = "forward"
+	schemaCompatibilityForwardTransitive  = "forward_transitive"
+	schemaCompatibilityFull               = "full"
+	schemaCompatibilityFullTransitive     = "full_transitive"
+)
+
+// schemaCandidate is a schema content which is checked against the existing versions of a schema
+type schemaCandidate struct {
+	Content           string
+	MessageStructName string
+	References        []resolvedSchemaReference
+}
+
+func validateSchemaCompatibilityMode(mode, schemaType string) error {
+	switch mode {
+	case schemaCompatibilityNone:
+		return nil
+	case schemaCompatibilityBackward, schemaCompatibilityBackwardTransitive, schemaCompatibilityForward, schemaCompatibilityForwardTransitive, schemaCompatibilityFull, schemaCompatibilityFullTransitive:
+		if schemaType == "graphql" || schemaType == "xsd" || schemaType == "thrift" {
+			return errors.New("compatibility checks are supported only for protobuf, avro and json schemas")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported compatibility mode %v, supported modes are: none, backward, backward_transitive, forward, forward_transitive, full, full_transitive", mode)
+	}
+}
+
+// checkSchemaCompatibility checks the new content against the existing versions according to the compatibility mode,
+// non transitive modes check only against the latest version, the returned violations are meant to be shown to the user
+func checkSchemaCompatibility(mode, schemaType string, newVersion schemaCandidate, versions []models.SchemaVersion) ([]string, error) {
+	if mode == _EMPTY_ || mode == schemaCompatibilityNone || len(versions) == 0 {
+		return []string{}, nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].VersionNumber > versions[j].VersionNumber
+	})
+	transitive := strings.HasSuffix(mode, "_transitive")
+	if !transitive {
+		versions = versions[:1]
+	}
+	checkBackward := strings.HasPrefix(mode, schemaCompatibilityBackward) || strings.HasPrefix(mode, schemaCompatibilityFull)
+	checkForward := strings.HasPrefix(mode, schemaCompatibilityForward) || strings.HasPrefix(mode, schemaCompatibilityFull)
+
+	violations := []string{}
+	for _, v := range versions {
+		references, err := resolveSchemaReferences(schemaType, v.TenantName, v.References)
+		if err != nil {
+			return []string{}, err
+		}
+		prevVersion := schemaCandidate{Content: v.SchemaContent, MessageStructName: v.MessageStructName, References: references}
+		if checkBackward {
+			versionViolations, err := checkReaderWriterCompatibility(schemaType, newVersion, prevVersion)
+			if err != nil {
+				return []string{}, err
+			}
+			for _, violation := range versionViolations {
+				violations = append(violations, fmt.Sprintf("backward (version %v): %v", v.VersionNumber, violation))
+			}
+		}
+		if checkForward {
+			versionViolations, err := checkReaderWriterCompatibility(schemaType, prevVersion, newVersion)
+			if err != nil {
+				return []string{}, err
+			}
+			for _, violation := range versionViolations {
+				violations = append(violations, fmt.Sprintf("forward (version %v): %v", v.VersionNumber, violation))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// checkReaderWriterCompatibility returns the reasons why data written with the writer schema can not be read with the reader schema
+func checkReaderWriterCompatibility(schemaType string, reader, writer schemaCandidate) ([]string, error) {
+	switch schemaType {
+	case "avro":
+		return checkAvroCompatibility(reader, writer)
+	case "json":
+		return checkJsonSchemaCompatibility(reader.Content, writer.Content)
+	case "protobuf":
+		return checkProtobufCompatibility(reader, writer)
+	default:
+		return []string{}, nil
+	}
+}
+
+func checkAvroCompatibility(readerVersion, writerVersion schemaCandidate) ([]string, error) {
+	// every version is parsed with its own cache since different versions usually share the same record names
+	reader, err := parseAvroWithReferences(readerVersion.Content, readerVersion.References)
+	if err != nil {
+		return []string{}, fmt.Errorf("your Avro file is invalid: %v", err.Error())
+	}
+	writer, err := parseAvroWithReferences(writerVersion.Content, writerVersion.References)
+	if err != nil {
+		return []string{}, fmt.Errorf("your Avro file is invalid: %v", err.Error())
+	}
+
+	err = avro.NewSchemaCompatibility().Compatible(reader, writer)
+	if err != nil {
+		return []string{strings.TrimPrefix(err.Error(), "avro: ")}, nil
+	}
+	return []string{}, nil
+}
+
+func checkJsonSchemaCompatibility(readerContent, writerContent string) ([]string, error) {
+	var reader, writer map[string]interface{}
+	err := json.Unmarshal([]byte(readerContent), &reader)
+	if err != nil {
+		return []string{}, errors.New("your json schema is invalid")
+	}
+	err = json.Unmarshal([]byte(writerContent), &writer)
+	if err != nil {
+		return []string{}, errors.New("your json schema is invalid")
+	}
+
+	violations := []string{}
+	compareJsonSchemas(reader, writer, "#", &violations)
+	return violations, nil
+}
+
+func jsonSchemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := []string{}
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	default:
+		return []string{}
+	}
+}
+
+func jsonSchemaTypeAccepted(readerTypes []string, writerType string) bool {
+	for _, t := range readerTypes {
+		if t == writerType || (t == "number" && writerType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonSchemaStringSet(schema map[string]interface{}, key string) map[string]bool {
+	set := map[string]bool{}
+	values, ok := schema[key].([]interface{})
+	if !ok {
+		return set
+	}
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+// compareJsonSchemas collects the reasons why a document valid against the writer schema could be rejected by the reader schema
+func compareJsonSchemas(reader, writer map[string]interface{}, path string, violations *[]string) {
+	readerTypes := jsonSchemaTypes(reader)
+	writerTypes := jsonSchemaTypes(writer)
+	if len(readerTypes) > 0 {
+		if len(writerTypes) == 0 {
+			*violations = append(*violations, fmt.Sprintf("%v: type was restricted to %v", path, strings.Join(readerTypes, ", ")))
+		}
+		for _, t := range writerTypes {
+			if !jsonSchemaTypeAccepted(readerTypes, t) {
+				*violations = append(*violations, fmt.Sprintf("%v: type %v is no longer accepted", path, t))
+			}
+		}
+	}
+
+	if readerEnum, ok := reader["enum"].([]interface{}); ok {
+		writerEnum, ok := writer["enum"].([]interface{})
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%v: values were restricted to an enum", path))
+		} else {
+			accepted := map[string]bool{}
+			for _, v := range readerEnum {
+				accepted[fmt.Sprintf("%v", v)] = true
+			}
+			for _, v := range writerEnum {
+				if !accepted[fmt.Sprintf("%v", v)] {
+					*violations = append(*violations, fmt.Sprintf("%v: enum value %v was removed", path, v))
+				}
+			}
+		}
+	}
+
+	readerRequired := jsonSchemaStringSet(reader, "required")
+	writerRequired := jsonSchemaStringSet(writer, "required")
+	requiredNames := []string{}
+	for name := range readerRequired {
+		if !writerRequired[name] {
+			requiredNames = append(requiredNames, name)
+		}
+	}
+	sort.Strings(requiredNames)
+	for _, name := range requiredNames {
+		*violations = append(*violations, fmt.Sprintf("%v: property %v is now required", path, name))
+	}
+
+	readerProps, _ := reader["properties"].(map[string]interface{})
+	writerProps, _ := writer["properties"].(map[string]interface{})
+	propNames := make([]string, 0, len(writerProps))
+	for name := range writerProps {
+		propNames = append(propNames, name)
+	}
+	sort.Strings(propNames)
+	closed := false
+	if additional, ok := reader["additionalProperties"].(bool); ok && !additional {
+		closed = true
+	}
+	// properties the reader does not declare are validated against its additionalProperties schema when it has one
+	readerAdditional, _ := reader["additionalProperties"].(map[string]interface{})
+	for _, name := range propNames {
+		writerPropSchema, wok := writerProps[name].(map[string]interface{})
+		readerProp, exist := readerProps[name]
+		if !exist {
+			if closed {
+				*violations = append(*violations, fmt.Sprintf("%v: property %v was removed while additional properties are not allowed", path, name))
+			} else if readerAdditional != nil && wok {
+				compareJsonSchemas(readerAdditional, writerPropSchema, path+"/properties/"+name, violations)
+			}
+			continue
+		}
+		readerPropSchema, rok := readerProp.(map[string]interface{})
+		if rok && wok {
+			compareJsonSchemas(readerPropSchema, writerPropSchema, path+"/properties/"+name, violations)
+		}
+	}
+	writerAdditional, writerAdditionalIsBool := writer["additionalProperties"].(bool)
+	writerAdditionalSchema, _ := writer["additionalProperties"].(map[string]interface{})
+	writerClosed := writerAdditionalIsBool && !writerAdditional
+	if closed && !writerClosed {
+		*violations = append(*violations, fmt.Sprintf("%v: additional properties are no longer allowed", path))
+	}
+	if len(readerAdditional) > 0 && !writerClosed {
+		if writerAdditionalSchema != nil {
+			compareJsonSchemas(readerAdditional, writerAdditionalSchema, path+"/additionalProperties", violations)
+		} else {
+			*violations = append(*violations, fmt.Sprintf("%v: additional properties were restricted to a schema", path))
+		}
+	}
+
+	readerItems, rok := reader["items"].(map[string]interface{})
+	writerItems, wok := writer["items"].(map[string]interface{})
+	if rok && wok {
+		compareJsonSchemas(readerItems, writerItems, path+"/items", violations)
+	}
+}
+
+func parseProtobufFile(content string, references []resolvedSchemaReference) (*desc.FileDescriptor, error) {
+	parser := protoparse.Parser{Accessor: protobufReferencesAccessor(content, references)}
+	files, err := parser.ParseFiles(_EMPTY_)
+	if err != nil {
+		return nil, fmt.Errorf("your Proto file is invalid: %v", err.Error())
+	}
+	return files[0], nil
+}
+
+func findProtobufMessage(messages []*desc.MessageDescriptor, name string) *desc.MessageDescriptor {
+	for _, m := range messages {
+		if m.GetName() == name || m.GetFullyQualifiedName() == name {
+			return m
+		}
+		if nested := findProtobufMessage(m.GetNestedMessageTypes(), name); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
+
+func checkProtobufCompatibility(reader, writer schemaCandidate) ([]string, error) {
+	readerFile, err := parseProtobufFile(reader.Content, reader.References)
+	if err != nil {
+		return []string{}, err
+	}
+	writerFile, err := parseProtobufFile(writer.Content, writer.References)
+	if err != nil {
+		return []string{}, err
+	}
+
+	writerMsg := findProtobufMessage(writerFile.GetMessageTypes(), writer.MessageStructName)
+	if writerMsg == nil {
+		return []string{}, fmt.Errorf("message %v does not exist in the proto file", writer.MessageStructName)
+	}
+	readerMsg := findProtobufMessage(readerFile.GetMessageTypes(), reader.MessageStructName)
+	if readerMsg == nil {
+		return []string{}, fmt.Errorf("message %v does not exist in the proto file", reader.MessageStructName)
+	}
+
+	violations := []string{}
+	compareProtobufMessages(readerMsg, writerMsg, readerMsg.GetName(), map[string]bool{}, &violations)
+	return violations, nil
+}
+
+// protobufWireGroup returns a group id for field types which share the same wire encoding and can be read one as the other
+func protobufWireGroup(t descriptorpb.FieldDescriptorProto_Type) string {
+	switch t {
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_BOOL, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return "varint"
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return "zigzag"
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return "fixed32"
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return "fixed64"
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		return "bytes"
+	default:
+		return t.String()
+	}
+}
+
+func protobufTypeName(field *desc.FieldDescriptor) string {
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+// compareProtobufMessages collects the reasons why a message encoded with the writer descriptor can not be decoded with the reader descriptor
+func compareProtobufMessages(reader, writer *desc.MessageDescriptor, path string, visited map[string]bool, violations *[]string) {
+	key := reader.GetFullyQualifiedName() + "|" + writer.GetFullyQualifiedName()
+	if visited[key] {
+		return
+	}
+	visited[key] = true
+
+	for _, writerField := range writer.GetFields() {
+		readerField := reader.FindFieldByNumber(writerField.GetNumber())
+		if readerField == nil {
+			continue
+		}
+		fieldPath := fmt.Sprintf("%v.%v", path, readerField.GetName())
+		if readerField.IsRepeated() != writerField.IsRepeated() {
+			*violations = append(*violations, fmt.Sprintf("%v: field number %v changed between repeated and singular", fieldPath, writerField.GetNumber()))
+			continue
+		}
+		if protobufWireGroup(readerField.GetType()) != protobufWireGroup(writerField.GetType()) {
+			*violations = append(*violations, fmt.Sprintf("%v: field number %v changed type from %v to %v", fieldPath, writerField.GetNumber(), protobufTypeName(writerField), protobufTypeName(readerField)))
+			continue
+		}
+		if readerField.GetMessageType() != nil && writerField.GetMessageType() != nil {
+			compareProtobufMessages(readerField.GetMessageType(), writerField.GetMessageType(), fieldPath, visited, violations)
+		}
+	}
+
+	for _, readerField := range reader.GetFields() {
+		if readerField.IsRequired() && writer.FindFieldByNumber(readerField.GetNumber()) == nil {
+			*violations = append(*violations, fmt.Sprintf("%v.%v: required field number %v was added", path, readerField.GetName(), readerField.GetNumber()))
+		}
+	}
+}
+
+// validateNewSchemaVersionCompatibility checks a new version against the stored versions of the schema using its compatibility mode
+func validateNewSchemaVersionCompatibility(schema models.Schema, newVersion schemaCandidate) ([]string, error) {
+	if schema.CompatibilityMode == _EMPTY_ || schema.CompatibilityMode == schemaCompatibilityNone {
+		return []string{}, nil
+	}
+	versions, err := getSchemaVersionsBySchemaId(schema.ID)
+	if err != nil {
+		return []string{}, err
+	}
+	err = loadSchemaVersionsReferences(versions)
+	if err != nil {
+		return []string{}, err
+	}
+	return checkSchemaCompatibility(schema.CompatibilityMode, schema.Type, newVersion, versions)
+}
+
+func schemaCompatibilityErrorMessage(mode string, violations []string) string {
+	return fmt.Sprintf("The new version breaks the %v compatibility of the schema: %v", mode, strings.Join(violations, "; "))
+}
@@ -0,0 +1,180 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	jsonV1 := `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"required":["id"]}`
+	avroV1 := `{"type":"record","name":"user","fields":[{"name":"id","type":"int"}]}`
+	versions := func(schemaType string) []models.SchemaVersion {
+		if schemaType == "json" {
+			return []models.SchemaVersion{{VersionNumber: 1, SchemaContent: jsonV1}}
+		}
+		return []models.SchemaVersion{{VersionNumber: 1, SchemaContent: avroV1}}
+	}
+
+	cases := []struct {
+		name       string
+		mode       string
+		schemaType string
+		content    string
+		compatible bool
+	}{
+		{name: "json new optional property", mode: schemaCompatibilityBackward, schemaType: "json", content: `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"},"age":{"type":"integer"}},"required":["id"]}`, compatible: true},
+		{name: "json new required property", mode: schemaCompatibilityBackward, schemaType: "json", content: `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"required":["id","name"]}`, compatible: false},
+		{name: "json widened type", mode: schemaCompatibilityBackward, schemaType: "json", content: `{"type":"object","properties":{"id":{"type":"number"},"name":{"type":"string"}},"required":["id"]}`, compatible: true},
+		{name: "json widened type forward", mode: schemaCompatibilityForward, schemaType: "json", content: `{"type":"object","properties":{"id":{"type":"number"},"name":{"type":"string"}},"required":["id"]}`, compatible: false},
+		{name: "json changed type with none", mode: schemaCompatibilityNone, schemaType: "json", content: `{"type":"object","properties":{"id":{"type":"string"}}}`, compatible: true},
+		{name: "avro field without default", mode: schemaCompatibilityBackward, schemaType: "avro", content: `{"type":"record","name":"user","fields":[{"name":"id","type":"int"},{"name":"email","type":"string"}]}`, compatible: false},
+		{name: "avro field without default forward", mode: schemaCompatibilityForward, schemaType: "avro", content: `{"type":"record","name":"user","fields":[{"name":"id","type":"int"},{"name":"email","type":"string"}]}`, compatible: true},
+		{name: "avro field with default full", mode: schemaCompatibilityFull, schemaType: "avro", content: `{"type":"record","name":"user","fields":[{"name":"id","type":"int"},{"name":"email","type":"string","default":""}]}`, compatible: true},
+	}
+
+	for _, c := range cases {
+		violations, err := checkSchemaCompatibility(c.mode, c.schemaType, schemaCandidate{Content: c.content}, versions(c.schemaType))
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		if (len(violations) == 0) != c.compatible {
+			t.Errorf("%v: expected compatible to be %v, got violations %v", c.name, c.compatible, violations)
+		}
+	}
+}
+
+func TestCheckJsonSchemaCompatibility(t *testing.T) {
+	cases := []struct {
+		name       string
+		reader     string
+		writer     string
+		violations []string
+	}{
+		{
+			name:       "nested required property added",
+			reader:     `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"},"zip":{"type":"string"}},"required":["city","zip"]}}}`,
+			writer:     `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"},"zip":{"type":"string"}},"required":["city"]}}}`,
+			violations: []string{"#/properties/address: property zip is now required"},
+		},
+		{
+			name:       "nested required property removed",
+			reader:     `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"}}}}}`,
+			writer:     `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}}`,
+			violations: []string{},
+		},
+		{
+			name:       "required property added in array items",
+			reader:     `{"type":"array","items":{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}}`,
+			writer:     `{"type":"array","items":{"type":"object","properties":{"id":{"type":"integer"}}}}`,
+			violations: []string{"#/items: property id is now required"},
+		},
+		{
+			name:       "enum narrowed",
+			reader:     `{"type":"object","properties":{"color":{"type":"string","enum":["red","green"]}}}`,
+			writer:     `{"type":"object","properties":{"color":{"type":"string","enum":["red","green","blue"]}}}`,
+			violations: []string{"#/properties/color: enum value blue was removed"},
+		},
+		{
+			name:       "enum widened",
+			reader:     `{"type":"string","enum":["red","green","blue"]}`,
+			writer:     `{"type":"string","enum":["red","green"]}`,
+			violations: []string{},
+		},
+		{
+			name:       "enum introduced",
+			reader:     `{"type":"string","enum":["red"]}`,
+			writer:     `{"type":"string"}`,
+			violations: []string{"#: values were restricted to an enum"},
+		},
+		{
+			name:       "type union widened",
+			reader:     `{"type":["string","null"]}`,
+			writer:     `{"type":"string"}`,
+			violations: []string{},
+		},
+		{
+			name:       "type union narrowed",
+			reader:     `{"type":"string"}`,
+			writer:     `{"type":["string","null"]}`,
+			violations: []string{"#: type null is no longer accepted"},
+		},
+		{
+			name:       "type union integer accepted as number",
+			reader:     `{"type":["number","string"]}`,
+			writer:     `{"type":["integer","string"]}`,
+			violations: []string{},
+		},
+		{
+			name:       "type introduced",
+			reader:     `{"type":["string","number"]}`,
+			writer:     `{}`,
+			violations: []string{"#: type was restricted to string, number"},
+		},
+		{
+			name:       "additional properties closed",
+			reader:     `{"type":"object","properties":{"id":{"type":"integer"}},"additionalProperties":false}`,
+			writer:     `{"type":"object","properties":{"id":{"type":"integer"}}}`,
+			violations: []string{"#: additional properties are no longer allowed"},
+		},
+		{
+			name:       "additional properties opened",
+			reader:     `{"type":"object","properties":{"id":{"type":"integer"}}}`,
+			writer:     `{"type":"object","properties":{"id":{"type":"integer"}},"additionalProperties":false}`,
+			violations: []string{},
+		},
+		{
+			name:       "property removed from closed object",
+			reader:     `{"type":"object","properties":{"id":{"type":"integer"}},"additionalProperties":false}`,
+			writer:     `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"additionalProperties":false}`,
+			violations: []string{"#: property name was removed while additional properties are not allowed"},
+		},
+		{
+			name:       "additional properties restricted to a schema",
+			reader:     `{"type":"object","additionalProperties":{"type":"string"}}`,
+			writer:     `{"type":"object"}`,
+			violations: []string{"#: additional properties were restricted to a schema"},
+		},
+		{
+			name:       "additional properties schema narrowed",
+			reader:     `{"type":"object","additionalProperties":{"type":"string"}}`,
+			writer:     `{"type":"object","additionalProperties":{"type":["string","integer"]}}`,
+			violations: []string{"#/additionalProperties: type integer is no longer accepted"},
+		},
+		{
+			name:       "removed property checked against additional properties schema",
+			reader:     `{"type":"object","additionalProperties":{"type":"string"}}`,
+			writer:     `{"type":"object","properties":{"age":{"type":"integer"}},"additionalProperties":false}`,
+			violations: []string{"#/properties/age: type integer is no longer accepted"},
+		},
+		{
+			name:       "empty additional properties schema",
+			reader:     `{"type":"object","additionalProperties":{}}`,
+			writer:     `{"type":"object"}`,
+			violations: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		violations, err := checkJsonSchemaCompatibility(c.reader, c.writer)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(violations, c.violations) {
+			t.Errorf("%v: expected violations %v, got %v", c.name, c.violations, violations)
+		}
+	}
+}
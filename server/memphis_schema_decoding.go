@@ -0,0 +1,276 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	messagePreviewLength = 80
+	schemaVersionHeader  = "$memphis_schema_version"
+)
+
+// schemaPayloadDecoder renders payloads of a station using the schema version which validated them,
+// messages which do not record their schema version are rendered using the active version
+type schemaPayloadDecoder struct {
+	schema        models.Schema
+	activeVersion int
+	loadVersion   func(versionNumber int) (bool, models.SchemaVersion, error)
+	lock          sync.Mutex
+	versions      map[int]*schemaVersionDecoder
+}
+
+type schemaVersionDecoder struct {
+	versionNumber int
+	format        string
+	decode        func(data []byte) ([]byte, error)
+}
+
+// payloadRendering is the rendering of a payload and the schema version which decoded it
+type payloadRendering struct {
+	data          string
+	format        string
+	versionNumber int
+}
+
+// messageSchemaVersion returns the schema version recorded in the message headers, 0 when it is not recorded
+func messageSchemaVersion(headers map[string]string) int {
+	versionNumber, err := strconv.Atoi(headers[schemaVersionHeader])
+	if err != nil || versionNumber < 1 {
+		return 0
+	}
+	return versionNumber
+}
+
+// loadStationSchemaDecoder returns nil when the station has no schema or its schema type has no rendering (graphql)
+func loadStationSchemaDecoder(station models.Station) (*schemaPayloadDecoder, error) {
+	if station.SchemaName == _EMPTY_ {
+		return nil, nil
+	}
+	exist, schema, err := db.GetSchemaByName(station.SchemaName, station.TenantName)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
+	if err != nil {
+		return nil, err
+	}
+	versionDecoder, err := newSchemaVersionDecoder(schema, schemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if versionDecoder == nil {
+		return nil, nil
+	}
+	decoder := &schemaPayloadDecoder{
+		schema:        schema,
+		activeVersion: schemaVersion.VersionNumber,
+		loadVersion: func(versionNumber int) (bool, models.SchemaVersion, error) {
+			return db.GetSchemaVersionByNumberAndID(versionNumber, schema.ID)
+		},
+		versions: map[int]*schemaVersionDecoder{schemaVersion.VersionNumber: versionDecoder},
+	}
+	return decoder, nil
+}
+
+// newSchemaVersionDecoder returns nil when the schema type has no rendering (graphql)
+func newSchemaVersionDecoder(schema models.Schema, schemaVersion models.SchemaVersion) (*schemaVersionDecoder, error) {
+	decoder := &schemaVersionDecoder{versionNumber: schemaVersion.VersionNumber, format: "json"}
+	switch schema.Type {
+	case "protobuf":
+		msgDescriptor, err := loadProtobufMessageDescriptor(schemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		decoder.decode = func(data []byte) ([]byte, error) {
+			msg := dynamicpb.NewMessage(msgDescriptor)
+			err := proto.Unmarshal(data, msg)
+			if err != nil {
+				return nil, err
+			}
+			return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+		}
+	case "avro":
+		references, err := db.GetSchemaReferencesByVersionId(schemaVersion.ID)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := resolveSchemaReferences(schema.Type, schema.TenantName, references)
+		if err != nil {
+			return nil, err
+		}
+		avroSchema, err := parseAvroWithReferences(schemaVersion.SchemaContent, resolved)
+		if err != nil {
+			return nil, err
+		}
+		// the SDKs produce avro messages as json, messages which are not json are decoded as avro binary
+		decoder.decode = func(data []byte) ([]byte, error) {
+			var value interface{}
+			if json.Unmarshal(data, &value) == nil {
+				_, err := avro.Marshal(avroSchema, value)
+				if err != nil {
+					return nil, err
+				}
+				return data, nil
+			}
+			err := avro.Unmarshal(avroSchema, data, &value)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(value)
+		}
+	case "json", "xsd":
+		// json and xml payloads are readable as they are, they are only labeled with the schema version once they validate against it
+		validator, err := compileSchemaValidator(schema.Type, schemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		if schema.Type == "xsd" {
+			decoder.format = "xml"
+		}
+		decoder.decode = func(data []byte) ([]byte, error) {
+			err := validator(data)
+			if err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+	case "thrift":
+		thriftSchema, err := parseThriftSchema(schemaVersion.SchemaContent)
+		if err != nil {
+			return nil, err
+		}
+		st, err := thriftSchema.messageStruct(schemaVersion.MessageStructName)
+		if err != nil {
+			return nil, err
+		}
+		decoder.decode = func(data []byte) ([]byte, error) {
+			if json.Valid(data) {
+				return data, thriftSchema.validateThriftMessage(st, data)
+			}
+			value, err := thriftSchema.decodeThriftMessage(st, data)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(value)
+		}
+	default:
+		return nil, nil
+	}
+	return decoder, nil
+}
+
+// versionDecoder returns the decoder of a schema version, versions which fail to load are not retried by the same decoder
+func (d *schemaPayloadDecoder) versionDecoder(versionNumber int) *schemaVersionDecoder {
+	if versionNumber == 0 {
+		versionNumber = d.activeVersion
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if versionDecoder, ok := d.versions[versionNumber]; ok {
+		return versionDecoder
+	}
+	d.versions[versionNumber] = nil
+	exist, schemaVersion, err := d.loadVersion(versionNumber)
+	if err != nil || !exist {
+		return nil
+	}
+	versionDecoder, err := newSchemaVersionDecoder(d.schema, schemaVersion)
+	if err != nil {
+		return nil
+	}
+	d.versions[versionNumber] = versionDecoder
+	return versionDecoder
+}
+
+// render returns the rendering of the payload by the schema version recorded in the message (0 for the active version),
+// it returns false when the payload could not be decoded by that version
+func (d *schemaPayloadDecoder) render(data []byte, versionNumber int, indent bool) (payloadRendering, bool) {
+	if d == nil {
+		return payloadRendering{}, false
+	}
+	versionDecoder := d.versionDecoder(versionNumber)
+	if versionDecoder == nil {
+		return payloadRendering{}, false
+	}
+	rendered, err := versionDecoder.decode(data)
+	if err != nil {
+		return payloadRendering{}, false
+	}
+	rendering := payloadRendering{data: string(rendered), format: versionDecoder.format, versionNumber: versionDecoder.versionNumber}
+	if versionDecoder.format == "json" {
+		var out bytes.Buffer
+		if indent {
+			err = json.Indent(&out, rendered, _EMPTY_, "  ")
+		} else {
+			err = json.Compact(&out, rendered)
+		}
+		if err == nil {
+			rendering.data = out.String()
+		}
+	}
+	return rendering, true
+}
+
+// renderMessagePayload fills the rendering of the message payload and the schema version which decoded it
+func (d *schemaPayloadDecoder) renderMessagePayload(payload *models.MessagePayload, data []byte, versionNumber int) {
+	rendering, ok := d.render(data, versionNumber, true)
+	if !ok {
+		return
+	}
+	payload.RenderedData = rendering.data
+	payload.RenderFormat = rendering.format
+	payload.SchemaName = d.schema.Name
+	payload.SchemaVersionNumber = rendering.versionNumber
+}
+
+// renderMessagePreview fills the rendering preview of a message in the messages list
+func (d *schemaPayloadDecoder) renderMessagePreview(message *models.MessageDetails, data []byte, versionNumber int) {
+	rendering, ok := d.render(data, versionNumber, false)
+	if !ok {
+		return
+	}
+	rendered := rendering.data
+	if runes := []rune(rendered); len(runes) > messagePreviewLength {
+		rendered = string(runes[:messagePreviewLength])
+	}
+	message.RenderedData = rendered
+	message.RenderFormat = rendering.format
+	message.SchemaName = d.schema.Name
+	message.SchemaVersionNumber = rendering.versionNumber
+}
+
+// decodeDlsMessageData returns the payload bytes of a dls message, which are stored hex encoded
+func decodeDlsMessageData(data string) []byte {
+	decoded, err := hex.DecodeString(data)
+	if err != nil {
+		return []byte(data)
+	}
+	return decoded
+}
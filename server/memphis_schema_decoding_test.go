@@ -0,0 +1,140 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestMessageSchemaVersion(t *testing.T) {
+	cases := []struct {
+		headers  map[string]string
+		expected int
+	}{
+		{headers: nil, expected: 0},
+		{headers: map[string]string{"$memphis_producedBy": "UI"}, expected: 0},
+		{headers: map[string]string{schemaVersionHeader: "3"}, expected: 3},
+		{headers: map[string]string{schemaVersionHeader: "0"}, expected: 0},
+		{headers: map[string]string{schemaVersionHeader: "latest"}, expected: 0},
+	}
+	for _, c := range cases {
+		if versionNumber := messageSchemaVersion(c.headers); versionNumber != c.expected {
+			t.Errorf("headers %v: expected version %v, got %v", c.headers, c.expected, versionNumber)
+		}
+	}
+}
+
+func newTestSchemaPayloadDecoder(t *testing.T, schema models.Schema, versions []models.SchemaVersion, activeVersion int) *schemaPayloadDecoder {
+	t.Helper()
+	loads := map[int]int{}
+	decoder := &schemaPayloadDecoder{
+		schema:        schema,
+		activeVersion: activeVersion,
+		loadVersion: func(versionNumber int) (bool, models.SchemaVersion, error) {
+			loads[versionNumber]++
+			if loads[versionNumber] > 1 {
+				t.Fatalf("version %v was loaded more than once", versionNumber)
+			}
+			for _, v := range versions {
+				if v.VersionNumber == versionNumber {
+					return true, v, nil
+				}
+			}
+			if versionNumber == 99 {
+				return false, models.SchemaVersion{}, errors.New("db is down")
+			}
+			return false, models.SchemaVersion{}, nil
+		},
+		versions: map[int]*schemaVersionDecoder{},
+	}
+	return decoder
+}
+
+func TestSchemaPayloadDecoderVersions(t *testing.T) {
+	schema := models.Schema{Name: "orders", Type: "json"}
+	versions := []models.SchemaVersion{
+		{VersionNumber: 1, SchemaContent: `{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}`},
+		{VersionNumber: 2, SchemaContent: `{"type":"object","properties":{"id":{"type":"integer"},"email":{"type":"string"}},"required":["id","email"]}`},
+	}
+	decoder := newTestSchemaPayloadDecoder(t, schema, versions, 2)
+
+	cases := []struct {
+		name          string
+		msg           string
+		versionNumber int
+		rendered      bool
+		expected      int
+	}{
+		{name: "recorded older version", msg: `{"id":1}`, versionNumber: 1, rendered: true, expected: 1},
+		{name: "active version", msg: `{"id":1,"email":"a@b.c"}`, rendered: true, expected: 2},
+		{name: "old message without a recorded version", msg: `{"id":1}`, rendered: false},
+		{name: "valid json which does not match the recorded version", msg: `{"id":"one"}`, versionNumber: 1, rendered: false},
+		{name: "not json", msg: `id=1`, versionNumber: 1, rendered: false},
+		{name: "missing version", msg: `{"id":1}`, versionNumber: 7, rendered: false},
+		{name: "missing version is not reloaded", msg: `{"id":1}`, versionNumber: 7, rendered: false},
+		{name: "version which fails to load", msg: `{"id":1}`, versionNumber: 99, rendered: false},
+	}
+	for _, c := range cases {
+		payload := models.MessagePayload{}
+		decoder.renderMessagePayload(&payload, []byte(c.msg), c.versionNumber)
+		if c.rendered != (payload.RenderedData != _EMPTY_) {
+			t.Errorf("%v: expected rendered %v, got %+v", c.name, c.rendered, payload)
+			continue
+		}
+		if c.rendered && (payload.SchemaVersionNumber != c.expected || payload.SchemaName != "orders" || payload.RenderFormat != "json") {
+			t.Errorf("%v: expected version %v of orders, got %+v", c.name, c.expected, payload)
+		}
+	}
+
+	message := models.MessageDetails{}
+	decoder.renderMessagePreview(&message, []byte(`{"id": 1}`), 1)
+	if message.RenderedData != `{"id":1}` || message.SchemaVersionNumber != 1 {
+		t.Errorf("expected a compact preview decoded by version 1, got %+v", message)
+	}
+
+	var nilDecoder *schemaPayloadDecoder
+	payload := models.MessagePayload{}
+	nilDecoder.renderMessagePayload(&payload, []byte(`{"id":1}`), 1)
+	if payload.RenderedData != _EMPTY_ || payload.SchemaName != _EMPTY_ {
+		t.Errorf("expected no rendering without a schema, got %+v", payload)
+	}
+}
+
+func TestSchemaPayloadDecoderXsd(t *testing.T) {
+	schema := models.Schema{Name: "invoices", Type: "xsd"}
+	versions := []models.SchemaVersion{{VersionNumber: 1, SchemaContent: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="invoice">
+			<xs:complexType>
+				<xs:sequence>
+					<xs:element name="total" type="xs:decimal"/>
+				</xs:sequence>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`}}
+	decoder := newTestSchemaPayloadDecoder(t, schema, versions, 1)
+
+	payload := models.MessagePayload{}
+	decoder.renderMessagePayload(&payload, []byte(`<invoice><total>9.5</total></invoice>`), 0)
+	if payload.RenderFormat != "xml" || payload.SchemaVersionNumber != 1 {
+		t.Errorf("expected the invoice to be rendered by version 1, got %+v", payload)
+	}
+
+	payload = models.MessagePayload{}
+	decoder.renderMessagePayload(&payload, []byte(`<receipt><total>9.5</total></receipt>`), 0)
+	if payload.RenderedData != _EMPTY_ {
+		t.Errorf("expected well formed xml which does not match the schema not to be labeled, got %+v", payload)
+	}
+}
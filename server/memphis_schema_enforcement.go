@@ -0,0 +1,543 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/hamba/avro/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	stationSchemaCacheType         = "station_schema"
+	stationSchemaLoadRetryInterval = 5 * time.Second
+)
+
+type schemaValidator func(msg []byte) error
+
+// validator is nil for stations which are not in strict schema mode (or do not exist),
+// in that case messages are stored without validation, messages of stations whose schema is loading are rejected
+type stationSchemaEnforcement struct {
+	stationId int
+	station   models.Station
+	validator schemaValidator
+	loading   bool
+}
+
+type stationSchemaCounters struct {
+	tenantName string
+	valid      int64
+	invalid    int64
+}
+
+// tenant:station -> schema enforcement of the stations in strict schema mode, stations which are not cached are not validated.
+// The cache is warmed on startup and refreshed by the station schema cache updates so the publish path never queries the db
+var stationSchemaEnforcementCache = NewConcurrentMap[stationSchemaEnforcement]()
+
+// serializes the refreshes of the cache so an older load never overrides a newer one
+var stationSchemaRefreshLock sync.Mutex
+
+// set once the schema enforcement of all the stations has been loaded, until then it is not known which stations are
+// in strict schema mode so the messages of all the stations are rejected
+var stationSchemaCacheWarm atomic.Bool
+
+// station id -> validation results which were not flushed to the db yet
+var stationSchemaCountersMap = NewConcurrentMap[*stationSchemaCounters]()
+
+func compileSchemaValidator(schemaType string, schemaVersion models.SchemaVersion) (schemaValidator, error) {
+	switch schemaType {
+	case "protobuf":
+		return compileProtobufValidator(schemaVersion)
+	case "json":
+		schema, err := jsonschema.CompileString(fmt.Sprintf("%v_%v", schemaVersion.SchemaId, schemaVersion.VersionNumber), schemaVersion.SchemaContent)
+		if err != nil {
+			return nil, err
+		}
+		return func(msg []byte) error {
+			var value interface{}
+			err := json.Unmarshal(msg, &value)
+			if err != nil {
+				return fmt.Errorf("invalid json: %v", err.Error())
+			}
+			return schema.Validate(value)
+		}, nil
+	case "graphql":
+		schema, err := graphql.ParseSchema(schemaVersion.SchemaContent, nil)
+		if err != nil {
+			return nil, err
+		}
+		return func(msg []byte) error {
+			errs := schema.Validate(string(msg))
+			if len(errs) > 0 {
+				return errs[0]
+			}
+			return nil
+		}, nil
+	case "avro":
+		references, err := db.GetSchemaReferencesByVersionId(schemaVersion.ID)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := resolveSchemaReferences(schemaType, schemaVersion.TenantName, references)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseAvroWithReferences(schemaVersion.SchemaContent, resolved)
+		if err != nil {
+			return nil, err
+		}
+		// same as the SDKs, avro messages are produced as json and validated by encoding them with the schema
+		return func(msg []byte) error {
+			var value interface{}
+			err := json.Unmarshal(msg, &value)
+			if err != nil {
+				return fmt.Errorf("invalid json: %v", err.Error())
+			}
+			_, err = avro.Marshal(schema, value)
+			return err
+		}, nil
+	case "xsd":
+		schema, err := parseXsdSchema(schemaVersion.SchemaContent)
+		if err != nil {
+			return nil, err
+		}
+		return schema.Validate, nil
+	case "thrift":
+		schema, err := parseThriftSchema(schemaVersion.SchemaContent)
+		if err != nil {
+			return nil, err
+		}
+		st, err := schema.messageStruct(schemaVersion.MessageStructName)
+		if err != nil {
+			return nil, err
+		}
+		return func(msg []byte) error {
+			return schema.validateThriftMessage(st, msg)
+		}, nil
+	default:
+		return nil, errors.New("unsupported schema type " + schemaType)
+	}
+}
+
+func compileProtobufValidator(schemaVersion models.SchemaVersion) (schemaValidator, error) {
+	msgDescriptor, err := loadProtobufMessageDescriptor(schemaVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(msg []byte) error {
+		return proto.Unmarshal(msg, dynamicpb.NewMessage(msgDescriptor))
+	}, nil
+}
+
+// loadProtobufMessageDescriptor returns the descriptor of the version's message struct out of its stored descriptor set
+func loadProtobufMessageDescriptor(schemaVersion models.SchemaVersion) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := base64.StdEncoding.DecodeString(schemaVersion.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	var descriptorSet descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(descriptor, &descriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	// the descriptor is generated without its imports so unresolvable dependencies are allowed
+	files, err := protodesc.FileOptions{AllowUnresolvable: true}.NewFiles(&descriptorSet)
+	if err != nil {
+		return nil, err
+	}
+
+	msgDescriptor := findProtoMessageDescriptor(files, schemaVersion.MessageStructName)
+	if msgDescriptor == nil {
+		return nil, fmt.Errorf("message struct %v was not found in the schema", schemaVersion.MessageStructName)
+	}
+	return msgDescriptor, nil
+}
+
+// the message struct name can be either the full name of the message or its name without the package
+func findProtoMessageDescriptor(files *protoregistry.Files, messageStructName string) protoreflect.MessageDescriptor {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(messageStructName))
+	if err == nil {
+		if msgDescriptor, ok := d.(protoreflect.MessageDescriptor); ok {
+			return msgDescriptor
+		}
+	}
+
+	var msgDescriptor protoreflect.MessageDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		messages := fd.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			if string(messages.Get(i).Name()) == messageStructName {
+				msgDescriptor = messages.Get(i)
+				return false
+			}
+		}
+		return true
+	})
+	return msgDescriptor
+}
+
+// validateProducedMessage validates a message produced through the UI against the station's schema (in any schema mode)
+// and returns the schema version it was validated against, 0 when it was not validated,
+// protobuf messages are skipped since the UI produces them in their json representation
+func validateProducedMessage(station models.Station, msg []byte) (int, error) {
+	if station.SchemaName == _EMPTY_ {
+		return 0, nil
+	}
+	exist, schema, err := db.GetSchemaByName(station.SchemaName, station.TenantName)
+	if err != nil {
+		return 0, err
+	}
+	if !exist || schema.Type == "protobuf" {
+		return 0, nil
+	}
+	schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
+	if err != nil {
+		return 0, err
+	}
+	validator, err := compileSchemaValidator(schema.Type, schemaVersion)
+	if err != nil {
+		return 0, err
+	}
+	err = validator(msg)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSchemaValidation, err.Error())
+	}
+	return schemaVersion.VersionNumber, nil
+}
+
+func stationSchemaCacheKey(tenantName, stationIntern string) string {
+	return tenantName + ":" + stationIntern
+}
+
+func loadStationSchemaEnforcement(tenantName string, stationName StationName) (stationSchemaEnforcement, error) {
+	exist, station, err := db.GetStationByName(stationName.Ext(), tenantName)
+	if err != nil {
+		return stationSchemaEnforcement{}, err
+	}
+	if !exist || !station.SchemaStrict || station.SchemaName == _EMPTY_ {
+		return stationSchemaEnforcement{stationId: station.ID, station: station}, nil
+	}
+
+	exist, schema, err := db.GetSchemaByName(station.SchemaName, station.TenantName)
+	if err != nil {
+		return stationSchemaEnforcement{}, err
+	}
+	if !exist {
+		return stationSchemaEnforcement{stationId: station.ID, station: station}, nil
+	}
+	schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
+	if err != nil {
+		return stationSchemaEnforcement{}, err
+	}
+	validator, err := compileSchemaValidator(schema.Type, schemaVersion)
+	if err != nil {
+		// a schema which can not be compiled would fail on every message so the station is left unvalidated
+		serv.Errorf("[tenant: %v]loadStationSchemaEnforcement at compileSchemaValidator: schema %v at station %v: %v", tenantName, schema.Name, station.Name, err.Error())
+		return stationSchemaEnforcement{stationId: station.ID, station: station}, nil
+	}
+
+	return stationSchemaEnforcement{stationId: station.ID, station: station, validator: validator}, nil
+}
+
+func getStationSchemaEnforcement(tenantName string, stationName StationName) (stationSchemaEnforcement, bool) {
+	return stationSchemaEnforcementCache.Load(stationSchemaCacheKey(tenantName, stationName.Intern()))
+}
+
+func setStationSchemaEnforcement(tenantName, stationIntern string, enforcement stationSchemaEnforcement) {
+	cacheKey := stationSchemaCacheKey(tenantName, stationIntern)
+	if enforcement.validator == nil && !enforcement.loading {
+		stationSchemaEnforcementCache.Delete(cacheKey)
+		return
+	}
+	stationSchemaEnforcementCache.Lock()
+	stationSchemaEnforcementCache.m[cacheKey] = enforcement
+	stationSchemaEnforcementCache.Unlock()
+}
+
+// refreshStationSchemaEnforcementCache reloads the schema enforcement of the given stations,
+// when no stations are given all the stations of the tenant are reloaded.
+// The stations are marked as loading first so their messages are rejected instead of being validated by a stale schema
+func refreshStationSchemaEnforcementCache(tenantName string, stationNames []string) {
+	stationSchemaRefreshLock.Lock()
+	defer stationSchemaRefreshLock.Unlock()
+	if len(stationNames) == 0 {
+		keys, _ := stationSchemaEnforcementCache.Array()
+		for _, key := range keys {
+			if strings.HasPrefix(key, tenantName+":") {
+				stationNames = append(stationNames, strings.TrimPrefix(key, tenantName+":"))
+			}
+		}
+		markStationSchemasLoading(tenantName, stationNames)
+		stations, err := db.GetActiveStationsPerTenant(tenantName)
+		if err != nil {
+			serv.Errorf("[tenant: %v]refreshStationSchemaEnforcementCache at GetActiveStationsPerTenant: %v", tenantName, err.Error())
+			retryStationSchemaLoad(tenantName, nil)
+			return
+		}
+		for _, station := range stations {
+			if !station.SchemaStrict {
+				continue
+			}
+			stationName, err := StationNameFromStr(station.Name)
+			if err == nil {
+				stationNames = append(stationNames, stationName.Intern())
+			}
+		}
+	}
+	markStationSchemasLoading(tenantName, stationNames)
+	var failed []string
+	for _, stationIntern := range stationNames {
+		enforcement, err := loadStationSchemaEnforcement(tenantName, StationNameFromStreamName(stationIntern))
+		if err != nil {
+			// the station stays in loading until a retry succeeds
+			serv.Errorf("[tenant: %v]refreshStationSchemaEnforcementCache at loadStationSchemaEnforcement: station %v: %v", tenantName, stationIntern, err.Error())
+			failed = append(failed, stationIntern)
+			continue
+		}
+		setStationSchemaEnforcement(tenantName, stationIntern, enforcement)
+	}
+	if len(failed) > 0 {
+		retryStationSchemaLoad(tenantName, failed)
+	}
+}
+
+func markStationSchemasLoading(tenantName string, stationNames []string) {
+	stationSchemaEnforcementCache.Lock()
+	defer stationSchemaEnforcementCache.Unlock()
+	for _, stationIntern := range stationNames {
+		cacheKey := stationSchemaCacheKey(tenantName, stationIntern)
+		enforcement := stationSchemaEnforcementCache.m[cacheKey]
+		enforcement.loading = true
+		stationSchemaEnforcementCache.m[cacheKey] = enforcement
+	}
+}
+
+func retryStationSchemaLoad(tenantName string, stationNames []string) {
+	time.AfterFunc(stationSchemaLoadRetryInterval, func() {
+		refreshStationSchemaEnforcementCache(tenantName, stationNames)
+	})
+}
+
+// WarmStationSchemaEnforcementCache loads the schema enforcement of all the stations in strict schema mode, it runs before
+// the broker accepts client connections and is retried in the background when it fails
+func (s *Server) WarmStationSchemaEnforcementCache() error {
+	stations, err := db.GetActiveStations()
+	if err != nil {
+		time.AfterFunc(stationSchemaLoadRetryInterval, func() {
+			err := s.WarmStationSchemaEnforcementCache()
+			if err != nil {
+				s.Errorf("WarmStationSchemaEnforcementCache at GetActiveStations: %v", err.Error())
+			}
+		})
+		return err
+	}
+	stationSchemaRefreshLock.Lock()
+	defer stationSchemaRefreshLock.Unlock()
+	defer stationSchemaCacheWarm.Store(true)
+	for _, station := range stations {
+		if !station.SchemaStrict || station.SchemaName == _EMPTY_ {
+			continue
+		}
+		stationName, err := StationNameFromStr(station.Name)
+		if err != nil {
+			continue
+		}
+		enforcement, err := loadStationSchemaEnforcement(station.TenantName, stationName)
+		if err != nil {
+			s.Errorf("[tenant: %v]WarmStationSchemaEnforcementCache at loadStationSchemaEnforcement: station %v: %v", station.TenantName, station.Name, err.Error())
+			enforcement = stationSchemaEnforcement{stationId: station.ID, station: station, loading: true}
+			retryStationSchemaLoad(station.TenantName, []string{stationName.Intern()})
+		}
+		setStationSchemaEnforcement(station.TenantName, stationName.Intern(), enforcement)
+	}
+	return nil
+}
+
+// sendStationSchemaCacheUpdate notifies all the brokers that the schema enforcement of the given stations has changed,
+// when no stations are given the enforcement of all the tenant's stations is reloaded
+func sendStationSchemaCacheUpdate(tenantName string, stationNames []string) {
+	cacheUpdate := models.CacheUpdateRequest{
+		CacheType:  stationSchemaCacheType,
+		Operation:  "delete",
+		TenantName: tenantName,
+		Stations:   stationNames,
+	}
+
+	msg, err := json.Marshal(cacheUpdate)
+	if err != nil {
+		serv.Errorf("[tenant: %v]sendStationSchemaCacheUpdate at json.Marshal: %v", tenantName, err.Error())
+		return
+	}
+
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), CACHE_UDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		serv.Errorf("[tenant: %v]sendStationSchemaCacheUpdate at sendInternalAccountMsgWithReply: %v", tenantName, err.Error())
+	}
+}
+
+func countSchemaValidation(tenantName string, stationId int, valid bool) {
+	key := strconv.Itoa(stationId)
+	counters, ok := stationSchemaCountersMap.Load(key)
+	if !ok {
+		stationSchemaCountersMap.Add(key, &stationSchemaCounters{tenantName: tenantName})
+		counters, _ = stationSchemaCountersMap.Load(key)
+	}
+	if valid {
+		atomic.AddInt64(&counters.valid, 1)
+	} else {
+		atomic.AddInt64(&counters.invalid, 1)
+	}
+}
+
+func (s *Server) FlushStationSchemaMetrics() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		keys, countersList := stationSchemaCountersMap.Array()
+		for i, counters := range countersList {
+			valid := atomic.SwapInt64(&counters.valid, 0)
+			invalid := atomic.SwapInt64(&counters.invalid, 0)
+			if valid == 0 && invalid == 0 {
+				continue
+			}
+			stationId, _ := strconv.Atoi(keys[i])
+			err := db.IncrementStationSchemaMetrics(stationId, counters.tenantName, valid, invalid)
+			if err != nil {
+				s.Errorf("[tenant: %v]FlushStationSchemaMetrics at IncrementStationSchemaMetrics: station id %v: %v", counters.tenantName, stationId, err.Error())
+				atomic.AddInt64(&counters.valid, valid)
+				atomic.AddInt64(&counters.invalid, invalid)
+			}
+		}
+	}
+}
+
+// enforceStationSchema validates messages published to stations in strict schema mode,
+// it returns false when the message is invalid and should not be stored
+func (c *client) enforceStationSchema(tenantName string, msg []byte) bool {
+	if c.kind != CLIENT {
+		return true
+	}
+	subj := string(c.pa.subject)
+	if strings.HasPrefix(subj, "$") || !strings.HasSuffix(subj, ".final") {
+		return true
+	}
+	streamName := strings.TrimSuffix(subj, ".final")
+	if strings.Contains(streamName, ".") {
+		return true
+	}
+	stationIntern, partition, _ := strings.Cut(streamName, "$")
+	partitionNumber := 0
+	if partition != _EMPTY_ {
+		var err error
+		partitionNumber, err = strconv.Atoi(partition)
+		if err != nil {
+			return true
+		}
+	}
+
+	stationName := StationNameFromStreamName(stationIntern)
+	enforcement, ok := getStationSchemaEnforcement(tenantName, stationName)
+	if !stationSchemaCacheWarm.Load() || enforcement.loading {
+		// a publisher without a reply subject is not notified, an -ERR would close its connection
+		if reply := string(c.pa.reply); reply != _EMPTY_ {
+			loadingErr := fmt.Sprintf("The schema of station %v is being loaded, please retry", stationName.Ext())
+			resp := JSPubAckResponse{Error: &ApiError{Code: 503, Description: loadingErr}}
+			c.srv.sendInternalAccountMsg(c.acc, reply, resp)
+		}
+		return false
+	}
+	if !ok || enforcement.validator == nil {
+		return true
+	}
+
+	hdr, payload := c.msgParts(msg)
+	// the wire message ends with CR_LF which is not part of the payload
+	if len(payload) >= LEN_CR_LF {
+		payload = payload[:len(payload)-LEN_CR_LF]
+	}
+	err := enforcement.validator(payload)
+	if err == nil {
+		countSchemaValidation(tenantName, enforcement.stationId, true)
+		return true
+	}
+	countSchemaValidation(tenantName, enforcement.stationId, false)
+
+	// a publisher without a reply subject (e.g. a plain nats publish) is not notified, an -ERR would close its connection,
+	// the message is dropped and is kept in the station's dls only when the schemaverse dls is enabled for the station
+	if reply := string(c.pa.reply); reply != _EMPTY_ {
+		validationErr := fmt.Sprintf("Schema validation has failed: %v", err.Error())
+		resp := JSPubAckResponse{Error: &ApiError{Code: 400, Description: validationErr}}
+		c.srv.sendInternalAccountMsg(c.acc, reply, resp)
+	}
+
+	if enforcement.station.DlsConfigurationSchemaverse {
+		go c.srv.sendInvalidMsgToSchemaverseDls(tenantName, enforcement.station, stationName, partitionNumber, copyBytes(hdr), copyBytes(payload), err.Error())
+	}
+	return false
+}
+
+func (s *Server) sendInvalidMsgToSchemaverseDls(tenantName string, station models.Station, stationName StationName, partitionNumber int, hdr, payload []byte, validationErr string) {
+	var err error
+	headers := map[string]string{}
+	if len(hdr) > 0 {
+		headers, err = DecodeHeader(hdr)
+		if err != nil {
+			s.Errorf("[tenant: %v]sendInvalidMsgToSchemaverseDls at DecodeHeader: station %v: %v", tenantName, stationName.Ext(), err.Error())
+			return
+		}
+	}
+	producerName := headers["$memphis_producedBy"]
+	if producerName == _EMPTY_ {
+		producerName = "unknown"
+	}
+
+	message := models.SchemaVerseDlsMessageSdk{
+		StationName: stationName.Intern(),
+		Producer: models.ProducerDetails{
+			Name:         producerName,
+			ConnectionId: headers["$memphis_connectionId"],
+		},
+		Message: models.MessagePayload{
+			Size:    len(hdr) + len(payload),
+			Data:    hex.EncodeToString(payload),
+			Headers: headers,
+		},
+		ValidationError: validationErr,
+		PartitionNumber: partitionNumber,
+	}
+	err = s.storeSchemaverseDlsMsg(station, message)
+	if err != nil {
+		s.Errorf("[tenant: %v]sendInvalidMsgToSchemaverseDls at storeSchemaverseDlsMsg: station %v: %v", tenantName, stationName.Ext(), err.Error())
+	}
+}
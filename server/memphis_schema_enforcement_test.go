@@ -0,0 +1,98 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestEnforceStationSchema(t *testing.T) {
+	validator, err := compileSchemaValidator("json", models.SchemaVersion{
+		SchemaId:      1,
+		VersionNumber: 1,
+		SchemaContent: `{"type": "object", "properties": {"id": {"type": "number"}}, "required": ["id"]}`,
+	})
+	if err != nil {
+		t.Fatalf("compileSchemaValidator: %v", err)
+	}
+	setStationSchemaEnforcement("acme", "orders", stationSchemaEnforcement{stationId: 9001, validator: validator})
+	// a station which is not in strict mode is never cached
+	setStationSchemaEnforcement("acme", "events", stationSchemaEnforcement{stationId: 9002})
+	defer stationSchemaEnforcementCache.Delete(stationSchemaCacheKey("acme", "orders"))
+	defer stationSchemaCountersMap.Delete(strconv.Itoa(9001))
+
+	if _, ok := getStationSchemaEnforcement("acme", StationNameFromStreamName("events")); ok {
+		t.Fatalf("expected a station without a validator not to be cached")
+	}
+
+	// until the cache is warm it is not known which stations are in strict mode
+	warm := stationSchemaCacheWarm.Load()
+	defer stationSchemaCacheWarm.Store(warm)
+	stationSchemaCacheWarm.Store(false)
+	c := &client{kind: CLIENT, srv: &Server{}}
+	c.pa.subject = []byte("events.final")
+	if c.enforceStationSchema("acme", []byte("{}\r\n")) {
+		t.Errorf("expected messages to be rejected before the cache is warm")
+	}
+	stationSchemaCacheWarm.Store(true)
+	markStationSchemasLoading("acme", []string{"payments"})
+	defer stationSchemaEnforcementCache.Delete(stationSchemaCacheKey("acme", "payments"))
+
+	cases := []struct {
+		name     string
+		subject  string
+		reply    string
+		payload  string
+		expected bool
+	}{
+		{name: "valid", subject: "orders.final", reply: "_INBOX.1", payload: `{"id": 1}`, expected: true},
+		{name: "valid partition", subject: "orders$2.final", reply: "_INBOX.1", payload: `{"id": 1}`, expected: true},
+		{name: "invalid", subject: "orders.final", reply: "_INBOX.1", payload: `{"name": "a"}`, expected: false},
+		{name: "invalid json", subject: "orders$1.final", reply: "_INBOX.1", payload: `not json`, expected: false},
+		{name: "invalid without reply", subject: "orders.final", payload: `{"id": "a"}`, expected: false},
+		{name: "non strict station", subject: "events.final", payload: `not json`, expected: true},
+		{name: "loading schema", subject: "payments.final", reply: "_INBOX.1", payload: `{"id": 1}`, expected: false},
+		{name: "other tenant", subject: "orders.final", payload: `not json`, expected: true},
+		{name: "internal subject", subject: "$memphis_orders.final", payload: `not json`, expected: true},
+	}
+	for _, tc := range cases {
+		tenant := "acme"
+		if tc.name == "other tenant" {
+			tenant = "globex"
+		}
+		hdr := "NATS/1.0\r\n$memphis_producedBy: p1\r\n\r\n"
+		c := &client{kind: CLIENT, srv: &Server{}}
+		c.pa.subject = []byte(tc.subject)
+		c.pa.reply = []byte(tc.reply)
+		c.pa.hdr = len(hdr)
+		if ok := c.enforceStationSchema(tenant, []byte(hdr+tc.payload+"\r\n")); ok != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expected, ok)
+		}
+		// the publisher is never sent an -ERR since it would close its connection
+		if c.out.pb != 0 {
+			t.Errorf("%v: expected nothing to be written to the client, got %v bytes", tc.name, c.out.pb)
+		}
+	}
+
+	counters, ok := stationSchemaCountersMap.Load(strconv.Itoa(9001))
+	if !ok {
+		t.Fatalf("expected the validation results to be counted")
+	}
+	if valid, invalid := atomic.LoadInt64(&counters.valid), atomic.LoadInt64(&counters.invalid); valid != 2 || invalid != 3 {
+		t.Errorf("expected 2 valid and 3 invalid messages, got %v valid and %v invalid", valid, invalid)
+	}
+}
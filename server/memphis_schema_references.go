@@ -0,0 +1,240 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/hamba/avro/v2"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+const maxSchemaReferencesDepth = 10
+
+// resolvedSchemaReference is a referenced schema version content under the name it is imported with
+type resolvedSchemaReference struct {
+	Name    string
+	Content string
+}
+
+// resolveSchemaReferences loads the referenced versions and their own references, dependencies come before the schemas using them
+func resolveSchemaReferences(schemaType, tenantName string, references []models.SchemaReference) ([]resolvedSchemaReference, error) {
+	if len(references) == 0 {
+		return []resolvedSchemaReference{}, nil
+	}
+	if schemaType != "protobuf" && schemaType != "avro" {
+		return []resolvedSchemaReference{}, fmt.Errorf("%w: references are supported only for protobuf and avro schemas", ErrInvalidSchemaReference)
+	}
+
+	resolved := []resolvedSchemaReference{}
+	contents := map[string]string{}
+	err := resolveSchemaReferencesRec(schemaType, tenantName, references, 0, contents, &resolved)
+	if err != nil {
+		return []resolvedSchemaReference{}, err
+	}
+	return resolved, nil
+}
+
+func resolveSchemaReferencesRec(schemaType, tenantName string, references []models.SchemaReference, depth int, contents map[string]string, resolved *[]resolvedSchemaReference) error {
+	if depth > maxSchemaReferencesDepth {
+		return fmt.Errorf("%w: references are nested more than %v levels deep", ErrInvalidSchemaReference, maxSchemaReferencesDepth)
+	}
+	for _, ref := range references {
+		if ref.Name == _EMPTY_ {
+			return fmt.Errorf("%w: reference to schema %v has no name", ErrInvalidSchemaReference, ref.SchemaName)
+		}
+		exist, schema, err := db.GetSchemaByName(strings.ToLower(ref.SchemaName), tenantName)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return fmt.Errorf("%w: schema %v does not exist", ErrInvalidSchemaReference, ref.SchemaName)
+		}
+		if schema.Type != schemaType {
+			return fmt.Errorf("%w: schema %v is of type %v while %v is expected", ErrInvalidSchemaReference, ref.SchemaName, schema.Type, schemaType)
+		}
+		exist, version, err := db.GetSchemaVersionByNumberAndID(ref.VersionNumber, schema.ID)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return fmt.Errorf("%w: schema %v version %v does not exist", ErrInvalidSchemaReference, ref.SchemaName, ref.VersionNumber)
+		}
+		if content, ok := contents[ref.Name]; ok {
+			if content != version.SchemaContent {
+				return fmt.Errorf("%w: %v is referenced with different contents", ErrInvalidSchemaReference, ref.Name)
+			}
+			continue
+		}
+
+		nested, err := db.GetSchemaReferencesByVersionId(version.ID)
+		if err != nil {
+			return err
+		}
+		err = resolveSchemaReferencesRec(schemaType, tenantName, nested, depth+1, contents, resolved)
+		if err != nil {
+			return err
+		}
+		contents[ref.Name] = version.SchemaContent
+		*resolved = append(*resolved, resolvedSchemaReference{Name: ref.Name, Content: version.SchemaContent})
+	}
+	return nil
+}
+
+// loadSchemaVersionsReferences fills the references stored for each of the versions
+func loadSchemaVersionsReferences(versions []models.SchemaVersion) error {
+	for i := range versions {
+		references, err := db.GetSchemaReferencesByVersionId(versions[i].ID)
+		if err != nil {
+			return err
+		}
+		versions[i].References = references
+	}
+	return nil
+}
+
+// getSchemaReferencedByMessage returns a message to show when schemas other than the ignored ones reference the schema,
+// otherwise an empty string
+func getSchemaReferencedByMessage(schema models.Schema, ignoredSchemas []string) (string, error) {
+	referencing, err := db.GetSchemaReferencingVersions(schema.ID)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	ignored := map[string]bool{}
+	for _, name := range ignoredSchemas {
+		ignored[name] = true
+	}
+	usages := []string{}
+	for _, ref := range referencing {
+		if ignored[ref.SchemaName] {
+			continue
+		}
+		usages = append(usages, fmt.Sprintf("%v version %v", ref.SchemaName, ref.VersionNumber))
+	}
+	if len(usages) == 0 {
+		return _EMPTY_, nil
+	}
+	return fmt.Sprintf("Schema %v is referenced by %v", schema.Name, strings.Join(usages, ", ")), nil
+}
+
+func protobufReferencesAccessor(schemaContent string, references []resolvedSchemaReference) func(filename string) (io.ReadCloser, error) {
+	files := map[string]string{_EMPTY_: schemaContent}
+	for _, ref := range references {
+		files[ref.Name] = ref.Content
+	}
+	return func(filename string) (io.ReadCloser, error) {
+		content, ok := files[filename]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+func parseAvroWithReferences(schemaContent string, references []resolvedSchemaReference) (avro.Schema, error) {
+	cache := &avro.SchemaCache{}
+	for _, ref := range references {
+		_, err := avro.ParseWithCache(ref.Content, _EMPTY_, cache)
+		if err != nil {
+			return nil, fmt.Errorf("referenced schema %v is invalid: %v", ref.Name, err.Error())
+		}
+	}
+	return avro.ParseWithCache(schemaContent, _EMPTY_, cache)
+}
+
+func validateSchemaContentWithReferences(schemaContent, schemaType string, references []resolvedSchemaReference) error {
+	if len(references) == 0 {
+		return validateSchemaContent(schemaContent, schemaType)
+	}
+	if len(schemaContent) == 0 {
+		return errors.New("your schema content is invalid")
+	}
+
+	switch schemaType {
+	case "protobuf":
+		parser := protoparse.Parser{Accessor: protobufReferencesAccessor(schemaContent, references)}
+		_, err := parser.ParseFiles(_EMPTY_)
+		if err != nil {
+			return fmt.Errorf("your Proto file is invalid: %v", err.Error())
+		}
+	case "avro":
+		_, err := parseAvroWithReferences(schemaContent, references)
+		if err != nil {
+			return fmt.Errorf("your Avro file is invalid: %v", err.Error())
+		}
+	default:
+		return validateSchemaContent(schemaContent, schemaType)
+	}
+	return nil
+}
+
+// generateProtobufDescriptorWithReferences compiles the proto file together with its imports into a single descriptor set
+func generateProtobufDescriptorWithReferences(schemaName string, schemaVersionNum int, schemaContent string, references []resolvedSchemaReference) ([]byte, error) {
+	dir, err := os.MkdirTemp(_EMPTY_, fmt.Sprintf("%v_%v_", schemaName, schemaVersionNum))
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	for _, ref := range references {
+		refPath := filepath.Join(dir, filepath.FromSlash(ref.Name))
+		if !strings.HasPrefix(refPath, dir+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("%w: %v is not a valid import path", ErrInvalidSchemaReference, ref.Name)
+		}
+		err = os.MkdirAll(filepath.Dir(refPath), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(refPath, []byte(ref.Content), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	filename := filepath.Join(dir, fmt.Sprintf("%v_%v.proto", schemaName, schemaVersionNum))
+	err = os.WriteFile(filename, []byte(schemaContent), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	descFilename := filepath.Join(dir, fmt.Sprintf("%v_%v_desc", schemaName, schemaVersionNum))
+	cmd := exec.Command("protoc", "--proto_path="+dir, "--include_imports", "--descriptor_set_out="+descFilename, filename)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", err.Error(), strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(descFilename)
+}
+
+func generateSchemaDescriptorWithReferences(schemaName string, schemaVersionNum int, schemaContent, schemaType string, references []resolvedSchemaReference) (string, error) {
+	if len(references) == 0 {
+		return generateSchemaDescriptor(schemaName, schemaVersionNum, schemaContent, schemaType)
+	}
+	if schemaType != "protobuf" {
+		return _EMPTY_, errors.New("descriptor generation with schema type: " + schemaType + ", while protobuf is expected")
+	}
+	descriptor, err := generateProtobufDescriptorWithReferences(schemaName, schemaVersionNum, schemaContent, references)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	return base64.StdEncoding.EncodeToString(descriptor), nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/hamba/avro/v2"
+)
+
+const (
+	testMoneyProto = `syntax = "proto3";
+package common;
+message Money {
+	int64 amount = 1;
+	string currency = 2;
+}`
+	testOrderProto = `syntax = "proto3";
+import "common/money.proto";
+message Order {
+	int64 id = 1;
+	common.Money total = 2;
+}`
+	testMoneyAvro = `{"type": "record", "name": "Money", "namespace": "common", "fields": [{"name": "amount", "type": "long"}, {"name": "currency", "type": "string"}]}`
+	testOrderAvro = `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "long"}, {"name": "total", "type": "common.Money"}]}`
+)
+
+func TestValidateSchemaContentWithReferences(t *testing.T) {
+	protoRefs := []resolvedSchemaReference{{Name: "common/money.proto", Content: testMoneyProto}}
+	avroRefs := []resolvedSchemaReference{{Name: "common.Money", Content: testMoneyAvro}}
+	cases := []struct {
+		name       string
+		schemaType string
+		content    string
+		references []resolvedSchemaReference
+		valid      bool
+	}{
+		{name: "protobuf import", schemaType: "protobuf", content: testOrderProto, references: protoRefs, valid: true},
+		{name: "protobuf missing import", schemaType: "protobuf", content: testOrderProto, references: []resolvedSchemaReference{{Name: "other.proto", Content: testMoneyProto}}, valid: false},
+		{name: "protobuf unresolved type", schemaType: "protobuf", content: strings.Replace(testOrderProto, "common.Money", "common.Price", 1), references: protoRefs, valid: false},
+		{name: "protobuf invalid reference", schemaType: "protobuf", content: testOrderProto, references: []resolvedSchemaReference{{Name: "common/money.proto", Content: "message {"}}, valid: false},
+		{name: "avro named type", schemaType: "avro", content: testOrderAvro, references: avroRefs, valid: true},
+		{name: "avro unknown named type", schemaType: "avro", content: strings.Replace(testOrderAvro, "common.Money", "common.Price", 1), references: avroRefs, valid: false},
+		{name: "avro invalid reference", schemaType: "avro", content: testOrderAvro, references: []resolvedSchemaReference{{Name: "common.Money", Content: `{"type": "record"}`}}, valid: false},
+		{name: "avro without references", schemaType: "avro", content: testOrderAvro, references: nil, valid: false},
+		{name: "empty content", schemaType: "avro", content: "", references: avroRefs, valid: false},
+	}
+	for _, c := range cases {
+		err := validateSchemaContentWithReferences(c.content, c.schemaType, c.references)
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid to be %v, got %v", c.name, c.valid, err)
+		}
+	}
+}
+
+func TestParseProtobufFileWithReferences(t *testing.T) {
+	fd, err := parseProtobufFile(testOrderProto, []resolvedSchemaReference{{Name: "common/money.proto", Content: testMoneyProto}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := fd.FindMessage("Order")
+	if order == nil {
+		t.Fatalf("message Order was not found")
+	}
+	total := order.FindFieldByName("total")
+	if total == nil || total.GetMessageType() == nil || total.GetMessageType().GetFullyQualifiedName() != "common.Money" {
+		t.Errorf("expected total to be resolved to common.Money")
+	}
+}
+
+func TestParseAvroWithReferences(t *testing.T) {
+	schema, err := parseAvroWithReferences(testOrderAvro, []resolvedSchemaReference{{Name: "common.Money", Content: testMoneyAvro}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type money struct {
+		Amount   int64  `avro:"amount"`
+		Currency string `avro:"currency"`
+	}
+	type order struct {
+		Id    int64 `avro:"id"`
+		Total money `avro:"total"`
+	}
+	data, err := avro.Marshal(schema, order{Id: 1, Total: money{Amount: 100, Currency: "usd"}})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var decoded order
+	if err := avro.Unmarshal(schema, data, &decoded); err != nil || decoded.Total.Currency != "usd" {
+		t.Errorf("expected the referenced record to round trip, got %+v %v", decoded, err)
+	}
+
+	// every parse uses its own cache so named types of one schema do not leak into another
+	if _, err := parseAvroWithReferences(testOrderAvro, nil); err == nil {
+		t.Errorf("expected an error for an unresolved named type")
+	}
+}
+
+func TestResolveSchemaReferencesValidation(t *testing.T) {
+	resolved, err := resolveSchemaReferences("json", "acme", nil)
+	if err != nil || len(resolved) != 0 {
+		t.Errorf("expected no references to resolve to nothing, got %v %v", resolved, err)
+	}
+	_, err = resolveSchemaReferences("json", "acme", []models.SchemaReference{{Name: "money", SchemaName: "money", VersionNumber: 1}})
+	if !errors.Is(err, ErrInvalidSchemaReference) {
+		t.Errorf("expected references of json schemas to be rejected, got %v", err)
+	}
+	_, err = resolveSchemaReferences("avro", "acme", []models.SchemaReference{{SchemaName: "money", VersionNumber: 1}})
+	if !errors.Is(err, ErrInvalidSchemaReference) {
+		t.Errorf("expected a reference without a name to be rejected, got %v", err)
+	}
+}
+
+func TestGenerateProtobufDescriptorWithReferencesPaths(t *testing.T) {
+	for _, name := range []string{"../money.proto", "common/../../money.proto"} {
+		_, err := generateProtobufDescriptorWithReferences("orders", 1, testOrderProto, []resolvedSchemaReference{{Name: name, Content: testMoneyProto}})
+		if !errors.Is(err, ErrInvalidSchemaReference) {
+			t.Errorf("%v: expected the import path to be rejected, got %v", name, err)
+		}
+	}
+}
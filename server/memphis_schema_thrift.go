@@ -0,0 +1,1016 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// thrift binary protocol type ids
+const (
+	thriftTypeStop   byte = 0
+	thriftTypeBool   byte = 2
+	thriftTypeByte   byte = 3
+	thriftTypeDouble byte = 4
+	thriftTypeI16    byte = 6
+	thriftTypeI32    byte = 8
+	thriftTypeI64    byte = 10
+	thriftTypeString byte = 11
+	thriftTypeStruct byte = 12
+	thriftTypeMap    byte = 13
+	thriftTypeSet    byte = 14
+	thriftTypeList   byte = 15
+	thriftTypeUuid   byte = 16
+)
+
+const thriftMaxDepth = 64
+
+var thriftBaseTypes = map[string]byte{
+	"bool":   thriftTypeBool,
+	"byte":   thriftTypeByte,
+	"i8":     thriftTypeByte,
+	"i16":    thriftTypeI16,
+	"i32":    thriftTypeI32,
+	"i64":    thriftTypeI64,
+	"double": thriftTypeDouble,
+	"string": thriftTypeString,
+	"binary": thriftTypeString,
+	"uuid":   thriftTypeUuid,
+}
+
+// thriftSchema is the parsed form of a thrift IDL file, services and constants are parsed but not kept
+// since only the data types are relevant for validating messages
+type thriftSchema struct {
+	Namespaces map[string]string        `json:"namespaces,omitempty"`
+	Structs    map[string]*thriftStruct `json:"structs"`
+	Enums      map[string]*thriftEnum   `json:"enums,omitempty"`
+	Typedefs   map[string]*thriftType   `json:"typedefs,omitempty"`
+	structList []string
+}
+
+type thriftType struct {
+	Name string      `json:"name"`
+	Key  *thriftType `json:"key,omitempty"`
+	Elem *thriftType `json:"elem,omitempty"`
+}
+
+type thriftField struct {
+	Id       int16       `json:"id"`
+	Name     string      `json:"name"`
+	Type     *thriftType `json:"type"`
+	Required bool        `json:"required,omitempty"`
+}
+
+type thriftStruct struct {
+	Name   string         `json:"name"`
+	Kind   string         `json:"kind"`
+	Fields []*thriftField `json:"fields"`
+}
+
+type thriftEnum struct {
+	Name   string           `json:"name"`
+	Values map[string]int32 `json:"values"`
+}
+
+type thriftParser struct {
+	tokens []string
+	pos    int
+}
+
+func tokenizeThrift(content string) ([]string, error) {
+	var tokens []string
+	runes := []rune(content)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || (r == '/' && i+1 < len(runes) && runes[i+1] == '/'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			j := i + 2
+			for j+1 < len(runes) && !(runes[j] == '*' && runes[j+1] == '/') {
+				j++
+			}
+			if j+1 >= len(runes) {
+				return nil, errors.New("unterminated comment")
+			}
+			i = j + 2
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '+' || r == '.':
+			j := i
+			for j < len(runes) {
+				c := runes[j]
+				sign := c == '-' || c == '+'
+				// a sign may only start a number or follow the exponent of a double constant
+				if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || (sign && (j == i || runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case strings.ContainsRune("{}()<>[],;:=*", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *thriftParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return _EMPTY_
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *thriftParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return _EMPTY_, errors.New("unexpected end of file")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *thriftParser) expect(expected string) error {
+	token, err := p.next()
+	if err != nil {
+		return fmt.Errorf("expected %v: %v", expected, err.Error())
+	}
+	if token != expected {
+		return fmt.Errorf("expected %v but found %v", expected, token)
+	}
+	return nil
+}
+
+func (p *thriftParser) identifier() (string, error) {
+	token, err := p.next()
+	if err != nil {
+		return _EMPTY_, err
+	}
+	first := []rune(token)[0]
+	if !unicode.IsLetter(first) && first != '_' {
+		return _EMPTY_, fmt.Errorf("expected an identifier but found %v", token)
+	}
+	return token, nil
+}
+
+// skipListSeparator consumes an optional , or ; after a field or an enum value
+func (p *thriftParser) skipListSeparator() {
+	if token := p.peek(); token == "," || token == ";" {
+		p.pos++
+	}
+}
+
+// skipBalanced skips a value or an annotation which may be nested in brackets
+func (p *thriftParser) skipBalanced() error {
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+	closing := map[string]string{"{": "}", "[": "]", "(": ")"}
+	if _, ok := closing[token]; !ok {
+		return nil
+	}
+	stack := []string{closing[token]}
+	for len(stack) > 0 {
+		token, err = p.next()
+		if err != nil {
+			return err
+		}
+		if close, ok := closing[token]; ok {
+			stack = append(stack, close)
+		} else if token == stack[len(stack)-1] {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return nil
+}
+
+func (p *thriftParser) skipAnnotations() error {
+	if p.peek() == "(" {
+		return p.skipBalanced()
+	}
+	return nil
+}
+
+func (p *thriftParser) parseType() (*thriftType, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	fieldType := &thriftType{Name: name}
+	switch name {
+	case "map":
+		if err = p.expect("<"); err != nil {
+			return nil, err
+		}
+		if fieldType.Key, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+		if fieldType.Elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(">"); err != nil {
+			return nil, err
+		}
+	case "list", "set":
+		if err = p.expect("<"); err != nil {
+			return nil, err
+		}
+		if fieldType.Elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(">"); err != nil {
+			return nil, err
+		}
+	}
+	return fieldType, p.skipAnnotations()
+}
+
+func (p *thriftParser) parseStruct(kind string) (*thriftStruct, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == "xsd_all" {
+		p.pos++
+	}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	st := &thriftStruct{Name: name, Kind: kind}
+	ids := make(map[int16]bool)
+	names := make(map[string]bool)
+	for p.peek() != "}" {
+		field := &thriftField{}
+		token, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ":" {
+			return nil, fmt.Errorf("field %v of %v must have an explicit id", token, name)
+		}
+		id, err := strconv.ParseInt(token, 0, 16)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid field id %v in %v", token, name)
+		}
+		field.Id = int16(id)
+		p.pos++
+		switch p.peek() {
+		case "required":
+			if kind == "union" {
+				return nil, fmt.Errorf("union %v can not have required fields", name)
+			}
+			field.Required = true
+			p.pos++
+		case "optional":
+			p.pos++
+		}
+		if field.Type, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if field.Name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+		if p.peek() == "=" {
+			p.pos++
+			if err = p.skipBalanced(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		p.skipListSeparator()
+		if ids[field.Id] {
+			return nil, fmt.Errorf("duplicate field id %v in %v", field.Id, name)
+		}
+		if names[field.Name] {
+			return nil, fmt.Errorf("duplicate field name %v in %v", field.Name, name)
+		}
+		ids[field.Id] = true
+		names[field.Name] = true
+		st.Fields = append(st.Fields, field)
+	}
+	p.pos++
+	return st, p.skipAnnotations()
+}
+
+func (p *thriftParser) parseEnum() (*thriftEnum, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	enum := &thriftEnum{Name: name, Values: make(map[string]int32)}
+	value := int64(0)
+	for p.peek() != "}" {
+		valueName, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == "=" {
+			p.pos++
+			token, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			value, err = strconv.ParseInt(token, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %v of %v.%v", token, name, valueName)
+			}
+		}
+		if _, ok := enum.Values[valueName]; ok {
+			return nil, fmt.Errorf("duplicate value %v in enum %v", valueName, name)
+		}
+		enum.Values[valueName] = int32(value)
+		value++
+		if err = p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		p.skipListSeparator()
+	}
+	p.pos++
+	return enum, p.skipAnnotations()
+}
+
+func parseThriftSchema(schemaContent string) (*thriftSchema, error) {
+	tokens, err := tokenizeThrift(schemaContent)
+	if err != nil {
+		return nil, err
+	}
+	p := &thriftParser{tokens: tokens}
+	schema := &thriftSchema{
+		Namespaces: make(map[string]string),
+		Structs:    make(map[string]*thriftStruct),
+		Enums:      make(map[string]*thriftEnum),
+		Typedefs:   make(map[string]*thriftType),
+	}
+	defined := func(name string) error {
+		_, isStruct := schema.Structs[name]
+		_, isEnum := schema.Enums[name]
+		_, isTypedef := schema.Typedefs[name]
+		if isStruct || isEnum || isTypedef {
+			return fmt.Errorf("%v is defined more than once", name)
+		}
+		return nil
+	}
+
+	for p.pos < len(p.tokens) {
+		token, _ := p.next()
+		switch token {
+		case "namespace":
+			scope, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			namespace, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			schema.Namespaces[scope] = namespace
+		case "include", "cpp_include":
+			return nil, errors.New("includes are not supported, all the definitions should be in a single file")
+		case "typedef":
+			fieldType, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			name, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			if err = defined(name); err != nil {
+				return nil, err
+			}
+			schema.Typedefs[name] = fieldType
+		case "const":
+			if _, err = p.parseType(); err != nil {
+				return nil, err
+			}
+			if _, err = p.identifier(); err != nil {
+				return nil, err
+			}
+			if err = p.expect("="); err != nil {
+				return nil, err
+			}
+			if err = p.skipBalanced(); err != nil {
+				return nil, err
+			}
+		case "enum":
+			enum, err := p.parseEnum()
+			if err != nil {
+				return nil, err
+			}
+			if err = defined(enum.Name); err != nil {
+				return nil, err
+			}
+			schema.Enums[enum.Name] = enum
+		case "struct", "union", "exception":
+			st, err := p.parseStruct(token)
+			if err != nil {
+				return nil, err
+			}
+			if err = defined(st.Name); err != nil {
+				return nil, err
+			}
+			schema.Structs[st.Name] = st
+			schema.structList = append(schema.structList, st.Name)
+		case "service":
+			if _, err = p.identifier(); err != nil {
+				return nil, err
+			}
+			if p.peek() == "extends" {
+				p.pos += 2
+			}
+			if p.peek() != "{" {
+				return nil, errors.New("expected service body")
+			}
+			if err = p.skipBalanced(); err != nil {
+				return nil, err
+			}
+			if err = p.skipAnnotations(); err != nil {
+				return nil, err
+			}
+		case ";", ",":
+		default:
+			return nil, fmt.Errorf("unexpected %v", token)
+		}
+	}
+	if len(schema.Structs) == 0 {
+		return nil, errors.New("the schema must define at least one struct")
+	}
+
+	for _, st := range schema.Structs {
+		for _, field := range st.Fields {
+			if err = schema.resolveType(field.Type, 0); err != nil {
+				return nil, fmt.Errorf("%v.%v: %v", st.Name, field.Name, err.Error())
+			}
+		}
+	}
+	for name, fieldType := range schema.Typedefs {
+		if err = schema.resolveType(fieldType, 0); err != nil {
+			return nil, fmt.Errorf("typedef %v: %v", name, err.Error())
+		}
+	}
+	return schema, nil
+}
+
+func (s *thriftSchema) resolveType(fieldType *thriftType, depth int) error {
+	if depth > thriftMaxDepth {
+		return errors.New("typedef cycle")
+	}
+	switch fieldType.Name {
+	case "map":
+		if err := s.resolveType(fieldType.Key, depth+1); err != nil {
+			return err
+		}
+		return s.resolveType(fieldType.Elem, depth+1)
+	case "list", "set":
+		return s.resolveType(fieldType.Elem, depth+1)
+	}
+	if _, ok := thriftBaseTypes[fieldType.Name]; ok {
+		return nil
+	}
+	if _, ok := s.Structs[fieldType.Name]; ok {
+		return nil
+	}
+	if _, ok := s.Enums[fieldType.Name]; ok {
+		return nil
+	}
+	if typedef, ok := s.Typedefs[fieldType.Name]; ok {
+		return s.resolveType(typedef, depth+1)
+	}
+	return fmt.Errorf("unknown type %v", fieldType.Name)
+}
+
+// underlying follows typedefs to the actual type
+func (s *thriftSchema) underlying(fieldType *thriftType) *thriftType {
+	for i := 0; i <= thriftMaxDepth; i++ {
+		typedef, ok := s.Typedefs[fieldType.Name]
+		if !ok {
+			break
+		}
+		fieldType = typedef
+	}
+	return fieldType
+}
+
+func (s *thriftSchema) wireType(fieldType *thriftType) byte {
+	fieldType = s.underlying(fieldType)
+	switch fieldType.Name {
+	case "map":
+		return thriftTypeMap
+	case "list":
+		return thriftTypeList
+	case "set":
+		return thriftTypeSet
+	}
+	if typeId, ok := thriftBaseTypes[fieldType.Name]; ok {
+		return typeId
+	}
+	if _, ok := s.Enums[fieldType.Name]; ok {
+		return thriftTypeI32
+	}
+	return thriftTypeStruct
+}
+
+func validateThriftSchemaContent(schemaContent string) error {
+	_, err := parseThriftSchema(schemaContent)
+	if err != nil {
+		return fmt.Errorf("your Thrift file is invalid: %v", err.Error())
+	}
+	return nil
+}
+
+// getThriftMessageStructName returns the last struct of the file, same as protobuf the root struct is usually declared last
+func getThriftMessageStructName(schemaContent string) (string, error) {
+	schema, err := parseThriftSchema(schemaContent)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	return schema.structList[len(schema.structList)-1], nil
+}
+
+func validateThriftMessageStructName(schemaContent, messageStructName string) error {
+	schema, err := parseThriftSchema(schemaContent)
+	if err != nil {
+		return err
+	}
+	_, err = schema.messageStruct(messageStructName)
+	return err
+}
+
+func generateThriftDescriptor(schemaContent string) ([]byte, error) {
+	schema, err := parseThriftSchema(schemaContent)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(schema)
+}
+
+func (s *thriftSchema) messageStruct(messageStructName string) (*thriftStruct, error) {
+	st, ok := s.Structs[messageStructName]
+	if !ok {
+		return nil, fmt.Errorf("message struct %v was not found in the schema", messageStructName)
+	}
+	return st, nil
+}
+
+// thriftReader decodes values encoded with the thrift binary protocol
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) read(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errors.New("unexpected end of message")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// readSize reads a container or string size and rejects sizes which can not fit in the remaining message
+func (r *thriftReader) readSize(minElemSize int) (int, error) {
+	size, err := r.readI32()
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 || int(size)*minElemSize > len(r.data)-r.pos {
+		return 0, fmt.Errorf("invalid size %v", size)
+	}
+	return int(size), nil
+}
+
+// decodeStruct validates the encoded struct against its definition and returns it as a json compatible map
+func (s *thriftSchema) decodeStruct(r *thriftReader, st *thriftStruct, depth int) (map[string]interface{}, error) {
+	if depth > thriftMaxDepth {
+		return nil, errors.New("the message is nested too deeply")
+	}
+	fields := make(map[int16]*thriftField, len(st.Fields))
+	for _, field := range st.Fields {
+		fields[field.Id] = field
+	}
+	result := make(map[string]interface{})
+	for {
+		typeId, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if typeId == thriftTypeStop {
+			break
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return nil, err
+		}
+		field, ok := fields[id]
+		if !ok {
+			// unknown fields are skipped, same as thrift does for fields added by newer writers
+			_, err = s.decodeValue(r, typeId, nil, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if expected := s.wireType(field.Type); expected != typeId {
+			return nil, fmt.Errorf("%v.%v: field type %v does not match the schema type %v", st.Name, field.Name, typeId, expected)
+		}
+		value, err := s.decodeValue(r, typeId, field.Type, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %v", st.Name, field.Name, err.Error())
+		}
+		result[field.Name] = value
+	}
+	return result, s.checkStructFields(st, result)
+}
+
+// checkStructFields checks the required fields and that a union has exactly one field set, null json values are treated as unset
+func (s *thriftSchema) checkStructFields(st *thriftStruct, values map[string]interface{}) error {
+	for _, field := range st.Fields {
+		if value, ok := values[field.Name]; field.Required && (!ok || value == nil) {
+			return fmt.Errorf("%v: required field %v is missing", st.Name, field.Name)
+		}
+	}
+	if st.Kind == "union" {
+		set := 0
+		for _, value := range values {
+			if value != nil {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("%v: union must have exactly one field set", st.Name)
+		}
+	}
+	return nil
+}
+
+// decodeValue decodes a single value of the given wire type, fieldType is nil for values of unknown fields
+func (s *thriftSchema) decodeValue(r *thriftReader, typeId byte, fieldType *thriftType, depth int) (interface{}, error) {
+	if depth > thriftMaxDepth {
+		return nil, errors.New("the message is nested too deeply")
+	}
+	if fieldType != nil {
+		fieldType = s.underlying(fieldType)
+	}
+	switch typeId {
+	case thriftTypeBool:
+		b, err := r.readByte()
+		return b != 0, err
+	case thriftTypeByte:
+		b, err := r.readByte()
+		return int8(b), err
+	case thriftTypeI16:
+		return r.readI16()
+	case thriftTypeI32:
+		value, err := r.readI32()
+		if err != nil || fieldType == nil {
+			return value, err
+		}
+		if enum, ok := s.Enums[fieldType.Name]; ok {
+			for name, enumValue := range enum.Values {
+				if enumValue == value {
+					return name, nil
+				}
+			}
+			return nil, fmt.Errorf("%v is not a valid value of enum %v", value, enum.Name)
+		}
+		return value, nil
+	case thriftTypeI64:
+		return r.readI64()
+	case thriftTypeDouble:
+		value, err := r.readI64()
+		return math.Float64frombits(uint64(value)), err
+	case thriftTypeString:
+		size, err := r.readSize(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.read(size)
+		if err != nil {
+			return nil, err
+		}
+		if fieldType != nil && fieldType.Name == "binary" {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+		return string(b), nil
+	case thriftTypeUuid:
+		b, err := r.read(16)
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	case thriftTypeStruct:
+		if fieldType == nil {
+			return s.decodeStruct(r, &thriftStruct{}, depth)
+		}
+		return s.decodeStruct(r, s.Structs[fieldType.Name], depth)
+	case thriftTypeList, thriftTypeSet:
+		elemType, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.readSize(1)
+		if err != nil {
+			return nil, err
+		}
+		var elem *thriftType
+		if fieldType != nil {
+			elem = fieldType.Elem
+			if size > 0 && s.wireType(elem) != elemType {
+				return nil, fmt.Errorf("element type %v does not match the schema type %v", elemType, s.wireType(elem))
+			}
+		}
+		values := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, err := s.decodeValue(r, elemType, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case thriftTypeMap:
+		keyType, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		valueType, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.readSize(2)
+		if err != nil {
+			return nil, err
+		}
+		var key, elem *thriftType
+		if fieldType != nil {
+			key, elem = fieldType.Key, fieldType.Elem
+			if size > 0 && (s.wireType(key) != keyType || s.wireType(elem) != valueType) {
+				return nil, errors.New("map key or value type does not match the schema")
+			}
+		}
+		values := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			k, err := s.decodeValue(r, keyType, key, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			v, err := s.decodeValue(r, valueType, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values[fmt.Sprint(k)] = v
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unknown field type %v", typeId)
+}
+
+// decodeThriftMessage decodes a message encoded with the thrift binary protocol as the given struct
+func (s *thriftSchema) decodeThriftMessage(st *thriftStruct, msg []byte) (map[string]interface{}, error) {
+	r := &thriftReader{data: msg}
+	value, err := s.decodeStruct(r, st, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(msg) {
+		return nil, errors.New("unexpected data after the end of the message")
+	}
+	return value, nil
+}
+
+// validateJsonValue validates the json representation of a thrift value,
+// it is used for messages produced as json (for example from the UI) in the same way avro messages are
+func (s *thriftSchema) validateJsonValue(fieldType *thriftType, value interface{}, depth int) error {
+	if depth > thriftMaxDepth {
+		return errors.New("the message is nested too deeply")
+	}
+	fieldType = s.underlying(fieldType)
+	switch fieldType.Name {
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%v is not a boolean", value)
+		}
+	case "byte", "i8", "i16", "i32", "i64":
+		bits := map[string]int{"byte": 8, "i8": 8, "i16": 16, "i32": 32, "i64": 64}[fieldType.Name]
+		num, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		if _, err := strconv.ParseInt(num.String(), 10, bits); err != nil {
+			return fmt.Errorf("%v is not a valid %v", value, fieldType.Name)
+		}
+	case "double":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		if _, err := num.Float64(); err != nil {
+			return fmt.Errorf("%v is not a valid double", value)
+		}
+	case "string", "uuid":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+	case "binary":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a base64 string", value)
+		}
+		if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+			return fmt.Errorf("%v is not a base64 string", value)
+		}
+	case "list", "set":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an array", value)
+		}
+		for _, item := range items {
+			if err := s.validateJsonValue(fieldType.Elem, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case "map":
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an object", value)
+		}
+		for key, entry := range entries {
+			if err := s.validateJsonMapKey(fieldType.Key, key, depth+1); err != nil {
+				return err
+			}
+			if err := s.validateJsonValue(fieldType.Elem, entry, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		if enum, ok := s.Enums[fieldType.Name]; ok {
+			switch v := value.(type) {
+			case string:
+				if _, ok := enum.Values[v]; ok {
+					return nil
+				}
+			case json.Number:
+				for _, enumValue := range enum.Values {
+					if v.String() == strconv.Itoa(int(enumValue)) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("%v is not a valid value of enum %v", value, enum.Name)
+		}
+		return s.validateJsonStruct(s.Structs[fieldType.Name], value, depth+1)
+	}
+	return nil
+}
+
+// validateJsonMapKey validates a json object key against the map key type, json keys are always strings
+// so numbers, booleans and enum values are parsed from the key
+func (s *thriftSchema) validateJsonMapKey(keyType *thriftType, key string, depth int) error {
+	keyType = s.underlying(keyType)
+	switch keyType.Name {
+	case "bool":
+		if key != "true" && key != "false" {
+			return fmt.Errorf("map key %v is not a boolean", key)
+		}
+		return nil
+	case "byte", "i8", "i16", "i32", "i64", "double":
+		return s.validateJsonValue(keyType, json.Number(key), depth)
+	}
+	if _, ok := s.Enums[keyType.Name]; ok {
+		if _, err := strconv.ParseInt(key, 10, 32); err == nil {
+			return s.validateJsonValue(keyType, json.Number(key), depth)
+		}
+	}
+	return s.validateJsonValue(keyType, key, depth)
+}
+
+func (s *thriftSchema) validateJsonStruct(st *thriftStruct, value interface{}, depth int) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%v: %v is not an object", st.Name, value)
+	}
+	fields := make(map[string]*thriftField, len(st.Fields))
+	for _, field := range st.Fields {
+		fields[field.Name] = field
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("%v: unknown field %v", st.Name, key)
+		}
+		if obj[key] == nil {
+			if field.Required {
+				return fmt.Errorf("%v: required field %v is missing", st.Name, field.Name)
+			}
+			continue
+		}
+		err := s.validateJsonValue(field.Type, obj[key], depth)
+		if err != nil {
+			return fmt.Errorf("%v.%v: %v", st.Name, field.Name, err.Error())
+		}
+	}
+	return s.checkStructFields(st, obj)
+}
+
+// validateThriftMessage accepts messages encoded with the thrift binary protocol, or their json representation,
+// a binary struct can never start with '{' since it is not a valid field type id
+func (s *thriftSchema) validateThriftMessage(st *thriftStruct, msg []byte) error {
+	trimmed := strings.TrimSpace(string(msg))
+	if strings.HasPrefix(trimmed, "{") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err != nil {
+			return fmt.Errorf("invalid json: %v", err.Error())
+		}
+		return s.validateJsonStruct(st, value, 0)
+	}
+	_, err := s.decodeThriftMessage(st, msg)
+	return err
+}
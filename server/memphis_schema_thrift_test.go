@@ -0,0 +1,261 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// thriftTestField encodes a field header followed by its already encoded value
+func thriftTestField(typeId byte, id int16, value ...byte) []byte {
+	b := []byte{typeId, 0, 0}
+	binary.BigEndian.PutUint16(b[1:], uint16(id))
+	return append(b, value...)
+}
+
+func thriftTestI32(v int32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+func thriftTestString(v string) []byte {
+	return append(thriftTestI32(int32(len(v))), v...)
+}
+
+func thriftTestStruct(fields ...[]byte) []byte {
+	var b []byte
+	for _, field := range fields {
+		b = append(b, field...)
+	}
+	return append(b, thriftTypeStop)
+}
+
+func thriftTestList(elemType byte, size int32, elems ...[]byte) []byte {
+	b := append([]byte{elemType}, thriftTestI32(size)...)
+	for _, elem := range elems {
+		b = append(b, elem...)
+	}
+	return b
+}
+
+func thriftTestMap(keyType, valueType byte, size int32, entries ...[]byte) []byte {
+	return append([]byte{keyType}, thriftTestList(valueType, size, entries...)...)
+}
+
+func TestThriftSchemaErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{name: "no structs", schema: `enum Status { ACTIVE }`, err: "at least one struct"},
+		{name: "include", schema: `include "shared.thrift" struct A { 1: i32 id }`, err: "includes are not supported"},
+		{name: "missing field id", schema: `struct A { i32 id }`, err: "must have an explicit id"},
+		{name: "invalid field id", schema: `struct A { 0: i32 id }`, err: "invalid field id"},
+		{name: "duplicate field id", schema: `struct A { 1: i32 id, 1: string name }`, err: "duplicate field id"},
+		{name: "duplicate field name", schema: `struct A { 1: i32 id, 2: string id }`, err: "duplicate field name"},
+		{name: "duplicate definition", schema: `struct A { 1: i32 id } enum A { X }`, err: "defined more than once"},
+		{name: "duplicate enum value", schema: `enum Status { ACTIVE, ACTIVE } struct A { 1: Status s }`, err: "duplicate value"},
+		{name: "invalid enum value", schema: `enum Status { ACTIVE = x } struct A { 1: Status s }`, err: "invalid value"},
+		{name: "unknown type", schema: `struct A { 1: Missing m }`, err: "unknown type Missing"},
+		{name: "unknown container element", schema: `struct A { 1: map<string, list<Missing>> m }`, err: "unknown type Missing"},
+		{name: "unclosed container", schema: `struct A { 1: list<i32 ids }`, err: "expected >"},
+		{name: "typedef cycle", schema: `typedef B A typedef A B struct C { 1: A a }`, err: "typedef cycle"},
+		{name: "required union field", schema: `union U { 1: required i32 a, 2: string b }`, err: "can not have required fields"},
+		{name: "unterminated comment", schema: `/* struct A { 1: i32 id }`, err: "unterminated comment"},
+		{name: "unterminated string", schema: `const string X = "a struct A { 1: i32 id }`, err: "unterminated string"},
+		{name: "unexpected token", schema: `struct A { 1: i32 id } message B {}`, err: "unexpected message"},
+		{name: "unexpected character", schema: `struct A { 1: i32 id @ }`, err: "unexpected character"},
+	}
+	for _, c := range cases {
+		err := validateThriftSchemaContent(c.schema)
+		if err == nil {
+			t.Errorf("%v: expected an error", c.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v: expected an error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestThriftSchemaParsing(t *testing.T) {
+	schemaContent := `
+		namespace go orders
+		# services and constants are parsed but not kept
+		const i32 MAX_ITEMS = 100
+		const map<string, i32> LIMITS = {"a": 1, "b": 2}
+		typedef i64 Timestamp
+		enum Status { PENDING, PAID = 5, SHIPPED } (annotation = "x")
+		struct Item { 1: required string sku; 2: optional i32 quantity = 1 }
+		union Payment { 1: string card, 2: double cash }
+		exception OrderError { 1: string message }
+		service Orders extends Base { Order get(1: i64 id) throws (1: OrderError err) }
+		struct Order {
+			1: required i64 id,
+			2: Timestamp created_at,
+			3: list<Item> items,
+			4: set<string> tags,
+			5: map<string, list<i32>> counters,
+			6: optional Payment payment,
+			7: Status status,
+		}`
+	schema, err := parseThriftSchema(schemaContent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schema.Namespaces["go"] != "orders" {
+		t.Errorf("expected the go namespace to be orders, got %v", schema.Namespaces)
+	}
+	status := schema.Enums["Status"].Values
+	if status["PENDING"] != 0 || status["PAID"] != 5 || status["SHIPPED"] != 6 {
+		t.Errorf("unexpected enum values %v", status)
+	}
+	if schema.Structs["Payment"].Kind != "union" || schema.Structs["OrderError"].Kind != "exception" {
+		t.Errorf("unexpected struct kinds")
+	}
+	order := schema.Structs["Order"]
+	if len(order.Fields) != 7 || !order.Fields[0].Required || order.Fields[5].Required {
+		t.Errorf("unexpected fields of Order")
+	}
+	counters := order.Fields[4].Type
+	if counters.Name != "map" || counters.Key.Name != "string" || counters.Elem.Name != "list" || counters.Elem.Elem.Name != "i32" {
+		t.Errorf("unexpected type of counters %+v", counters)
+	}
+	if name, err := getThriftMessageStructName(schemaContent); err != nil || name != "Order" {
+		t.Errorf("expected the message struct to be Order, got %v %v", name, err)
+	}
+	if err := validateThriftMessageStructName(schemaContent, "Missing"); err == nil {
+		t.Errorf("expected an error for an unknown message struct")
+	}
+}
+
+func TestThriftJsonMessages(t *testing.T) {
+	schema, err := parseThriftSchema(`
+		enum Status { PENDING = 1, PAID = 2 }
+		struct Item { 1: required string sku, 2: optional i16 quantity }
+		union Payment { 1: string card, 2: double cash }
+		struct Order {
+			1: required i64 id,
+			2: list<Item> items,
+			3: set<string> tags,
+			4: map<i32, Status> history,
+			5: optional Payment payment,
+			6: binary signature,
+			7: map<bool, string> flags,
+		}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := schema.Structs["Order"]
+	cases := []struct {
+		name  string
+		msg   string
+		valid bool
+	}{
+		{name: "required only", msg: `{"id": 1}`, valid: true},
+		{name: "missing required", msg: `{"items": []}`, valid: false},
+		{name: "null required", msg: `{"id": null}`, valid: false},
+		{name: "null optional", msg: `{"id": 1, "payment": null}`, valid: true},
+		{name: "unknown field", msg: `{"id": 1, "note": "x"}`, valid: false},
+		{name: "i64 range", msg: `{"id": 9223372036854775808}`, valid: false},
+		{name: "list of structs", msg: `{"id": 1, "items": [{"sku": "a", "quantity": 2}, {"sku": "b"}]}`, valid: true},
+		{name: "nested missing required", msg: `{"id": 1, "items": [{"quantity": 2}]}`, valid: false},
+		{name: "i16 range", msg: `{"id": 1, "items": [{"sku": "a", "quantity": 40000}]}`, valid: false},
+		{name: "list not an array", msg: `{"id": 1, "items": {"sku": "a"}}`, valid: false},
+		{name: "set", msg: `{"id": 1, "tags": ["a", "b"]}`, valid: true},
+		{name: "set element type", msg: `{"id": 1, "tags": ["a", 2]}`, valid: false},
+		{name: "map with enum names and numbers", msg: `{"id": 1, "history": {"1": "PENDING", "2": 2}}`, valid: true},
+		{name: "map key type", msg: `{"id": 1, "history": {"first": "PENDING"}}`, valid: false},
+		{name: "map invalid enum", msg: `{"id": 1, "history": {"1": 3}}`, valid: false},
+		{name: "map bool keys", msg: `{"id": 1, "flags": {"true": "a", "false": "b"}}`, valid: true},
+		{name: "map invalid bool key", msg: `{"id": 1, "flags": {"yes": "a"}}`, valid: false},
+		{name: "union", msg: `{"id": 1, "payment": {"cash": 10.5}}`, valid: true},
+		{name: "union with a null member", msg: `{"id": 1, "payment": {"card": "visa", "cash": null}}`, valid: true},
+		{name: "union with two members", msg: `{"id": 1, "payment": {"card": "visa", "cash": 10}}`, valid: false},
+		{name: "empty union", msg: `{"id": 1, "payment": {}}`, valid: false},
+		{name: "binary", msg: `{"id": 1, "signature": "c2lnbmVk"}`, valid: true},
+		{name: "invalid binary", msg: `{"id": 1, "signature": "not base64!"}`, valid: false},
+		{name: "invalid json", msg: `{"id": 1,}`, valid: false},
+	}
+	for _, c := range cases {
+		err := schema.validateThriftMessage(order, []byte(c.msg))
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid to be %v, got %v", c.name, c.valid, err)
+		}
+	}
+}
+
+func TestThriftBinaryMessages(t *testing.T) {
+	schema, err := parseThriftSchema(`
+		enum Status { PENDING = 1, PAID = 2 }
+		union Payment { 1: string card, 2: i32 cash }
+		struct Order {
+			1: required i32 id,
+			2: list<string> tags,
+			3: map<string, i32> counters,
+			4: optional Payment payment,
+			5: Status status,
+		}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := schema.Structs["Order"]
+	id := thriftTestField(thriftTypeI32, 1, thriftTestI32(7)...)
+	tags := thriftTestField(thriftTypeList, 2, thriftTestList(thriftTypeString, 2, thriftTestString("a"), thriftTestString("b"))...)
+	counters := thriftTestField(thriftTypeMap, 3, thriftTestMap(thriftTypeString, thriftTypeI32, 1, thriftTestString("a"), thriftTestI32(3))...)
+	card := thriftTestField(thriftTypeString, 1, thriftTestString("visa")...)
+	cash := thriftTestField(thriftTypeI32, 2, thriftTestI32(10)...)
+
+	valid, err := schema.decodeThriftMessage(order, thriftTestStruct(
+		id, tags, counters,
+		thriftTestField(thriftTypeStruct, 4, thriftTestStruct(card)...),
+		thriftTestField(thriftTypeI32, 5, thriftTestI32(2)...),
+		thriftTestField(thriftTypeI64, 9, make([]byte, 8)...),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid["id"] != int32(7) || valid["status"] != "PAID" || len(valid["tags"].([]interface{})) != 2 || valid["counters"].(map[string]interface{})["a"] != int32(3) {
+		t.Errorf("unexpected decoded message %v", valid)
+	}
+	if payment := valid["payment"].(map[string]interface{}); payment["card"] != "visa" {
+		t.Errorf("unexpected decoded union %v", payment)
+	}
+
+	cases := []struct {
+		name string
+		msg  []byte
+	}{
+		{name: "missing required", msg: thriftTestStruct(tags)},
+		{name: "field type mismatch", msg: thriftTestStruct(thriftTestField(thriftTypeString, 1, thriftTestString("7")...))},
+		{name: "list element type mismatch", msg: thriftTestStruct(id, thriftTestField(thriftTypeList, 2, thriftTestList(thriftTypeI32, 1, thriftTestI32(1))...))},
+		{name: "map value type mismatch", msg: thriftTestStruct(id, thriftTestField(thriftTypeMap, 3, thriftTestMap(thriftTypeString, thriftTypeString, 1, thriftTestString("a"), thriftTestString("b"))...))},
+		{name: "union with two members", msg: thriftTestStruct(id, thriftTestField(thriftTypeStruct, 4, thriftTestStruct(card, cash)...))},
+		{name: "empty union", msg: thriftTestStruct(id, thriftTestField(thriftTypeStruct, 4, thriftTestStruct()...))},
+		{name: "invalid enum value", msg: thriftTestStruct(id, thriftTestField(thriftTypeI32, 5, thriftTestI32(3)...))},
+		{name: "oversized list", msg: thriftTestStruct(id, thriftTestField(thriftTypeList, 2, thriftTestList(thriftTypeString, 1000)...))},
+		{name: "negative string size", msg: thriftTestStruct(id, thriftTestField(thriftTypeList, 2, thriftTestList(thriftTypeString, 1, thriftTestI32(-1))...))},
+		{name: "missing stop", msg: id},
+		{name: "trailing data", msg: append(thriftTestStruct(id), 0)},
+		{name: "unknown type id", msg: thriftTestStruct(id, thriftTestField(1, 9))},
+	}
+	for _, c := range cases {
+		if _, err := schema.decodeThriftMessage(order, c.msg); err == nil {
+			t.Errorf("%v: expected an error", c.name)
+		}
+	}
+}
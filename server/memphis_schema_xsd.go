@@ -0,0 +1,1204 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+const xsdMaxDepth = 64
+
+// xmlNode is a generic representation of an xml element used both for parsing xsd files and the validated documents
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []xmlNode  `xml:",any"`
+	Content string     `xml:",chardata"`
+}
+
+func (n xmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name && attr.Name.Space == _EMPTY_ {
+			return attr.Value, true
+		}
+	}
+	return _EMPTY_, false
+}
+
+// xsdSchema is the compiled subset of an xml schema which is supported by memphis:
+// top level elements, named and anonymous complex/simple types, sequence/choice/all groups,
+// occurrence constraints, attributes, restrictions (enumeration, pattern, length, bounds and digits),
+// the target namespace and the built-in types, imports and includes are not supported
+type xsdSchema struct {
+	TargetNamespace      string                     `json:"target_namespace,omitempty"`
+	ElementFormQualified bool                       `json:"element_form_qualified,omitempty"`
+	Elements             map[string]*xsdElement     `json:"elements"`
+	ComplexTypes         map[string]*xsdComplexType `json:"complex_types,omitempty"`
+	SimpleTypes          map[string]*xsdSimpleType  `json:"simple_types,omitempty"`
+}
+
+type xsdElement struct {
+	Name        string          `json:"name"`
+	Namespace   string          `json:"namespace,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	ComplexType *xsdComplexType `json:"complex_type,omitempty"`
+	SimpleType  *xsdSimpleType  `json:"simple_type,omitempty"`
+	Nillable    bool            `json:"nillable,omitempty"`
+	form        string
+}
+
+type xsdParticle struct {
+	Kind      string         `json:"kind"`
+	Element   *xsdElement    `json:"element,omitempty"`
+	Ref       string         `json:"ref,omitempty"`
+	Particles []*xsdParticle `json:"particles,omitempty"`
+	MinOccurs int            `json:"min_occurs"`
+	MaxOccurs int            `json:"max_occurs"` // -1 means unbounded
+}
+
+type xsdComplexType struct {
+	Name         string          `json:"name,omitempty"`
+	Mixed        bool            `json:"mixed,omitempty"`
+	Base         string          `json:"base,omitempty"`
+	SimpleBase   string          `json:"simple_base,omitempty"`
+	Content      *xsdParticle    `json:"content,omitempty"`
+	Attributes   []*xsdAttribute `json:"attributes,omitempty"`
+	AnyAttribute bool            `json:"any_attribute,omitempty"`
+}
+
+type xsdAttribute struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type,omitempty"`
+	SimpleType *xsdSimpleType `json:"simple_type,omitempty"`
+	Required   bool           `json:"required,omitempty"`
+}
+
+type xsdSimpleType struct {
+	Name           string   `json:"name,omitempty"`
+	Base           string   `json:"base"`
+	List           bool     `json:"list,omitempty"`
+	Enumeration    []string `json:"enumeration,omitempty"`
+	Patterns       []string `json:"patterns,omitempty"`
+	MinLength      *int     `json:"min_length,omitempty"`
+	MaxLength      *int     `json:"max_length,omitempty"`
+	MinInclusive   string   `json:"min_inclusive,omitempty"`
+	MaxInclusive   string   `json:"max_inclusive,omitempty"`
+	MinExclusive   string   `json:"min_exclusive,omitempty"`
+	MaxExclusive   string   `json:"max_exclusive,omitempty"`
+	TotalDigits    *int     `json:"total_digits,omitempty"`
+	FractionDigits *int     `json:"fraction_digits,omitempty"`
+	patterns       []*regexp.Regexp
+}
+
+var xsdBuiltinTypes = map[string]func(value string) error{
+	"anyType":            nil,
+	"anySimpleType":      nil,
+	"string":             nil,
+	"normalizedString":   nil,
+	"token":              nil,
+	"language":           nil,
+	"Name":               nil,
+	"NCName":             nil,
+	"ID":                 nil,
+	"IDREF":              nil,
+	"IDREFS":             nil,
+	"ENTITY":             nil,
+	"ENTITIES":           nil,
+	"NMTOKEN":            nil,
+	"NMTOKENS":           nil,
+	"QName":              nil,
+	"NOTATION":           nil,
+	"anyURI":             nil,
+	"duration":           nil,
+	"gYearMonth":         nil,
+	"gYear":              nil,
+	"gMonthDay":          nil,
+	"gDay":               nil,
+	"gMonth":             nil,
+	"boolean":            validateXsdBoolean,
+	"decimal":            validateXsdDecimal,
+	"float":              validateXsdFloat,
+	"double":             validateXsdFloat,
+	"integer":            xsdIntegerValidator(nil, nil),
+	"nonPositiveInteger": xsdIntegerValidator(nil, big.NewInt(0)),
+	"negativeInteger":    xsdIntegerValidator(nil, big.NewInt(-1)),
+	"nonNegativeInteger": xsdIntegerValidator(big.NewInt(0), nil),
+	"positiveInteger":    xsdIntegerValidator(big.NewInt(1), nil),
+	"long":               xsdIntegerValidator(big.NewInt(-1<<63), big.NewInt(1<<63-1)),
+	"int":                xsdIntegerValidator(big.NewInt(-1<<31), big.NewInt(1<<31-1)),
+	"short":              xsdIntegerValidator(big.NewInt(-1<<15), big.NewInt(1<<15-1)),
+	"byte":               xsdIntegerValidator(big.NewInt(-1<<7), big.NewInt(1<<7-1)),
+	"unsignedLong":       xsdIntegerValidator(big.NewInt(0), new(big.Int).SetUint64(1<<64-1)),
+	"unsignedInt":        xsdIntegerValidator(big.NewInt(0), big.NewInt(1<<32-1)),
+	"unsignedShort":      xsdIntegerValidator(big.NewInt(0), big.NewInt(1<<16-1)),
+	"unsignedByte":       xsdIntegerValidator(big.NewInt(0), big.NewInt(1<<8-1)),
+	"date":               xsdTimeValidator("2006-01-02", "2006-01-02Z07:00"),
+	"time":               xsdTimeValidator("15:04:05", "15:04:05Z07:00", "15:04:05.999999999", "15:04:05.999999999Z07:00"),
+	"dateTime":           xsdTimeValidator("2006-01-02T15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05.999999999", time.RFC3339Nano),
+	"base64Binary": func(value string) error {
+		_, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), _EMPTY_))
+		return err
+	},
+	"hexBinary": func(value string) error {
+		_, err := hex.DecodeString(value)
+		return err
+	},
+}
+
+func validateXsdBoolean(value string) error {
+	switch value {
+	case "true", "false", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("%v is not a valid boolean", value)
+}
+
+func validateXsdDecimal(value string) error {
+	if _, ok := new(big.Float).SetString(value); !ok || strings.ContainsAny(value, "eExX") {
+		return fmt.Errorf("%v is not a valid decimal", value)
+	}
+	return nil
+}
+
+func validateXsdFloat(value string) error {
+	switch value {
+	case "INF", "-INF", "+INF", "NaN":
+		return nil
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return fmt.Errorf("%v is not a valid floating point number", value)
+	}
+	return nil
+}
+
+func xsdIntegerValidator(min, max *big.Int) func(value string) error {
+	return func(value string) error {
+		num, ok := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+		if !ok {
+			return fmt.Errorf("%v is not a valid integer", value)
+		}
+		if (min != nil && num.Cmp(min) < 0) || (max != nil && num.Cmp(max) > 0) {
+			return fmt.Errorf("%v is out of range", value)
+		}
+		return nil
+	}
+}
+
+func xsdTimeValidator(layouts ...string) func(value string) error {
+	return func(value string) error {
+		for _, layout := range layouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not in a valid format", value)
+	}
+}
+
+// xsdLocalName strips the namespace prefix of a qualified name
+func xsdLocalName(qname string) string {
+	if idx := strings.LastIndex(qname, ":"); idx >= 0 {
+		return qname[idx+1:]
+	}
+	return qname
+}
+
+func parseXmlNode(content []byte) (xmlNode, error) {
+	var root xmlNode
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	err := decoder.Decode(&root)
+	if err != nil {
+		return root, err
+	}
+	// only whitespaces, comments and processing instructions are allowed after the root element
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return root, err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return root, errors.New("unexpected content after the root element")
+			}
+		case xml.StartElement:
+			return root, errors.New("xml documents must have a single root element")
+		}
+	}
+	return root, nil
+}
+
+func parseXsdSchema(schemaContent string) (*xsdSchema, error) {
+	root, err := parseXmlNode([]byte(schemaContent))
+	if err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "schema" || root.XMLName.Space != xsdNamespace {
+		return nil, fmt.Errorf("the root element must be schema in the %v namespace", xsdNamespace)
+	}
+
+	schema := &xsdSchema{
+		Elements:     make(map[string]*xsdElement),
+		ComplexTypes: make(map[string]*xsdComplexType),
+		SimpleTypes:  make(map[string]*xsdSimpleType),
+	}
+	schema.TargetNamespace, _ = root.attr("targetNamespace")
+	if elementForm, _ := root.attr("elementFormDefault"); elementForm == "qualified" {
+		schema.ElementFormQualified = true
+	}
+	for _, node := range root.Nodes {
+		if node.XMLName.Space != xsdNamespace {
+			return nil, fmt.Errorf("unexpected element %v", node.XMLName.Local)
+		}
+		switch node.XMLName.Local {
+		case "element":
+			element, err := parseXsdElement(node)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := schema.Elements[element.Name]; ok {
+				return nil, fmt.Errorf("element %v is defined more than once", element.Name)
+			}
+			schema.Elements[element.Name] = element
+		case "complexType":
+			complexType, err := parseXsdComplexType(node)
+			if err != nil {
+				return nil, err
+			}
+			if complexType.Name == _EMPTY_ {
+				return nil, errors.New("top level complexType must have a name")
+			}
+			if _, ok := schema.ComplexTypes[complexType.Name]; ok {
+				return nil, fmt.Errorf("complexType %v is defined more than once", complexType.Name)
+			}
+			schema.ComplexTypes[complexType.Name] = complexType
+		case "simpleType":
+			simpleType, err := parseXsdSimpleType(node)
+			if err != nil {
+				return nil, err
+			}
+			if simpleType.Name == _EMPTY_ {
+				return nil, errors.New("top level simpleType must have a name")
+			}
+			if _, ok := schema.SimpleTypes[simpleType.Name]; ok {
+				return nil, fmt.Errorf("simpleType %v is defined more than once", simpleType.Name)
+			}
+			schema.SimpleTypes[simpleType.Name] = simpleType
+		case "annotation":
+		case "import", "include", "redefine", "override":
+			return nil, fmt.Errorf("%v is not supported, all the definitions should be in a single schema", node.XMLName.Local)
+		default:
+			return nil, fmt.Errorf("%v is not supported", node.XMLName.Local)
+		}
+	}
+	if len(schema.Elements) == 0 {
+		return nil, errors.New("the schema must define at least one top level element")
+	}
+
+	err = schema.resolve()
+	if err != nil {
+		return nil, err
+	}
+	schema.assignNamespaces()
+	return schema, nil
+}
+
+func parseXsdOccurs(node xmlNode) (int, int, error) {
+	min, max := 1, 1
+	if value, ok := node.attr("minOccurs"); ok {
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 0 {
+			return 0, 0, fmt.Errorf("invalid minOccurs %v", value)
+		}
+		min = num
+	}
+	if value, ok := node.attr("maxOccurs"); ok {
+		if value == "unbounded" {
+			max = -1
+		} else {
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 0 {
+				return 0, 0, fmt.Errorf("invalid maxOccurs %v", value)
+			}
+			max = num
+		}
+	}
+	if max != -1 && max < min {
+		return 0, 0, errors.New("maxOccurs must be greater than or equal to minOccurs")
+	}
+	return min, max, nil
+}
+
+func parseXsdElement(node xmlNode) (*xsdElement, error) {
+	name, _ := node.attr("name")
+	if name == _EMPTY_ {
+		return nil, errors.New("element must have a name")
+	}
+	element := &xsdElement{Name: name}
+	if typeName, ok := node.attr("type"); ok {
+		element.Type = xsdLocalName(typeName)
+	}
+	if nillable, _ := node.attr("nillable"); nillable == "true" {
+		element.Nillable = true
+	}
+	element.form, _ = node.attr("form")
+	for _, child := range node.Nodes {
+		var err error
+		switch child.XMLName.Local {
+		case "complexType":
+			element.ComplexType, err = parseXsdComplexType(child)
+		case "simpleType":
+			element.SimpleType, err = parseXsdSimpleType(child)
+		case "annotation", "unique", "key", "keyref":
+		default:
+			err = fmt.Errorf("unexpected %v in element %v", child.XMLName.Local, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	inlineTypes := 0
+	if element.ComplexType != nil {
+		inlineTypes++
+	}
+	if element.SimpleType != nil {
+		inlineTypes++
+	}
+	if inlineTypes > 1 || (inlineTypes == 1 && element.Type != _EMPTY_) {
+		return nil, fmt.Errorf("element %v must have a single type", name)
+	}
+	if inlineTypes == 0 && element.Type == _EMPTY_ {
+		element.Type = "anyType"
+	}
+	return element, nil
+}
+
+func parseXsdParticle(node xmlNode) (*xsdParticle, error) {
+	min, max, err := parseXsdOccurs(node)
+	if err != nil {
+		return nil, err
+	}
+	particle := &xsdParticle{Kind: node.XMLName.Local, MinOccurs: min, MaxOccurs: max}
+	switch particle.Kind {
+	case "element":
+		if ref, ok := node.attr("ref"); ok {
+			particle.Ref = xsdLocalName(ref)
+			return particle, nil
+		}
+		particle.Element, err = parseXsdElement(node)
+		if err != nil {
+			return nil, err
+		}
+	case "sequence", "choice", "all":
+		for _, child := range node.Nodes {
+			if child.XMLName.Local == "annotation" {
+				continue
+			}
+			if particle.Kind == "all" && child.XMLName.Local != "element" {
+				return nil, errors.New("all may only contain elements")
+			}
+			inner, err := parseXsdParticle(child)
+			if err != nil {
+				return nil, err
+			}
+			particle.Particles = append(particle.Particles, inner)
+		}
+	case "any":
+	case "group":
+		return nil, errors.New("model groups are not supported")
+	default:
+		return nil, fmt.Errorf("unexpected %v in content model", particle.Kind)
+	}
+	return particle, nil
+}
+
+func parseXsdAttribute(node xmlNode) (*xsdAttribute, error) {
+	name, _ := node.attr("name")
+	if name == _EMPTY_ {
+		if _, ok := node.attr("ref"); ok {
+			return nil, errors.New("attribute references are not supported")
+		}
+		return nil, errors.New("attribute must have a name")
+	}
+	attribute := &xsdAttribute{Name: name, Type: "string"}
+	if typeName, ok := node.attr("type"); ok {
+		attribute.Type = xsdLocalName(typeName)
+	}
+	if use, _ := node.attr("use"); use == "required" {
+		attribute.Required = true
+	}
+	for _, child := range node.Nodes {
+		switch child.XMLName.Local {
+		case "simpleType":
+			simpleType, err := parseXsdSimpleType(child)
+			if err != nil {
+				return nil, err
+			}
+			attribute.SimpleType = simpleType
+			attribute.Type = _EMPTY_
+		case "annotation":
+		default:
+			return nil, fmt.Errorf("unexpected %v in attribute %v", child.XMLName.Local, name)
+		}
+	}
+	return attribute, nil
+}
+
+// parseXsdAttributes parses the attribute declarations of a complex type or one of its extensions
+func parseXsdAttributes(complexType *xsdComplexType, node xmlNode) error {
+	switch node.XMLName.Local {
+	case "attribute":
+		attribute, err := parseXsdAttribute(node)
+		if err != nil {
+			return err
+		}
+		complexType.Attributes = append(complexType.Attributes, attribute)
+	case "anyAttribute":
+		complexType.AnyAttribute = true
+	case "attributeGroup":
+		return errors.New("attribute groups are not supported")
+	}
+	return nil
+}
+
+func parseXsdComplexType(node xmlNode) (*xsdComplexType, error) {
+	complexType := &xsdComplexType{}
+	complexType.Name, _ = node.attr("name")
+	if mixed, _ := node.attr("mixed"); mixed == "true" {
+		complexType.Mixed = true
+	}
+	for _, child := range node.Nodes {
+		switch child.XMLName.Local {
+		case "sequence", "choice", "all":
+			if complexType.Content != nil {
+				return nil, errors.New("complexType may only have a single content model")
+			}
+			content, err := parseXsdParticle(child)
+			if err != nil {
+				return nil, err
+			}
+			complexType.Content = content
+		case "attribute", "anyAttribute", "attributeGroup":
+			err := parseXsdAttributes(complexType, child)
+			if err != nil {
+				return nil, err
+			}
+		case "simpleContent", "complexContent":
+			for _, derivation := range child.Nodes {
+				if derivation.XMLName.Local == "annotation" {
+					continue
+				}
+				if derivation.XMLName.Local != "extension" {
+					return nil, fmt.Errorf("%v is not supported in %v", derivation.XMLName.Local, child.XMLName.Local)
+				}
+				base, _ := derivation.attr("base")
+				if base == _EMPTY_ {
+					return nil, errors.New("extension must have a base type")
+				}
+				if child.XMLName.Local == "simpleContent" {
+					complexType.SimpleBase = xsdLocalName(base)
+				} else {
+					complexType.Base = xsdLocalName(base)
+				}
+				for _, extChild := range derivation.Nodes {
+					switch extChild.XMLName.Local {
+					case "sequence", "choice", "all":
+						if child.XMLName.Local == "simpleContent" {
+							return nil, errors.New("simpleContent may not have a content model")
+						}
+						content, err := parseXsdParticle(extChild)
+						if err != nil {
+							return nil, err
+						}
+						complexType.Content = content
+					case "annotation":
+					default:
+						err := parseXsdAttributes(complexType, extChild)
+						if err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		case "annotation":
+		default:
+			return nil, fmt.Errorf("unexpected %v in complexType", child.XMLName.Local)
+		}
+	}
+	return complexType, nil
+}
+
+func parseXsdSimpleType(node xmlNode) (*xsdSimpleType, error) {
+	simpleType := &xsdSimpleType{Base: "string"}
+	simpleType.Name, _ = node.attr("name")
+	for _, child := range node.Nodes {
+		switch child.XMLName.Local {
+		case "restriction":
+			if base, ok := child.attr("base"); ok {
+				simpleType.Base = xsdLocalName(base)
+			}
+			for _, facet := range child.Nodes {
+				value, _ := facet.attr("value")
+				switch facet.XMLName.Local {
+				case "enumeration":
+					simpleType.Enumeration = append(simpleType.Enumeration, value)
+				case "pattern":
+					// xsd patterns are implicitly anchored
+					re, err := regexp.Compile("^(?:" + value + ")$")
+					if err != nil {
+						return nil, fmt.Errorf("invalid pattern %v: %v", value, err.Error())
+					}
+					simpleType.Patterns = append(simpleType.Patterns, value)
+					simpleType.patterns = append(simpleType.patterns, re)
+				case "length", "minLength", "maxLength":
+					num, err := strconv.Atoi(value)
+					if err != nil || num < 0 {
+						return nil, fmt.Errorf("invalid %v %v", facet.XMLName.Local, value)
+					}
+					if facet.XMLName.Local != "maxLength" {
+						simpleType.MinLength = &num
+					}
+					if facet.XMLName.Local != "minLength" {
+						simpleType.MaxLength = &num
+					}
+				case "minInclusive", "maxInclusive", "minExclusive", "maxExclusive":
+					// bounds are supported for numeric types only
+					if _, ok := new(big.Float).SetString(value); !ok {
+						return nil, fmt.Errorf("invalid %v %v, bounds are supported for numeric types only", facet.XMLName.Local, value)
+					}
+					switch facet.XMLName.Local {
+					case "minInclusive":
+						simpleType.MinInclusive = value
+					case "maxInclusive":
+						simpleType.MaxInclusive = value
+					case "minExclusive":
+						simpleType.MinExclusive = value
+					case "maxExclusive":
+						simpleType.MaxExclusive = value
+					}
+				case "totalDigits", "fractionDigits":
+					num, err := strconv.Atoi(value)
+					if err != nil || num < 0 || (num == 0 && facet.XMLName.Local == "totalDigits") {
+						return nil, fmt.Errorf("invalid %v %v", facet.XMLName.Local, value)
+					}
+					if facet.XMLName.Local == "totalDigits" {
+						simpleType.TotalDigits = &num
+					} else {
+						simpleType.FractionDigits = &num
+					}
+				case "whiteSpace", "annotation":
+					// values are validated after their whitespaces are collapsed
+				default:
+					return nil, fmt.Errorf("%v restriction is not supported", facet.XMLName.Local)
+				}
+			}
+		case "list":
+			simpleType.List = true
+			if itemType, ok := child.attr("itemType"); ok {
+				simpleType.Base = xsdLocalName(itemType)
+			}
+		case "union":
+			// union members are not resolved, the value is validated as a string
+			simpleType.Base = "string"
+		case "annotation":
+		default:
+			return nil, fmt.Errorf("unexpected %v in simpleType", child.XMLName.Local)
+		}
+	}
+	return simpleType, nil
+}
+
+// resolve makes sure every type and element reference points to a definition
+func (s *xsdSchema) resolve() error {
+	var resolveElement func(element *xsdElement) error
+	var resolveComplexType func(complexType *xsdComplexType) error
+	var resolveParticle func(particle *xsdParticle) error
+
+	resolveSimpleType := func(simpleType *xsdSimpleType) error {
+		if _, ok := s.SimpleTypes[simpleType.Base]; ok {
+			return nil
+		}
+		if _, ok := xsdBuiltinTypes[simpleType.Base]; ok {
+			return nil
+		}
+		return fmt.Errorf("unknown simple type %v", simpleType.Base)
+	}
+	resolveParticle = func(particle *xsdParticle) error {
+		if particle.Ref != _EMPTY_ {
+			if _, ok := s.Elements[particle.Ref]; !ok {
+				return fmt.Errorf("unknown element %v", particle.Ref)
+			}
+			return nil
+		}
+		if particle.Element != nil {
+			return resolveElement(particle.Element)
+		}
+		for _, inner := range particle.Particles {
+			err := resolveParticle(inner)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	resolveComplexType = func(complexType *xsdComplexType) error {
+		if complexType.Base != _EMPTY_ {
+			if _, ok := s.ComplexTypes[complexType.Base]; !ok && complexType.Base != "anyType" {
+				return fmt.Errorf("unknown complex type %v", complexType.Base)
+			}
+		}
+		if complexType.SimpleBase != _EMPTY_ {
+			err := resolveSimpleType(&xsdSimpleType{Base: complexType.SimpleBase})
+			if err != nil {
+				return err
+			}
+		}
+		for _, attribute := range complexType.Attributes {
+			simpleType := attribute.SimpleType
+			if simpleType == nil {
+				simpleType = &xsdSimpleType{Base: attribute.Type}
+			}
+			err := resolveSimpleType(simpleType)
+			if err != nil {
+				return err
+			}
+		}
+		if complexType.Content != nil {
+			return resolveParticle(complexType.Content)
+		}
+		return nil
+	}
+	resolveElement = func(element *xsdElement) error {
+		switch {
+		case element.ComplexType != nil:
+			return resolveComplexType(element.ComplexType)
+		case element.SimpleType != nil:
+			return resolveSimpleType(element.SimpleType)
+		}
+		if _, ok := s.ComplexTypes[element.Type]; ok {
+			return nil
+		}
+		if err := resolveSimpleType(&xsdSimpleType{Base: element.Type}); err != nil {
+			return fmt.Errorf("element %v has an unknown type %v", element.Name, element.Type)
+		}
+		return nil
+	}
+
+	for _, element := range s.Elements {
+		err := resolveElement(element)
+		if err != nil {
+			return err
+		}
+	}
+	for _, complexType := range s.ComplexTypes {
+		err := resolveComplexType(complexType)
+		if err != nil {
+			return err
+		}
+	}
+	for _, simpleType := range s.SimpleTypes {
+		err := resolveSimpleType(simpleType)
+		if err != nil {
+			return err
+		}
+	}
+	return s.checkDerivationCycles()
+}
+
+func (s *xsdSchema) checkDerivationCycles() error {
+	for name := range s.ComplexTypes {
+		seen := map[string]bool{}
+		for current := name; current != _EMPTY_; {
+			if seen[current] {
+				return fmt.Errorf("complex type %v is derived from itself", name)
+			}
+			seen[current] = true
+			complexType, ok := s.ComplexTypes[current]
+			if !ok {
+				break
+			}
+			current = complexType.Base
+		}
+	}
+	for name := range s.SimpleTypes {
+		seen := map[string]bool{}
+		for current := name; current != _EMPTY_; {
+			if seen[current] {
+				return fmt.Errorf("simple type %v is derived from itself", name)
+			}
+			seen[current] = true
+			simpleType, ok := s.SimpleTypes[current]
+			if !ok {
+				break
+			}
+			current = simpleType.Base
+		}
+	}
+	return nil
+}
+
+// assignNamespaces sets the namespace every element is expected in, top level elements are in the target namespace
+// and local elements are only in it when they are qualified
+func (s *xsdSchema) assignNamespaces() {
+	var assignParticle func(particle *xsdParticle)
+	var assignComplexType func(complexType *xsdComplexType)
+	assignElement := func(element *xsdElement, topLevel bool) {
+		qualified := element.form == "qualified" || (element.form == _EMPTY_ && s.ElementFormQualified)
+		if topLevel || qualified {
+			element.Namespace = s.TargetNamespace
+		}
+		if element.ComplexType != nil {
+			assignComplexType(element.ComplexType)
+		}
+	}
+	assignParticle = func(particle *xsdParticle) {
+		if particle.Element != nil {
+			assignElement(particle.Element, false)
+		}
+		for _, inner := range particle.Particles {
+			assignParticle(inner)
+		}
+	}
+	assignComplexType = func(complexType *xsdComplexType) {
+		if complexType.Content != nil {
+			assignParticle(complexType.Content)
+		}
+	}
+
+	for _, element := range s.Elements {
+		assignElement(element, true)
+	}
+	for _, complexType := range s.ComplexTypes {
+		assignComplexType(complexType)
+	}
+}
+
+func validateXsdSchemaContent(schemaContent string) error {
+	_, err := parseXsdSchema(schemaContent)
+	if err != nil {
+		return fmt.Errorf("your XSD file is invalid: %v", err.Error())
+	}
+	return nil
+}
+
+func generateXsdDescriptor(schemaContent string) ([]byte, error) {
+	schema, err := parseXsdSchema(schemaContent)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(schema)
+}
+
+// Validate checks that the xml document conforms to one of the schema top level elements
+func (s *xsdSchema) Validate(msg []byte) error {
+	root, err := parseXmlNode(msg)
+	if err != nil {
+		return fmt.Errorf("invalid xml: %v", err.Error())
+	}
+	element, ok := s.Elements[root.XMLName.Local]
+	if !ok {
+		return fmt.Errorf("root element %v is not declared in the schema", root.XMLName.Local)
+	}
+	if root.XMLName.Space != element.Namespace {
+		return fmt.Errorf("root element %v must be in the namespace %q but is in %q", root.XMLName.Local, element.Namespace, root.XMLName.Space)
+	}
+	return s.validateElement(element, root, 0)
+}
+
+func (s *xsdSchema) validateElement(element *xsdElement, node xmlNode, depth int) error {
+	if depth > xsdMaxDepth {
+		return errors.New("the document is nested too deeply")
+	}
+	if element.Nillable {
+		for _, attr := range node.Attrs {
+			if attr.Name.Local == "nil" && attr.Value == "true" {
+				if len(node.Nodes) > 0 || strings.TrimSpace(node.Content) != _EMPTY_ {
+					return fmt.Errorf("element %v is nil but has content", element.Name)
+				}
+				return nil
+			}
+		}
+	}
+
+	complexType := element.ComplexType
+	simpleType := element.SimpleType
+	if complexType == nil && simpleType == nil {
+		if named, ok := s.ComplexTypes[element.Type]; ok {
+			complexType = named
+		} else if element.Type != "anyType" {
+			simpleType = &xsdSimpleType{Base: element.Type}
+		}
+	}
+
+	if complexType != nil {
+		err := s.validateComplexType(complexType, node, depth)
+		if err != nil {
+			return fmt.Errorf("%v: %v", element.Name, err.Error())
+		}
+		return nil
+	}
+	if simpleType == nil {
+		// anyType accepts any content
+		return nil
+	}
+	if len(node.Nodes) > 0 {
+		return fmt.Errorf("%v: element has a simple type and can not contain child elements", element.Name)
+	}
+	err := s.validateSimpleValue(simpleType, node.Content)
+	if err != nil {
+		return fmt.Errorf("%v: %v", element.Name, err.Error())
+	}
+	return nil
+}
+
+// flatten returns the attributes and content model of a complex type including the ones inherited from its base types
+func (s *xsdSchema) flatten(complexType *xsdComplexType) ([]*xsdAttribute, *xsdParticle, bool, string) {
+	attributes := complexType.Attributes
+	content := complexType.Content
+	anyAttribute := complexType.AnyAttribute
+	simpleBase := complexType.SimpleBase
+	if base, ok := s.ComplexTypes[complexType.Base]; ok {
+		baseAttributes, baseContent, baseAnyAttribute, baseSimple := s.flatten(base)
+		attributes = append(append([]*xsdAttribute{}, baseAttributes...), attributes...)
+		anyAttribute = anyAttribute || baseAnyAttribute
+		if simpleBase == _EMPTY_ {
+			simpleBase = baseSimple
+		}
+		switch {
+		case baseContent == nil:
+		case content == nil:
+			content = baseContent
+		default:
+			content = &xsdParticle{Kind: "sequence", Particles: []*xsdParticle{baseContent, content}, MinOccurs: 1, MaxOccurs: 1}
+		}
+	}
+	return attributes, content, anyAttribute, simpleBase
+}
+
+func (s *xsdSchema) validateComplexType(complexType *xsdComplexType, node xmlNode, depth int) error {
+	attributes, content, anyAttribute, simpleBase := s.flatten(complexType)
+
+	declared := make(map[string]*xsdAttribute, len(attributes))
+	for _, attribute := range attributes {
+		declared[attribute.Name] = attribute
+	}
+	present := make(map[string]bool, len(node.Attrs))
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == _EMPTY_ && attr.Name.Local == "xmlns") || attr.Name.Space == "http://www.w3.org/2001/XMLSchema-instance" {
+			continue
+		}
+		attribute, ok := declared[attr.Name.Local]
+		if !ok {
+			if anyAttribute {
+				continue
+			}
+			return fmt.Errorf("attribute %v is not declared", attr.Name.Local)
+		}
+		present[attr.Name.Local] = true
+		simpleType := attribute.SimpleType
+		if simpleType == nil {
+			simpleType = &xsdSimpleType{Base: attribute.Type}
+		}
+		err := s.validateSimpleValue(simpleType, attr.Value)
+		if err != nil {
+			return fmt.Errorf("attribute %v: %v", attr.Name.Local, err.Error())
+		}
+	}
+	for _, attribute := range attributes {
+		if attribute.Required && !present[attribute.Name] {
+			return fmt.Errorf("attribute %v is required", attribute.Name)
+		}
+	}
+
+	if simpleBase != _EMPTY_ {
+		if len(node.Nodes) > 0 {
+			return errors.New("element has simple content and can not contain child elements")
+		}
+		return s.validateSimpleValue(&xsdSimpleType{Base: simpleBase}, node.Content)
+	}
+	if !complexType.Mixed && strings.TrimSpace(node.Content) != _EMPTY_ {
+		return errors.New("text content is not allowed")
+	}
+	if content == nil {
+		if len(node.Nodes) > 0 {
+			return fmt.Errorf("unexpected element %v", node.Nodes[0].XMLName.Local)
+		}
+		return nil
+	}
+	pos, err := s.matchParticle(content, node.Nodes, 0, depth)
+	if err != nil {
+		return err
+	}
+	if pos < len(node.Nodes) {
+		return fmt.Errorf("unexpected element %v", node.Nodes[pos].XMLName.Local)
+	}
+	return nil
+}
+
+// matchParticle greedily matches the children starting at pos against the particle and returns the position after the match,
+// greedy matching is enough since xsd requires content models to be deterministic (unique particle attribution)
+func (s *xsdSchema) matchParticle(particle *xsdParticle, nodes []xmlNode, pos, depth int) (int, error) {
+	count := 0
+	for particle.MaxOccurs == -1 || count < particle.MaxOccurs {
+		next, matched, err := s.matchParticleOnce(particle, nodes, pos, depth)
+		if err != nil {
+			return pos, err
+		}
+		if !matched || (next == pos && count >= particle.MinOccurs) {
+			break
+		}
+		pos = next
+		count++
+	}
+	if count < particle.MinOccurs {
+		return pos, s.missingParticleError(particle, nodes, pos)
+	}
+	return pos, nil
+}
+
+// xsdMismatchError is returned when the children do not match a required particle,
+// unlike validation errors of matched elements it may be recovered from by an enclosing choice or optional sequence
+type xsdMismatchError struct {
+	msg string
+}
+
+func (e *xsdMismatchError) Error() string {
+	return e.msg
+}
+
+func isXsdMismatch(err error) bool {
+	var mismatch *xsdMismatchError
+	return errors.As(err, &mismatch)
+}
+
+func (s *xsdSchema) missingParticleError(particle *xsdParticle, nodes []xmlNode, pos int) error {
+	// a missing sequence is reported by its first element
+	for particle.Kind == "sequence" && len(particle.Particles) > 0 {
+		particle = particle.Particles[0]
+	}
+	expected := particle.Kind
+	switch {
+	case particle.Ref != _EMPTY_:
+		expected = particle.Ref
+	case particle.Element != nil:
+		expected = particle.Element.Name
+	}
+	if pos < len(nodes) {
+		return &xsdMismatchError{msg: fmt.Sprintf("expected %v but found %v", expected, nodes[pos].XMLName.Local)}
+	}
+	return &xsdMismatchError{msg: fmt.Sprintf("missing %v", expected)}
+}
+
+func (s *xsdSchema) matchParticleOnce(particle *xsdParticle, nodes []xmlNode, pos, depth int) (int, bool, error) {
+	switch particle.Kind {
+	case "element":
+		element := particle.Element
+		if particle.Ref != _EMPTY_ {
+			element = s.Elements[particle.Ref]
+		}
+		if pos >= len(nodes) || nodes[pos].XMLName.Local != element.Name || nodes[pos].XMLName.Space != element.Namespace {
+			return pos, false, nil
+		}
+		err := s.validateElement(element, nodes[pos], depth+1)
+		if err != nil {
+			return pos, false, err
+		}
+		return pos + 1, true, nil
+	case "any":
+		if pos >= len(nodes) {
+			return pos, false, nil
+		}
+		return pos + 1, true, nil
+	case "sequence":
+		start := pos
+		for _, inner := range particle.Particles {
+			next, err := s.matchParticle(inner, nodes, pos, depth)
+			if err != nil {
+				// a sequence which did not start matching is simply absent
+				if pos == start && isXsdMismatch(err) {
+					return start, false, nil
+				}
+				return start, false, err
+			}
+			pos = next
+		}
+		return pos, true, nil
+	case "choice":
+		for _, inner := range particle.Particles {
+			next, err := s.matchParticle(inner, nodes, pos, depth)
+			if err != nil {
+				if next != pos || !isXsdMismatch(err) {
+					return pos, false, err
+				}
+				continue
+			}
+			if next > pos {
+				return next, true, nil
+			}
+		}
+		for _, inner := range particle.Particles {
+			if inner.MinOccurs == 0 {
+				return pos, true, nil
+			}
+		}
+		return pos, false, nil
+	case "all":
+		matched := make(map[*xsdParticle]bool, len(particle.Particles))
+		for pos < len(nodes) {
+			found := false
+			for _, inner := range particle.Particles {
+				if matched[inner] {
+					continue
+				}
+				next, ok, err := s.matchParticleOnce(inner, nodes, pos, depth)
+				if err != nil {
+					return pos, false, err
+				}
+				if ok {
+					matched[inner] = true
+					pos = next
+					found = true
+					break
+				}
+			}
+			if !found {
+				break
+			}
+		}
+		for _, inner := range particle.Particles {
+			if !matched[inner] && inner.MinOccurs > 0 {
+				return pos, false, s.missingParticleError(inner, nodes, pos)
+			}
+		}
+		return pos, true, nil
+	}
+	return pos, false, fmt.Errorf("unsupported content model %v", particle.Kind)
+}
+
+func (s *xsdSchema) validateSimpleValue(simpleType *xsdSimpleType, value string) error {
+	for depth := 0; simpleType != nil; depth++ {
+		if depth > xsdMaxDepth {
+			return errors.New("simple type derivation is too deep")
+		}
+		if simpleType.List {
+			for _, item := range strings.Fields(value) {
+				err := s.validateSimpleValue(&xsdSimpleType{Base: simpleType.Base}, item)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if simpleType.Base != "string" && simpleType.Base != "normalizedString" {
+			value = strings.TrimSpace(value)
+		}
+		if len(simpleType.Enumeration) > 0 {
+			found := false
+			for _, option := range simpleType.Enumeration {
+				if option == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%v is not one of the allowed values %v", value, strings.Join(simpleType.Enumeration, ", "))
+			}
+		}
+		for i, re := range simpleType.patterns {
+			if !re.MatchString(value) {
+				return fmt.Errorf("%v does not match the pattern %v", value, simpleType.Patterns[i])
+			}
+		}
+		length := len([]rune(value))
+		if simpleType.MinLength != nil && length < *simpleType.MinLength {
+			return fmt.Errorf("%v is shorter than %v", value, *simpleType.MinLength)
+		}
+		if simpleType.MaxLength != nil && length > *simpleType.MaxLength {
+			return fmt.Errorf("%v is longer than %v", value, *simpleType.MaxLength)
+		}
+		err := validateXsdNumericFacets(simpleType, value)
+		if err != nil {
+			return err
+		}
+
+		if builtin, ok := xsdBuiltinTypes[simpleType.Base]; ok {
+			if _, userDefined := s.SimpleTypes[simpleType.Base]; !userDefined {
+				if builtin != nil {
+					return builtin(value)
+				}
+				return nil
+			}
+		}
+		simpleType = s.SimpleTypes[simpleType.Base]
+	}
+	return nil
+}
+
+// validateXsdNumericFacets validates the bounds and digits restrictions of a simple type
+func validateXsdNumericFacets(simpleType *xsdSimpleType, value string) error {
+	bounds := []struct {
+		bound   string
+		allowed func(cmp int) bool
+		message string
+	}{
+		{bound: simpleType.MinInclusive, allowed: func(cmp int) bool { return cmp >= 0 }, message: "smaller than"},
+		{bound: simpleType.MaxInclusive, allowed: func(cmp int) bool { return cmp <= 0 }, message: "greater than"},
+		{bound: simpleType.MinExclusive, allowed: func(cmp int) bool { return cmp > 0 }, message: "smaller than or equal to"},
+		{bound: simpleType.MaxExclusive, allowed: func(cmp int) bool { return cmp < 0 }, message: "greater than or equal to"},
+	}
+	for _, b := range bounds {
+		if b.bound == _EMPTY_ {
+			continue
+		}
+		num, ok := new(big.Float).SetString(value)
+		if !ok {
+			return fmt.Errorf("%v is not a valid number", value)
+		}
+		bound, _ := new(big.Float).SetString(b.bound)
+		if !b.allowed(num.Cmp(bound)) {
+			return fmt.Errorf("%v is %v %v", value, b.message, b.bound)
+		}
+	}
+	if simpleType.TotalDigits == nil && simpleType.FractionDigits == nil {
+		return nil
+	}
+	if validateXsdDecimal(value) != nil {
+		return fmt.Errorf("%v is not a valid decimal", value)
+	}
+	integer, fraction, _ := strings.Cut(strings.TrimLeft(value, "+-"), ".")
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if simpleType.FractionDigits != nil && len(fraction) > *simpleType.FractionDigits {
+		return fmt.Errorf("%v has more than %v fraction digits", value, *simpleType.FractionDigits)
+	}
+	if simpleType.TotalDigits != nil && len(integer)+len(fraction) > *simpleType.TotalDigits {
+		return fmt.Errorf("%v has more than %v digits", value, *simpleType.TotalDigits)
+	}
+	return nil
+}
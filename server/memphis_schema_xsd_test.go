@@ -0,0 +1,381 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestXsdAndThriftValidators(t *testing.T) {
+	xsdContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="order">
+			<xs:complexType>
+				<xs:sequence>
+					<xs:element name="id" type="xs:int"/>
+					<xs:element name="tag" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+				</xs:sequence>
+				<xs:attribute name="currency" type="xs:string" use="required"/>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`
+	thriftContent := `enum Status { ACTIVE = 1, INACTIVE = 2 }
+		struct User { 1: required i32 id, 2: optional string name, 3: Status status }`
+
+	cases := []struct {
+		name       string
+		schemaType string
+		msg        string
+		valid      bool
+	}{
+		{name: "xsd valid", schemaType: "xsd", msg: `<order currency="usd"><id>1</id><tag>a</tag><tag>b</tag></order>`, valid: true},
+		{name: "xsd missing attribute", schemaType: "xsd", msg: `<order><id>1</id></order>`, valid: false},
+		{name: "xsd invalid int", schemaType: "xsd", msg: `<order currency="usd"><id>one</id></order>`, valid: false},
+		{name: "xsd unknown element", schemaType: "xsd", msg: `<order currency="usd"><id>1</id><note/></order>`, valid: false},
+		{name: "thrift binary", schemaType: "thrift", msg: "\x08\x00\x01\x00\x00\x00\x07\x0b\x00\x02\x00\x00\x00\x03bob\x00", valid: true},
+		{name: "thrift binary missing required", schemaType: "thrift", msg: "\x0b\x00\x02\x00\x00\x00\x03bob\x00", valid: false},
+		{name: "thrift binary truncated", schemaType: "thrift", msg: "\x08\x00\x01\x00\x00", valid: false},
+		{name: "thrift json", schemaType: "thrift", msg: `{"id": 1, "status": "ACTIVE"}`, valid: true},
+		{name: "thrift json invalid enum", schemaType: "thrift", msg: `{"id": 1, "status": "DELETED"}`, valid: false},
+	}
+
+	for _, c := range cases {
+		schemaVersion := models.SchemaVersion{SchemaContent: xsdContent}
+		if c.schemaType == "thrift" {
+			schemaVersion = models.SchemaVersion{SchemaContent: thriftContent, MessageStructName: "User"}
+		}
+		validator, err := compileSchemaValidator(c.schemaType, schemaVersion)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		err = validator([]byte(c.msg))
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid to be %v, got %v", c.name, c.valid, err)
+		}
+	}
+}
+
+type xsdTestCase struct {
+	name  string
+	msg   string
+	valid bool
+}
+
+func runXsdTestCases(t *testing.T, schemaContent string, cases []xsdTestCase) {
+	t.Helper()
+	schema, err := parseXsdSchema(schemaContent)
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+	for _, c := range cases {
+		err := schema.Validate([]byte(c.msg))
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid to be %v, got %v", c.name, c.valid, err)
+		}
+	}
+}
+
+func TestXsdSchemaErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{
+			name:   "not xml",
+			schema: `{"type": "object"}`,
+			err:    "",
+		},
+		{
+			name:   "wrong root",
+			schema: `<schema><element name="a" type="string"/></schema>`,
+			err:    "the root element must be schema",
+		},
+		{
+			name:   "no elements",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:simpleType name="a"><xs:restriction base="xs:string"/></xs:simpleType></xs:schema>`,
+			err:    "at least one top level element",
+		},
+		{
+			name:   "import",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:import namespace="urn:other" schemaLocation="other.xsd"/><xs:element name="a" type="xs:string"/></xs:schema>`,
+			err:    "import is not supported",
+		},
+		{
+			name:   "include",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:include schemaLocation="types.xsd"/><xs:element name="a" type="xs:string"/></xs:schema>`,
+			err:    "include is not supported",
+		},
+		{
+			name:   "redefine",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:redefine schemaLocation="types.xsd"/><xs:element name="a" type="xs:string"/></xs:schema>`,
+			err:    "redefine is not supported",
+		},
+		{
+			name:   "duplicate element",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="xs:string"/><xs:element name="a" type="xs:int"/></xs:schema>`,
+			err:    "defined more than once",
+		},
+		{
+			name:   "unknown type",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="Missing"/></xs:schema>`,
+			err:    "unknown type",
+		},
+		{
+			name:   "unknown element reference",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:complexType><xs:sequence><xs:element ref="b"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+			err:    "unknown element b",
+		},
+		{
+			name:   "invalid max occurs",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:complexType><xs:sequence><xs:element name="b" type="xs:string" minOccurs="2" maxOccurs="1"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+			err:    "maxOccurs must be greater than or equal to minOccurs",
+		},
+		{
+			name:   "negative min occurs",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:complexType><xs:sequence><xs:element name="b" type="xs:string" minOccurs="-1"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+			err:    "invalid minOccurs",
+		},
+		{
+			name:   "model group",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:group name="g"><xs:sequence><xs:element name="b" type="xs:string"/></xs:sequence></xs:group><xs:element name="a" type="xs:string"/></xs:schema>`,
+			err:    "group is not supported",
+		},
+		{
+			name:   "invalid pattern",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:simpleType><xs:restriction base="xs:string"><xs:pattern value="[a-"/></xs:restriction></xs:simpleType></xs:element></xs:schema>`,
+			err:    "invalid pattern",
+		},
+		{
+			name:   "unsupported facet",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:simpleType><xs:restriction base="xs:string"><xs:assertion test="true()"/></xs:restriction></xs:simpleType></xs:element></xs:schema>`,
+			err:    "assertion restriction is not supported",
+		},
+		{
+			name:   "non numeric bound",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:simpleType><xs:restriction base="xs:date"><xs:minInclusive value="2020-01-01"/></xs:restriction></xs:simpleType></xs:element></xs:schema>`,
+			err:    "bounds are supported for numeric types only",
+		},
+		{
+			name:   "invalid length",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:simpleType><xs:restriction base="xs:string"><xs:maxLength value="many"/></xs:restriction></xs:simpleType></xs:element></xs:schema>`,
+			err:    "invalid maxLength",
+		},
+		{
+			name:   "derivation cycle",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:simpleType name="a"><xs:restriction base="b"/></xs:simpleType><xs:simpleType name="b"><xs:restriction base="a"/></xs:simpleType><xs:element name="e" type="a"/></xs:schema>`,
+			err:    "derived from itself",
+		},
+	}
+	for _, c := range cases {
+		err := validateXsdSchemaContent(c.schema)
+		if err == nil {
+			t.Errorf("%v: expected an error", c.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v: expected an error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestXsdNamespaces(t *testing.T) {
+	unqualified := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:orders" targetNamespace="urn:orders">
+		<xs:complexType name="Order">
+			<xs:sequence>
+				<xs:element name="id" type="xs:int"/>
+				<xs:element name="note" type="xs:string" form="qualified" minOccurs="0"/>
+			</xs:sequence>
+		</xs:complexType>
+		<xs:element name="order" type="tns:Order"/>
+	</xs:schema>`
+	runXsdTestCases(t, unqualified, []xsdTestCase{
+		{name: "prefixed root", msg: `<o:order xmlns:o="urn:orders"><id>1</id></o:order>`, valid: true},
+		{name: "default namespace root with unqualified child", msg: `<order xmlns="urn:orders"><id xmlns="">1</id></order>`, valid: true},
+		{name: "qualified form child", msg: `<o:order xmlns:o="urn:orders"><id>1</id><o:note>x</o:note></o:order>`, valid: true},
+		{name: "unqualified form child in the namespace", msg: `<order xmlns="urn:orders"><id>1</id></order>`, valid: false},
+		{name: "qualified child outside the namespace", msg: `<o:order xmlns:o="urn:orders"><id>1</id><note>x</note></o:order>`, valid: false},
+		{name: "root without namespace", msg: `<order><id>1</id></order>`, valid: false},
+		{name: "root in another namespace", msg: `<o:order xmlns:o="urn:other"><id>1</id></o:order>`, valid: false},
+	})
+
+	qualified := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:orders" elementFormDefault="qualified">
+		<xs:element name="order">
+			<xs:complexType>
+				<xs:sequence>
+					<xs:element name="id" type="xs:int"/>
+					<xs:element name="note" type="xs:string" form="unqualified" minOccurs="0"/>
+				</xs:sequence>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`
+	runXsdTestCases(t, qualified, []xsdTestCase{
+		{name: "default namespace", msg: `<order xmlns="urn:orders"><id>1</id></order>`, valid: true},
+		{name: "unqualified form child", msg: `<order xmlns="urn:orders"><id>1</id><note xmlns="">x</note></order>`, valid: true},
+		{name: "unqualified child", msg: `<o:order xmlns:o="urn:orders"><id>1</id></o:order>`, valid: false},
+		{name: "unqualified form child in the namespace", msg: `<order xmlns="urn:orders"><id>1</id><note>x</note></order>`, valid: false},
+	})
+
+	noNamespace := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="order" type="xs:string"/></xs:schema>`
+	runXsdTestCases(t, noNamespace, []xsdTestCase{
+		{name: "no namespace", msg: `<order>1</order>`, valid: true},
+		{name: "unexpected namespace", msg: `<order xmlns="urn:orders">1</order>`, valid: false},
+	})
+}
+
+func TestXsdFacets(t *testing.T) {
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:simpleType name="Currency">
+			<xs:restriction base="xs:string">
+				<xs:enumeration value="usd"/>
+				<xs:enumeration value="eur"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="Sku">
+			<xs:restriction base="xs:string">
+				<xs:pattern value="[A-Z]{3}-[0-9]+"/>
+				<xs:maxLength value="8"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="Code">
+			<xs:restriction base="xs:string">
+				<xs:length value="2"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="Quantity">
+			<xs:restriction base="xs:int">
+				<xs:minInclusive value="1"/>
+				<xs:maxExclusive value="100"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="Discount">
+			<xs:restriction base="xs:decimal">
+				<xs:minExclusive value="0"/>
+				<xs:maxInclusive value="0.5"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="Price">
+			<xs:restriction base="xs:decimal">
+				<xs:totalDigits value="5"/>
+				<xs:fractionDigits value="2"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="SmallQuantity">
+			<xs:restriction base="Quantity">
+				<xs:maxInclusive value="10"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:element name="item">
+			<xs:complexType>
+				<xs:all>
+					<xs:element name="currency" type="Currency" minOccurs="0"/>
+					<xs:element name="sku" type="Sku" minOccurs="0"/>
+					<xs:element name="code" type="Code" minOccurs="0"/>
+					<xs:element name="quantity" type="Quantity" minOccurs="0"/>
+					<xs:element name="discount" type="Discount" minOccurs="0"/>
+					<xs:element name="price" type="Price" minOccurs="0"/>
+					<xs:element name="small" type="SmallQuantity" minOccurs="0"/>
+				</xs:all>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`
+	runXsdTestCases(t, schemaContent, []xsdTestCase{
+		{name: "enumeration", msg: `<item><currency>eur</currency></item>`, valid: true},
+		{name: "enumeration mismatch", msg: `<item><currency>gbp</currency></item>`, valid: false},
+		{name: "pattern", msg: `<item><sku>ABC-12</sku></item>`, valid: true},
+		{name: "pattern mismatch", msg: `<item><sku>abc-12</sku></item>`, valid: false},
+		{name: "pattern is anchored", msg: `<item><sku>XABC-12</sku></item>`, valid: false},
+		{name: "max length", msg: `<item><sku>ABC-12345</sku></item>`, valid: false},
+		{name: "length", msg: `<item><code>il</code></item>`, valid: true},
+		{name: "length mismatch", msg: `<item><code>isr</code></item>`, valid: false},
+		{name: "min inclusive", msg: `<item><quantity>1</quantity></item>`, valid: true},
+		{name: "below min inclusive", msg: `<item><quantity>0</quantity></item>`, valid: false},
+		{name: "below max exclusive", msg: `<item><quantity>99</quantity></item>`, valid: true},
+		{name: "max exclusive", msg: `<item><quantity>100</quantity></item>`, valid: false},
+		{name: "bounds keep the base type", msg: `<item><quantity>1.5</quantity></item>`, valid: false},
+		{name: "min exclusive", msg: `<item><discount>0</discount></item>`, valid: false},
+		{name: "above min exclusive", msg: `<item><discount>0.01</discount></item>`, valid: true},
+		{name: "max inclusive", msg: `<item><discount>0.5</discount></item>`, valid: true},
+		{name: "above max inclusive", msg: `<item><discount>0.51</discount></item>`, valid: false},
+		{name: "digits", msg: `<item><price>123.45</price></item>`, valid: true},
+		{name: "trailing zeros are not digits", msg: `<item><price>-123.4500</price></item>`, valid: true},
+		{name: "fraction digits", msg: `<item><price>1.234</price></item>`, valid: false},
+		{name: "total digits", msg: `<item><price>12345.6</price></item>`, valid: false},
+		{name: "derived restriction", msg: `<item><small>10</small></item>`, valid: true},
+		{name: "derived restriction bound", msg: `<item><small>11</small></item>`, valid: false},
+		{name: "base restriction bound", msg: `<item><small>0</small></item>`, valid: false},
+	})
+}
+
+func TestXsdContentModels(t *testing.T) {
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="shipment">
+			<xs:complexType>
+				<xs:sequence>
+					<xs:element name="id" type="xs:int"/>
+					<xs:choice maxOccurs="unbounded">
+						<xs:element name="box" type="xs:string"/>
+						<xs:element name="pallet" type="xs:string"/>
+					</xs:choice>
+					<xs:sequence minOccurs="0" maxOccurs="2">
+						<xs:element name="from" type="xs:string"/>
+						<xs:element name="to" type="xs:string"/>
+					</xs:sequence>
+					<xs:element name="note" type="xs:string" minOccurs="1" maxOccurs="2"/>
+				</xs:sequence>
+			</xs:complexType>
+		</xs:element>
+		<xs:element name="contact">
+			<xs:complexType>
+				<xs:all>
+					<xs:element name="name" type="xs:string"/>
+					<xs:element name="email" type="xs:string" minOccurs="0"/>
+				</xs:all>
+			</xs:complexType>
+		</xs:element>
+		<xs:element name="payment">
+			<xs:complexType>
+				<xs:choice>
+					<xs:element name="card" type="xs:string"/>
+					<xs:element name="cash" type="xs:string"/>
+				</xs:choice>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`
+	runXsdTestCases(t, schemaContent, []xsdTestCase{
+		{name: "single choice", msg: `<shipment><id>1</id><box>a</box><note>n</note></shipment>`, valid: true},
+		{name: "repeated choice", msg: `<shipment><id>1</id><box>a</box><pallet>b</pallet><box>c</box><note>n</note></shipment>`, valid: true},
+		{name: "missing choice", msg: `<shipment><id>1</id><note>n</note></shipment>`, valid: false},
+		{name: "optional sequence", msg: `<shipment><id>1</id><box>a</box><from>x</from><to>y</to><note>n</note></shipment>`, valid: true},
+		{name: "repeated sequence", msg: `<shipment><id>1</id><box>a</box><from>x</from><to>y</to><from>y</from><to>z</to><note>n</note></shipment>`, valid: true},
+		{name: "sequence over max occurs", msg: `<shipment><id>1</id><box>a</box><from>x</from><to>y</to><from>y</from><to>z</to><from>z</from><to>w</to><note>n</note></shipment>`, valid: false},
+		{name: "incomplete sequence", msg: `<shipment><id>1</id><box>a</box><from>x</from><note>n</note></shipment>`, valid: false},
+		{name: "element max occurs", msg: `<shipment><id>1</id><box>a</box><note>n</note><note>m</note></shipment>`, valid: true},
+		{name: "element over max occurs", msg: `<shipment><id>1</id><box>a</box><note>n</note><note>m</note><note>o</note></shipment>`, valid: false},
+		{name: "element under min occurs", msg: `<shipment><id>1</id><box>a</box></shipment>`, valid: false},
+		{name: "wrong order", msg: `<shipment><box>a</box><id>1</id><note>n</note></shipment>`, valid: false},
+		{name: "all in any order", msg: `<contact><email>a@b.c</email><name>bob</name></contact>`, valid: true},
+		{name: "all optional member", msg: `<contact><name>bob</name></contact>`, valid: true},
+		{name: "all missing member", msg: `<contact><email>a@b.c</email></contact>`, valid: false},
+		{name: "all repeated member", msg: `<contact><name>bob</name><name>alice</name></contact>`, valid: false},
+		{name: "choice", msg: `<payment><cash>10</cash></payment>`, valid: true},
+		{name: "choice of both", msg: `<payment><card>1</card><cash>10</cash></payment>`, valid: false},
+		{name: "empty choice", msg: `<payment/>`, valid: false},
+		{name: "text in element only content", msg: `<payment>cash<cash>10</cash></payment>`, valid: false},
+		{name: "undeclared root", msg: `<invoice/>`, valid: false},
+		{name: "invalid xml", msg: `<payment><cash>10</payment>`, valid: false},
+		{name: "multiple roots", msg: `<payment><cash>10</cash></payment><payment/>`, valid: false},
+	})
+}
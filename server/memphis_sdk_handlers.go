@@ -13,6 +13,7 @@ package server
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/memphisdev/memphis/models"
 )
@@ -41,6 +42,19 @@ type createStationRequest struct {
 	DlsStation           string                  `json:"dls_station"`
 }
 
+// fields which are not set keep their current values
+type updateStationRequest struct {
+	StationName          string                        `json:"station_name"`
+	RetentionType        string                        `json:"retention_type"`
+	RetentionValue       *int                          `json:"retention_value"`
+	Replicas             *int                          `json:"replicas"`
+	IdempotencyWindow    *int64                        `json:"idempotency_window_in_ms"`
+	DlsConfiguration     models.UpdateDlsConfiguration `json:"dls_configuration"`
+	TieredStorageEnabled *bool                         `json:"tiered_storage_enabled"`
+	Username             string                        `json:"username"`
+	TenantName           string                        `json:"tenant_name"`
+}
+
 type destroyStationRequest struct {
 	StationName string `json:"station_name"`
 	Username    string `json:"username"`
@@ -98,6 +112,23 @@ type createConsumerResponseV1 struct {
 	Err              string                  `json:"error"`
 }
 
+type resetConsumerGroupRequest struct {
+	StationName    string     `json:"station_name"`
+	ConsumersGroup string     `json:"consumers_group"`
+	ResetTo        string     `json:"reset_to"`
+	Sequence       uint64     `json:"sequence"`
+	Time           *time.Time `json:"time"`
+	LastMessages   int64      `json:"last_messages"`
+	DryRun         bool       `json:"dry_run"`
+	Username       string     `json:"username"`
+	TenantName     string     `json:"tenant_name"`
+}
+
+type resetConsumerGroupResponse struct {
+	models.ResetConsumerGroupResponse
+	Err string `json:"error"`
+}
+
 type createProducerResponse struct {
 	SchemaUpdate                    models.SchemaUpdateInit `json:"schema_update"`
 	PartitionsUpdate                models.PartitionsUpdate `json:"partitions_update"`
@@ -236,6 +267,10 @@ func (ccr *createConsumerResponseV1) SetError(err error) {
 	ccr.Err = err.Error()
 }
 
+func (rcgr *resetConsumerGroupResponse) SetError(err error) {
+	rcgr.Err = err.Error()
+}
+
 func (csresp *SchemaResponse) SetError(err error) {
 	if err != nil {
 		csresp.Err = err.Error()
@@ -252,6 +287,9 @@ func (s *Server) initializeSDKHandlers() {
 	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_station_destructions",
 		"memphis_station_destructions_listeners_group",
 		destroyStationHandler(s))
+	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_station_updates",
+		"memphis_station_updates_listeners_group",
+		updateStationHandler(s))
 
 	// producers
 	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_producer_creations",
@@ -268,6 +306,9 @@ func (s *Server) initializeSDKHandlers() {
 	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_consumer_destructions",
 		"memphis_consumer_destructions_listeners_group",
 		destroyConsumerHandler(s))
+	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_consumer_group_resets",
+		"memphis_consumer_group_resets_listeners_group",
+		resetConsumerGroupHandler(s))
 
 	// schemas
 	s.queueSubscribe(s.MemphisGlobalAccountString(), "$memphis_schema_attachments",
@@ -300,6 +341,12 @@ func destroyStationHandler(s *Server) simplifiedMsgHandler {
 	}
 }
 
+func updateStationHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.updateStationDirect(c, reply, copyBytes(msg))
+	}
+}
+
 func createProducerHandler(s *Server) simplifiedMsgHandler {
 	return func(c *client, subject, reply string, msg []byte) {
 		go s.createProducerDirect(c, reply, copyBytes(msg))
@@ -324,6 +371,12 @@ func destroyConsumerHandler(s *Server) simplifiedMsgHandler {
 	}
 }
 
+func resetConsumerGroupHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.resetConsumerGroupDirect(c, reply, copyBytes(msg))
+	}
+}
+
 func attachSchemaHandler(s *Server) simplifiedMsgHandler {
 	return func(c *client, subject, reply string, msg []byte) {
 		go s.useSchemaDirect(c, reply, copyBytes(msg))
@@ -0,0 +1,697 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	stationArchiveFormat          = "memphis-station-archive"
+	stationArchiveFormatVersion   = 1
+	stationArchiveBatchSize       = 1000
+	stationArchiveBatchTimeout    = 2 * time.Second
+	stationArchiveOriginalTimeHdr = "$memphis_original_timestamp"
+	stationArchiveNoPartition     = 0
+)
+
+// stationArchivePartitions returns the partitions of the station in order, non partitioned stations are represented by partition 0
+func stationArchivePartitions(station models.Station) []int {
+	if len(station.PartitionsList) == 0 {
+		return []int{stationArchiveNoPartition}
+	}
+	partitions := append([]int{}, station.PartitionsList...)
+	sort.Ints(partitions)
+	return partitions
+}
+
+func stationArchiveStreamName(sn StationName, partition int) string {
+	if partition == stationArchiveNoPartition {
+		return sn.Intern()
+	}
+	return fmt.Sprintf("%v$%v", sn.Intern(), partition)
+}
+
+// resolveArchivePartition maps a partition of an archive to the partition of the target station its messages are published to
+func resolveArchivePartition(targetPartitions []int, partition int) (int, error) {
+	if len(targetPartitions) == 0 {
+		if partition > 1 {
+			return 0, fmt.Errorf("partition %v can not be imported into a station without partitions", partition)
+		}
+		return stationArchiveNoPartition, nil
+	}
+	if partition == stationArchiveNoPartition {
+		partitions := append([]int{}, targetPartitions...)
+		sort.Ints(partitions)
+		return partitions[0], nil
+	}
+	for _, p := range targetPartitions {
+		if p == partition {
+			return partition, nil
+		}
+	}
+	return 0, fmt.Errorf("partition %v does not exist on the station", partition)
+}
+
+// resolveExportRanges validates the requested ranges against the station partitions, an empty request exports every partition
+func resolveExportRanges(station models.Station, requested []models.ExportStationPartitionRange) ([]models.ExportStationPartitionRange, error) {
+	partitions := stationArchivePartitions(station)
+	if len(requested) == 0 {
+		ranges := make([]models.ExportStationPartitionRange, 0, len(partitions))
+		for _, p := range partitions {
+			ranges = append(ranges, models.ExportStationPartitionRange{PartitionNumber: p})
+		}
+		return ranges, nil
+	}
+
+	seen := make(map[int]bool)
+	ranges := make([]models.ExportStationPartitionRange, 0, len(requested))
+	for _, r := range requested {
+		found := false
+		for _, p := range partitions {
+			if p == r.PartitionNumber {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("partition %v does not exist on station %v", r.PartitionNumber, station.Name)
+		}
+		if seen[r.PartitionNumber] {
+			return nil, fmt.Errorf("partition %v was requested more than once", r.PartitionNumber)
+		}
+		if r.ToSeq > 0 && r.FromSeq > r.ToSeq {
+			return nil, fmt.Errorf("from_seq of partition %v can not be larger than its to_seq", r.PartitionNumber)
+		}
+		seen[r.PartitionNumber] = true
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].PartitionNumber < ranges[j].PartitionNumber
+	})
+	return ranges, nil
+}
+
+func stationArchiveConfig(station models.Station) models.StationArchiveConfig {
+	return models.StationArchiveConfig{
+		Name:                 station.Name,
+		RetentionType:        station.RetentionType,
+		RetentionValue:       station.RetentionValue,
+		StorageType:          station.StorageType,
+		Replicas:             station.Replicas,
+		IdempotencyWindow:    station.IdempotencyWindow,
+		DlsPoison:            station.DlsConfigurationPoison,
+		DlsSchemaverse:       station.DlsConfigurationSchemaverse,
+		TieredStorageEnabled: station.TieredStorageEnabled,
+		SchemaStrict:         station.SchemaStrict,
+		PartitionsList:       station.PartitionsList,
+	}
+}
+
+func stationArchiveSchema(station models.Station) (*models.StationArchiveSchema, error) {
+	if station.SchemaName == _EMPTY_ {
+		return nil, nil
+	}
+	exist, schema, err := db.GetSchemaByName(station.SchemaName, station.TenantName)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	exist, schemaVersion, err := db.GetSchemaVersionByNumberAndID(station.SchemaVersionNumber, schema.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	return &models.StationArchiveSchema{
+		Name:              schema.Name,
+		Type:              schema.Type,
+		VersionNumber:     schemaVersion.VersionNumber,
+		SchemaContent:     schemaVersion.SchemaContent,
+		MessageStructName: schemaVersion.MessageStructName,
+	}, nil
+}
+
+// writeStationArchiveMessages writes the messages of a single partition range in sequence order
+func (s *Server) writeStationArchiveMessages(station models.Station, sn StationName, r models.ExportStationPartitionRange, streamInfo *StreamInfo, enc *json.Encoder) (int, error) {
+	streamName := stationArchiveStreamName(sn, r.PartitionNumber)
+	filterSubj := streamName + ".final"
+	replicas := 1
+	if streamInfo.Config.Retention == InterestPolicy {
+		replicas = streamInfo.Config.Replicas
+	}
+
+	startSeq := r.FromSeq
+	if startSeq < streamInfo.State.FirstSeq {
+		startSeq = streamInfo.State.FirstSeq
+	}
+	lastSeq := streamInfo.State.LastSeq
+	if r.ToSeq > 0 && r.ToSeq < lastSeq {
+		lastSeq = r.ToSeq
+	}
+
+	written := 0
+	for startSeq <= lastSeq && streamInfo.State.Msgs > 0 {
+		amount := stationArchiveBatchSize
+		if remaining := lastSeq - startSeq + 1; remaining < uint64(amount) {
+			amount = int(remaining)
+		}
+		msgs, err := s.memphisGetMsgs(station.TenantName, filterSubj, streamName, startSeq, amount, stationArchiveBatchTimeout, true, station.RetentionType == "ack_based", replicas)
+		if err != nil {
+			return written, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		sort.Slice(msgs, func(i, j int) bool {
+			return msgs[i].Sequence < msgs[j].Sequence
+		})
+
+		for _, msg := range msgs {
+			if msg.Sequence > lastSeq {
+				break
+			}
+			startSeq = msg.Sequence + 1
+			headers := map[string]string{}
+			if len(msg.Header) > 0 {
+				headers, err = DecodeHeader(msg.Header)
+				if err != nil {
+					return written, err
+				}
+			}
+			if headers["$memphis_producedBy"] == "$memphis_dls" { // skip poison messages which have been resent
+				continue
+			}
+			err = enc.Encode(models.StationArchiveMessage{
+				Partition: r.PartitionNumber,
+				Sequence:  msg.Sequence,
+				Time:      msg.Time,
+				Headers:   headers,
+				Data:      msg.Data,
+			})
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(msgs) < amount {
+			// the rest of the sequences up to the last one were deleted
+			break
+		}
+	}
+	return written, nil
+}
+
+func (sh StationsHandler) ExportStationMessages(c *gin.Context) {
+	var body models.ExportStationMessagesSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ExportStationMessages at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "read")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ExportStationMessages at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to read from station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ExportStationMessages at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if !station.IsNative {
+		errMsg := "Export is supported only for stations created by Memphis SDKs"
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	ranges, err := resolveExportRanges(station, body.Partitions)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages at resolveExportRanges: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	streamInfos := make([]*StreamInfo, 0, len(ranges))
+	for i, r := range ranges {
+		streamInfo, err := sh.S.memphisStreamInfo(user.TenantName, stationArchiveStreamName(stationName, r.PartitionNumber))
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]ExportStationMessages at memphisStreamInfo: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		// the manifest records the actual range which is exported
+		if ranges[i].FromSeq < streamInfo.State.FirstSeq {
+			ranges[i].FromSeq = streamInfo.State.FirstSeq
+		}
+		if ranges[i].ToSeq == 0 || ranges[i].ToSeq > streamInfo.State.LastSeq {
+			ranges[i].ToSeq = streamInfo.State.LastSeq
+		}
+		streamInfos = append(streamInfos, streamInfo)
+	}
+
+	schema, err := stationArchiveSchema(station)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ExportStationMessages at stationArchiveSchema: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	manifest := models.StationArchiveManifest{
+		Format:        stationArchiveFormat,
+		FormatVersion: stationArchiveFormatVersion,
+		ExportedAt:    time.Now(),
+		Station:       stationArchiveConfig(station),
+		Schema:        schema,
+		Partitions:    ranges,
+	}
+
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%v.memphis.ndjson.gz", station.Name))
+	c.Status(200)
+	gz := gzip.NewWriter(c.Writer)
+	defer gz.Close()
+	enc := json.NewEncoder(gz)
+	err = enc.Encode(manifest)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ExportStationMessages at Encode: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		return
+	}
+
+	exported := 0
+	for i, r := range ranges {
+		written, err := sh.S.writeStationArchiveMessages(station, stationName, r, streamInfos[i], enc)
+		exported += written
+		if err != nil {
+			// the response has already started so the archive is left truncated
+			serv.Errorf("[tenant: %v][user: %v]ExportStationMessages at writeStationArchiveMessages: At station %v partition %v: %v", user.TenantName, user.Username, body.StationName, r.PartitionNumber, err.Error())
+			return
+		}
+	}
+
+	serv.Noticef("[tenant: %v][user: %v]%v messages have been exported from station %v", user.TenantName, user.Username, exported, station.Name)
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": station.Name, "messages": exported}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-export-station-messages")
+	}
+}
+
+func validateStationArchiveConfig(config *models.StationArchiveConfig) error {
+	config.RetentionType = strings.ToLower(config.RetentionType)
+	err := validateRetentionType(config.RetentionType)
+	if err != nil {
+		return err
+	}
+	config.StorageType = getStationStorageType(config.StorageType)
+	err = validateStorageType(config.StorageType)
+	if err != nil {
+		return err
+	}
+	config.Replicas = GetStationReplicas(config.Replicas)
+	err = validateReplicas(config.Replicas)
+	if err != nil {
+		return err
+	}
+	return validateIdempotencyWindow(config.RetentionType, config.RetentionValue, config.IdempotencyWindow)
+}
+
+// ensureStationArchiveSchema makes the schema of the archive available on this cluster, an existing schema with the same name is reused
+func (s *Server) ensureStationArchiveSchema(user models.User, schema *models.StationArchiveSchema) (string, int, error) {
+	if schema == nil {
+		return _EMPTY_, 0, nil
+	}
+	if !ValidataAccessToFeature(user.TenantName, "feature-schemaverse-enforcement") {
+		return _EMPTY_, 0, errors.New("schema enforcement is not available on your plan")
+	}
+
+	exist, existingSchema, err := db.GetSchemaByName(schema.Name, user.TenantName)
+	if err != nil {
+		return _EMPTY_, 0, err
+	}
+	if exist {
+		if existingSchema.Type != schema.Type {
+			return _EMPTY_, 0, fmt.Errorf("schema %v already exists with type %v", schema.Name, existingSchema.Type)
+		}
+		schemaVersion, err := getActiveVersionBySchemaId(existingSchema.ID)
+		if err != nil {
+			return _EMPTY_, 0, err
+		}
+		return existingSchema.Name, schemaVersion.VersionNumber, nil
+	}
+
+	err = validateSchemaContent(schema.SchemaContent, schema.Type)
+	if err != nil {
+		return _EMPTY_, 0, err
+	}
+	err = s.createNewSchema(CreateSchemaReq{
+		Name:              schema.Name,
+		Type:              schema.Type,
+		CreatedByUsername: user.Username,
+		SchemaContent:     schema.SchemaContent,
+		MessageStructName: schema.MessageStructName,
+	}, user.TenantName)
+	if err != nil {
+		return _EMPTY_, 0, err
+	}
+	return schema.Name, 1, nil
+}
+
+// createStationFromArchive creates the station with the configuration recorded in the archive
+func (s *Server) createStationFromArchive(sn StationName, user models.User, config models.StationArchiveConfig, schemaName string, schemaVersionNumber int) (models.Station, bool, error) {
+	partitionsNumber := len(config.PartitionsList)
+	if partitionsNumber == 0 {
+		partitionsNumber = 1
+	}
+	if config.IdempotencyWindow <= 0 {
+		config.IdempotencyWindow = 120000 // default
+	} else if config.IdempotencyWindow < 100 {
+		config.IdempotencyWindow = 100 // minimum is 100 millis
+	}
+
+	partitionsList := make([]int, 0, partitionsNumber)
+	for p := 1; p <= partitionsNumber; p++ {
+		err := s.CreateStream(user.TenantName, sn, config.RetentionType, config.RetentionValue, config.StorageType, config.IdempotencyWindow, config.Replicas, config.TieredStorageEnabled, p, true)
+		if err != nil {
+			// remove all partitions that were created
+			for _, partition := range partitionsList {
+				removeErr := s.RemoveStream(user.TenantName, stationArchiveStreamName(sn, partition))
+				if removeErr != nil {
+					s.Errorf("[tenant: %v][user: %v]createStationFromArchive at RemoveStream: Station %v: %v", user.TenantName, user.Username, sn.Ext(), removeErr.Error())
+				}
+			}
+			return models.Station{}, false, err
+		}
+		partitionsList = append(partitionsList, p)
+	}
+
+	dlsConfiguration := models.DlsConfiguration{Poison: config.DlsPoison, Schemaverse: config.DlsSchemaverse}
+	newStation, rowsUpdated, err := db.InsertNewStation(sn.Ext(), user.ID, user.Username, config.RetentionType, config.RetentionValue, config.StorageType, config.Replicas, schemaName, schemaVersionNumber, config.IdempotencyWindow, true, dlsConfiguration, config.TieredStorageEnabled, user.TenantName, partitionsList, 2, _EMPTY_)
+	if err != nil {
+		return models.Station{}, false, err
+	}
+	if rowsUpdated == 0 {
+		return models.Station{}, false, nil
+	}
+	sendStationPartitionsCacheUpdate(newStation.TenantName, sn.Intern(), newStation.PartitionsList)
+
+	err = CreateDefaultTags("station", newStation.ID, user.TenantName)
+	if err != nil {
+		return models.Station{}, false, err
+	}
+
+	if schemaName != _EMPTY_ && config.SchemaStrict {
+		err = db.UpdateStationSchemaStrict(newStation.Name, true, user.TenantName)
+		if err != nil {
+			return models.Station{}, false, err
+		}
+		newStation.SchemaStrict = true
+		sendStationSchemaCacheUpdate(user.TenantName, []string{sn.Intern()})
+	}
+
+	return newStation, true, nil
+}
+
+func readStationArchiveLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		return line, nil
+	}
+	return line, err
+}
+
+func (sh StationsHandler) ImportStationMessages(c *gin.Context) {
+	var body models.ImportStationMessagesSchema
+	err := c.ShouldBindQuery(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ImportStationMessages at getUserDetailsFromMiddleware: %v", err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	gz, err := gzip.NewReader(c.Request.Body)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at gzip.NewReader: %v", user.TenantName, user.Username, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "The archive is not a valid gzip file"})
+		return
+	}
+	defer gz.Close()
+	reader := bufio.NewReader(gz)
+
+	var manifest models.StationArchiveManifest
+	line, err := readStationArchiveLine(reader)
+	if err == nil {
+		err = json.Unmarshal(line, &manifest)
+	}
+	if err != nil || manifest.Format != stationArchiveFormat {
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: the archive does not start with a valid manifest", user.TenantName, user.Username)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "The archive does not start with a valid manifest"})
+		return
+	}
+	if manifest.FormatVersion > stationArchiveFormatVersion {
+		errMsg := fmt.Sprintf("Archive format version %v is not supported by this version of Memphis", manifest.FormatVersion)
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	name := body.StationName
+	if name == _EMPTY_ {
+		name = manifest.Station.Name
+	}
+	stationName, err := StationNameFromStr(name)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, reloadNeeded, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to write to station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if reloadNeeded {
+		defer func() {
+			err := serv.SendReloadSignal()
+			if err != nil {
+				serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at SendReloadSignal: Station %v: %v", user.TenantName, user.Username, name, err.Error())
+			}
+		}()
+	}
+
+	response := models.ImportStationMessagesResponse{StationName: stationName.Ext(), Warnings: []string{}}
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at GetStationByName: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if exist && !station.IsNative {
+		errMsg := "Import is supported only for stations created by Memphis SDKs"
+		serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: At station %v: %v", user.TenantName, user.Username, name, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if !exist {
+		err = validateStationArchiveConfig(&manifest.Station)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at validateStationArchiveConfig: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		stationsCount, err := db.CountStationsByTenant(user.TenantName)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at CountStationsByTenant: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		canCreate, stationsLimit := ValidataUsageLimitOfFeature(user.TenantName, "feature-stations-limitation", stationsCount+1)
+		if !canCreate {
+			errMsg := fmt.Sprintf("cannot create station (max amount of stations for this plan :%v)", stationsLimit)
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: %v", user.TenantName, user.Username, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+
+		schemaName, schemaVersionNumber, err := sh.S.ensureStationArchiveSchema(user, manifest.Schema)
+		if err != nil {
+			warning := fmt.Sprintf("the station was created without the schema %v of the archive: %v", manifest.Schema.Name, err.Error())
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at ensureStationArchiveSchema: At station %v: %v", user.TenantName, user.Username, name, warning)
+			response.Warnings = append(response.Warnings, warning)
+		}
+
+		station, response.StationCreated, err = sh.S.createStationFromArchive(stationName, user, manifest.Station, schemaName, schemaVersionNumber)
+		if err != nil {
+			if IsNatsErr(err, JSInsufficientResourcesErr) {
+				errMsg := "Station can not be created, probably since replicas count is larger than the cluster size"
+				serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: Station %v: %v", user.TenantName, user.Username, name, errMsg)
+				c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+				return
+			}
+			serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at createStationFromArchive: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !response.StationCreated {
+			// the station has been created concurrently
+			_, station, err = db.GetStationByName(stationName.Ext(), user.TenantName)
+			if err != nil {
+				serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at GetStationByName: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+				return
+			}
+		}
+	}
+
+	for _, r := range manifest.Partitions {
+		_, err = resolveArchivePartition(station.PartitionsList, r.PartitionNumber)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at resolveArchivePartition: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	// messages are published one by one with an ack so the order within each partition is kept
+	for {
+		line, err = readStationArchiveLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("the archive could not be read after %v messages: %v", response.ImportedMessages, err.Error())
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: At station %v: %v", user.TenantName, user.Username, name, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+		var msg models.StationArchiveMessage
+		err = json.Unmarshal(line, &msg)
+		if err != nil {
+			errMsg := fmt.Sprintf("the archive contains an invalid message after %v messages: %v", response.ImportedMessages, err.Error())
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages: At station %v: %v", user.TenantName, user.Username, name, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+		partition, err := resolveArchivePartition(station.PartitionsList, msg.Partition)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]ImportStationMessages at resolveArchivePartition: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+
+		headers := msg.Headers
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[stationArchiveOriginalTimeHdr] = msg.Time.Format(time.RFC3339Nano)
+		subject := stationArchiveStreamName(stationName, partition) + ".final"
+		err = sh.S.memphisPublishWithAck(user.TenantName, subject, headers, msg.Data)
+		if err != nil {
+			serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at memphisPublishWithAck: At station %v: imported %v messages: %v", user.TenantName, user.Username, name, response.ImportedMessages, err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": fmt.Sprintf("Server error, %v messages have been imported", response.ImportedMessages)})
+			return
+		}
+		response.ImportedMessages++
+	}
+
+	message := fmt.Sprintf("%v messages have been imported into station %v by user %v", response.ImportedMessages, stationName.Ext(), user.Username)
+	if response.StationCreated {
+		message = fmt.Sprintf("Station %v has been created from an archive and %v messages have been imported by user %v", stationName.Ext(), response.ImportedMessages, user.Username)
+	}
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]ImportStationMessages at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, name, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": stationName.Ext(), "messages": response.ImportedMessages, "station-created": response.StationCreated}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-import-station-messages")
+	}
+
+	c.IndentedJSON(200, response)
+}
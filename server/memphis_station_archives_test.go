@@ -0,0 +1,56 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestResolveArchivePartition(t *testing.T) {
+	cases := []struct {
+		name      string
+		target    []int
+		partition int
+		expected  int
+		valid     bool
+	}{
+		{name: "same partition", target: []int{1, 2, 3}, partition: 2, expected: 2, valid: true},
+		{name: "missing partition", target: []int{1, 2}, partition: 3, valid: false},
+		{name: "non partitioned source", target: []int{3, 1, 2}, partition: 0, expected: 1, valid: true},
+		{name: "non partitioned target", target: nil, partition: 1, expected: 0, valid: true},
+		{name: "non partitioned target with many partitions", target: nil, partition: 2, valid: false},
+	}
+
+	for _, c := range cases {
+		partition, err := resolveArchivePartition(c.target, c.partition)
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid %v, got %v", c.name, c.valid, err)
+			continue
+		}
+		if c.valid && partition != c.expected {
+			t.Errorf("%v: expected partition %v, got %v", c.name, c.expected, partition)
+		}
+	}
+
+	station := models.Station{Name: "orders", PartitionsList: []int{2, 1}}
+	ranges, err := resolveExportRanges(station, nil)
+	if err != nil || len(ranges) != 2 || ranges[0].PartitionNumber != 1 || ranges[1].PartitionNumber != 2 {
+		t.Errorf("expected all the partitions in order, got %v %v", ranges, err)
+	}
+	_, err = resolveExportRanges(station, []models.ExportStationPartitionRange{{PartitionNumber: 1, FromSeq: 10, ToSeq: 5}})
+	if err == nil {
+		t.Errorf("expected an error for a reversed range")
+	}
+}
@@ -1142,7 +1142,7 @@ func (ms *memStore) removeMsg(seq uint64, secure bool) bool {
 	// send the message to tiered 2 storage if needed
 	tieredStorageEnabled := ms.cfg.TieredStorageEnabled
 	if !secure && !strings.HasPrefix(ms.cfg.Name, MEMPHIS_GLOBAL_ACCOUNT) && tieredStorageEnabled && serv != nil {
-		serv.sendToTier2Storage(ms, copyBytes(sm.buf), sm.seq, "s3")
+		serv.sendToTier2Storage(ms, copyBytes(sm.buf), sm.seq, sm.ts)
 	}
 	// ** added by memphis
 
@@ -13,14 +13,27 @@ package server
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/db"
+	"sync"
 	"time"
+
+	"k8s.io/utils/strings/slices"
 )
 
 const (
-	slackIntegrationName = "slack"
+	slackIntegrationName   = "slack"
+	webhookIntegrationName = "webhook"
+	smtpIntegrationName    = "smtp"
+	teamsIntegrationName   = "teams"
+	discordIntegrationName = "discord"
+
+	notificationsRetryBaseDelay = 2 * time.Second
+	notificationsRetryMaxDelay  = 5 * time.Minute
+	notificationsMaxDeliveries  = 10 // aligned with the max deliver of the notifications buffer consumer
+
+	notificationsColorHex = "#6557FF"
+	notificationsColorInt = 0x6557FF
 )
 
 type NotificationMsg struct {
@@ -29,6 +42,8 @@ type NotificationMsg struct {
 	Message    string    `json:"message"`
 	MsgType    string    `json:"msgType"`
 	Time       time.Time `json:"time"`
+	// Integration is the integration the notification is delivered to, notifications without it are sent to slack
+	Integration string `json:"integration,omitempty"`
 }
 
 type NotificationMsgWithReply struct {
@@ -36,45 +51,74 @@ type NotificationMsgWithReply struct {
 	ReplySubject    string
 }
 
+type notificationBufferMsg struct {
+	Msg          []byte
+	ReplySubject string
+}
+
+// notifier delivers the notifications of a single notifications integration
+type notifier interface {
+	// properties returns the alert toggles of the tenant integration, false is returned when the integration is not connected
+	properties(tenantName string) (map[string]bool, bool)
+	// send delivers the buffered notifications of a tenant, every notification is acked or nacked by the notifier
+	send(s *Server, tenantName string, msgs []NotificationMsgWithReply)
+}
+
+func cachedIntegration[T any](tenantName, integrationName string) (T, bool) {
+	var integration T
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return integration, false
+	}
+	integration, ok = tenantIntegrations[integrationName].(T)
+	return integration, ok
+}
+
 func (s *Server) SendNotification(tenantName string, title string, message string, msgType string) error {
-	for k := range NotificationFunctionsMap {
-		switch k {
-		case slackIntegrationName:
-			if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(tenantName); ok {
-				if slackIntegration, ok := tenantInetgrations[slackIntegrationName].(models.SlackIntegration); ok {
-					if slackIntegration.Properties[msgType] {
-						// TODO: if the stream doesn't exist save the messages in buffer
-						if !NOTIFICATIONS_BUFFER_STREAM_CREATED {
-							return nil
-						}
-
-						// TODO: do we need msg-id here? if yes - what's the best way to generate it? hash title?
-						if tenantName == "" {
-							tenantName = serv.MemphisGlobalAccountString()
-						}
-						notificationMsg := NotificationMsg{
-							TenantName: tenantName,
-							Title:      title,
-							Message:    message,
-							MsgType:    msgType,
-							Time:       time.Now(),
-						}
-
-						err := saveSlackNotificationToQueue(s, notificationsStreamName+".user_notifications", tenantName, &notificationMsg)
-						if err != nil {
-							return err
-						}
-					}
-				}
-			}
-		default:
-			return errors.New("failed sending notification: unsupported integration")
+	return s.sendNotificationToIntegrations(tenantName, title, message, msgType, nil)
+}
+
+// sendNotificationToIntegrations sends the notification only to the given integrations, all the integrations get it when none is given
+func (s *Server) sendNotificationToIntegrations(tenantName string, title string, message string, msgType string, integrations []string) error {
+	for k, n := range NotificationFunctionsMap {
+		if len(integrations) > 0 && !slices.Contains(integrations, k) {
+			continue
+		}
+		properties, connected := n.properties(tenantName)
+		// alert rules are defined by the user so they do not depend on the integration properties
+		if !connected || (!properties[msgType] && msgType != AlertRuleAlert) {
+			continue
+		}
+		err := queueNotification(s, tenantName, title, message, msgType, k)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func saveSlackNotificationToQueue(s *Server, subject, tenantName string, notificationMsg *NotificationMsg) error {
+func queueNotification(s *Server, tenantName, title, message, msgType, integrationName string) error {
+	// TODO: if the stream doesn't exist save the messages in buffer
+	if !NOTIFICATIONS_BUFFER_STREAM_CREATED {
+		return nil
+	}
+
+	// TODO: do we need msg-id here? if yes - what's the best way to generate it? hash title?
+	if tenantName == "" {
+		tenantName = serv.MemphisGlobalAccountString()
+	}
+	notificationMsg := NotificationMsg{
+		TenantName:  tenantName,
+		Title:       title,
+		Message:     message,
+		MsgType:     msgType,
+		Time:        time.Now(),
+		Integration: integrationName,
+	}
+	return saveNotificationToQueue(s, notificationsStreamName+".user_notifications", tenantName, &notificationMsg)
+}
+
+func saveNotificationToQueue(s *Server, subject, tenantName string, notificationMsg *NotificationMsg) error {
 	msg, err := json.Marshal(notificationMsg)
 	if err != nil {
 		return err
@@ -88,12 +132,174 @@ func saveSlackNotificationToQueue(s *Server, subject, tenantName string, notific
 }
 
 func shouldSendNotification(tenantName string, alertType string) bool {
-	if tenantInetgrations, ok := IntegrationsConcurrentCache.Load(tenantName); ok {
-		if slackIntegration, ok := tenantInetgrations["slack"].(models.SlackIntegration); ok {
-			if slackIntegration.Properties[alertType] {
-				return true
-			}
+	for _, n := range NotificationFunctionsMap {
+		if properties, connected := n.properties(tenantName); connected && properties[alertType] {
+			return true
 		}
 	}
 	return false
 }
+
+// IsNotificationsEnabled returns true when the tenant has a connected notifications integration
+func IsNotificationsEnabled(tenantName string) (bool, error) {
+	_, integrations, err := db.GetAllIntegrationsByTenant(tenantName)
+	if err != nil {
+		return false, err
+	}
+	for _, integration := range integrations {
+		if _, ok := NotificationFunctionsMap[integration.Name]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sendBufferedNotifications groups the buffered notifications by tenant and hands each integration its own notifications,
+// the integrations are delivered concurrently so an unreachable endpoint does not hold the notifications of the others
+func sendBufferedNotifications(s *Server, msgs []notificationBufferMsg) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	tenantMsgs := groupMessagesByTenant(msgs, s)
+	for tenantName, tMsgs := range tenantMsgs {
+		integrationMsgs := make(map[string][]NotificationMsgWithReply)
+		for _, m := range tMsgs {
+			integrationName := m.NotificationMsg.Integration
+			if integrationName == _EMPTY_ {
+				integrationName = slackIntegrationName
+			}
+			integrationMsgs[integrationName] = append(integrationMsgs[integrationName], m)
+		}
+		for integrationName, iMsgs := range integrationMsgs {
+			n, ok := NotificationFunctionsMap[integrationName]
+			if !ok {
+				s.Warnf("[tenant: %v]sendBufferedNotifications: unsupported notifications integration %v", tenantName, integrationName)
+				ackMsgs(s, iMsgs)
+				continue
+			}
+			if _, connected := n.properties(tenantName); !connected {
+				// the integration has been disconnected - just ack these messages
+				ackMsgs(s, iMsgs)
+				continue
+			}
+			wg.Add(1)
+			go func(n notifier, tenantName string, iMsgs []NotificationMsgWithReply) {
+				defer wg.Done()
+				n.send(s, tenantName, iMsgs)
+			}(n, tenantName, iMsgs)
+		}
+	}
+}
+
+// sendNotificationsOneByOne delivers every notification on its own, notifications that fail with a retriable error
+// are redelivered with a backoff until the buffer consumer max deliver is reached, the rest of the batch is redelivered
+// with the failed notification so an unreachable endpoint is not waited for once per notification
+func sendNotificationsOneByOne(s *Server, integrationName, tenantName string, msgs []NotificationMsgWithReply, deliver func(NotificationMsg) error, retriable func(error) bool) {
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		err := deliver(*m.NotificationMsg)
+		if err != nil && retriable(err) {
+			retryNotifications(s, integrationName, tenantName, msgs[i:], err)
+			return
+		}
+		if err != nil {
+			_, _, deliveryCount := ackReplyInfo(m.ReplySubject)
+			dropNotification(s, integrationName, tenantName, m, deliveryCount, err)
+			continue
+		}
+
+		err = s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, []byte(_EMPTY_))
+		if err != nil {
+			s.Errorf("[tenant: %v]failed to send ACK for %v notification: %v", tenantName, integrationName, err.Error())
+		}
+	}
+}
+
+// retryNotifications redelivers the notifications with a backoff, the ones which reached the max deliver are dropped
+func retryNotifications(s *Server, integrationName, tenantName string, msgs []NotificationMsgWithReply, deliveryErr error) {
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		_, _, deliveryCount := ackReplyInfo(m.ReplySubject)
+		if deliveryCount >= notificationsMaxDeliveries {
+			dropNotification(s, integrationName, tenantName, m, deliveryCount, deliveryErr)
+			continue
+		}
+		delay := notificationRetryBackoff(deliveryCount)
+		if i == 0 {
+			s.Warnf("[tenant: %v]failed to send %v notification, retrying in %v: %v", tenantName, integrationName, delay, deliveryErr.Error())
+		}
+		err := nackMsgs(s, msgs[i:i+1], delay)
+		if err != nil {
+			s.Errorf("[tenant: %v]failed to send NACK for %v notification: %v", tenantName, integrationName, err.Error())
+		}
+	}
+}
+
+func dropNotification(s *Server, integrationName, tenantName string, m NotificationMsgWithReply, deliveryCount uint64, deliveryErr error) {
+	s.Errorf("[tenant: %v]failed to send %v notification, dropping it after %v attempts: %v", tenantName, integrationName, deliveryCount, deliveryErr.Error())
+	s.sendIntegrationAuditLogToSubject(integrationName, tenantName, "[ERR] Failed to deliver notification "+m.NotificationMsg.Title+": "+deliveryErr.Error())
+	err := s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, []byte(_EMPTY_))
+	if err != nil {
+		s.Errorf("[tenant: %v]failed to send ACK for %v notification: %v", tenantName, integrationName, err.Error())
+	}
+}
+
+// notificationRetryBackoff returns the delay before a failed notification is redelivered, it doubles on every delivery
+func notificationRetryBackoff(deliveryCount uint64) time.Duration {
+	if deliveryCount < 1 {
+		deliveryCount = 1
+	}
+	delay := notificationsRetryBaseDelay
+	for i := uint64(1); i < deliveryCount; i++ {
+		delay *= 2
+		if delay >= notificationsRetryMaxDelay {
+			return notificationsRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+func nackMsgs(s *Server, msgs []NotificationMsgWithReply, nackDuration time.Duration) error {
+	nakPayload := []byte(fmt.Sprintf("%s {\"delay\": %d}", AckNak, nackDuration.Nanoseconds()))
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		err := s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, nakPayload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ackMsgs(s *Server, msgs []NotificationMsgWithReply) {
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, []byte(_EMPTY_))
+	}
+}
+
+func groupMessagesByTenant(msgs []notificationBufferMsg, l Logger) map[string][]NotificationMsgWithReply {
+	tenantMsgs := make(map[string][]NotificationMsgWithReply)
+	for _, message := range msgs {
+		msg := message.Msg
+		reply := message.ReplySubject
+		var nm NotificationMsg
+		err := json.Unmarshal(msg, &nm)
+		if err != nil {
+			// TODO: does it make sense to send ack for this message?
+			// TODO: it's malformed and won't be unmarshalled next time as well
+			l.Errorf("failed to unmarshal notification message: %v", err)
+			continue
+		}
+		nmr := NotificationMsgWithReply{
+			NotificationMsg: &nm,
+			ReplySubject:    reply,
+		}
+		if _, ok := tenantMsgs[nm.TenantName]; !ok {
+			tenantMsgs[nm.TenantName] = []NotificationMsgWithReply{}
+		}
+		tenantMsgs[nm.TenantName] = append(tenantMsgs[nm.TenantName], nmr)
+	}
+
+	return tenantMsgs
+}
@@ -0,0 +1,309 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+const (
+	discordTitleMaxLength       = 256
+	discordDescriptionMaxLength = 4096
+)
+
+// incomingWebhookNotifier delivers notifications to chat channels which accept messages through an incoming webhook url
+type incomingWebhookNotifier struct {
+	name   string
+	format func(msg NotificationMsg) ([]byte, error)
+}
+
+var teamsNotifier = incomingWebhookNotifier{name: teamsIntegrationName, format: formatTeamsMessage}
+var discordNotifier = incomingWebhookNotifier{name: discordIntegrationName, format: formatDiscordMessage}
+
+func (n incomingWebhookNotifier) properties(tenantName string) (map[string]bool, bool) {
+	integration, ok := cachedIntegration[models.IncomingWebhookIntegration](tenantName, n.name)
+	return integration.Properties, ok
+}
+
+func (n incomingWebhookNotifier) send(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
+	integration, ok := cachedIntegration[models.IncomingWebhookIntegration](tenantName, n.name)
+	if !ok {
+		// the integration is either not enabled or have been disabled - just ack these messages
+		ackMsgs(s, msgs)
+		return
+	}
+	sendNotificationsOneByOne(s, n.name, tenantName, msgs, func(msg NotificationMsg) error {
+		body, err := n.format(msg)
+		if err != nil {
+			return err
+		}
+		return postIncomingWebhook(webhookHttpClient, integration.Keys["webhook_url"], body)
+	}, isRetriableWebhookError)
+}
+
+func postIncomingWebhook(client *http.Client, webhookUrl string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookDeliveryError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func truncateNotificationText(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength-3]) + "..."
+}
+
+// formatTeamsMessage builds an adaptive card equivalent to the slack attachment of the notification
+func formatTeamsMessage(msg NotificationMsg) ([]byte, error) {
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "text": "Memphis", "isSubtle": true, "size": "Small"},
+			{"type": "TextBlock", "text": msg.Title, "weight": "Bolder", "size": "Medium", "color": "Accent", "wrap": true},
+			{"type": "TextBlock", "text": msg.Message, "wrap": true},
+		},
+	}
+	return json.Marshal(map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	})
+}
+
+// formatDiscordMessage builds an embed equivalent to the slack attachment of the notification
+func formatDiscordMessage(msg NotificationMsg) ([]byte, error) {
+	embed := map[string]interface{}{
+		"author":      map[string]string{"name": "Memphis"},
+		"title":       truncateNotificationText(msg.Title, discordTitleMaxLength),
+		"description": truncateNotificationText(msg.Message, discordDescriptionMaxLength),
+		"color":       notificationsColorInt,
+	}
+	if !msg.Time.IsZero() {
+		embed["timestamp"] = msg.Time.UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(map[string]interface{}{
+		"username": "Memphis",
+		"embeds":   []map[string]interface{}{embed},
+	})
+}
+
+func validateIncomingWebhookUrl(integrationName, webhookUrl string) error {
+	u, err := url.Parse(webhookUrl)
+	if err != nil || u.Scheme != "https" || u.Host == _EMPTY_ {
+		return fmt.Errorf("invalid %v webhook url, it should be an https url", integrationName)
+	}
+	if integrationName == discordIntegrationName {
+		host := strings.ToLower(u.Hostname())
+		if (host != "discord.com" && host != "discordapp.com" && !strings.HasSuffix(host, ".discord.com")) || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return errors.New("invalid discord webhook url, it should look like https://discord.com/api/webhooks/<id>/<token>")
+		}
+	}
+	return nil
+}
+
+func testIncomingWebhookIntegration(integrationName, webhookUrl string) error {
+	err := validateIncomingWebhookUrl(integrationName, webhookUrl)
+	if err != nil {
+		return err
+	}
+	if integrationName != discordIntegrationName {
+		// teams webhooks can not be tested without posting a message
+		return testWebhookIntegration(webhookUrl)
+	}
+	resp, err := webhookHttpClient.Get(webhookUrl)
+	if err != nil {
+		return fmt.Errorf("discord webhook url is unreachable")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("invalid discord webhook url, the webhook does not exist")
+	}
+	return nil
+}
+
+func hideIncomingWebhookUrl(webhookUrl string) string {
+	u, err := url.Parse(webhookUrl)
+	if webhookUrl == _EMPTY_ || err != nil {
+		return webhookUrl
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
+
+func cacheDetailsIncomingWebhook(integrationName string, keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	integration := models.IncomingWebhookIntegration{}
+	integration.Keys = make(map[string]string)
+	integration.Properties = make(map[string]bool)
+	if keys == nil {
+		deleteIntegrationFromTenant(tenantName, integrationName, IntegrationsConcurrentCache)
+		return
+	}
+	webhookUrl, ok := keys["webhook_url"].(string)
+	if !ok {
+		deleteIntegrationFromTenant(tenantName, integrationName, IntegrationsConcurrentCache)
+		return
+	}
+	integration.Keys["webhook_url"] = webhookUrl
+	integration.Properties[PoisonMAlert] = properties[PoisonMAlert]
+	integration.Properties[SchemaVAlert] = properties[SchemaVAlert]
+	integration.Properties[DisconEAlert] = properties[DisconEAlert]
+	integration.Name = integrationName
+	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
+		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{integrationName: integration})
+	} else {
+		err := addIntegrationToTenant(tenantName, integrationName, IntegrationsConcurrentCache, integration)
+		if err != nil {
+			serv.Errorf("cacheDetailsIncomingWebhook: " + err.Error())
+			return
+		}
+	}
+}
+
+func (it IntegrationsHandler) getIncomingWebhookIntegrationDetails(integrationName, tenantName string, body models.CreateIntegrationSchema) (map[string]interface{}, map[string]bool, int, error) {
+	webhookUrl, ok := body.Keys["webhook_url"].(string)
+	if !ok {
+		webhookUrl = _EMPTY_
+	}
+	if webhookUrl == _EMPTY_ {
+		// the webhook url contains a token so it is hidden from the users, an empty url keeps the existing one
+		exist, integrationFromDb, err := db.GetIntegration(integrationName, tenantName)
+		if err != nil {
+			return map[string]interface{}{}, map[string]bool{}, 500, err
+		}
+		value, ok := integrationFromDb.Keys["webhook_url"].(string)
+		if !exist || !ok || value == _EMPTY_ {
+			return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("must provide webhook url for %v integration", integrationName)
+		}
+		decryptedValue, err := DecryptAES(getAESKey(), value)
+		if err != nil {
+			return map[string]interface{}{}, map[string]bool{}, 500, err
+		}
+		webhookUrl = decryptedValue
+	}
+
+	keys := map[string]interface{}{"webhook_url": webhookUrl}
+	properties := map[string]bool{
+		PoisonMAlert: body.Properties[PoisonMAlert],
+		SchemaVAlert: body.Properties[SchemaVAlert],
+		DisconEAlert: body.Properties[DisconEAlert],
+	}
+	return keys, properties, 0, nil
+}
+
+func (it IntegrationsHandler) handleCreateIncomingWebhookIntegration(integrationName, tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	exist, _, err := db.GetIntegration(integrationName, tenantName)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	if exist {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("%v integration already exists", integrationName)
+	}
+	keys, properties, errorCode, err := it.getIncomingWebhookIntegrationDetails(integrationName, tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	err = testIncomingWebhookIntegration(integrationName, keys["webhook_url"].(string))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	integration, err := saveIncomingWebhookIntegration(integrationName, tenantName, keys, properties, false)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return integration, 0, nil
+}
+
+func (it IntegrationsHandler) handleUpdateIncomingWebhookIntegration(integrationName, tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, properties, errorCode, err := it.getIncomingWebhookIntegrationDetails(integrationName, tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	err = testIncomingWebhookIntegration(integrationName, keys["webhook_url"].(string))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	integration, err := saveIncomingWebhookIntegration(integrationName, tenantName, keys, properties, true)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return integration, 0, nil
+}
+
+func saveIncomingWebhookIntegration(integrationName, tenantName string, keys map[string]interface{}, properties map[string]bool, isUpdate bool) (models.Integration, error) {
+	webhookUrl := keys["webhook_url"].(string)
+	encryptedValue, err := EncryptAES([]byte(webhookUrl))
+	if err != nil {
+		return models.Integration{}, err
+	}
+	cloneKeys := map[string]interface{}{"webhook_url": encryptedValue}
+
+	var integration models.Integration
+	if isUpdate {
+		integration, err = db.UpdateIntegration(tenantName, integrationName, cloneKeys, properties)
+	} else {
+		integration, err = db.InsertNewIntegration(tenantName, integrationName, cloneKeys, properties)
+	}
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integrationToUpdate := models.CreateIntegration{
+		Name:       integrationName,
+		Keys:       keys,
+		Properties: properties,
+		TenantName: tenantName,
+		IsValid:    integration.IsValid,
+	}
+	msg, err := json.Marshal(integrationToUpdate)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	update := models.SdkClientsUpdates{
+		Type:   sendNotificationType,
+		Update: properties[SchemaVAlert] || shouldSendNotification(tenantName, SchemaVAlert),
+	}
+	serv.SendUpdateToClients(update)
+
+	integration.Keys = map[string]interface{}{"webhook_url": hideIncomingWebhookUrl(webhookUrl)}
+	integration.Properties = properties
+	return integration, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncomingWebhookNotifications(t *testing.T) {
+	msg := NotificationMsg{Title: "Poison message", Message: strings.Repeat("a", discordDescriptionMaxLength+10), Time: time.Now()}
+
+	var discordMsg struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+		} `json:"embeds"`
+	}
+	body, err := formatDiscordMessage(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	json.Unmarshal(body, &discordMsg)
+	if len(discordMsg.Embeds) != 1 || discordMsg.Embeds[0].Color != notificationsColorInt || len([]rune(discordMsg.Embeds[0].Description)) != discordDescriptionMaxLength {
+		t.Errorf("unexpected discord message: %s", body)
+	}
+
+	var teamsMsg struct {
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+		} `json:"attachments"`
+	}
+	body, err = formatTeamsMessage(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	json.Unmarshal(body, &teamsMsg)
+	if len(teamsMsg.Attachments) != 1 || teamsMsg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("unexpected teams message: %s", body)
+	}
+
+	if err := validateIncomingWebhookUrl(discordIntegrationName, "https://example.com/api/webhooks/1/token"); err == nil {
+		t.Errorf("expected an error for a non discord url")
+	}
+	if err := validateIncomingWebhookUrl(discordIntegrationName, "https://discord.com/api/webhooks/1/token"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+	err = postIncomingWebhook(ts.Client(), ts.URL, body)
+	if err == nil || !isRetriableWebhookError(err) {
+		t.Errorf("expected a retriable error, got %v", err)
+	}
+}
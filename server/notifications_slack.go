@@ -14,11 +14,9 @@ package server
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/memphisdev/memphis/db"
 	"github.com/memphisdev/memphis/models"
 	"strings"
-	"time"
 
 	"github.com/slack-go/slack"
 )
@@ -41,7 +39,7 @@ func sendMessageToSlackChannel(integration models.SlackIntegration, title string
 		AuthorName: "Memphis",
 		Title:      title,
 		Text:       message,
-		Color:      "#6557FF",
+		Color:      notificationsColorHex,
 	}
 
 	_, _, err := integration.Client.PostMessage(
@@ -316,16 +314,15 @@ func hideSlackAuthToken(authToken string) string {
 	return authToken
 }
 
-type slackMsg struct {
-	Msg          []byte
-	ReplySubject string
+type slackNotifier struct{}
+
+func (slackNotifier) properties(tenantName string) (map[string]bool, bool) {
+	slackIntegration, ok := cachedIntegration[models.SlackIntegration](tenantName, slackIntegrationName)
+	return slackIntegration.Properties, ok
 }
 
-func sendSlackNotifications(s *Server, msgs []slackMsg) {
-	tenantMsgs := groupMessagesByTenant(msgs, s)
-	for tenantName, tMsgs := range tenantMsgs {
-		sendTenantSlackNotifications(s, tenantName, tMsgs)
-	}
+func (slackNotifier) send(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
+	sendTenantSlackNotifications(s, tenantName, msgs)
 }
 
 func sendTenantSlackNotifications(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
@@ -378,49 +375,3 @@ func sendTenantSlackNotifications(s *Server, tenantName string, msgs []Notificat
 		}
 	}
 }
-
-func nackMsgs(s *Server, msgs []NotificationMsgWithReply, nackDuration time.Duration) error {
-	nakPayload := []byte(fmt.Sprintf("%s {\"delay\": %d}", AckNak, nackDuration.Nanoseconds()))
-	for i := 0; i < len(msgs); i++ {
-		m := msgs[i]
-		err := s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, nakPayload)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func ackMsgs(s *Server, msgs []NotificationMsgWithReply) {
-	for i := 0; i < len(msgs); i++ {
-		m := msgs[i]
-		s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, []byte(_EMPTY_))
-	}
-}
-
-func groupMessagesByTenant(msgs []slackMsg, l Logger) map[string][]NotificationMsgWithReply {
-	tenantMsgs := make(map[string][]NotificationMsgWithReply)
-	for _, message := range msgs {
-		msg := message.Msg
-		reply := message.ReplySubject
-		var nm NotificationMsg
-		err := json.Unmarshal(msg, &nm)
-		if err != nil {
-			// TODO: does it make sense to send ack for this message?
-			// TODO: it's malformed and won't be unmarshalled next time as well
-			l.Errorf("failed to unmarshal slack message: %v", err)
-			continue
-		}
-		nmr := NotificationMsgWithReply{
-			NotificationMsg: &nm,
-			ReplySubject:    reply,
-		}
-		if _, ok := tenantMsgs[nm.TenantName]; !ok {
-			tenantMsgs[nm.TenantName] = []NotificationMsgWithReply{}
-		}
-		tenantMsgs[nm.TenantName] = append(tenantMsgs[nm.TenantName], nmr)
-	}
-
-	return tenantMsgs
-}
@@ -0,0 +1,458 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+const (
+	smtpTlsModeNone     = "none"
+	smtpTlsModeStartTls = "starttls"
+	smtpTlsModeTls      = "tls"
+	smtpTimeout         = 10 * time.Second
+	smtpDigestInterval  = time.Minute
+)
+
+// smtpLastDigests holds the last time a digest was sent per tenant, the tenants are delivered concurrently by the notifications buffer consumer
+var smtpLastDigests = NewConcurrentMap[time.Time]()
+
+func dialSmtp(keys map[string]string) (*smtp.Client, error) {
+	host := keys["host"]
+	addr := net.JoinHostPort(host, keys["port"])
+	tlsConfig := &tls.Config{ServerName: host}
+	var conn net.Conn
+	var err error
+	if keys["tls_mode"] == smtpTlsModeTls {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, smtpTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(3 * smtpTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if keys["tls_mode"] == smtpTlsModeStartTls {
+		err = client.StartTLS(tlsConfig)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	if keys["username"] != _EMPTY_ {
+		err = client.Auth(smtp.PlainAuth(_EMPTY_, keys["username"], keys["password"], host))
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// testSmtpIntegration performs the SMTP handshake, including TLS and authentication, without sending an email
+func testSmtpIntegration(keys map[string]string) error {
+	client, err := dialSmtp(keys)
+	if err != nil {
+		return fmt.Errorf("smtp handshake with %v failed: %v", keys["host"], err.Error())
+	}
+	return client.Quit()
+}
+
+func sanitizeEmailHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// buildSmtpDigest builds a single email out of all the given notifications
+func buildSmtpDigest(from string, recipients []string, notifications []NotificationMsg) []byte {
+	subject := fmt.Sprintf("Memphis: %v new notifications", len(notifications))
+	if len(notifications) == 1 {
+		subject = "Memphis: " + notifications[0].Title
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString("From: " + sanitizeEmailHeader(from) + "\r\n")
+	msg.WriteString("To: " + sanitizeEmailHeader(strings.Join(recipients, ", ")) + "\r\n")
+	msg.WriteString("Subject: " + sanitizeEmailHeader(subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	msg.WriteString("\r\n")
+	for i, notification := range notifications {
+		if i > 0 {
+			msg.WriteString("\r\n----------\r\n\r\n")
+		}
+		msg.WriteString(notification.Title + "\r\n")
+		msg.WriteString(notification.Time.UTC().Format(time.RFC3339) + "\r\n\r\n")
+		msg.WriteString(strings.ReplaceAll(notification.Message, "\n", "\r\n") + "\r\n")
+	}
+	return msg.Bytes()
+}
+
+func sendMessageToSmtp(integration models.SmtpIntegration, notifications []NotificationMsg) error {
+	client, err := dialSmtp(integration.Keys)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.Mail(integration.Keys["from"])
+	if err != nil {
+		return err
+	}
+	for _, recipient := range integration.Recipients {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buildSmtpDigest(integration.Keys["from"], integration.Recipients, notifications))
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// isRetriableSmtpError returns false for permanent SMTP failures (5xx replies)
+func isRetriableSmtpError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code < 500
+	}
+	return true
+}
+
+func hideSmtpPassword(password string) string {
+	if password != _EMPTY_ {
+		return "****"
+	}
+	return password
+}
+
+func smtpRecipients(value interface{}) []string {
+	recipients := []string{}
+	switch v := value.(type) {
+	case []string:
+		recipients = append(recipients, v...)
+	case []interface{}:
+		for _, r := range v {
+			if recipient, ok := r.(string); ok {
+				recipients = append(recipients, recipient)
+			}
+		}
+	}
+	return recipients
+}
+
+func cacheDetailsSmtp(keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	smtpIntegration := models.SmtpIntegration{}
+	smtpIntegration.Keys = make(map[string]string)
+	smtpIntegration.Properties = make(map[string]bool)
+	if keys == nil {
+		deleteIntegrationFromTenant(tenantName, smtpIntegrationName, IntegrationsConcurrentCache)
+		return
+	}
+	for _, key := range []string{"host", "port", "tls_mode", "from"} {
+		value, ok := keys[key].(string)
+		if !ok {
+			deleteIntegrationFromTenant(tenantName, smtpIntegrationName, IntegrationsConcurrentCache)
+			return
+		}
+		smtpIntegration.Keys[key] = value
+	}
+	for _, key := range []string{"username", "password"} {
+		if value, ok := keys[key].(string); ok {
+			smtpIntegration.Keys[key] = value
+		}
+	}
+	smtpIntegration.Recipients = smtpRecipients(keys["recipients"])
+	smtpIntegration.Properties[PoisonMAlert] = properties[PoisonMAlert]
+	smtpIntegration.Properties[SchemaVAlert] = properties[SchemaVAlert]
+	smtpIntegration.Properties[DisconEAlert] = properties[DisconEAlert]
+	smtpIntegration.Name = smtpIntegrationName
+	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
+		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{smtpIntegrationName: smtpIntegration})
+	} else {
+		err := addIntegrationToTenant(tenantName, smtpIntegrationName, IntegrationsConcurrentCache, smtpIntegration)
+		if err != nil {
+			serv.Errorf("cacheDetailsSmtp: " + err.Error())
+			return
+		}
+	}
+}
+
+func (it IntegrationsHandler) getSmtpIntegrationDetails(tenantName string, body models.CreateIntegrationSchema) (map[string]interface{}, map[string]bool, int, error) {
+	host, ok := body.Keys["host"].(string)
+	if !ok || host == _EMPTY_ {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide host for smtp integration")
+	}
+	port := fmt.Sprintf("%v", body.Keys["port"])
+	if portNum, err := strconv.Atoi(port); err != nil || portNum < 1 || portNum > 65535 {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide a valid port for smtp integration")
+	}
+	tlsMode, ok := body.Keys["tls_mode"].(string)
+	if !ok || tlsMode == _EMPTY_ {
+		tlsMode = smtpTlsModeStartTls
+	}
+	tlsMode = strings.ToLower(tlsMode)
+	if tlsMode != smtpTlsModeNone && tlsMode != smtpTlsModeStartTls && tlsMode != smtpTlsModeTls {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("tls mode can be one of the following %v/%v/%v", smtpTlsModeNone, smtpTlsModeStartTls, smtpTlsModeTls)
+	}
+	from, ok := body.Keys["from"].(string)
+	if !ok {
+		from = _EMPTY_
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide a valid from address for smtp integration")
+	}
+	recipients := smtpRecipients(body.Keys["recipients"])
+	if len(recipients) == 0 {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide at least one recipient for smtp integration")
+	}
+	for _, recipient := range recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("invalid recipient address %v", recipient)
+		}
+	}
+
+	username, ok := body.Keys["username"].(string)
+	if !ok {
+		username = _EMPTY_
+	}
+	password, ok := body.Keys["password"].(string)
+	if !ok {
+		password = _EMPTY_
+	}
+	if username != _EMPTY_ && password == _EMPTY_ {
+		// the password is hidden from the users so an empty password keeps the existing one
+		exist, integrationFromDb, err := db.GetIntegration(smtpIntegrationName, tenantName)
+		if err != nil {
+			return map[string]interface{}{}, map[string]bool{}, 500, err
+		}
+		value, ok := integrationFromDb.Keys["password"].(string)
+		if !exist || !ok || value == _EMPTY_ {
+			return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide password for smtp integration")
+		}
+		decryptedValue, err := DecryptAES(getAESKey(), value)
+		if err != nil {
+			return map[string]interface{}{}, map[string]bool{}, 500, err
+		}
+		password = decryptedValue
+	}
+
+	keys := map[string]interface{}{
+		"host":       host,
+		"port":       port,
+		"tls_mode":   tlsMode,
+		"username":   username,
+		"password":   password,
+		"from":       from,
+		"recipients": recipients,
+	}
+	properties := map[string]bool{
+		PoisonMAlert: body.Properties[PoisonMAlert],
+		SchemaVAlert: body.Properties[SchemaVAlert],
+		DisconEAlert: body.Properties[DisconEAlert],
+	}
+	return keys, properties, 0, nil
+}
+
+func smtpKeysAsStringMap(keys map[string]interface{}) map[string]string {
+	stringKeys := make(map[string]string)
+	for k, v := range keys {
+		if value, ok := v.(string); ok {
+			stringKeys[k] = value
+		}
+	}
+	return stringKeys
+}
+
+func (it IntegrationsHandler) handleCreateSmtpIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, properties, errorCode, err := it.getSmtpIntegrationDetails(tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	exist, _, err := db.GetIntegration(smtpIntegrationName, tenantName)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	if exist {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("smtp integration already exists")
+	}
+	err = testSmtpIntegration(smtpKeysAsStringMap(keys))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	smtpIntegration, err := saveSmtpIntegration(tenantName, keys, properties, false)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return smtpIntegration, 0, nil
+}
+
+func (it IntegrationsHandler) handleUpdateSmtpIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, properties, errorCode, err := it.getSmtpIntegrationDetails(tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	err = testSmtpIntegration(smtpKeysAsStringMap(keys))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	smtpIntegration, err := saveSmtpIntegration(tenantName, keys, properties, true)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return smtpIntegration, 0, nil
+}
+
+func saveSmtpIntegration(tenantName string, keys map[string]interface{}, properties map[string]bool, isUpdate bool) (models.Integration, error) {
+	cloneKeys := make(map[string]interface{})
+	for k, v := range keys {
+		cloneKeys[k] = v
+	}
+	password := keys["password"].(string)
+	if password != _EMPTY_ {
+		encryptedValue, err := EncryptAES([]byte(password))
+		if err != nil {
+			return models.Integration{}, err
+		}
+		cloneKeys["password"] = encryptedValue
+	}
+
+	var smtpIntegration models.Integration
+	var err error
+	if isUpdate {
+		smtpIntegration, err = db.UpdateIntegration(tenantName, smtpIntegrationName, cloneKeys, properties)
+	} else {
+		smtpIntegration, err = db.InsertNewIntegration(tenantName, smtpIntegrationName, cloneKeys, properties)
+	}
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integrationToUpdate := models.CreateIntegration{
+		Name:       smtpIntegrationName,
+		Keys:       keys,
+		Properties: properties,
+		TenantName: tenantName,
+		IsValid:    smtpIntegration.IsValid,
+	}
+	msg, err := json.Marshal(integrationToUpdate)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	update := models.SdkClientsUpdates{
+		Type:   sendNotificationType,
+		Update: properties[SchemaVAlert] || shouldSendNotification(tenantName, SchemaVAlert),
+	}
+	serv.SendUpdateToClients(update)
+
+	smtpIntegration.Keys = cloneKeys
+	smtpIntegration.Keys["password"] = hideSmtpPassword(password)
+	smtpIntegration.Properties = properties
+	return smtpIntegration, nil
+}
+
+type smtpNotifier struct{}
+
+func (smtpNotifier) properties(tenantName string) (map[string]bool, bool) {
+	smtpIntegration, ok := cachedIntegration[models.SmtpIntegration](tenantName, smtpIntegrationName)
+	return smtpIntegration.Properties, ok
+}
+
+func (smtpNotifier) send(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
+	sendTenantSmtpNotifications(s, tenantName, msgs)
+}
+
+// sendTenantSmtpNotifications sends all the pending notifications of a tenant as a single digest email,
+// notifications arriving less than smtpDigestInterval after the last digest are delayed to the next one
+func sendTenantSmtpNotifications(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
+	smtpIntegration, ok := cachedIntegration[models.SmtpIntegration](tenantName, smtpIntegrationName)
+	if !ok {
+		// smtp is either not enabled or have been disabled - just ack these messages
+		ackMsgs(s, msgs)
+		return
+	}
+
+	if lastDigest, ok := smtpLastDigests.Load(tenantName); ok && time.Since(lastDigest) < smtpDigestInterval {
+		err := nackMsgs(s, msgs, smtpDigestInterval-time.Since(lastDigest))
+		if err != nil {
+			s.Errorf("[tenant: %v]failed to send NACK for smtp notification: %v", tenantName, err.Error())
+		}
+		return
+	}
+
+	notifications := make([]NotificationMsg, 0, len(msgs))
+	for _, m := range msgs {
+		notifications = append(notifications, *m.NotificationMsg)
+	}
+	err := sendMessageToSmtp(smtpIntegration, notifications)
+	if err == nil {
+		smtpLastDigests.Lock()
+		smtpLastDigests.m[tenantName] = time.Now()
+		smtpLastDigests.Unlock()
+		ackMsgs(s, msgs)
+		return
+	}
+
+	retriable := isRetriableSmtpError(err)
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		_, _, deliveryCount := ackReplyInfo(m.ReplySubject)
+		if retriable && deliveryCount < notificationsMaxDeliveries {
+			nackErr := nackMsgs(s, msgs[i:i+1], notificationRetryBackoff(deliveryCount))
+			if nackErr != nil {
+				s.Errorf("[tenant: %v]failed to send NACK for smtp notification: %v", tenantName, nackErr.Error())
+			}
+			continue
+		}
+		s.sendIntegrationAuditLogToSubject(smtpIntegrationName, tenantName, "[ERR] Failed to deliver notification "+m.NotificationMsg.Title+": "+err.Error())
+		ackErr := s.sendInternalAccountMsg(s.MemphisGlobalAccount(), m.ReplySubject, []byte(_EMPTY_))
+		if ackErr != nil {
+			s.Errorf("[tenant: %v]failed to send ACK for smtp notification: %v", tenantName, ackErr.Error())
+		}
+	}
+	s.Warnf("[tenant: %v]failed to send smtp notifications digest: %v", tenantName, err.Error())
+}
@@ -0,0 +1,44 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSmtpDigest(t *testing.T) {
+	notifications := []NotificationMsg{
+		{Title: "Poison message", Message: "line1\nline2", Time: time.Now()},
+		{Title: "Disconnection\r\nBcc: someone@example.com", Message: "producer disconnected", Time: time.Now()},
+	}
+	digest := string(buildSmtpDigest("memphis@example.com", []string{"a@example.com", "b@example.com"}, notifications))
+	if !strings.Contains(digest, "Subject: Memphis: 2 new notifications\r\n") || !strings.Contains(digest, "To: a@example.com, b@example.com\r\n") {
+		t.Errorf("unexpected digest headers: %v", digest)
+	}
+	if !strings.Contains(digest, "line1\r\nline2") || !strings.Contains(digest, "producer disconnected") {
+		t.Errorf("expected all the notifications in the digest: %v", digest)
+	}
+
+	digest = string(buildSmtpDigest("memphis@example.com", []string{"a@example.com"}, notifications[1:]))
+	headers := digest[:strings.Index(digest, "\r\n\r\n")]
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Errorf("expected the subject to be sanitized: %v", headers)
+	}
+
+	if isRetriableSmtpError(&textproto.Error{Code: 550, Msg: "mailbox unavailable"}) || !isRetriableSmtpError(&textproto.Error{Code: 421, Msg: "try again later"}) {
+		t.Errorf("unexpected smtp error classification")
+	}
+}
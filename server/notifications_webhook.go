@@ -0,0 +1,415 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+const (
+	webhookSignatureHeader = "X-Memphis-Signature"
+	webhookTimestampHeader = "X-Memphis-Timestamp"
+	webhookRequestTimeout  = 5 * time.Second
+)
+
+var webhookHttpClient = &http.Client{Timeout: webhookRequestTimeout}
+
+type webhookPayload struct {
+	TenantName string    `json:"tenant_name"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message"`
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+}
+
+// webhookDeliveryError is returned when the webhook endpoint rejects a notification
+type webhookDeliveryError struct {
+	StatusCode int
+}
+
+func (e *webhookDeliveryError) Error() string {
+	return fmt.Sprintf("webhook endpoint responded with status code %v", e.StatusCode)
+}
+
+// signWebhookPayload returns the HMAC-SHA256 signature of the timestamp and the body joined by a dot
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func sendMessageToWebhook(client *http.Client, integration models.WebhookIntegration, msg NotificationMsg) error {
+	body, err := json.Marshal(webhookPayload{
+		TenantName: msg.TenantName,
+		Title:      msg.Title,
+		Message:    msg.Message,
+		Type:       msg.MsgType,
+		Time:       msg.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, integration.Keys["url"], bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range integration.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Memphis")
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	if secret := integration.Keys["signing_secret"]; secret != _EMPTY_ {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookDeliveryError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// isRetriableWebhookError returns false for rejections that are not expected to succeed when retried
+func isRetriableWebhookError(err error) bool {
+	var deliveryErr *webhookDeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.StatusCode == http.StatusRequestTimeout || deliveryErr.StatusCode == http.StatusTooManyRequests || deliveryErr.StatusCode >= 500
+	}
+	return true
+}
+
+func cacheDetailsWebhook(keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	webhookIntegration := models.WebhookIntegration{}
+	webhookIntegration.Keys = make(map[string]string)
+	webhookIntegration.Headers = make(map[string]string)
+	webhookIntegration.Properties = make(map[string]bool)
+	if keys == nil {
+		deleteIntegrationFromTenant(tenantName, webhookIntegrationName, IntegrationsConcurrentCache)
+		return
+	}
+	webhookUrl, ok := keys["url"].(string)
+	if !ok {
+		deleteIntegrationFromTenant(tenantName, webhookIntegrationName, IntegrationsConcurrentCache)
+		return
+	}
+	signingSecret, ok := keys["signing_secret"].(string)
+	if !ok {
+		signingSecret = _EMPTY_
+	}
+	if headers, ok := keys["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if v, ok := value.(string); ok {
+				webhookIntegration.Headers[name] = v
+			}
+		}
+	}
+
+	webhookIntegration.Keys["url"] = webhookUrl
+	webhookIntegration.Keys["signing_secret"] = signingSecret
+	webhookIntegration.Properties[PoisonMAlert] = properties[PoisonMAlert]
+	webhookIntegration.Properties[SchemaVAlert] = properties[SchemaVAlert]
+	webhookIntegration.Properties[DisconEAlert] = properties[DisconEAlert]
+	webhookIntegration.Name = webhookIntegrationName
+	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
+		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{webhookIntegrationName: webhookIntegration})
+	} else {
+		err := addIntegrationToTenant(tenantName, webhookIntegrationName, IntegrationsConcurrentCache, webhookIntegration)
+		if err != nil {
+			serv.Errorf("cacheDetailsWebhook: " + err.Error())
+			return
+		}
+	}
+}
+
+func testWebhookIntegration(webhookUrl string) error {
+	u, err := url.Parse(webhookUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == _EMPTY_ {
+		return errors.New("invalid webhook url, it should be an http or https url")
+	}
+	port := u.Port()
+	if port == _EMPTY_ {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), webhookRequestTimeout)
+	if err != nil {
+		return fmt.Errorf("webhook url %v is unreachable", webhookUrl)
+	}
+	conn.Close()
+	return nil
+}
+
+func (it IntegrationsHandler) getWebhookIntegrationDetails(tenantName string, body models.CreateIntegrationSchema) (map[string]interface{}, map[string]bool, int, error) {
+	webhookUrl, ok := body.Keys["url"].(string)
+	if !ok || webhookUrl == _EMPTY_ {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide url for webhook integration")
+	}
+
+	exist, integrationFromDb, err := db.GetIntegration(webhookIntegrationName, tenantName)
+	if err != nil {
+		return map[string]interface{}{}, map[string]bool{}, 500, err
+	}
+	existingHeaders := map[string]interface{}{}
+	if exist {
+		if encryptedHeaders, ok := integrationFromDb.Keys["headers"].(map[string]interface{}); ok {
+			existingHeaders = encryptedHeaders
+		}
+	}
+
+	headers := make(map[string]interface{})
+	if rawHeaders, ok := body.Keys["headers"]; ok && rawHeaders != nil {
+		headersMap, ok := rawHeaders.(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("webhook headers should be an object of header names and values")
+		}
+		for name, value := range headersMap {
+			v, ok := value.(string)
+			if !ok || strings.TrimSpace(name) == _EMPTY_ || strings.ContainsAny(name, " :\r\n") || strings.ContainsAny(v, "\r\n") {
+				return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("invalid webhook header %v", name)
+			}
+			// the header values are hidden from the users like the secret so a hidden value keeps the existing one
+			if encryptedValue, ok := existingHeaders[name].(string); ok && encryptedValue != _EMPTY_ {
+				decryptedValue, err := DecryptAES(getAESKey(), encryptedValue)
+				if err != nil {
+					return map[string]interface{}{}, map[string]bool{}, 500, err
+				}
+				if v == hideWebhookHeaderValue(encryptedValue) || v == hideWebhookHeaderValue(decryptedValue) {
+					v = decryptedValue
+				}
+			}
+			headers[name] = v
+		}
+	}
+
+	signingSecret, ok := body.Keys["signing_secret"].(string)
+	if !ok {
+		signingSecret = _EMPTY_
+	}
+	if signingSecret == _EMPTY_ {
+		// the secret is hidden from the users so an empty secret keeps the existing one
+		if value, ok := integrationFromDb.Keys["signing_secret"].(string); exist && ok && value != _EMPTY_ {
+			decryptedValue, err := DecryptAES(getAESKey(), value)
+			if err != nil {
+				return map[string]interface{}{}, map[string]bool{}, 500, err
+			}
+			signingSecret = decryptedValue
+		}
+	} else if len(signingSecret) < 8 {
+		return map[string]interface{}{}, map[string]bool{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("webhook signing secret should be at least 8 characters long")
+	}
+
+	keys := map[string]interface{}{
+		"url":            webhookUrl,
+		"headers":        headers,
+		"signing_secret": signingSecret,
+	}
+	properties := map[string]bool{
+		PoisonMAlert: body.Properties[PoisonMAlert],
+		SchemaVAlert: body.Properties[SchemaVAlert],
+		DisconEAlert: body.Properties[DisconEAlert],
+	}
+	return keys, properties, 0, nil
+}
+
+func (it IntegrationsHandler) handleCreateWebhookIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, properties, errorCode, err := it.getWebhookIntegrationDetails(tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	err = testWebhookIntegration(keys["url"].(string))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	exist, _, err := db.GetIntegration(webhookIntegrationName, tenantName)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	if exist {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("webhook integration already exists")
+	}
+	webhookIntegration, err := saveWebhookIntegration(tenantName, keys, properties, false)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return webhookIntegration, 0, nil
+}
+
+func (it IntegrationsHandler) handleUpdateWebhookIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, properties, errorCode, err := it.getWebhookIntegrationDetails(tenantName, body)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	err = testWebhookIntegration(keys["url"].(string))
+	if err != nil {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	webhookIntegration, err := saveWebhookIntegration(tenantName, keys, properties, true)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return webhookIntegration, 0, nil
+}
+
+func saveWebhookIntegration(tenantName string, keys map[string]interface{}, properties map[string]bool, isUpdate bool) (models.Integration, error) {
+	cloneKeys := make(map[string]interface{})
+	for k, v := range keys {
+		cloneKeys[k] = v
+	}
+	signingSecret := keys["signing_secret"].(string)
+	if signingSecret != _EMPTY_ {
+		encryptedValue, err := EncryptAES([]byte(signingSecret))
+		if err != nil {
+			return models.Integration{}, err
+		}
+		cloneKeys["signing_secret"] = encryptedValue
+	}
+	headers, _ := keys["headers"].(map[string]interface{})
+	encryptedHeaders, err := encryptWebhookHeaders(headers)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	cloneKeys["headers"] = encryptedHeaders
+
+	var webhookIntegration models.Integration
+	if isUpdate {
+		webhookIntegration, err = db.UpdateIntegration(tenantName, webhookIntegrationName, cloneKeys, properties)
+	} else {
+		webhookIntegration, err = db.InsertNewIntegration(tenantName, webhookIntegrationName, cloneKeys, properties)
+	}
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integrationToUpdate := models.CreateIntegration{
+		Name:       webhookIntegrationName,
+		Keys:       keys,
+		Properties: properties,
+		TenantName: tenantName,
+		IsValid:    webhookIntegration.IsValid,
+	}
+	msg, err := json.Marshal(integrationToUpdate)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	update := models.SdkClientsUpdates{
+		Type:   sendNotificationType,
+		Update: properties[SchemaVAlert] || shouldSendNotification(tenantName, SchemaVAlert),
+	}
+	serv.SendUpdateToClients(update)
+
+	webhookIntegration.Keys = cloneKeys
+	webhookIntegration.Keys["signing_secret"] = hideIntegrationSecretKey(signingSecret)
+	webhookIntegration.Keys["headers"] = hideWebhookHeaders(headers)
+	webhookIntegration.Properties = properties
+	return webhookIntegration, nil
+}
+
+// encryptWebhookHeaders returns the custom headers with their values encrypted, they may carry credentials like the signing secret
+func encryptWebhookHeaders(headers map[string]interface{}) (map[string]interface{}, error) {
+	encryptedHeaders := make(map[string]interface{})
+	for name, value := range headers {
+		v, ok := value.(string)
+		if !ok {
+			continue
+		}
+		encryptedValue, err := EncryptAES([]byte(v))
+		if err != nil {
+			return map[string]interface{}{}, err
+		}
+		encryptedHeaders[name] = encryptedValue
+	}
+	return encryptedHeaders, nil
+}
+
+func decryptWebhookHeaders(key []byte, headers map[string]interface{}) (map[string]interface{}, error) {
+	decryptedHeaders := make(map[string]interface{})
+	for name, value := range headers {
+		v, ok := value.(string)
+		if !ok {
+			continue
+		}
+		decryptedValue, err := DecryptAES(key, v)
+		if err != nil {
+			return map[string]interface{}{}, err
+		}
+		decryptedHeaders[name] = decryptedValue
+	}
+	return decryptedHeaders, nil
+}
+
+// hideWebhookHeaderValue hides a header value the same way the signing secret is hidden, short values are hidden completely
+func hideWebhookHeaderValue(value string) string {
+	if len(value) < 8 {
+		return "****"
+	}
+	return hideIntegrationSecretKey(value)
+}
+
+func hideWebhookHeaders(headers map[string]interface{}) map[string]interface{} {
+	hiddenHeaders := make(map[string]interface{})
+	for name, value := range headers {
+		if v, ok := value.(string); ok {
+			hiddenHeaders[name] = hideWebhookHeaderValue(v)
+		}
+	}
+	return hiddenHeaders
+}
+
+type webhookNotifier struct{}
+
+func (webhookNotifier) properties(tenantName string) (map[string]bool, bool) {
+	webhookIntegration, ok := cachedIntegration[models.WebhookIntegration](tenantName, webhookIntegrationName)
+	return webhookIntegration.Properties, ok
+}
+
+func (webhookNotifier) send(s *Server, tenantName string, msgs []NotificationMsgWithReply) {
+	webhookIntegration, ok := cachedIntegration[models.WebhookIntegration](tenantName, webhookIntegrationName)
+	if !ok {
+		// webhook is either not enabled or have been disabled - just ack these messages
+		ackMsgs(s, msgs)
+		return
+	}
+	sendNotificationsOneByOne(s, webhookIntegrationName, tenantName, msgs, func(msg NotificationMsg) error {
+		return sendMessageToWebhook(webhookHttpClient, webhookIntegration, msg)
+	}, isRetriableWebhookError)
+}
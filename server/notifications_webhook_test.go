@@ -0,0 +1,97 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestSendMessageToWebhook(t *testing.T) {
+	var received webhookPayload
+	var signature, timestamp, customHeader string
+	var body []byte
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		signature = r.Header.Get(webhookSignatureHeader)
+		timestamp = r.Header.Get(webhookTimestampHeader)
+		customHeader = r.Header.Get("X-Team")
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	integration := models.WebhookIntegration{
+		Name:    webhookIntegrationName,
+		Keys:    map[string]string{"url": ts.URL, "signing_secret": "top-secret"},
+		Headers: map[string]string{"X-Team": "on-call"},
+	}
+	msg := NotificationMsg{TenantName: "memphis", Title: "title", Message: "message", MsgType: PoisonMAlert, Time: time.Now()}
+	if err := sendMessageToWebhook(ts.Client(), integration, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Title != "title" || received.Type != PoisonMAlert || customHeader != "on-call" {
+		t.Errorf("unexpected delivery: %+v, header %v", received, customHeader)
+	}
+	if signature != signWebhookPayload("top-secret", timestamp, body) {
+		t.Errorf("unexpected signature %v", signature)
+	}
+
+	status = http.StatusServiceUnavailable
+	err := sendMessageToWebhook(ts.Client(), integration, msg)
+	if err == nil || !isRetriableWebhookError(err) {
+		t.Errorf("expected a retriable error, got %v", err)
+	}
+	status = http.StatusBadRequest
+	err = sendMessageToWebhook(ts.Client(), integration, msg)
+	if err == nil || isRetriableWebhookError(err) {
+		t.Errorf("expected a non retriable error, got %v", err)
+	}
+
+	if delay := notificationRetryBackoff(3); delay != 4*notificationsRetryBaseDelay {
+		t.Errorf("expected %v, got %v", 4*notificationsRetryBaseDelay, delay)
+	}
+	if delay := notificationRetryBackoff(20); delay != notificationsRetryMaxDelay {
+		t.Errorf("expected %v, got %v", notificationsRetryMaxDelay, delay)
+	}
+}
+
+func TestWebhookHeadersEncryption(t *testing.T) {
+	headers := map[string]interface{}{"Authorization": "Bearer some-token", "X-Team": "ops"}
+	encryptedHeaders, err := encryptWebhookHeaders(headers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if encryptedHeaders["Authorization"] == headers["Authorization"] || encryptedHeaders["X-Team"] == headers["X-Team"] {
+		t.Errorf("expected the header values to be encrypted, got %v", encryptedHeaders)
+	}
+	decryptedHeaders, err := decryptWebhookHeaders(getAESKey(), encryptedHeaders)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decryptedHeaders["Authorization"] != "Bearer some-token" || decryptedHeaders["X-Team"] != "ops" {
+		t.Errorf("unexpected decrypted headers %v", decryptedHeaders)
+	}
+
+	hiddenHeaders := hideWebhookHeaders(headers)
+	if hiddenHeaders["Authorization"] != "****oken" || hiddenHeaders["X-Team"] != "****" {
+		t.Errorf("unexpected hidden headers %v", hiddenHeaders)
+	}
+}
@@ -4546,6 +4546,14 @@ func (s *Server) initializeMemphis() {
 	}
 	s.CompleteRelevantStuckAsyncTasks()
 	s.InitializeMemphisHandlers()
+	err = s.LoadStationPartitionsCache()
+	if err != nil {
+		s.Errorf("Failed loading stations partitions cache: %v", err.Error())
+	}
+	err = s.WarmStationSchemaEnforcementCache()
+	if err != nil {
+		s.Errorf("Failed loading stations schema enforcement cache: %v", err.Error())
+	}
 	opts := s.getOpts()
 	if !opts.DontListen {
 		s.AcceptClientConnections()
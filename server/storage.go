@@ -12,53 +12,58 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/memphisdev/memphis/db"
 	"github.com/memphisdev/memphis/models"
 )
 
-func flushMapToTier2Storage() error {
-	it := IntegrationsHandler{S: serv}
-	for t, tenant := range tieredStorageMsgsMap.m {
-		if IsStorageLimitExceeded(t) {
-			serv.Warnf("[tenant:%s]flushMapToTier2Storage: %s", t, ErrUpgradePlan.Error())
-			it.Warnf("s3", t, "Can't upload messages to tiered storage: you've reached your storage limit for this month")
-			continue
-		}
-		if ValidataAccessToFeature(t, "feature-storage-tiering") {
-			for k, f := range StorageFunctionsMap {
-				switch k {
-				case "s3":
-					if tenantIntegrations, ok := IntegrationsConcurrentCache.Load(t); !ok {
-						continue
-					} else {
-						if _, ok = tenantIntegrations["s3"].(models.Integration); ok {
-							err := f.(func(string, map[string][]StoredMsg) error)(t, tenant)
-							if err != nil {
-								return err
-							}
-							it.Noticef(k, t, "Uploaded a batch of messages to S3 successfully")
-						}
-					}
-				default:
-					return errors.New("failed uploading to tiered storage : unsupported integration")
-				}
-			}
-		} else {
-			serv.Warnf("[tenant: %v]flushMapToTier2Storage: Has no access to feature-storage-tiering in its Plan", t)
-		}
-	}
-	return nil
+var tier2StorageLabels = map[string]string{
+	"s3":                      "S3",
+	filesystemIntegrationName: "the filesystem",
+	gcsIntegrationName:        "Google Cloud Storage",
+	azureBlobIntegrationName:  "Azure Blob Storage",
+}
+
+// tieredStorageObjectInfo describes an object which has been uploaded to a tier 2 storage
+type tieredStorageObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// tieredStorageReader reads back the objects of a tier 2 storage, keys are the ones the objects were uploaded with
+type tieredStorageReader interface {
+	List(ctx context.Context, prefix string) ([]tieredStorageObjectInfo, error)
+	Read(ctx context.Context, key string) ([]byte, error)
+}
 
+func tieredStorageReaderFromIntegration(integrationType string, integration models.Integration) (tieredStorageReader, error) {
+	switch integrationType {
+	case "s3":
+		return newS3TieredStorageReader(integration)
+	case filesystemIntegrationName:
+		return newFilesystemTieredStorageReader(integration)
+	case gcsIntegrationName:
+		return newGcsTieredStorageReader(integration)
+	case azureBlobIntegrationName:
+		return newAzureBlobTieredStorageReader(integration)
+	default:
+		return nil, fmt.Errorf("%v is not a tiered storage integration", integrationType)
+	}
 }
 
-func (s *Server) sendToTier2Storage(storageType interface{}, buf []byte, seq uint64, tierStorageType string) error {
+func (s *Server) sendToTier2Storage(storageType interface{}, buf []byte, seq uint64, ts int64) error {
+	// rehydrated messages are already in the tiered storage, uploading them again would archive them twice
+	if isRehydratedMsg(buf) {
+		return nil
+	}
 	storedType := reflect.TypeOf(storageType).Elem().Name()
 	var streamName, tenantName string
 	switch storedType {
@@ -77,59 +82,48 @@ func (s *Server) sendToTier2Storage(storageType interface{}, buf []byte, seq uin
 		return nil
 	}
 
-	for k := range StorageFunctionsMap {
-		if ValidataAccessToFeature(tenantName, "feature-storage-tiering") {
-			switch k {
-			case "s3":
-				if tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
-					continue
-				} else {
-					if _, ok = tenantIntegrations["s3"].(models.Integration); ok {
-						msgId := map[string]string{}
-						seqNumber := strconv.Itoa(int(seq))
-						msgId["msg-id"] = streamName + seqNumber
-						if tenantName == _EMPTY_ {
-							tenantName = serv.MemphisGlobalAccountString()
-						}
-						subject := fmt.Sprintf("%s.%s.%s", tieredStorageStream, streamName, tenantName)
-						// TODO: if the stream is not exists save the messages in buffer
-						if TIERED_STORAGE_STREAM_CREATED {
-							tierStorageMsg := TieredStorageMsg{
-								Buf:         buf,
-								StationName: streamName,
-								TenantName:  tenantName,
-							}
-
-							msg, err := json.Marshal(tierStorageMsg)
-							if err != nil {
-								return err
-							}
-							s.sendInternalAccountMsgWithHeadersWithEcho(s.MemphisGlobalAccount(), subject, msg, msgId)
-						}
-					}
-				}
-			default:
-				return errors.New("failed send to tiered storage : unsupported integration")
-			}
+	if !ValidataAccessToFeature(tenantName, "feature-storage-tiering") {
+		return nil
+	}
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok || !hasTier2StorageIntegration(tenantIntegrations) {
+		return nil
+	}
+	// the message is published once and uploaded to every connected tier 2 storage on flush
+	msgId := map[string]string{}
+	seqNumber := strconv.Itoa(int(seq))
+	msgId["msg-id"] = streamName + seqNumber
+	if tenantName == _EMPTY_ {
+		tenantName = serv.MemphisGlobalAccountString()
+	}
+	subject := fmt.Sprintf("%s.%s.%s", tieredStorageStream, streamName, tenantName)
+	// TODO: if the stream is not exists save the messages in buffer
+	if TIERED_STORAGE_STREAM_CREATED {
+		tierStorageMsg := TieredStorageMsg{
+			Buf:         buf,
+			StationName: streamName,
+			TenantName:  tenantName,
+			Sequence:    seq,
+			Timestamp:   ts,
 		}
+
+		msg, err := json.Marshal(tierStorageMsg)
+		if err != nil {
+			return err
+		}
+		s.sendInternalAccountMsgWithHeadersWithEcho(s.MemphisGlobalAccount(), subject, msg, msgId)
 	}
 	return nil
 }
 
-func (s *Server) storeInTieredStorageMap(msg StoredMsg) {
-	tieredStorageMapLock.Lock()
-	stationName := msg.Subject
-	tenantName := msg.TenantName
-	if strings.Contains(msg.Subject, "#") {
-		stationName = strings.Replace(msg.Subject, "#", ".", -1)
-	}
-	_, ok := tieredStorageMsgsMap.Load(tenantName)
-	if !ok {
-		tieredStorageMsgsMap.Add(tenantName, map[string][]StoredMsg{})
+// hasTier2StorageIntegration reports whether one of the tier 2 storage integrations is connected
+func hasTier2StorageIntegration(tenantIntegrations map[string]interface{}) bool {
+	for k := range StorageFunctionsMap {
+		if _, ok := tenantIntegrations[k].(models.Integration); ok {
+			return true
+		}
 	}
-
-	tieredStorageMsgsMap.m[tenantName][stationName] = append(tieredStorageMsgsMap.m[tenantName][stationName], msg)
-	tieredStorageMapLock.Unlock()
+	return false
 }
 
 func (s *Server) handleNewTieredStorageMsg(msg []byte, reply string) {
@@ -154,6 +148,14 @@ func (s *Server) handleNewTieredStorageMsg(msg []byte, reply string) {
 		return
 	}
 
+	// messages sent by older versions do not carry the original sequence and timestamp
+	if tieredStorageMsg.Sequence != 0 {
+		seq = tieredStorageMsg.Sequence
+	}
+	if tieredStorageMsg.Timestamp != 0 {
+		intTs = int(tieredStorageMsg.Timestamp)
+	}
+
 	dataFirstIdx := 0
 	dataFirstIdx = getHdrLastIdxFromRaw(payload) + 1
 	if dataFirstIdx > len(payload)-len(CR_LF) {
@@ -173,5 +175,126 @@ func (s *Server) handleNewTieredStorageMsg(msg []byte, reply string) {
 		TenantName:   tieredStorageMsg.TenantName,
 	}
 
-	s.storeInTieredStorageMap(message)
+	s.storeInTieredStorageBuffer(message)
+}
+
+// cacheDetailsCloudStorage caches a cloud object storage integration whose keys are all strings
+func cacheDetailsCloudStorage(integrationType string, keyNames []string, keys map[string]interface{}, tenantName string) {
+	integration := models.Integration{}
+	integration.Keys = make(map[string]interface{})
+	integration.Properties = make(map[string]bool)
+	if keys == nil {
+		deleteIntegrationFromTenant(tenantName, integrationType, IntegrationsConcurrentCache)
+		return
+	}
+	for _, keyName := range keyNames {
+		if value, ok := keys[keyName].(string); ok {
+			integration.Keys[keyName] = value
+		} else {
+			integration.Keys[keyName] = _EMPTY_
+		}
+	}
+	if value, ok := keys["archive_format"].(string); ok && value != _EMPTY_ {
+		integration.Keys["archive_format"] = value
+	} else {
+		integration.Keys["archive_format"] = tieredStorageFormatJson
+	}
+	integration.Name = integrationType
+	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
+		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{integrationType: integration})
+	} else {
+		err := addIntegrationToTenant(tenantName, integrationType, IntegrationsConcurrentCache, integration)
+		if err != nil {
+			serv.Errorf("cacheDetailsCloudStorage: %s: %s", integrationType, err.Error())
+			return
+		}
+	}
+}
+
+// cloudStorageIntegrationKeys validates the common keys of a cloud object storage integration,
+// an empty secret key keeps the one which is already stored since it is hidden from the users
+func cloudStorageIntegrationKeys(integrationType, tenantName string, keys map[string]interface{}, keyNames []string) (map[string]interface{}, int, error) {
+	validated := make(map[string]interface{})
+	for _, keyName := range keyNames {
+		value, ok := keys[keyName].(string)
+		if !ok {
+			value = _EMPTY_
+		}
+		validated[keyName] = strings.TrimSpace(value)
+	}
+
+	format := tieredStorageFormatJson
+	if value, ok := keys["archive_format"].(string); ok && value != _EMPTY_ {
+		format = strings.ToLower(value)
+		err := validateTieredStorageFormat(format)
+		if err != nil {
+			return map[string]interface{}{}, SHOWABLE_ERROR_STATUS_CODE, err
+		}
+	}
+	validated["archive_format"] = format
+
+	if validated["secret_key"] == _EMPTY_ {
+		exist, integrationFromDb, err := db.GetIntegration(integrationType, tenantName)
+		if err != nil {
+			return map[string]interface{}{}, 500, err
+		}
+		if value, ok := integrationFromDb.Keys["secret_key"].(string); exist && ok && value != _EMPTY_ {
+			decryptedValue, err := DecryptAES(getAESKey(), value)
+			if err != nil {
+				return map[string]interface{}{}, 500, err
+			}
+			validated["secret_key"] = decryptedValue
+		}
+	}
+	return validated, 0, nil
+}
+
+func saveCloudStorageIntegration(integrationType, tenantName string, keys map[string]interface{}, isUpdate bool) (models.Integration, error) {
+	properties := map[string]bool{}
+	if !isUpdate {
+		exist, _, err := db.GetIntegration(integrationType, tenantName)
+		if err != nil {
+			return models.Integration{}, err
+		}
+		if exist {
+			return models.Integration{}, fmt.Errorf("%v integration already exists", integrationType)
+		}
+	}
+	cloneKeys := copyStringMapToInterfaceMap(GetKeysAsStringMap(keys))
+	encryptedValue, err := EncryptAES([]byte(keys["secret_key"].(string)))
+	if err != nil {
+		return models.Integration{}, err
+	}
+	cloneKeys["secret_key"] = encryptedValue
+
+	var integration models.Integration
+	if isUpdate {
+		integration, err = db.UpdateIntegration(tenantName, integrationType, cloneKeys, properties)
+	} else {
+		integration, err = db.InsertNewIntegration(tenantName, integrationType, cloneKeys, properties)
+	}
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integrationToUpdate := models.CreateIntegration{
+		Name:       integrationType,
+		Keys:       keys,
+		Properties: properties,
+		TenantName: tenantName,
+		IsValid:    integration.IsValid,
+	}
+	msg, err := json.Marshal(integrationToUpdate)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integration.Keys = copyStringMapToInterfaceMap(GetKeysAsStringMap(keys))
+	integration.Keys["secret_key"] = hideIntegrationSecretKey(keys["secret_key"].(string))
+	integration.Properties = properties
+	return integration, nil
 }
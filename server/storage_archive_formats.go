@@ -0,0 +1,431 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+
+	"github.com/hamba/avro/v2"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	tieredStorageFormatJson       = "json"
+	tieredStorageFormatNdjsonGzip = "ndjson_gzip"
+	tieredStorageFormatNdjsonZstd = "ndjson_zstd"
+	tieredStorageFormatAvro       = "avro"
+	tieredStorageFormatParquet    = "parquet"
+	tieredStorageDateLayout       = "2006-01-02"
+	avroContainerMagic            = "Obj\x01"
+	avroContainerBlockSize        = 1000
+	tieredStorageAvroSchema       = `{"type":"record","name":"TieredStorageMessage","namespace":"dev.memphis","fields":[{"name":"sequence","type":"long"},{"name":"timestamp","type":"long","doc":"unix time in nanoseconds"},{"name":"headers","type":{"type":"map","values":{"type":"array","items":"string"}}},{"name":"payload","type":"bytes"}]}`
+)
+
+// the extension of the objects is what the format of an archive is detected by when it is read back
+var tieredStorageFormatExtensions = map[string]string{
+	tieredStorageFormatJson:       ".json",
+	tieredStorageFormatNdjsonGzip: ".ndjson.gz",
+	tieredStorageFormatNdjsonZstd: ".ndjson.zst",
+	tieredStorageFormatAvro:       ".avro",
+	tieredStorageFormatParquet:    ".parquet",
+}
+
+var tieredStorageParsedAvroSchema = avro.MustParse(tieredStorageAvroSchema)
+
+// tieredStorageRecord is a message within the archive formats of the tiered storage, the payload is kept as its original
+// bytes and the headers keep their original case and every value of repeated headers
+type tieredStorageRecord struct {
+	Sequence  int64                `json:"sequence" avro:"sequence"`
+	Timestamp int64                `json:"timestamp" avro:"timestamp"`
+	Headers   tieredStorageHeaders `json:"headers" avro:"headers"`
+	Payload   []byte               `json:"payload" avro:"payload"`
+}
+
+type tieredStorageUpload struct {
+	Key      string
+	Body     []byte
+	Messages int
+}
+
+func validateTieredStorageFormat(format string) error {
+	if _, ok := tieredStorageFormatExtensions[format]; !ok {
+		return fmt.Errorf("archive format %v is not supported, use json, ndjson_gzip, ndjson_zstd, avro or parquet", format)
+	}
+	return nil
+}
+
+func tieredStorageFormat(integration models.Integration) string {
+	format, _ := integration.Keys["archive_format"].(string)
+	if format == _EMPTY_ {
+		return tieredStorageFormatJson
+	}
+	return format
+}
+
+func tieredStorageFormatFromKey(key string) (string, error) {
+	for format, extension := range tieredStorageFormatExtensions {
+		if format != tieredStorageFormatJson && strings.HasSuffix(key, extension) {
+			return format, nil
+		}
+	}
+	if strings.HasSuffix(key, tieredStorageFormatExtensions[tieredStorageFormatJson]) {
+		return tieredStorageFormatJson, nil
+	}
+	return _EMPTY_, fmt.Errorf("object %v is not a tiered storage archive", key)
+}
+
+// tieredStorageStreamPartition splits the name of a station's stream into the station name and its partition
+func tieredStorageStreamPartition(streamName string) (string, int) {
+	streamName = strings.Replace(streamName, "#", ".", -1)
+	idx := strings.LastIndex(streamName, "$")
+	if idx == -1 {
+		return streamName, stationArchiveNoPartition
+	}
+	partition, err := strconv.Atoi(streamName[idx+1:])
+	if err != nil {
+		return streamName, stationArchiveNoPartition
+	}
+	return streamName[:idx], partition
+}
+
+// tieredStorageArchivePrefix returns the prefix of the objects of a partition in the archive formats, the key layout is
+// partitioned by station, partition and date so data lake engines can prune the objects they scan
+func tieredStorageArchivePrefix(tenantName, stationName string, partition int) string {
+	return fmt.Sprintf("memphis/%v/station=%v/partition=%v/", tieredStorageTenantFolder(tenantName), stationName, partition)
+}
+
+// buildTieredStorageUploads encodes the messages of a stream into the objects to upload, the archive formats are split by
+// the date the messages were produced at while the json format keeps its original single object layout
+func buildTieredStorageUploads(tenantName, streamName, keyPrefix, format, uid string, msgs []StoredMsg) ([]tieredStorageUpload, error) {
+	if format == tieredStorageFormatJson {
+		var messages []Msg
+		for _, msg := range msgs {
+			hdrs := tieredStorageHeaders{}
+			if len(msg.Header) > 0 {
+				hdrs = parseTieredStorageHeaders(msg.Header)
+			}
+			messages = append(messages, Msg{Payload: hex.EncodeToString(msg.Data), Headers: hdrs, Sequence: msg.Sequence, Timestamp: msg.Time})
+		}
+		var buf bytes.Buffer
+		err := json.NewEncoder(&buf).Encode(messages)
+		if err != nil {
+			return nil, err
+		}
+		key := keyPrefix + tieredStorageObjectsPrefix(tenantName, streamName) + uid + "(" + strconv.Itoa(len(msgs)) + ").json"
+		return []tieredStorageUpload{{Key: key, Body: buf.Bytes(), Messages: len(msgs)}}, nil
+	}
+
+	stationName, partition := tieredStorageStreamPartition(streamName)
+	recordsByDate := map[string][]tieredStorageRecord{}
+	for _, msg := range msgs {
+		record := tieredStorageRecord{
+			Sequence:  int64(msg.Sequence),
+			Timestamp: msg.Time.UnixNano(),
+			Headers:   tieredStorageHeaders{},
+			Payload:   msg.Data,
+		}
+		if len(msg.Header) > 0 {
+			record.Headers = parseTieredStorageHeaders(msg.Header)
+		}
+		date := msg.Time.UTC().Format(tieredStorageDateLayout)
+		recordsByDate[date] = append(recordsByDate[date], record)
+	}
+	dates := make([]string, 0, len(recordsByDate))
+	for date := range recordsByDate {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	uploads := make([]tieredStorageUpload, 0, len(dates))
+	for _, date := range dates {
+		records := recordsByDate[date]
+		body, err := encodeTieredStorageRecords(format, records)
+		if err != nil {
+			return nil, err
+		}
+		key := keyPrefix + tieredStorageArchivePrefix(tenantName, stationName, partition) + "date=" + date + "/" + uid + "(" + strconv.Itoa(len(records)) + ")" + tieredStorageFormatExtensions[format]
+		uploads = append(uploads, tieredStorageUpload{Key: key, Body: body, Messages: len(records)})
+	}
+	return uploads, nil
+}
+
+func encodeTieredStorageRecords(format string, records []tieredStorageRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case tieredStorageFormatNdjsonGzip:
+		writer := gzip.NewWriter(&buf)
+		err := writeNdjson(writer, records)
+		if err != nil {
+			return nil, err
+		}
+	case tieredStorageFormatNdjsonZstd:
+		writer, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		err = writeNdjson(writer, records)
+		if err != nil {
+			return nil, err
+		}
+	case tieredStorageFormatAvro:
+		err := writeAvroContainer(&buf, records)
+		if err != nil {
+			return nil, err
+		}
+	case tieredStorageFormatParquet:
+		return writeParquetFile(records)
+	default:
+		return nil, fmt.Errorf("unsupported archive format %v", format)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeNdjson(writer io.WriteCloser, records []tieredStorageRecord) error {
+	enc := json.NewEncoder(writer)
+	for _, record := range records {
+		err := enc.Encode(record)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// decodeTieredStorageObject decodes an object of the tiered storage by the format its key was written with
+func decodeTieredStorageObject(key string, body []byte) ([]Msg, error) {
+	format, err := tieredStorageFormatFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var records []tieredStorageRecord
+	switch format {
+	case tieredStorageFormatJson:
+		var msgs []Msg
+		err = json.Unmarshal(body, &msgs)
+		return msgs, err
+	case tieredStorageFormatNdjsonGzip:
+		var reader *gzip.Reader
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		records, err = readNdjson(reader)
+	case tieredStorageFormatNdjsonZstd:
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		records, err = readNdjson(decoder)
+	case tieredStorageFormatAvro:
+		records, err = readAvroContainer(body)
+	case tieredStorageFormatParquet:
+		records, err = readParquetFile(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Msg, 0, len(records))
+	for _, record := range records {
+		msgs = append(msgs, Msg{
+			Payload:   hex.EncodeToString(record.Payload),
+			Headers:   record.Headers,
+			Sequence:  uint64(record.Sequence),
+			Timestamp: time.Unix(0, record.Timestamp),
+		})
+	}
+	return msgs, nil
+}
+
+func readNdjson(reader io.Reader) ([]tieredStorageRecord, error) {
+	var records []tieredStorageRecord
+	dec := json.NewDecoder(bufio.NewReader(reader))
+	for {
+		var record tieredStorageRecord
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func writeAvroVarint(w *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	w.Write(b[:n])
+}
+
+func writeAvroBytes(w *bytes.Buffer, v []byte) {
+	writeAvroVarint(w, int64(len(v)))
+	w.Write(v)
+}
+
+// writeAvroContainer writes the records as an avro object container file with deflate compressed blocks
+func writeAvroContainer(w *bytes.Buffer, records []tieredStorageRecord) error {
+	var sync [16]byte
+	_, err := rand.Read(sync[:])
+	if err != nil {
+		return err
+	}
+	w.WriteString(avroContainerMagic)
+	writeAvroVarint(w, 2)
+	writeAvroBytes(w, []byte("avro.schema"))
+	writeAvroBytes(w, []byte(tieredStorageAvroSchema))
+	writeAvroBytes(w, []byte("avro.codec"))
+	writeAvroBytes(w, []byte("deflate"))
+	writeAvroVarint(w, 0)
+	w.Write(sync[:])
+
+	for start := 0; start < len(records); start += avroContainerBlockSize {
+		end := start + avroContainerBlockSize
+		if end > len(records) {
+			end = len(records)
+		}
+		var block bytes.Buffer
+		compressor, err := flate.NewWriter(&block, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		enc := avro.NewEncoderForSchema(tieredStorageParsedAvroSchema, compressor)
+		for _, record := range records[start:end] {
+			err = enc.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+		err = compressor.Close()
+		if err != nil {
+			return err
+		}
+		writeAvroVarint(w, int64(end-start))
+		writeAvroBytes(w, block.Bytes())
+		w.Write(sync[:])
+	}
+	return nil
+}
+
+func readAvroBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 || size > int64(r.Len()) {
+		return nil, errors.New("invalid avro container")
+	}
+	v := make([]byte, size)
+	_, err = io.ReadFull(r, v)
+	return v, err
+}
+
+func readAvroContainer(body []byte) ([]tieredStorageRecord, error) {
+	if !bytes.HasPrefix(body, []byte(avroContainerMagic)) {
+		return nil, errors.New("not an avro container")
+	}
+	r := bytes.NewReader(body[len(avroContainerMagic):])
+	metaData := map[string][]byte{}
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err = binary.ReadVarint(r); err != nil {
+				return nil, err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			key, err := readAvroBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readAvroBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			metaData[string(key)] = value
+		}
+	}
+	var sync [16]byte
+	if _, err := io.ReadFull(r, sync[:]); err != nil {
+		return nil, err
+	}
+	schema, err := avro.Parse(string(metaData["avro.schema"]))
+	if err != nil {
+		return nil, err
+	}
+	codec := string(metaData["avro.codec"])
+	if codec != _EMPTY_ && codec != "null" && codec != "deflate" {
+		return nil, fmt.Errorf("unsupported avro codec %v", codec)
+	}
+
+	var records []tieredStorageRecord
+	for r.Len() > 0 {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		block, err := readAvroBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		var blockSync [16]byte
+		if _, err = io.ReadFull(r, blockSync[:]); err != nil {
+			return nil, err
+		}
+		if blockSync != sync {
+			return nil, errors.New("invalid avro container sync marker")
+		}
+		var blockReader io.Reader = bytes.NewReader(block)
+		if codec == "deflate" {
+			blockReader = flate.NewReader(blockReader)
+		}
+		dec := avro.NewDecoderForSchema(schema, blockReader)
+		for i := int64(0); i < count; i++ {
+			var record tieredStorageRecord
+			err = dec.Decode(&record)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTieredStorageArchiveFormats(t *testing.T) {
+	day := time.Date(2023, 10, 16, 23, 59, 59, 123456789, time.UTC)
+	msgs := []StoredMsg{
+		{Sequence: 10, Time: day, Header: []byte("NATS/1.0\r\nTrace-Id: a:b\r\nTag: x\r\nTag:  padded \r\n\r\n"), Data: []byte{0, 1, 2, 255}},
+		{Sequence: 11, Time: day.Add(time.Second), Data: []byte{}},
+	}
+	for format := range tieredStorageFormatExtensions {
+		uploads, err := buildTieredStorageUploads("acme", "orders$2", "", format, "uid", msgs)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+		expectedUploads := 2
+		if format == tieredStorageFormatJson {
+			expectedUploads = 1
+		}
+		if len(uploads) != expectedUploads {
+			t.Fatalf("%v: expected %v uploads, got %v", format, expectedUploads, len(uploads))
+		}
+		if format != tieredStorageFormatJson && uploads[0].Key != "memphis/acme/station=orders/partition=2/date=2023-10-16/uid(1)"+tieredStorageFormatExtensions[format] {
+			t.Errorf("%v: unexpected key %v", format, uploads[0].Key)
+		}
+
+		var decoded []Msg
+		for _, upload := range uploads {
+			objectMsgs, err := decodeTieredStorageObject(upload.Key, upload.Body)
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v", format, err)
+			}
+			decoded = append(decoded, objectMsgs...)
+		}
+		if len(decoded) != len(msgs) {
+			t.Fatalf("%v: expected %v messages, got %v", format, len(msgs), len(decoded))
+		}
+		for i, msg := range decoded {
+			if msg.Sequence != msgs[i].Sequence || !msg.Timestamp.Equal(msgs[i].Time) || msg.Payload != hex.EncodeToString(msgs[i].Data) {
+				t.Errorf("%v: message %v was not restored losslessly: %+v", format, i, msg)
+			}
+		}
+		expectedHeaders := tieredStorageHeaders{"Trace-Id": {"a:b"}, "Tag": {"x", " padded "}}
+		if !reflect.DeepEqual(decoded[0].Headers, expectedHeaders) {
+			t.Errorf("%v: headers were not restored: %v", format, decoded[0].Headers)
+		}
+	}
+
+	legacy := []byte(`[{"payload":"00","headers":{"Trace-Id":"a:b"},"timestamp":"2023-10-16T00:00:00Z"}]`)
+	decoded, err := decodeTieredStorageObject("memphis/acme/orders$1/uid(1).json", legacy)
+	if err != nil || len(decoded) != 1 || !reflect.DeepEqual(decoded[0].Headers, tieredStorageHeaders{"Trace-Id": {"a:b"}}) {
+		t.Errorf("expected the single valued headers of older objects to be read: %+v: %v", decoded, err)
+	}
+}
+
+var parquetGoldenRecords = []tieredStorageRecord{
+	{Sequence: 1, Timestamp: 1697500799123456789, Headers: tieredStorageHeaders{"Tag": {"x", " padded "}}, Payload: []byte("first")},
+	{Sequence: 2, Timestamp: 1697500800000000000, Headers: tieredStorageHeaders{}, Payload: []byte{0, 1, 2, 255}},
+}
+
+const parquetGoldenFile = "testdata/tiered_storage_golden.parquet"
+
+// the golden file pins the bytes of the parquet writer, TestParquetGoldenFileStandardReader checks them against pyarrow
+func TestParquetGoldenFile(t *testing.T) {
+	golden, err := os.ReadFile(parquetGoldenFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written, err := writeParquetFile(parquetGoldenRecords)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(written, golden) {
+		t.Errorf("the parquet writer output differs from %v", parquetGoldenFile)
+	}
+	records, err := readParquetFile(golden)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(records, parquetGoldenRecords) {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+// run with python3 and pyarrow installed to read the golden file with a standard parquet reader
+func TestParquetGoldenFileStandardReader(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	if exec.Command(python, "-c", "import pyarrow.parquet").Run() != nil {
+		t.Skip("pyarrow is not installed")
+	}
+	path, err := filepath.Abs(parquetGoldenFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	script := `import json, sys
+import pyarrow as pa, pyarrow.parquet as pq
+table = pq.read_table(sys.argv[1])
+print(json.dumps({
+    "sequence": table.column("sequence").to_pylist(),
+    "timestamp": table.column("timestamp").cast(pa.int64()).to_pylist(),
+    "timestamp_type": str(table.schema.field("timestamp").type),
+    "headers": table.column("headers").to_pylist(),
+    "payload": [p.hex() for p in table.column("payload").to_pylist()],
+}))`
+	out, err := exec.Command(python, "-c", script, path).Output()
+	if err != nil {
+		t.Fatalf("pyarrow failed reading %v: %v", parquetGoldenFile, err)
+	}
+	var table struct {
+		Sequence      []int64  `json:"sequence"`
+		Timestamp     []int64  `json:"timestamp"`
+		TimestampType string   `json:"timestamp_type"`
+		Headers       []string `json:"headers"`
+		Payload       []string `json:"payload"`
+	}
+	if err := json.Unmarshal(out, &table); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.TimestampType != "timestamp[ns, tz=UTC]" {
+		t.Errorf("unexpected timestamp type %v", table.TimestampType)
+	}
+	for i, record := range parquetGoldenRecords {
+		headers, _ := json.Marshal(record.Headers)
+		if table.Sequence[i] != record.Sequence || table.Timestamp[i] != record.Timestamp || table.Headers[i] != string(headers) || table.Payload[i] != hex.EncodeToString(record.Payload) {
+			t.Errorf("record %v was not read back by pyarrow: %+v", i, table)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+const (
+	azureBlobIntegrationName = "azure_blob"
+	azureBlobApiVersion      = "2020-10-02"
+)
+
+var azureBlobIntegrationKeys = []string{"account_name", "secret_key", "container_name", "url"}
+
+type azureBlobClient struct {
+	httpClient  *http.Client
+	endpoint    *url.URL
+	accountName string
+	accountKey  []byte
+}
+
+func cacheDetailsAzureBlob(keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	cacheDetailsCloudStorage(azureBlobIntegrationName, azureBlobIntegrationKeys, keys, tenantName)
+}
+
+// newAzureBlobClient authorizes its requests with the storage account key (the secret key),
+// a custom url such as the one of Azurite replaces the public endpoint of the account
+func newAzureBlobClient(accountName, secretKey, integrationUrl string) (*azureBlobClient, error) {
+	if accountName == _EMPTY_ {
+		return nil, errors.New("must provide account name for azure blob integration")
+	}
+	accountKey, err := base64.StdEncoding.DecodeString(secretKey)
+	if err != nil || len(accountKey) == 0 {
+		return nil, errors.New("the secret key should be the base64 encoded key of the storage account")
+	}
+	if integrationUrl == _EMPTY_ {
+		integrationUrl = fmt.Sprintf("https://%s.blob.core.windows.net", accountName)
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(integrationUrl, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == _EMPTY_ {
+		return nil, errors.New("invalid url, it should be an http or https url")
+	}
+	return &azureBlobClient{
+		httpClient:  &http.Client{Timeout: cloudStorageRequestTimeout},
+		endpoint:    endpoint,
+		accountName: accountName,
+		accountKey:  accountKey,
+	}, nil
+}
+
+func (c *azureBlobClient) do(method, path string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	return c.doWithContext(context.Background(), method, path, query, headers, body)
+}
+
+func (c *azureBlobClient) doWithContext(ctx context.Context, method, path string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = c.endpoint.Path + "/" + path
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureBlobApiVersion)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Authorization", "SharedKey "+c.accountName+":"+c.sign(req, len(body)))
+	return c.httpClient.Do(req)
+}
+
+// sign computes the Shared Key signature of the request as described in
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (c *azureBlobClient) sign(req *http.Request, contentLength int) string {
+	length := _EMPTY_
+	if contentLength > 0 {
+		length = strconv.Itoa(contentLength)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, "x-ms-") {
+			msHeaders = append(msHeaders, lowerName+":"+strings.TrimSpace(req.Header.Get(name))+"\n")
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + c.accountName + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		_EMPTY_, // Date, x-ms-date is used instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, _EMPTY_) + resource,
+	}, "\n")
+	mac := hmac.New(sha256.New, c.accountKey)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func testAzureBlobIntegration(client *azureBlobClient, containerName string) (int, error) {
+	resp, err := client.do(http.MethodGet, containerName, url.Values{"restype": {"container"}}, nil, nil)
+	if err != nil {
+		return SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("the endpoint url %s is unreachable", client.endpoint.String())
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return 0, nil
+	case http.StatusForbidden:
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("invalid account name or secret key")
+	case http.StatusNotFound:
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("container does not exist")
+	case http.StatusBadRequest:
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("invalid container name")
+	default:
+		return 500, fmt.Errorf("unexpected response from azure blob storage: %v", resp.Status)
+	}
+}
+
+func testAzureBlobIntegrationKeys(keys map[string]string) (int, error) {
+	if keys["container_name"] == _EMPTY_ {
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide container name for azure blob integration")
+	}
+	client, err := newAzureBlobClient(keys["account_name"], keys["secret_key"], keys["url"])
+	if err != nil {
+		return SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	return testAzureBlobIntegration(client, keys["container_name"])
+}
+
+func uploadToAzureBlob(client *azureBlobClient, containerName, key string, body []byte) error {
+	headers := map[string]string{
+		"x-ms-blob-type": "BlockBlob",
+		"Content-Type":   "application/octet-stream",
+	}
+	resp, err := client.do(http.MethodPut, containerName+"/"+key, url.Values{}, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+type azureBlobTieredStorageReader struct {
+	client        *azureBlobClient
+	containerName string
+}
+
+func newAzureBlobTieredStorageReader(integration models.Integration) (tieredStorageReader, error) {
+	keys := GetKeysAsStringMap(integration.Keys)
+	client, err := newAzureBlobClient(keys["account_name"], keys["secret_key"], keys["url"])
+	if err != nil {
+		return nil, err
+	}
+	return &azureBlobTieredStorageReader{client: client, containerName: keys["container_name"]}, nil
+}
+
+func (r *azureBlobTieredStorageReader) get(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	resp, err := r.client.doWithContext(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// List pages through the List Blobs operation, see https://learn.microsoft.com/en-us/rest/api/storageservices/list-blobs
+func (r *azureBlobTieredStorageReader) List(ctx context.Context, prefix string) ([]tieredStorageObjectInfo, error) {
+	objects := []tieredStorageObjectInfo{}
+	marker := _EMPTY_
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != _EMPTY_ {
+			query.Set("marker", marker)
+		}
+		body, err := r.get(ctx, r.containerName, query)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Blobs []struct {
+				Name       string `xml:"Name"`
+				Properties struct {
+					LastModified  string `xml:"Last-Modified"`
+					ContentLength int64  `xml:"Content-Length"`
+				} `xml:"Properties"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.Unmarshal(body, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range page.Blobs {
+			lastModified, _ := http.ParseTime(blob.Properties.LastModified)
+			objects = append(objects, tieredStorageObjectInfo{Key: blob.Name, Size: blob.Properties.ContentLength, LastModified: lastModified})
+		}
+		if page.NextMarker == _EMPTY_ {
+			return objects, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+func (r *azureBlobTieredStorageReader) Read(ctx context.Context, key string) ([]byte, error) {
+	return r.get(ctx, r.containerName+"/"+key, url.Values{})
+}
+
+func (s *Server) uploadToAzureBlobStorage(tenantName string, tenant map[string][]StoredMsg, keyPrefix string) error {
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return nil
+	}
+	integration, ok := tenantIntegrations[azureBlobIntegrationName].(models.Integration)
+	if !ok {
+		return nil
+	}
+	keys := GetKeysAsStringMap(integration.Keys)
+	client, err := newAzureBlobClient(keys["account_name"], keys["secret_key"], keys["url"])
+	if err != nil {
+		return errors.New("uploadToAzureBlobStorage failure " + err.Error())
+	}
+
+	for k, msgs := range tenant {
+		uploads, err := buildTieredStorageUploads(tenantName, k, keyPrefix, tieredStorageFormat(integration), s.memphis.nuid.Next(), msgs)
+		if err != nil {
+			return err
+		}
+		size := int64(0)
+		for _, msg := range msgs {
+			size += int64(len(msg.Data)) + int64(len(msg.Header))
+		}
+		for _, upload := range uploads {
+			err = uploadToAzureBlob(client, keys["container_name"], upload.Key, upload.Body)
+			if err != nil {
+				return errors.New("uploadToAzureBlobStorage: failed to upload blob to Azure: " + err.Error())
+			}
+			s.Noticef("new file has been uploaded to Azure Blob Storage: %s", upload.Key)
+		}
+		IncrementEventCounter(tieredStorageTenantFolder(tenantName), "tiered", size, int64(len(msgs)), _EMPTY_, []byte{}, []byte{})
+	}
+	return nil
+}
+
+func (it IntegrationsHandler) handleAzureBlobIntegration(tenantName string, keys map[string]interface{}) (int, map[string]interface{}, error) {
+	validatedKeys, statusCode, err := cloudStorageIntegrationKeys(azureBlobIntegrationName, tenantName, keys, azureBlobIntegrationKeys)
+	if err != nil {
+		return statusCode, map[string]interface{}{}, err
+	}
+	statusCode, err = testAzureBlobIntegrationKeys(GetKeysAsStringMap(validatedKeys))
+	if err != nil {
+		return statusCode, map[string]interface{}{}, err
+	}
+	return statusCode, validatedKeys, nil
+}
+
+func (it IntegrationsHandler) handleCreateAzureBlobIntegration(tenantName string, keys map[string]interface{}) (models.Integration, int, error) {
+	statusCode, keys, err := it.handleAzureBlobIntegration(tenantName, keys)
+	if err != nil {
+		return models.Integration{}, statusCode, err
+	}
+	azureBlobIntegration, err := saveCloudStorageIntegration(azureBlobIntegrationName, tenantName, keys, false)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+		} else {
+			return models.Integration{}, 500, err
+		}
+	}
+	return azureBlobIntegration, statusCode, nil
+}
+
+func (it IntegrationsHandler) handleUpdateAzureBlobIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	statusCode, keys, err := it.handleAzureBlobIntegration(tenantName, body.Keys)
+	if err != nil {
+		return models.Integration{}, statusCode, err
+	}
+	azureBlobIntegration, err := saveCloudStorageIntegration(azureBlobIntegrationName, tenantName, keys, true)
+	if err != nil {
+		return azureBlobIntegration, 500, err
+	}
+	return azureBlobIntegration, statusCode, nil
+}
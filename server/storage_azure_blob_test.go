@@ -0,0 +1,76 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// run Azurite with AZURITE_BLOB_ENDPOINT=http://127.0.0.1:10000/devstoreaccount1 to run this test
+func TestAzureBlobTieredStorageEmulator(t *testing.T) {
+	endpoint := os.Getenv("AZURITE_BLOB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AZURITE_BLOB_ENDPOINT is not set")
+	}
+	// the well known credentials of the Azurite account
+	account := "devstoreaccount1"
+	accountKey := "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	client, err := newAzureBlobClient(account, accountKey, endpoint)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	container := "memphis-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	resp, err := client.do(http.MethodPut, container, url.Values{"restype": {"container"}}, nil, nil)
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed creating the container: %v %v", err, resp)
+	}
+	resp.Body.Close()
+
+	keys := map[string]string{"account_name": account, "secret_key": accountKey, "container_name": container, "url": endpoint}
+	if _, err := testAzureBlobIntegrationKeys(keys); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys["secret_key"] = base64.StdEncoding.EncodeToString([]byte("wrong key"))
+	if _, err := testAzureBlobIntegrationKeys(keys); err == nil {
+		t.Errorf("expected a wrong key to fail the test")
+	}
+
+	msgs := []StoredMsg{{Sequence: 1, Time: time.Now(), Data: []byte("azure")}}
+	uploads, err := buildTieredStorageUploads("acme", "orders$1", "", tieredStorageFormatParquet, "uid", msgs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, upload := range uploads {
+		if err := uploadToAzureBlob(client, container, upload.Key, upload.Body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := client.do(http.MethodGet, container+"/"+upload.Key, url.Values{}, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		decoded, err := decodeTieredStorageObject(upload.Key, body)
+		if err != nil || len(decoded) != 1 || decoded[0].Payload != hex.EncodeToString([]byte("azure")) {
+			t.Errorf("unexpected blob %v: %v", upload.Key, err)
+		}
+	}
+}
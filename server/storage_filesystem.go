@@ -0,0 +1,553 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+)
+
+const (
+	filesystemIntegrationName      = "filesystem"
+	filesystemDefaultMaxFileSizeMb = 100
+	filesystemCleanupInterval      = time.Minute
+	filesystemTempFilePrefix       = ".memphis-tmp-"
+	filesystemProbeFilePrefix      = ".memphis-write-probe-"
+	// leftovers of writes that were interrupted by a crash
+	filesystemStaleTempFileAge = time.Hour
+)
+
+var filesystemLastCleanup = map[string]time.Time{}
+var filesystemCleanupLock sync.Mutex
+
+type filesystemStorageLimits struct {
+	MaxFileSize int64
+	MaxSize     int64
+	MaxAge      time.Duration
+}
+
+func cacheDetailsFilesystem(keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	filesystemIntegration := models.Integration{}
+	filesystemIntegration.Keys = make(map[string]interface{})
+	filesystemIntegration.Properties = make(map[string]bool)
+	if keys == nil {
+		deleteIntegrationFromTenant(tenantName, filesystemIntegrationName, IntegrationsConcurrentCache)
+		return
+	}
+	path, ok := keys["path"].(string)
+	if !ok {
+		deleteIntegrationFromTenant(tenantName, filesystemIntegrationName, IntegrationsConcurrentCache)
+		return
+	}
+
+	filesystemIntegration.Keys["path"] = path
+	for _, key := range []string{"max_file_size_mb", "max_size_mb", "max_age_hours"} {
+		if value, ok := keys[key].(string); ok {
+			filesystemIntegration.Keys[key] = value
+		} else {
+			filesystemIntegration.Keys[key] = "0"
+		}
+	}
+	if value, ok := keys["archive_format"].(string); ok {
+		filesystemIntegration.Keys["archive_format"] = value
+	} else {
+		filesystemIntegration.Keys["archive_format"] = tieredStorageFormatJson
+	}
+	filesystemIntegration.Name = filesystemIntegrationName
+	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
+		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{filesystemIntegrationName: filesystemIntegration})
+	} else {
+		err := addIntegrationToTenant(tenantName, filesystemIntegrationName, IntegrationsConcurrentCache, filesystemIntegration)
+		if err != nil {
+			serv.Errorf("cacheDetailsFilesystem: %s ", err.Error())
+			return
+		}
+	}
+}
+
+// filesystemIntegrationLimits parses the rotation and cleanup limits of the integration, 0 means unlimited
+func filesystemIntegrationLimits(integration models.Integration) filesystemStorageLimits {
+	parse := func(key string) int64 {
+		value, _ := integration.Keys[key].(string)
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n
+	}
+	return filesystemStorageLimits{
+		MaxFileSize: parse("max_file_size_mb") * 1024 * 1024,
+		MaxSize:     parse("max_size_mb") * 1024 * 1024,
+		MaxAge:      time.Duration(parse("max_age_hours")) * time.Hour,
+	}
+}
+
+func (s *Server) uploadToFilesystemStorage(tenantName string, tenant map[string][]StoredMsg, keyPrefix string) error {
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return nil
+	}
+	integration, ok := tenantIntegrations[filesystemIntegrationName].(models.Integration)
+	if !ok {
+		return nil
+	}
+	root := integration.Keys["path"].(string)
+	// the allowed root may have been changed since the integration was created
+	err := filesystemAllowedPath(root)
+	if err != nil {
+		return errors.New("uploadToFilesystemStorage: " + err.Error())
+	}
+	limits := filesystemIntegrationLimits(integration)
+	format := tieredStorageFormat(integration)
+
+	for k, msgs := range tenant {
+		size := int64(0)
+		for _, msg := range msgs {
+			size += int64(len(msg.Data)) + int64(len(msg.Header))
+		}
+		for _, batch := range rotateFilesystemBatch(msgs, limits.MaxFileSize) {
+			uploads, err := buildTieredStorageUploads(tenantName, k, keyPrefix, format, s.memphis.nuid.Next(), batch)
+			if err != nil {
+				return err
+			}
+			for _, upload := range uploads {
+				path := filepath.Join(root, filepath.FromSlash(upload.Key))
+				err = writeFileAtomically(root, path, upload.Body)
+				if err != nil {
+					return errors.New("uploadToFilesystemStorage: failed to write file: " + err.Error())
+				}
+				s.Noticef("new file has been written to the filesystem tiered storage: %s", path)
+			}
+		}
+		IncrementEventCounter(tieredStorageTenantFolder(tenantName), "tiered", size, int64(len(msgs)), _EMPTY_, []byte{}, []byte{})
+	}
+
+	// a failing cleanup must not fail the flush, otherwise the messages which were already written will be written again
+	err = cleanupFilesystemStorage(tenantName, root, keyPrefix, limits, false)
+	if err != nil {
+		s.Warnf("[tenant: %v]uploadToFilesystemStorage at cleanupFilesystemStorage: %v", tenantName, err.Error())
+	}
+	return nil
+}
+
+// rotateFilesystemBatch splits the messages into batches whose raw size does not exceed maxFileSize
+func rotateFilesystemBatch(msgs []StoredMsg, maxFileSize int64) [][]StoredMsg {
+	if maxFileSize <= 0 {
+		return [][]StoredMsg{msgs}
+	}
+	var batches [][]StoredMsg
+	var current []StoredMsg
+	currentSize := int64(0)
+	for _, msg := range msgs {
+		msgSize := int64(len(msg.Data)) + int64(len(msg.Header))
+		if len(current) > 0 && currentSize+msgSize > maxFileSize {
+			batches = append(batches, current)
+			current = nil
+			currentSize = 0
+		}
+		current = append(current, msg)
+		currentSize += msgSize
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// writeFileAtomically writes the data into a temp file next to the target, fsyncs it and renames it into place,
+// so readers never see a partially written file and the file survives a crash once this function returns.
+// Only the directories below the root are created, a missing root (e.g. an unmounted volume) fails the write
+func writeFileAtomically(root, path string, data []byte) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%v is not a directory", root)
+	}
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filesystemTempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+type filesystemStoredFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// cleanupFilesystemStorage removes the files of the tenant under the key prefix which are older than the max age
+// and then the oldest files until the tenant folder fits within the max size
+func cleanupFilesystemStorage(tenantName, root, keyPrefix string, limits filesystemStorageLimits, force bool) error {
+	filesystemCleanupLock.Lock()
+	if !force && time.Since(filesystemLastCleanup[keyPrefix+tenantName]) < filesystemCleanupInterval {
+		filesystemCleanupLock.Unlock()
+		return nil
+	}
+	filesystemLastCleanup[keyPrefix+tenantName] = time.Now()
+	filesystemCleanupLock.Unlock()
+
+	tenantRoot := filepath.Join(root, filepath.FromSlash(keyPrefix), "memphis", tieredStorageTenantFolder(tenantName))
+	var files []filesystemStoredFile
+	err := filepath.WalkDir(tenantRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), filesystemTempFilePrefix) {
+			if time.Since(info.ModTime()) > filesystemStaleTempFileAge {
+				os.Remove(path)
+			}
+			return nil
+		}
+		files = append(files, filesystemStoredFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	totalSize := int64(0)
+	for _, file := range files {
+		totalSize += file.size
+	}
+	for _, file := range files {
+		expired := limits.MaxAge > 0 && time.Since(file.modTime) > limits.MaxAge
+		oversized := limits.MaxSize > 0 && totalSize > limits.MaxSize
+		if !expired && !oversized {
+			break
+		}
+		err = os.Remove(file.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		totalSize -= file.size
+		removeEmptyParentDirs(filepath.Dir(file.path), tenantRoot)
+	}
+	return nil
+}
+
+func removeEmptyParentDirs(dir, stopAt string) {
+	for dir != stopAt && strings.HasPrefix(dir, stopAt) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+type filesystemTieredStorageReader struct {
+	root string
+}
+
+func newFilesystemTieredStorageReader(integration models.Integration) (tieredStorageReader, error) {
+	root, ok := integration.Keys["path"].(string)
+	if !ok || root == _EMPTY_ {
+		return nil, errors.New("the filesystem integration has no path")
+	}
+	err := filesystemAllowedPath(root)
+	if err != nil {
+		return nil, err
+	}
+	return &filesystemTieredStorageReader{root: filepath.Clean(root)}, nil
+}
+
+// List walks the directory the prefix points into, files which are still being written are skipped
+func (r *filesystemTieredStorageReader) List(ctx context.Context, prefix string) ([]tieredStorageObjectInfo, error) {
+	dir := r.root
+	if idx := strings.LastIndex(prefix, "/"); idx >= 0 {
+		dir = filepath.Join(r.root, filepath.FromSlash(prefix[:idx]))
+	}
+	objects := []tieredStorageObjectInfo{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), filesystemTempFilePrefix) || strings.HasPrefix(d.Name(), filesystemProbeFilePrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(r.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, tieredStorageObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (r *filesystemTieredStorageReader) Read(ctx context.Context, key string) ([]byte, error) {
+	path := filepath.Join(r.root, filepath.FromSlash(key))
+	if rel, err := filepath.Rel(r.root, path); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("key %v is outside of the filesystem integration path", key)
+	}
+	return os.ReadFile(path)
+}
+
+// filesystemAllowedPath makes sure the path is within the root the operator allowed with FILESYSTEM_STORAGE_ROOT, symbolic
+// links are resolved so they can not point outside of it, without an allowed root the filesystem integration is disabled
+func filesystemAllowedPath(path string) error {
+	allowedRoot := configuration.FILESYSTEM_STORAGE_ROOT
+	if allowedRoot == _EMPTY_ {
+		return errors.New("the filesystem integration is disabled, set FILESYSTEM_STORAGE_ROOT on the broker to the directory the paths are allowed under")
+	}
+	if !filepath.IsAbs(path) {
+		return errors.New("path should be an absolute path")
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(allowedRoot)
+	if err != nil {
+		return fmt.Errorf("the allowed root %v can not be resolved: %v", allowedRoot, err.Error())
+	}
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("path %v does not exist", path)
+		}
+		return err
+	}
+	rel, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %v is not under the allowed root %v", path, allowedRoot)
+	}
+	return nil
+}
+
+func testFilesystemIntegration(path string) error {
+	// the directory is not created on purpose, an unmounted volume should fail the check instead of filling the local disk
+	err := filesystemAllowedPath(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("path %v is not a directory", path)
+	}
+	probe := filepath.Join(path, filesystemProbeFilePrefix+strconv.FormatInt(time.Now().UnixNano(), 10))
+	err = writeFileAtomically(path, probe, []byte("memphis"))
+	if err != nil {
+		return fmt.Errorf("path %v is not writable: %v", path, err.Error())
+	}
+	err = os.Remove(probe)
+	if err != nil {
+		return fmt.Errorf("path %v is not writable: %v", path, err.Error())
+	}
+	return nil
+}
+
+func filesystemIntegrationSizeKey(keys map[string]interface{}, key string, defaultValue int64) (string, error) {
+	var n int64
+	switch value := keys[key].(type) {
+	case nil:
+		n = defaultValue
+	case string:
+		if value == _EMPTY_ {
+			n = defaultValue
+			break
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return _EMPTY_, fmt.Errorf("%v should be a whole number", key)
+		}
+		n = parsed
+	case float64:
+		if value != float64(int64(value)) {
+			return _EMPTY_, fmt.Errorf("%v should be a whole number", key)
+		}
+		n = int64(value)
+	default:
+		return _EMPTY_, fmt.Errorf("%v should be a whole number", key)
+	}
+	if n < 0 {
+		return _EMPTY_, fmt.Errorf("%v can not be negative", key)
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+func getFilesystemIntegrationDetails(keys map[string]interface{}) (map[string]interface{}, int, error) {
+	path, ok := keys["path"].(string)
+	if !ok || path == _EMPTY_ {
+		return map[string]interface{}{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide path for filesystem integration")
+	}
+	path = filepath.Clean(path)
+
+	format := tieredStorageFormatJson
+	if value, ok := keys["archive_format"].(string); ok && value != _EMPTY_ {
+		format = strings.ToLower(value)
+		err := validateTieredStorageFormat(format)
+		if err != nil {
+			return map[string]interface{}{}, SHOWABLE_ERROR_STATUS_CODE, err
+		}
+	}
+
+	details := map[string]interface{}{
+		"path":           path,
+		"archive_format": format,
+	}
+	defaults := map[string]int64{
+		"max_file_size_mb": filesystemDefaultMaxFileSizeMb,
+		"max_size_mb":      0,
+		"max_age_hours":    0,
+	}
+	for key, defaultValue := range defaults {
+		value, err := filesystemIntegrationSizeKey(keys, key, defaultValue)
+		if err != nil {
+			return map[string]interface{}{}, SHOWABLE_ERROR_STATUS_CODE, err
+		}
+		details[key] = value
+	}
+
+	err := testFilesystemIntegration(path)
+	if err != nil {
+		return map[string]interface{}{}, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	return details, 0, nil
+}
+
+func (it IntegrationsHandler) handleCreateFilesystemIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, errorCode, err := getFilesystemIntegrationDetails(body.Keys)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	exist, _, err := db.GetIntegration(filesystemIntegrationName, tenantName)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	if exist {
+		return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, errors.New("filesystem integration already exists")
+	}
+	filesystemIntegration, err := saveFilesystemIntegration(tenantName, keys, false)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return filesystemIntegration, 0, nil
+}
+
+func (it IntegrationsHandler) handleUpdateFilesystemIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	keys, errorCode, err := getFilesystemIntegrationDetails(body.Keys)
+	if err != nil {
+		return models.Integration{}, errorCode, err
+	}
+	filesystemIntegration, err := saveFilesystemIntegration(tenantName, keys, true)
+	if err != nil {
+		return models.Integration{}, 500, err
+	}
+	return filesystemIntegration, 0, nil
+}
+
+func saveFilesystemIntegration(tenantName string, keys map[string]interface{}, isUpdate bool) (models.Integration, error) {
+	properties := map[string]bool{}
+	var filesystemIntegration models.Integration
+	var err error
+	if isUpdate {
+		filesystemIntegration, err = db.UpdateIntegration(tenantName, filesystemIntegrationName, keys, properties)
+	} else {
+		filesystemIntegration, err = db.InsertNewIntegration(tenantName, filesystemIntegrationName, keys, properties)
+	}
+	if err != nil {
+		return models.Integration{}, err
+	}
+
+	integrationToUpdate := models.CreateIntegration{
+		Name:       filesystemIntegrationName,
+		Keys:       keys,
+		Properties: properties,
+		TenantName: tenantName,
+		IsValid:    filesystemIntegration.IsValid,
+	}
+	msg, err := json.Marshal(integrationToUpdate)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	err = serv.sendInternalAccountMsgWithReply(serv.MemphisGlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+	if err != nil {
+		return models.Integration{}, err
+	}
+	return filesystemIntegration, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilesystemTieredStorage(t *testing.T) {
+	root := t.TempDir()
+	allowedRoot := configuration.FILESYSTEM_STORAGE_ROOT
+	defer func() { configuration.FILESYSTEM_STORAGE_ROOT = allowedRoot }()
+	configuration.FILESYSTEM_STORAGE_ROOT = _EMPTY_
+	if err := testFilesystemIntegration(root); err == nil {
+		t.Errorf("expected the filesystem integration to be disabled without an allowed root")
+	}
+	configuration.FILESYSTEM_STORAGE_ROOT = root
+
+	if err := testFilesystemIntegration(root); err != nil {
+		t.Fatalf("expected %v to be writable: %v", root, err)
+	}
+	if err := testFilesystemIntegration(filepath.Join(root, "unmounted")); err == nil {
+		t.Errorf("expected a missing directory to fail the writability probe")
+	}
+	if err := testFilesystemIntegration("relative/path"); err == nil {
+		t.Errorf("expected a relative path to fail the writability probe")
+	}
+	outside := t.TempDir()
+	if err := testFilesystemIntegration(outside); err == nil {
+		t.Errorf("expected a path outside of the allowed root to be rejected")
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := testFilesystemIntegration(link); err == nil {
+		t.Errorf("expected a symbolic link out of the allowed root to be rejected")
+	}
+	os.Remove(link)
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 0 {
+		t.Errorf("expected the writability probe to clean up after itself, found %v entries", len(entries))
+	}
+	unmounted := filepath.Join(root, "unmounted")
+	if err := writeFileAtomically(unmounted, filepath.Join(unmounted, "memphis", "file"), []byte("memphis")); err == nil {
+		t.Errorf("expected a write under a missing root to fail")
+	}
+	if _, err := os.Stat(unmounted); err == nil {
+		t.Errorf("expected a missing root not to be created")
+	}
+
+	msgs := []StoredMsg{{Data: make([]byte, 600)}, {Data: make([]byte, 600)}, {Data: make([]byte, 100)}}
+	if batches := rotateFilesystemBatch(msgs, 1000); len(batches) != 2 || len(batches[0]) != 1 || len(batches[1]) != 2 {
+		t.Errorf("unexpected rotation of the batch: %v batches", len(batches))
+	}
+	if batches := rotateFilesystemBatch(msgs, 0); len(batches) != 1 {
+		t.Errorf("expected no rotation without a max file size, got %v batches", len(batches))
+	}
+
+	tenantRoot := filepath.Join(root, "memphis", "acme")
+	files := map[string]time.Duration{
+		"station=a/partition=1/date=2023-10-14/old(1).ndjson.gz":  -72 * time.Hour,
+		"station=a/partition=1/date=2023-10-15/mid(1).ndjson.gz":  -30 * time.Hour,
+		"station=a/partition=1/date=2023-10-16/new1(1).ndjson.gz": -2 * time.Hour,
+		"station=a/partition=1/date=2023-10-16/new2(1).ndjson.gz": -time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(tenantRoot, filepath.FromSlash(name))
+		if err := writeFileAtomically(root, path, make([]byte, 1024)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		modTime := time.Now().Add(age)
+		os.Chtimes(path, modTime, modTime)
+	}
+	limits := filesystemStorageLimits{MaxAge: 48 * time.Hour, MaxSize: 2048}
+	if err := cleanupFilesystemStorage("acme", root, "", limits, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(tenantRoot, filepath.FromSlash(name)))
+		kept := strings.Contains(name, "new")
+		if kept && err != nil {
+			t.Errorf("expected %v to be kept: %v", name, err)
+		} else if !kept && err == nil {
+			t.Errorf("expected %v to be removed", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tenantRoot, "station=a/partition=1/date=2023-10-14")); err == nil {
+		t.Errorf("expected empty directories to be removed")
+	}
+}
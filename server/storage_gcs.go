@@ -0,0 +1,298 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	gcsIntegrationName         = "gcs"
+	gcsDefaultEndpoint         = "https://storage.googleapis.com"
+	gcsDefaultTokenUrl         = "https://oauth2.googleapis.com/token"
+	gcsReadWriteScope          = "https://www.googleapis.com/auth/devstorage.read_write"
+	cloudStorageRequestTimeout = 60 * time.Second
+)
+
+var gcsIntegrationKeys = []string{"bucket_name", "secret_key", "url"}
+
+// gcsHttpClients holds a client per service account key so the access tokens are reused between uploads
+var gcsHttpClients sync.Map
+
+type gcsServiceAccountKey struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+func cacheDetailsGcs(keys map[string]interface{}, properties map[string]bool, tenantName string) {
+	cacheDetailsCloudStorage(gcsIntegrationName, gcsIntegrationKeys, keys, tenantName)
+}
+
+func gcsEndpoint(integrationUrl string) string {
+	if integrationUrl == _EMPTY_ {
+		return gcsDefaultEndpoint
+	}
+	return strings.TrimSuffix(integrationUrl, "/")
+}
+
+// gcsHttpClient returns a client which authorizes its requests with the service account key (the secret key),
+// emulators reached through a custom url accept requests without credentials
+func gcsHttpClient(secretKey, integrationUrl string) (*http.Client, error) {
+	if secretKey == _EMPTY_ {
+		if integrationUrl == _EMPTY_ {
+			return nil, errors.New("must provide the service account key for gcs integration")
+		}
+		return &http.Client{Timeout: cloudStorageRequestTimeout}, nil
+	}
+	if client, ok := gcsHttpClients.Load(secretKey); ok {
+		return client.(*http.Client), nil
+	}
+	var key gcsServiceAccountKey
+	err := json.Unmarshal([]byte(secretKey), &key)
+	if err != nil || key.ClientEmail == _EMPTY_ || key.PrivateKey == _EMPTY_ {
+		return nil, errors.New("the secret key should be the JSON key file of a service account")
+	}
+	tokenUrl := key.TokenURI
+	if tokenUrl == _EMPTY_ {
+		tokenUrl = gcsDefaultTokenUrl
+	}
+	conf := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		Scopes:       []string{gcsReadWriteScope},
+		TokenURL:     tokenUrl,
+	}
+	client := conf.Client(context.Background())
+	client.Timeout = cloudStorageRequestTimeout
+	gcsHttpClients.Store(secretKey, client)
+	return client, nil
+}
+
+func testGcsIntegration(client *http.Client, endpoint, bucketName string) (int, error) {
+	// listing the objects requires the same role as uploading them, unlike reading the bucket metadata
+	resp, err := client.Get(endpoint + "/storage/v1/b/" + url.PathEscape(bucketName) + "/o?maxResults=1")
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return SHOWABLE_ERROR_STATUS_CODE, errors.New("the service account key is invalid or was revoked")
+		} else if strings.Contains(err.Error(), "private key") {
+			return SHOWABLE_ERROR_STATUS_CODE, errors.New("the private key of the service account key is invalid")
+		}
+		return SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("the endpoint url %s is unreachable", endpoint)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return 0, nil
+	case http.StatusUnauthorized:
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("the service account key is invalid")
+	case http.StatusForbidden:
+		return SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("the service account does not have the necessary permissions to access the bucket %s", bucketName)
+	case http.StatusNotFound:
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("bucket does not exist")
+	default:
+		return 500, fmt.Errorf("unexpected response from google cloud storage: %v", resp.Status)
+	}
+}
+
+func testGcsIntegrationKeys(keys map[string]string) (int, error) {
+	if keys["bucket_name"] == _EMPTY_ {
+		return SHOWABLE_ERROR_STATUS_CODE, errors.New("must provide bucket name for gcs integration")
+	}
+	client, err := gcsHttpClient(keys["secret_key"], keys["url"])
+	if err != nil {
+		return SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	return testGcsIntegration(client, gcsEndpoint(keys["url"]), keys["bucket_name"])
+}
+
+func uploadToGcs(client *http.Client, endpoint, bucketName, key string, body []byte) error {
+	uploadUrl := endpoint + "/upload/storage/v1/b/" + url.PathEscape(bucketName) + "/o?uploadType=media&name=" + url.QueryEscape(key)
+	resp, err := client.Post(uploadUrl, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+type gcsTieredStorageReader struct {
+	client     *http.Client
+	endpoint   string
+	bucketName string
+}
+
+func newGcsTieredStorageReader(integration models.Integration) (tieredStorageReader, error) {
+	keys := GetKeysAsStringMap(integration.Keys)
+	client, err := gcsHttpClient(keys["secret_key"], keys["url"])
+	if err != nil {
+		return nil, err
+	}
+	return &gcsTieredStorageReader{client: client, endpoint: gcsEndpoint(keys["url"]), bucketName: keys["bucket_name"]}, nil
+}
+
+func (r *gcsTieredStorageReader) get(ctx context.Context, requestUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (r *gcsTieredStorageReader) List(ctx context.Context, prefix string) ([]tieredStorageObjectInfo, error) {
+	objects := []tieredStorageObjectInfo{}
+	pageToken := _EMPTY_
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if pageToken != _EMPTY_ {
+			query.Set("pageToken", pageToken)
+		}
+		body, err := r.get(ctx, r.endpoint+"/storage/v1/b/"+url.PathEscape(r.bucketName)+"/o?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name    string    `json:"name"`
+				Size    string    `json:"size"`
+				Updated time.Time `json:"updated"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.Unmarshal(body, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			size, _ := strconv.ParseInt(item.Size, 10, 64)
+			objects = append(objects, tieredStorageObjectInfo{Key: item.Name, Size: size, LastModified: item.Updated})
+		}
+		if page.NextPageToken == _EMPTY_ {
+			return objects, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (r *gcsTieredStorageReader) Read(ctx context.Context, key string) ([]byte, error) {
+	return r.get(ctx, r.endpoint+"/storage/v1/b/"+url.PathEscape(r.bucketName)+"/o/"+url.PathEscape(key)+"?alt=media")
+}
+
+func (s *Server) uploadToGcsStorage(tenantName string, tenant map[string][]StoredMsg, keyPrefix string) error {
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return nil
+	}
+	integration, ok := tenantIntegrations[gcsIntegrationName].(models.Integration)
+	if !ok {
+		return nil
+	}
+	keys := GetKeysAsStringMap(integration.Keys)
+	client, err := gcsHttpClient(keys["secret_key"], keys["url"])
+	if err != nil {
+		return errors.New("uploadToGcsStorage failure " + err.Error())
+	}
+	endpoint := gcsEndpoint(keys["url"])
+
+	for k, msgs := range tenant {
+		uploads, err := buildTieredStorageUploads(tenantName, k, keyPrefix, tieredStorageFormat(integration), s.memphis.nuid.Next(), msgs)
+		if err != nil {
+			return err
+		}
+		size := int64(0)
+		for _, msg := range msgs {
+			size += int64(len(msg.Data)) + int64(len(msg.Header))
+		}
+		for _, upload := range uploads {
+			err = uploadToGcs(client, endpoint, keys["bucket_name"], upload.Key, upload.Body)
+			if err != nil {
+				return errors.New("uploadToGcsStorage: failed to upload object to GCS: " + err.Error())
+			}
+			s.Noticef("new file has been uploaded to GCS: %s", upload.Key)
+		}
+		IncrementEventCounter(tieredStorageTenantFolder(tenantName), "tiered", size, int64(len(msgs)), _EMPTY_, []byte{}, []byte{})
+	}
+	return nil
+}
+
+func (it IntegrationsHandler) handleGcsIntegration(tenantName string, keys map[string]interface{}) (int, map[string]interface{}, error) {
+	validatedKeys, statusCode, err := cloudStorageIntegrationKeys(gcsIntegrationName, tenantName, keys, gcsIntegrationKeys)
+	if err != nil {
+		return statusCode, map[string]interface{}{}, err
+	}
+	statusCode, err = testGcsIntegrationKeys(GetKeysAsStringMap(validatedKeys))
+	if err != nil {
+		return statusCode, map[string]interface{}{}, err
+	}
+	return statusCode, validatedKeys, nil
+}
+
+func (it IntegrationsHandler) handleCreateGcsIntegration(tenantName string, keys map[string]interface{}) (models.Integration, int, error) {
+	statusCode, keys, err := it.handleGcsIntegration(tenantName, keys)
+	if err != nil {
+		return models.Integration{}, statusCode, err
+	}
+	gcsIntegration, err := saveCloudStorageIntegration(gcsIntegrationName, tenantName, keys, false)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return models.Integration{}, SHOWABLE_ERROR_STATUS_CODE, err
+		} else {
+			return models.Integration{}, 500, err
+		}
+	}
+	return gcsIntegration, statusCode, nil
+}
+
+func (it IntegrationsHandler) handleUpdateGcsIntegration(tenantName string, body models.CreateIntegrationSchema) (models.Integration, int, error) {
+	statusCode, keys, err := it.handleGcsIntegration(tenantName, body.Keys)
+	if err != nil {
+		return models.Integration{}, statusCode, err
+	}
+	gcsIntegration, err := saveCloudStorageIntegration(gcsIntegrationName, tenantName, keys, true)
+	if err != nil {
+		return gcsIntegration, 500, err
+	}
+	return gcsIntegration, statusCode, nil
+}
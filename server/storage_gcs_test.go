@@ -0,0 +1,73 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"encoding/hex"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run fake-gcs-server with STORAGE_EMULATOR_HOST=http://localhost:4443 to run this test
+func TestGcsTieredStorageEmulator(t *testing.T) {
+	endpoint := os.Getenv("STORAGE_EMULATOR_HOST")
+	if endpoint == "" {
+		t.Skip("STORAGE_EMULATOR_HOST is not set")
+	}
+	if !strings.HasPrefix(endpoint, "http") {
+		endpoint = "http://" + endpoint
+	}
+	client, err := gcsHttpClient("", endpoint)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bucket := "memphis-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	resp, err := client.Post(endpoint+"/storage/v1/b?project=memphis", "application/json", strings.NewReader(`{"name":"`+bucket+`"}`))
+	if err != nil {
+		t.Fatalf("failed creating the bucket: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := testGcsIntegrationKeys(map[string]string{"bucket_name": bucket, "url": endpoint}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := testGcsIntegrationKeys(map[string]string{"bucket_name": bucket + "-missing", "url": endpoint}); err == nil || err.Error() != "bucket does not exist" {
+		t.Errorf("expected a missing bucket to fail the test, got %v", err)
+	}
+
+	msgs := []StoredMsg{{Sequence: 1, Time: time.Now(), Data: []byte("gcs")}}
+	uploads, err := buildTieredStorageUploads("acme", "orders$1", "", tieredStorageFormatNdjsonGzip, "uid", msgs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, upload := range uploads {
+		if err := uploadToGcs(client, endpoint, bucket, upload.Key, upload.Body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := client.Get(endpoint + "/storage/v1/b/" + bucket + "/o/" + url.PathEscape(upload.Key) + "?alt=media")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		decoded, err := decodeTieredStorageObject(upload.Key, body)
+		if err != nil || len(decoded) != 1 || decoded[0].Payload != hex.EncodeToString([]byte("gcs")) {
+			t.Errorf("unexpected object %v: %v", upload.Key, err)
+		}
+	}
+}
@@ -0,0 +1,628 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// a minimal parquet writer and reader for the tiered storage archives, every archive holds a single row group of
+// required columns which are PLAIN encoded into a single snappy compressed data page per column
+
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetRepetitionRequired = 0
+	parquetConvertedUtf8      = 0
+	parquetEncodingPlain      = 0
+	parquetEncodingRle        = 3
+	parquetPageData           = 0
+
+	parquetCodecUncompressed = 0
+	parquetCodecSnappy       = 1
+	parquetCodecGzip         = 2
+	parquetCodecZstd         = 6
+
+	thriftCompactTrue   = 1
+	thriftCompactFalse  = 2
+	thriftCompactByte   = 3
+	thriftCompactI16    = 4
+	thriftCompactI32    = 5
+	thriftCompactI64    = 6
+	thriftCompactDouble = 7
+	thriftCompactBinary = 8
+	thriftCompactList   = 9
+	thriftCompactSet    = 10
+	thriftCompactMap    = 11
+	thriftCompactStruct = 12
+)
+
+type parquetColumn struct {
+	name   string
+	typ    int32
+	values []byte
+}
+
+// thriftCompactWriter writes the thrift compact protocol which the parquet metadata is serialized with
+type thriftCompactWriter struct {
+	buf     bytes.Buffer
+	lastIds []int16
+}
+
+func (w *thriftCompactWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *thriftCompactWriter) zigzag(v int64) {
+	w.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftCompactWriter) fieldHeader(id int16, typ byte) {
+	last := &w.lastIds[len(w.lastIds)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftCompactWriter) beginStruct() {
+	w.lastIds = append(w.lastIds, 0)
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.lastIds = w.lastIds[:len(w.lastIds)-1]
+}
+
+func (w *thriftCompactWriter) structField(id int16) {
+	w.fieldHeader(id, thriftCompactStruct)
+	w.beginStruct()
+}
+
+func (w *thriftCompactWriter) boolField(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, thriftCompactTrue)
+	} else {
+		w.fieldHeader(id, thriftCompactFalse)
+	}
+}
+
+func (w *thriftCompactWriter) i32Field(id int16, v int32) {
+	w.fieldHeader(id, thriftCompactI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftCompactWriter) i64Field(id int16, v int64) {
+	w.fieldHeader(id, thriftCompactI64)
+	w.zigzag(v)
+}
+
+func (w *thriftCompactWriter) binary(v []byte) {
+	w.uvarint(uint64(len(v)))
+	w.buf.Write(v)
+}
+
+func (w *thriftCompactWriter) binaryField(id int16, v []byte) {
+	w.fieldHeader(id, thriftCompactBinary)
+	w.binary(v)
+}
+
+func (w *thriftCompactWriter) listField(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftCompactList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.uvarint(uint64(size))
+}
+
+// thriftCompactReader reads any thrift compact struct into a map of its field ids, nested structs are maps as well,
+// lists and sets are slices, integers are int64 and binaries are byte slices
+type thriftCompactReader struct {
+	data []byte
+	pos  int
+}
+
+var errThriftCompactTruncated = errors.New("truncated thrift compact data")
+
+func (r *thriftCompactReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errThriftCompactTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftCompactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errThriftCompactTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftCompactReader) zigzag() (int64, error) {
+	v, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func (r *thriftCompactReader) readValue(typ byte, inCollection bool) (interface{}, error) {
+	switch typ {
+	case thriftCompactTrue, thriftCompactFalse:
+		if inCollection {
+			b, err := r.readByte()
+			return b == thriftCompactTrue, err
+		}
+		return typ == thriftCompactTrue, nil
+	case thriftCompactByte:
+		b, err := r.readByte()
+		return int64(b), err
+	case thriftCompactI16, thriftCompactI32, thriftCompactI64:
+		return r.zigzag()
+	case thriftCompactDouble:
+		if r.pos+8 > len(r.data) {
+			return nil, errThriftCompactTruncated
+		}
+		r.pos += 8
+		return nil, nil
+	case thriftCompactBinary:
+		size, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.data)-r.pos) < size {
+			return nil, errThriftCompactTruncated
+		}
+		v := r.data[r.pos : r.pos+int(size)]
+		r.pos += int(size)
+		return v, nil
+	case thriftCompactList, thriftCompactSet:
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			size, err = r.uvarint()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return nil, errThriftCompactTruncated
+		}
+		values := make([]interface{}, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.readValue(header&0x0f, true)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case thriftCompactMap:
+		size, err := r.uvarint()
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		types, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			if _, err = r.readValue(types>>4, true); err != nil {
+				return nil, err
+			}
+			if _, err = r.readValue(types&0x0f, true); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftCompactStruct:
+		return r.readStruct()
+	default:
+		return nil, fmt.Errorf("unknown thrift compact type %v", typ)
+	}
+}
+
+func (r *thriftCompactReader) readStruct() (map[int16]interface{}, error) {
+	fields := map[int16]interface{}{}
+	last := int16(0)
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return fields, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		v, err := r.readValue(header&0x0f, false)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+		last = id
+	}
+}
+
+func thriftCompactIntField(fields map[int16]interface{}, id int16) (int64, error) {
+	v, ok := fields[id].(int64)
+	if !ok {
+		return 0, fmt.Errorf("missing integer field %v", id)
+	}
+	return v, nil
+}
+
+func thriftCompactStructField(fields map[int16]interface{}, id int16) (map[int16]interface{}, error) {
+	v, ok := fields[id].(map[int16]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing struct field %v", id)
+	}
+	return v, nil
+}
+
+func thriftCompactListField(fields map[int16]interface{}, id int16) ([]interface{}, error) {
+	v, ok := fields[id].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing list field %v", id)
+	}
+	return v, nil
+}
+
+func parquetColumns(records []tieredStorageRecord) ([]parquetColumn, error) {
+	columns := []parquetColumn{
+		{name: "sequence", typ: parquetTypeInt64},
+		{name: "timestamp", typ: parquetTypeInt64},
+		{name: "headers", typ: parquetTypeByteArray},
+		{name: "payload", typ: parquetTypeByteArray},
+	}
+	var num [8]byte
+	appendByteArray := func(column *parquetColumn, v []byte) {
+		binary.LittleEndian.PutUint32(num[:4], uint32(len(v)))
+		column.values = append(column.values, num[:4]...)
+		column.values = append(column.values, v...)
+	}
+	for _, record := range records {
+		binary.LittleEndian.PutUint64(num[:], uint64(record.Sequence))
+		columns[0].values = append(columns[0].values, num[:]...)
+		binary.LittleEndian.PutUint64(num[:], uint64(record.Timestamp))
+		columns[1].values = append(columns[1].values, num[:]...)
+		headers, err := json.Marshal(record.Headers)
+		if err != nil {
+			return nil, err
+		}
+		appendByteArray(&columns[2], headers)
+		appendByteArray(&columns[3], record.Payload)
+	}
+	return columns, nil
+}
+
+func writeParquetSchemaElement(w *thriftCompactWriter, column parquetColumn) {
+	w.beginStruct()
+	w.i32Field(1, column.typ)
+	w.i32Field(3, parquetRepetitionRequired)
+	w.binaryField(4, []byte(column.name))
+	switch column.name {
+	case "headers":
+		w.i32Field(6, parquetConvertedUtf8)
+		w.structField(10) // logical type
+		w.structField(1)  // string
+		w.endStruct()
+		w.endStruct()
+	case "timestamp":
+		w.structField(10) // logical type
+		w.structField(8)  // timestamp
+		w.boolField(1, true)
+		w.structField(2) // unit
+		w.structField(3) // nanos
+		w.endStruct()
+		w.endStruct()
+		w.endStruct()
+		w.endStruct()
+	}
+	w.endStruct()
+}
+
+func writeParquetFile(records []tieredStorageRecord) ([]byte, error) {
+	columns, err := parquetColumns(records)
+	if err != nil {
+		return nil, err
+	}
+	numRows := int64(len(records))
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	offsets := make([]int64, len(columns))
+	uncompressedSizes := make([]int64, len(columns))
+	compressedSizes := make([]int64, len(columns))
+	totalByteSize := int64(0)
+	for i, column := range columns {
+		page := s2.EncodeSnappy(nil, column.values)
+		header := thriftCompactWriter{}
+		header.beginStruct()
+		header.i32Field(1, parquetPageData)
+		header.i32Field(2, int32(len(column.values)))
+		header.i32Field(3, int32(len(page)))
+		header.structField(5)
+		header.i32Field(1, int32(numRows))
+		header.i32Field(2, parquetEncodingPlain)
+		header.i32Field(3, parquetEncodingRle)
+		header.i32Field(4, parquetEncodingRle)
+		header.endStruct()
+		header.endStruct()
+
+		offsets[i] = int64(out.Len())
+		uncompressedSizes[i] = int64(header.buf.Len() + len(column.values))
+		compressedSizes[i] = int64(header.buf.Len() + len(page))
+		totalByteSize += uncompressedSizes[i]
+		out.Write(header.buf.Bytes())
+		out.Write(page)
+	}
+
+	footer := thriftCompactWriter{}
+	footer.beginStruct()
+	footer.i32Field(1, 1) // version
+	footer.listField(2, thriftCompactStruct, len(columns)+1)
+	footer.beginStruct()
+	footer.binaryField(4, []byte("schema"))
+	footer.i32Field(5, int32(len(columns)))
+	footer.endStruct()
+	for _, column := range columns {
+		writeParquetSchemaElement(&footer, column)
+	}
+	footer.i64Field(3, numRows)
+	footer.listField(4, thriftCompactStruct, 1)
+	footer.beginStruct()
+	footer.listField(1, thriftCompactStruct, len(columns))
+	for i, column := range columns {
+		footer.beginStruct()
+		footer.i64Field(2, offsets[i])
+		footer.structField(3)
+		footer.i32Field(1, column.typ)
+		footer.listField(2, thriftCompactI32, 2)
+		footer.zigzag(parquetEncodingPlain)
+		footer.zigzag(parquetEncodingRle)
+		footer.listField(3, thriftCompactBinary, 1)
+		footer.binary([]byte(column.name))
+		footer.i32Field(4, parquetCodecSnappy)
+		footer.i64Field(5, numRows)
+		footer.i64Field(6, uncompressedSizes[i])
+		footer.i64Field(7, compressedSizes[i])
+		footer.i64Field(9, offsets[i])
+		footer.endStruct()
+		footer.endStruct()
+	}
+	footer.i64Field(2, totalByteSize)
+	footer.i64Field(3, numRows)
+	footer.endStruct()
+	footer.binaryField(6, []byte("memphis"))
+	footer.endStruct()
+
+	out.Write(footer.buf.Bytes())
+	var footerLen [4]byte
+	binary.LittleEndian.PutUint32(footerLen[:], uint32(footer.buf.Len()))
+	out.Write(footerLen[:])
+	out.WriteString(parquetMagic)
+	return out.Bytes(), nil
+}
+
+func decompressParquetPage(codec int64, page []byte) ([]byte, error) {
+	switch codec {
+	case parquetCodecUncompressed:
+		return page, nil
+	case parquetCodecSnappy:
+		return s2.Decode(nil, page)
+	case parquetCodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case parquetCodecZstd:
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(page, nil)
+	default:
+		return nil, fmt.Errorf("unsupported parquet codec %v", codec)
+	}
+}
+
+// readParquetColumnChunk returns the PLAIN encoded values of a column chunk written by writeParquetFile
+func readParquetColumnChunk(data []byte, offset, numValues, codec int64) ([]byte, error) {
+	var values []byte
+	r := thriftCompactReader{data: data, pos: int(offset)}
+	for read := int64(0); read < numValues; {
+		if r.pos < 0 || r.pos >= len(data) {
+			return nil, errThriftCompactTruncated
+		}
+		header, err := r.readStruct()
+		if err != nil {
+			return nil, err
+		}
+		pageType, err := thriftCompactIntField(header, 1)
+		if err != nil {
+			return nil, err
+		}
+		if pageType != parquetPageData {
+			return nil, fmt.Errorf("unsupported parquet page type %v", pageType)
+		}
+		size, err := thriftCompactIntField(header, 3)
+		if err != nil {
+			return nil, err
+		}
+		dataPage, err := thriftCompactStructField(header, 5)
+		if err != nil {
+			return nil, err
+		}
+		pageValues, err := thriftCompactIntField(dataPage, 1)
+		if err != nil {
+			return nil, err
+		}
+		encoding, err := thriftCompactIntField(dataPage, 2)
+		if err != nil {
+			return nil, err
+		}
+		if encoding != parquetEncodingPlain {
+			return nil, fmt.Errorf("unsupported parquet encoding %v", encoding)
+		}
+		if size < 0 || int64(len(data)-r.pos) < size {
+			return nil, errThriftCompactTruncated
+		}
+		page, err := decompressParquetPage(codec, data[r.pos:r.pos+int(size)])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, page...)
+		r.pos += int(size)
+		read += pageValues
+	}
+	return values, nil
+}
+
+func readParquetFile(data []byte) ([]tieredStorageRecord, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return nil, errors.New("not a parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen > len(data)-12 {
+		return nil, errors.New("invalid parquet footer length")
+	}
+	footer := thriftCompactReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	metaData, err := footer.readStruct()
+	if err != nil {
+		return nil, err
+	}
+	rowGroups, err := thriftCompactListField(metaData, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []tieredStorageRecord
+	for _, rg := range rowGroups {
+		rowGroup, ok := rg.(map[int16]interface{})
+		if !ok {
+			return nil, errors.New("invalid parquet row group")
+		}
+		numRows, err := thriftCompactIntField(rowGroup, 3)
+		if err != nil {
+			return nil, err
+		}
+		chunks, err := thriftCompactListField(rowGroup, 1)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string][]byte{}
+		for _, c := range chunks {
+			chunk, ok := c.(map[int16]interface{})
+			if !ok {
+				return nil, errors.New("invalid parquet column chunk")
+			}
+			columnMetaData, err := thriftCompactStructField(chunk, 3)
+			if err != nil {
+				return nil, err
+			}
+			path, err := thriftCompactListField(columnMetaData, 3)
+			if err != nil || len(path) != 1 {
+				return nil, errors.New("invalid parquet column path")
+			}
+			name, _ := path[0].([]byte)
+			codec, err := thriftCompactIntField(columnMetaData, 4)
+			if err != nil {
+				return nil, err
+			}
+			numValues, err := thriftCompactIntField(columnMetaData, 5)
+			if err != nil {
+				return nil, err
+			}
+			offset, err := thriftCompactIntField(columnMetaData, 9)
+			if err != nil {
+				return nil, err
+			}
+			values[string(name)], err = readParquetColumnChunk(data, offset, numValues, codec)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", name, err.Error())
+			}
+		}
+
+		sequences, timestamps, headers, payloads := values["sequence"], values["timestamp"], values["headers"], values["payload"]
+		if int64(len(sequences)) != numRows*8 || int64(len(timestamps)) != numRows*8 {
+			return nil, errors.New("invalid parquet sequence or timestamp column")
+		}
+		readByteArray := func(column []byte) ([]byte, []byte, error) {
+			if len(column) < 4 {
+				return nil, nil, errors.New("invalid parquet byte array column")
+			}
+			size := binary.LittleEndian.Uint32(column[:4])
+			if uint64(len(column)-4) < uint64(size) {
+				return nil, nil, errors.New("invalid parquet byte array column")
+			}
+			return column[4 : 4+size], column[4+size:], nil
+		}
+		for i := int64(0); i < numRows; i++ {
+			record := tieredStorageRecord{
+				Sequence:  int64(binary.LittleEndian.Uint64(sequences[i*8:])),
+				Timestamp: int64(binary.LittleEndian.Uint64(timestamps[i*8:])),
+			}
+			var rawHeaders []byte
+			rawHeaders, headers, err = readByteArray(headers)
+			if err != nil {
+				return nil, err
+			}
+			err = json.Unmarshal(rawHeaders, &record.Headers)
+			if err != nil {
+				return nil, err
+			}
+			record.Payload, payloads, err = readByteArray(payloads)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
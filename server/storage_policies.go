@@ -0,0 +1,820 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	tieredStorageSchedulerInterval           = time.Second
+	tieredStoragePolicyRefreshInterval       = 30 * time.Second
+	tieredStoragePolicyLoadRetryInterval     = 5 * time.Second
+	tieredStorageUploadRetryInterval         = 30 * time.Second
+	tieredStorageMaxUploadLatencySec         = 86400
+	tieredStorageBufferMaxMessages           = 100000
+	tieredStorageBufferMaxBytes              = 64 * 1024 * 1024
+	tieredStorageMaxKeyPrefixLength          = 256
+	tieredStorageRedactionRegex              = "regex"
+	tieredStorageRedactionJsonFields         = "json_fields"
+	tieredStorageRedactionDropPayload        = "drop_payload"
+	tieredStorageRedactionDefaultReplacement = "[REDACTED]"
+)
+
+var tieredStorageKeyPrefixRegex = regexp.MustCompile(`^[a-zA-Z0-9/!_.*'()-]*$`)
+
+// tieredStoragePolicyError is returned when the policy of a station can not be applied on its messages,
+// retrying the upload would fail the same way so the messages are held until the policy is changed
+type tieredStoragePolicyError struct {
+	err error
+}
+
+func (e tieredStoragePolicyError) Error() string {
+	return "invalid tiered storage policy: " + e.err.Error()
+}
+
+func (e tieredStoragePolicyError) Unwrap() error {
+	return e.err
+}
+
+// tieredStorageBuffers holds the messages waiting to be uploaded per tenant and stream, each buffer is flushed by its own
+// upload so a slow storage only holds back the streams it is uploading
+var tieredStorageBuffers = NewConcurrentMap[*tieredStorageBuffer]()
+
+type tieredStorageBufferedMsg struct {
+	msg       StoredMsg
+	arrivedAt time.Time
+}
+
+type tieredStorageBuffer struct {
+	sync.Mutex
+	tenantName string
+	streamName string
+	msgs       []tieredStorageBufferedMsg
+	size       int64
+	inflight   []tieredStorageBufferedMsg
+	// a batch which failed to upload is retried as is and skips the integrations it was already uploaded to
+	failed     []tieredStorageBufferedMsg
+	uploadedTo map[string]bool
+	retryAt    time.Time
+	// set when the policy can not be applied on the messages, the buffer is held until the policy changes
+	policyErr error
+	// set while the uploads of the buffer fail, only the failed batch is held and the messages which were not taken
+	// into it are left to be redelivered by the tiered storage consumer instead of growing the buffer
+	uploadFailing  bool
+	lastActivity   time.Time
+	removed        bool
+	policy         *models.TieredStoragePolicy
+	policyLoaded   bool
+	policyLoading  bool
+	policyLoadedAt time.Time
+	policyRetryAt  time.Time
+}
+
+func tieredStorageMsgSize(msg StoredMsg) int64 {
+	return int64(len(msg.Data)) + int64(len(msg.Header))
+}
+
+func (s *Server) storeInTieredStorageBuffer(msg StoredMsg) {
+	streamName := strings.Replace(msg.Subject, "#", ".", -1)
+	key := msg.TenantName + "/" + streamName
+	for {
+		b, ok := tieredStorageBuffers.Load(key)
+		if !ok {
+			b = &tieredStorageBuffer{tenantName: msg.TenantName, streamName: streamName}
+			if !tieredStorageBuffers.Add(key, b) {
+				continue
+			}
+		}
+		b.Lock()
+		// the buffer may have been removed by the scheduler after it was loaded
+		if b.removed {
+			b.Unlock()
+			continue
+		}
+		// a full buffer or a buffer which fails to upload does not hold the message, it is redelivered by the consumer once its ack wait expires
+		if b.uploadFailing || len(b.msgs) >= tieredStorageBufferMaxMessages || b.size >= tieredStorageBufferMaxBytes {
+			b.Unlock()
+			return
+		}
+		now := time.Now()
+		b.msgs = append(b.msgs, tieredStorageBufferedMsg{msg: msg, arrivedAt: now})
+		b.size += tieredStorageMsgSize(msg)
+		b.lastActivity = now
+		b.Unlock()
+		return
+	}
+}
+
+// due reports whether the buffer should be flushed, the batch thresholds of the policy and the buffer limits flush it early
+// and the oldest message is never held longer than the upload latency
+func (b *tieredStorageBuffer) due(now time.Time, defaultLatency time.Duration) bool {
+	if b.inflight != nil || !b.policyLoaded || b.policyErr != nil || now.Before(b.retryAt) {
+		return false
+	}
+	if b.failed != nil {
+		return true
+	}
+	if len(b.msgs) == 0 {
+		return false
+	}
+	if len(b.msgs) >= tieredStorageBufferMaxMessages || b.size >= tieredStorageBufferMaxBytes {
+		return true
+	}
+	latency := defaultLatency
+	if b.policy != nil {
+		if b.policy.MaxBatchMessages > 0 && len(b.msgs) >= b.policy.MaxBatchMessages {
+			return true
+		}
+		if b.policy.MaxBatchBytes > 0 && b.size >= b.policy.MaxBatchBytes {
+			return true
+		}
+		if b.policy.MaxUploadLatencySec > 0 {
+			latency = time.Duration(b.policy.MaxUploadLatencySec) * time.Second
+		}
+	}
+	return now.Sub(b.msgs[0].arrivedAt) >= latency
+}
+
+// takeBatch moves the batch which failed to upload, or the oldest messages up to the batch thresholds of the policy, into flight,
+// the message which crosses the bytes threshold is still part of the batch
+func (b *tieredStorageBuffer) takeBatch() []tieredStorageBufferedMsg {
+	if b.failed != nil {
+		b.inflight = b.failed
+		b.failed = nil
+		return b.inflight
+	}
+	n := len(b.msgs)
+	if b.policy != nil {
+		if b.policy.MaxBatchMessages > 0 && n > b.policy.MaxBatchMessages {
+			n = b.policy.MaxBatchMessages
+		}
+		if b.policy.MaxBatchBytes > 0 {
+			size := int64(0)
+			for i := 0; i < n; i++ {
+				size += tieredStorageMsgSize(b.msgs[i].msg)
+				if size >= b.policy.MaxBatchBytes {
+					n = i + 1
+					break
+				}
+			}
+		}
+	}
+	batch := b.msgs[:n:n]
+	b.msgs = append([]tieredStorageBufferedMsg{}, b.msgs[n:]...)
+	for _, m := range batch {
+		b.size -= tieredStorageMsgSize(m.msg)
+	}
+	b.inflight = batch
+	return batch
+}
+
+// returnBatch keeps a batch which failed to upload for a retry, uploadedTo holds the integrations it was already uploaded to
+func (b *tieredStorageBuffer) returnBatch(batch []tieredStorageBufferedMsg, uploadedTo map[string]bool, retryAt time.Time) {
+	b.failed = batch
+	b.uploadedTo = uploadedTo
+	b.inflight = nil
+	b.retryAt = retryAt
+}
+
+func (b *tieredStorageBuffer) stationName() string {
+	stationName, _ := tieredStorageStreamPartition(b.streamName)
+	return stationName
+}
+
+// scheduleTieredStorageBuffers flushes every due buffer on its own goroutine and keeps the messages
+// held in the buffers from being redelivered by the tiered storage consumer as long as their uploads do not fail
+func (s *Server) scheduleTieredStorageBuffers(now time.Time, defaultLatency time.Duration, sendProgress bool) {
+	keys, buffers := tieredStorageBuffers.Array()
+	for i, b := range buffers {
+		var replies []string
+		b.Lock()
+		if len(b.msgs) == 0 && b.inflight == nil && b.failed == nil && now.Sub(b.lastActivity) > tieredStoragePolicyRefreshInterval {
+			b.removed = true
+			tieredStorageBuffers.Delete(keys[i])
+			b.Unlock()
+			continue
+		}
+		if !b.policyLoading && now.After(b.policyRetryAt) && (!b.policyLoaded || now.Sub(b.policyLoadedAt) >= tieredStoragePolicyRefreshInterval) {
+			b.policyLoading = true
+			go s.loadTieredStoragePolicy(b)
+		}
+		if sendProgress && !b.uploadFailing {
+			for _, m := range b.msgs {
+				replies = append(replies, m.msg.ReplySubject)
+			}
+			for _, m := range b.inflight {
+				replies = append(replies, m.msg.ReplySubject)
+			}
+			for _, m := range b.failed {
+				replies = append(replies, m.msg.ReplySubject)
+			}
+		}
+		if b.due(now, defaultLatency) {
+			var policy *models.TieredStoragePolicy
+			if b.policy != nil {
+				p := *b.policy
+				policy = &p
+			}
+			uploadedTo := b.uploadedTo
+			if b.failed == nil || uploadedTo == nil {
+				uploadedTo = map[string]bool{}
+			}
+			b.uploadedTo = nil
+			go s.flushTieredStorageBuffer(b, policy, b.takeBatch(), uploadedTo)
+		}
+		b.Unlock()
+		for _, reply := range replies {
+			s.sendInternalAccountMsg(s.MemphisGlobalAccount(), reply, AckProgress)
+		}
+	}
+}
+
+func (s *Server) loadTieredStoragePolicy(b *tieredStorageBuffer) {
+	policy, err := getStationTieredStoragePolicy(b.tenantName, b.stationName())
+	b.Lock()
+	defer b.Unlock()
+	b.policyLoading = false
+	if err != nil {
+		// the buffer keeps its previous policy and is not flushed before its first policy is loaded
+		s.Errorf("[tenant: %v]loadTieredStoragePolicy: station %v: %v", b.tenantName, b.stationName(), err.Error())
+		b.policyRetryAt = time.Now().Add(tieredStoragePolicyLoadRetryInterval)
+		return
+	}
+	if b.policyErr != nil && !reflect.DeepEqual(policy, b.policy) {
+		b.policyErr = nil
+	}
+	b.policy = policy
+	b.policyLoaded = true
+	b.policyLoadedAt = time.Now()
+}
+
+func getStationTieredStoragePolicy(tenantName, stationName string) (*models.TieredStoragePolicy, error) {
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return nil, err
+	}
+	exist, station, err := db.GetStationByName(sn.Ext(), tenantName)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	exist, policy, err := db.GetTieredStoragePolicy(station.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// invalidateTieredStoragePolicy makes the buffers of the station reload their policy on the next schedule
+func invalidateTieredStoragePolicy(tenantName, stationName string) {
+	_, buffers := tieredStorageBuffers.Array()
+	for _, b := range buffers {
+		b.Lock()
+		if strings.EqualFold(b.tenantName, tenantName) && b.stationName() == stationName {
+			b.policyLoadedAt = time.Time{}
+			b.policyRetryAt = time.Time{}
+		}
+		b.Unlock()
+	}
+}
+
+func (s *Server) flushTieredStorageBuffer(b *tieredStorageBuffer, policy *models.TieredStoragePolicy, batch []tieredStorageBufferedMsg, uploadedTo map[string]bool) {
+	msgs := make([]StoredMsg, 0, len(batch))
+	for _, m := range batch {
+		msgs = append(msgs, m.msg)
+	}
+	err := s.uploadTieredStorageBatch(b.tenantName, b.streamName, policy, msgs, uploadedTo)
+	b.Lock()
+	if err != nil {
+		s.Errorf("[tenant: %v]Failed upload messages of %v to tiered 2 storage: %v", b.tenantName, b.streamName, err.Error())
+		var policyErr tieredStoragePolicyError
+		if errors.As(err, &policyErr) {
+			b.policyErr = err
+		}
+		b.returnBatch(batch, uploadedTo, time.Now().Add(tieredStorageUploadRetryInterval))
+		b.uploadFailing = true
+		b.msgs = nil
+		b.size = 0
+		b.Unlock()
+		return
+	}
+	b.inflight = nil
+	b.uploadFailing = false
+	b.Unlock()
+
+	// ack the messages uploaded to tiered 2 storage or when there is no tiered 2 storage to upload them to
+	for _, msg := range msgs {
+		s.sendInternalAccountMsg(s.MemphisGlobalAccount(), msg.ReplySubject, []byte(_EMPTY_))
+	}
+}
+
+// uploadTieredStorageBatch uploads a batch of a stream to the integration the policy targets or to every connected
+// tier 2 storage when the station has no policy or its policy has no target integration,
+// every integration the batch is uploaded to is marked in uploadedTo and skipped when the batch is retried
+func (s *Server) uploadTieredStorageBatch(tenantName, streamName string, policy *models.TieredStoragePolicy, msgs []StoredMsg, uploadedTo map[string]bool) error {
+	it := IntegrationsHandler{S: s}
+	if IsStorageLimitExceeded(tenantName) {
+		s.Warnf("[tenant:%s]uploadTieredStorageBatch: %s", tenantName, ErrUpgradePlan.Error())
+		it.Warnf("s3", tenantName, "Can't upload messages to tiered storage: you've reached your storage limit for this month")
+		return nil
+	}
+	if !ValidataAccessToFeature(tenantName, "feature-storage-tiering") {
+		s.Warnf("[tenant: %v]uploadTieredStorageBatch: Has no access to feature-storage-tiering in its Plan", tenantName)
+		return nil
+	}
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return nil
+	}
+
+	keyPrefix := _EMPTY_
+	if policy != nil {
+		if policy.Integration != _EMPTY_ {
+			// the messages are kept until the integration is connected again
+			if _, ok := tenantIntegrations[policy.Integration].(models.Integration); !ok {
+				return fmt.Errorf("integration %v of the tiered storage policy of %v is not connected", policy.Integration, streamName)
+			}
+		}
+		keyPrefix = tieredStorageKeyPrefix(policy.KeyPrefix)
+		var err error
+		var notJson int
+		msgs, notJson, err = redactTieredStorageMsgs(msgs, policy.Redaction)
+		if notJson > 0 {
+			s.Warnf("[tenant: %v]uploadTieredStorageBatch: %v payloads of %v are not json, their fields can not be redacted so they were replaced", tenantName, notJson, streamName)
+		}
+		if err != nil {
+			err = tieredStoragePolicyError{err: err}
+			for k := range StorageFunctionsMap {
+				if _, ok := tenantIntegrations[k].(models.Integration); ok && (policy.Integration == _EMPTY_ || policy.Integration == k) {
+					it.Errorf(k, tenantName, fmt.Sprintf("Can't upload messages of %v to tiered storage until its policy is fixed: %v", streamName, err.Error()))
+				}
+			}
+			return err
+		}
+	}
+
+	batch := map[string][]StoredMsg{streamName: msgs}
+	for k, f := range StorageFunctionsMap {
+		if policy != nil && policy.Integration != _EMPTY_ && policy.Integration != k {
+			continue
+		}
+		if _, ok := tenantIntegrations[k].(models.Integration); !ok || uploadedTo[k] {
+			continue
+		}
+		err := f.(func(string, map[string][]StoredMsg, string) error)(tenantName, batch, keyPrefix)
+		if err != nil {
+			return err
+		}
+		uploadedTo[k] = true
+		it.Noticef(k, tenantName, fmt.Sprintf("Uploaded a batch of messages to %v successfully", tier2StorageLabels[k]))
+	}
+	return nil
+}
+
+// tieredStorageKeyPrefix returns the prefix the object keys of a policy start with
+func tieredStorageKeyPrefix(keyPrefix string) string {
+	if keyPrefix == _EMPTY_ {
+		return _EMPTY_
+	}
+	return keyPrefix + "/"
+}
+
+func validateTieredStorageKeyPrefix(keyPrefix string) (string, error) {
+	keyPrefix = strings.Trim(strings.TrimSpace(keyPrefix), "/")
+	if keyPrefix == _EMPTY_ {
+		return _EMPTY_, nil
+	}
+	if len(keyPrefix) > tieredStorageMaxKeyPrefixLength {
+		return _EMPTY_, fmt.Errorf("key prefix can not be longer than %v characters", tieredStorageMaxKeyPrefixLength)
+	}
+	if !tieredStorageKeyPrefixRegex.MatchString(keyPrefix) {
+		return _EMPTY_, errors.New("key prefix can only contain letters, digits and the characters / ! _ . * ' ( ) -")
+	}
+	for _, segment := range strings.Split(keyPrefix, "/") {
+		if segment == _EMPTY_ || segment == "." || segment == ".." {
+			return _EMPTY_, errors.New("key prefix can not contain empty, '.' or '..' segments")
+		}
+	}
+	return keyPrefix, nil
+}
+
+func validateTieredStorageRedaction(redaction models.TieredStorageRedaction) (models.TieredStorageRedaction, error) {
+	replacement := redaction.Replacement
+	if replacement == _EMPTY_ {
+		replacement = tieredStorageRedactionDefaultReplacement
+	}
+	switch redaction.Type {
+	case _EMPTY_:
+		return models.TieredStorageRedaction{}, nil
+	case tieredStorageRedactionRegex:
+		if redaction.Pattern == _EMPTY_ {
+			return models.TieredStorageRedaction{}, errors.New("regex redaction requires a pattern")
+		}
+		_, err := regexp.Compile(redaction.Pattern)
+		if err != nil {
+			return models.TieredStorageRedaction{}, fmt.Errorf("invalid redaction pattern: %v", err.Error())
+		}
+		return models.TieredStorageRedaction{Type: redaction.Type, Pattern: redaction.Pattern, Replacement: replacement}, nil
+	case tieredStorageRedactionJsonFields:
+		fields := []string{}
+		for _, field := range redaction.Fields {
+			field = strings.TrimSpace(field)
+			if field == _EMPTY_ {
+				continue
+			}
+			for _, segment := range strings.Split(field, ".") {
+				if segment == _EMPTY_ {
+					return models.TieredStorageRedaction{}, fmt.Errorf("invalid redaction field %v", field)
+				}
+			}
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			return models.TieredStorageRedaction{}, errors.New("json_fields redaction requires at least one field")
+		}
+		return models.TieredStorageRedaction{Type: redaction.Type, Fields: fields, Replacement: replacement}, nil
+	case tieredStorageRedactionDropPayload:
+		return models.TieredStorageRedaction{Type: redaction.Type}, nil
+	default:
+		return models.TieredStorageRedaction{}, fmt.Errorf("unsupported redaction type %v, supported types are %v, %v and %v", redaction.Type, tieredStorageRedactionRegex, tieredStorageRedactionJsonFields, tieredStorageRedactionDropPayload)
+	}
+}
+
+// redactTieredStorageMsgs applies the redaction to the payloads of the messages before they are uploaded,
+// the headers are kept as is and the payloads of the given messages are not modified,
+// the number of payloads which were replaced since their fields could not be redacted is returned
+func redactTieredStorageMsgs(msgs []StoredMsg, redaction models.TieredStorageRedaction) ([]StoredMsg, int, error) {
+	if redaction.Type == _EMPTY_ {
+		return msgs, 0, nil
+	}
+	var re *regexp.Regexp
+	if redaction.Type == tieredStorageRedactionRegex {
+		var err error
+		re, err = regexp.Compile(redaction.Pattern)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	notJson := 0
+	redacted := make([]StoredMsg, 0, len(msgs))
+	for _, msg := range msgs {
+		switch redaction.Type {
+		case tieredStorageRedactionRegex:
+			msg.Data = re.ReplaceAllLiteral(msg.Data, []byte(redaction.Replacement))
+		case tieredStorageRedactionJsonFields:
+			var ok bool
+			msg.Data, ok = redactTieredStorageJsonFields(msg.Data, redaction.Fields, redaction.Replacement)
+			if !ok {
+				notJson++
+			}
+		case tieredStorageRedactionDropPayload:
+			msg.Data = []byte{}
+		}
+		redacted = append(redacted, msg)
+	}
+	return redacted, notJson, nil
+}
+
+// redactTieredStorageJsonFields replaces the values of the dot separated fields of a json payload,
+// a payload which is not json may hold the fields in any form so it is replaced as a whole and false is returned
+func redactTieredStorageJsonFields(data []byte, fields []string, replacement string) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var payload interface{}
+	err := decoder.Decode(&payload)
+	if err != nil || decoder.More() {
+		return []byte(replacement), false
+	}
+	changed := false
+	for _, field := range fields {
+		if redactTieredStorageJsonPath(payload, strings.Split(field, "."), replacement) {
+			changed = true
+		}
+	}
+	if !changed {
+		return data, true
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(payload)
+	if err != nil {
+		return []byte(replacement), false
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true
+}
+
+// redactTieredStorageJsonPath replaces the value at the path, the path is applied to every element of an array
+func redactTieredStorageJsonPath(value interface{}, path []string, replacement string) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			v[path[0]] = replacement
+			return true
+		}
+		return redactTieredStorageJsonPath(child, path[1:], replacement)
+	case []interface{}:
+		changed := false
+		for _, item := range v {
+			if redactTieredStorageJsonPath(item, path, replacement) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+func (sh StationsHandler) UpdateTieredStoragePolicy(c *gin.Context) {
+	var body models.UpdateTieredStoragePolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateTieredStoragePolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	if body.MaxUploadLatencySec > tieredStorageMaxUploadLatencySec {
+		errMsg := fmt.Sprintf("Max upload latency can not be longer than %v seconds", tieredStorageMaxUploadLatencySec)
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	keyPrefix, err := validateTieredStorageKeyPrefix(body.KeyPrefix)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at validateTieredStorageKeyPrefix: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	redaction, err := validateTieredStorageRedaction(body.Redaction)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at validateTieredStorageRedaction: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	integration := strings.TrimSpace(body.Integration)
+	if integration != _EMPTY_ {
+		if _, ok := StorageFunctionsMap[integration]; !ok {
+			errMsg := fmt.Sprintf("Integration %v is not a tiered storage integration", integration)
+			serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+		tenantIntegrations, ok := IntegrationsConcurrentCache.Load(user.TenantName)
+		if ok {
+			_, ok = tenantIntegrations[integration].(models.Integration)
+		}
+		if !ok {
+			errMsg := fmt.Sprintf("Integration %v is not connected", integration)
+			serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, errMsg)
+			c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]UpdateTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	policy, err := db.UpsertTieredStoragePolicy(models.TieredStoragePolicy{
+		StationId:           station.ID,
+		TenantName:          station.TenantName,
+		Integration:         integration,
+		KeyPrefix:           keyPrefix,
+		MaxBatchMessages:    body.MaxBatchMessages,
+		MaxBatchBytes:       body.MaxBatchBytes,
+		MaxUploadLatencySec: body.MaxUploadLatencySec,
+		Redaction:           redaction,
+	})
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at UpsertTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	invalidateTieredStoragePolicy(station.TenantName, stationName.Ext())
+
+	message := fmt.Sprintf("Tiered storage policy of station %v has been updated by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]UpdateTieredStoragePolicy at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	c.IndentedJSON(200, policy)
+}
+
+func (sh StationsHandler) GetTieredStoragePolicy(c *gin.Context) {
+	var body models.GetTieredStoragePolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("GetTieredStoragePolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]GetTieredStoragePolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "read")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetTieredStoragePolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to read station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]GetTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetTieredStoragePolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]GetTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, policy, err := db.GetTieredStoragePolicy(station.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]GetTieredStoragePolicy at db.GetTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		c.IndentedJSON(200, gin.H{})
+		return
+	}
+
+	c.IndentedJSON(200, policy)
+}
+
+func (sh StationsHandler) RemoveTieredStoragePolicy(c *gin.Context) {
+	var body models.RemoveTieredStoragePolicySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveTieredStoragePolicy at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("[tenant: %v][user: %v]RemoveTieredStoragePolicy at StationNameFromStr: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	allowed, _, err := ValidateStationPermissions(user.Roles, stationName.Ext(), user.TenantName, "write")
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveTieredStoragePolicy at ValidateStationPermissions: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !allowed {
+		errMsg := fmt.Sprintf("user %v is not allowed to update station %v", user.Username, stationName.Ext())
+		serv.Warnf("[tenant: %v][user: %v]RemoveTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, station, err := db.GetStationByName(stationName.Ext(), user.TenantName)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveTieredStoragePolicy at GetStationByName: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("Station %v does not exist", body.StationName)
+		serv.Warnf("[tenant: %v][user: %v]RemoveTieredStoragePolicy: %v", user.TenantName, user.Username, errMsg)
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = db.RemoveTieredStoragePolicy(station.ID)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveTieredStoragePolicy at db.RemoveTieredStoragePolicy: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	invalidateTieredStoragePolicy(station.TenantName, stationName.Ext())
+
+	message := fmt.Sprintf("Tiered storage policy of station %v has been removed by user %v", stationName.Ext(), user.Username)
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       stationName.Ext(),
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RemoveTieredStoragePolicy at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, body.StationName, err.Error())
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
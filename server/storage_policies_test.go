@@ -0,0 +1,224 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+func TestTieredStoragePolicies(t *testing.T) {
+	now := time.Now()
+	policy := &models.TieredStoragePolicy{MaxBatchMessages: 3, MaxBatchBytes: 10, MaxUploadLatencySec: 60}
+	b := &tieredStorageBuffer{tenantName: "acme", streamName: "orders$1", policy: policy, policyLoaded: true}
+	for i := 0; i < 2; i++ {
+		msg := StoredMsg{Sequence: uint64(i), Data: []byte("ab")}
+		b.msgs = append(b.msgs, tieredStorageBufferedMsg{msg: msg, arrivedAt: now})
+		b.size += tieredStorageMsgSize(msg)
+	}
+	if b.due(now, time.Second) {
+		t.Errorf("expected a buffer below its thresholds and latency not to be due")
+	}
+	if !b.due(now.Add(time.Minute), time.Second) {
+		t.Errorf("expected a buffer past its upload latency to be due")
+	}
+	for i := 2; i < 6; i++ {
+		msg := StoredMsg{Sequence: uint64(i), Data: []byte("abcd")}
+		b.msgs = append(b.msgs, tieredStorageBufferedMsg{msg: msg, arrivedAt: now})
+		b.size += tieredStorageMsgSize(msg)
+	}
+	if !b.due(now, time.Second) {
+		t.Fatalf("expected a buffer past its batch thresholds to be due")
+	}
+	batch := b.takeBatch()
+	if len(batch) != 3 || len(b.msgs) != 3 || b.size != 12 {
+		t.Fatalf("expected a batch of 3 messages leaving 12 bytes, got %v messages leaving %v bytes", len(batch), b.size)
+	}
+	if b.due(now.Add(time.Minute), time.Second) {
+		t.Errorf("expected a buffer with a batch in flight not to be due")
+	}
+	b.returnBatch(batch, map[string]bool{"s3": true}, now.Add(time.Minute))
+	if len(b.failed) != 3 || len(b.msgs) != 3 || b.size != 12 || b.inflight != nil {
+		t.Fatalf("expected the failed batch to be kept aside for a retry")
+	}
+	if b.due(now, time.Second) {
+		t.Errorf("expected a buffer waiting for its retry not to be due")
+	}
+	b.retryAt = time.Time{}
+	if !b.due(now, time.Second) {
+		t.Errorf("expected a failed batch to be due once its retry time has passed")
+	}
+	if retried := b.takeBatch(); len(retried) != 3 || retried[0].msg.Sequence != 0 || b.failed != nil {
+		t.Fatalf("expected the failed batch to be retried as is")
+	}
+	b.inflight = nil
+	b.policy.MaxBatchMessages = 0
+	if batch := b.takeBatch(); len(batch) != 3 || batch[0].msg.Sequence != 3 {
+		t.Errorf("expected the message crossing the bytes threshold to end the batch, got %v messages", len(batch))
+	}
+
+	for prefix, expected := range map[string]string{"": "", " /team/orders/ ": "team/orders"} {
+		if keyPrefix, err := validateTieredStorageKeyPrefix(prefix); err != nil || keyPrefix != expected {
+			t.Errorf("unexpected key prefix %q for %q: %v", keyPrefix, prefix, err)
+		}
+	}
+	for _, prefix := range []string{"a/../b", "a//b", "a b"} {
+		if _, err := validateTieredStorageKeyPrefix(prefix); err == nil {
+			t.Errorf("expected key prefix %q to fail the validation", prefix)
+		}
+	}
+	uploads, err := buildTieredStorageUploads("acme", "orders$1", tieredStorageKeyPrefix("team"), tieredStorageFormatJson, "uid", []StoredMsg{{Time: now}})
+	if err != nil || !strings.HasPrefix(uploads[0].Key, "team/memphis/acme/") {
+		t.Errorf("expected the key to start with the prefix: %v", err)
+	}
+
+	if _, err := validateTieredStorageRedaction(models.TieredStorageRedaction{Type: tieredStorageRedactionRegex, Pattern: "("}); err == nil {
+		t.Errorf("expected an invalid pattern to fail the validation")
+	}
+	msgs := []StoredMsg{
+		{Data: []byte(`{"user":{"email":"a@b.c","id":1},"items":[{"card":"4111"},{"card":"4222"}],"note":"<x>"}`)},
+		{Data: []byte("not json a@b.c")},
+	}
+	redaction, err := validateTieredStorageRedaction(models.TieredStorageRedaction{Type: tieredStorageRedactionJsonFields, Fields: []string{"user.email", "items.card", "missing.field"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	redacted, notJson, err := redactTieredStorageMsgs(msgs, redaction)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(redacted[0].Data) != `{"items":[{"card":"[REDACTED]"},{"card":"[REDACTED]"}],"note":"<x>","user":{"email":"[REDACTED]","id":1}}` {
+		t.Errorf("unexpected redacted payload %s", redacted[0].Data)
+	}
+	if string(redacted[1].Data) != tieredStorageRedactionDefaultReplacement || notJson != 1 {
+		t.Errorf("expected a non json payload to be replaced, got %s", redacted[1].Data)
+	}
+	if string(msgs[1].Data) != "not json a@b.c" || strings.Contains(string(msgs[0].Data), "REDACTED") {
+		t.Errorf("expected the original messages to be left unchanged")
+	}
+	redaction, _ = validateTieredStorageRedaction(models.TieredStorageRedaction{Type: tieredStorageRedactionRegex, Pattern: `[a-z]+@[a-z.]+`, Replacement: "***"})
+	redacted, _, _ = redactTieredStorageMsgs(msgs[1:], redaction)
+	if string(redacted[0].Data) != "not json ***" {
+		t.Errorf("unexpected redacted payload %s", redacted[0].Data)
+	}
+	redacted, _, _ = redactTieredStorageMsgs(msgs, models.TieredStorageRedaction{Type: tieredStorageRedactionDropPayload})
+	if len(redacted[0].Data) != 0 || len(redacted[1].Data) != 0 {
+		t.Errorf("expected the payloads to be dropped")
+	}
+}
+
+func TestUploadTieredStorageBatch(t *testing.T) {
+	originalFunctions, originalIntegrations := StorageFunctionsMap, IntegrationsConcurrentCache
+	defer func() { StorageFunctionsMap, IntegrationsConcurrentCache = originalFunctions, originalIntegrations }()
+
+	uploads := map[string]int{}
+	failing := map[string]bool{}
+	fakeStorage := func(name string) func(string, map[string][]StoredMsg, string) error {
+		return func(tenantName string, batch map[string][]StoredMsg, keyPrefix string) error {
+			if failing[name] {
+				return errors.New(name + " is unavailable")
+			}
+			uploads[name]++
+			return nil
+		}
+	}
+	StorageFunctionsMap = map[string]interface{}{"fake_a": fakeStorage("fake_a"), "fake_b": fakeStorage("fake_b"), "fake_c": fakeStorage("fake_c")}
+	IntegrationsConcurrentCache = NewConcurrentMap[map[string]interface{}]()
+	IntegrationsConcurrentCache.Add("acme", map[string]interface{}{"fake_a": models.Integration{}, "fake_b": models.Integration{}})
+	s := &Server{}
+	msgs := []StoredMsg{{Sequence: 1, Data: []byte("a@b.c")}}
+
+	// a failed integration is retried without uploading the batch again to the integrations which succeeded
+	failing["fake_b"] = true
+	uploadedTo := map[string]bool{}
+	if err := s.uploadTieredStorageBatch("acme", "orders", nil, msgs, uploadedTo); err == nil {
+		t.Fatalf("expected the upload to fail")
+	}
+	failing["fake_b"] = false
+	if err := s.uploadTieredStorageBatch("acme", "orders", nil, msgs, uploadedTo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if uploads["fake_a"] != 1 || uploads["fake_b"] != 1 || uploads["fake_c"] != 0 {
+		t.Errorf("expected one upload to every connected integration, got %v", uploads)
+	}
+
+	// the integration of the policy is not connected, the batch has to be kept for a retry
+	policy := &models.TieredStoragePolicy{Integration: "fake_c"}
+	err := s.uploadTieredStorageBatch("acme", "orders", policy, msgs, map[string]bool{})
+	if err == nil || uploads["fake_c"] != 0 {
+		t.Errorf("expected an error when the integration of the policy is not connected, got %v", err)
+	}
+	policy.Integration = "fake_a"
+	if err := s.uploadTieredStorageBatch("acme", "orders", policy, msgs, map[string]bool{}); err != nil || uploads["fake_a"] != 2 || uploads["fake_b"] != 1 {
+		t.Errorf("expected the batch to be uploaded only to the integration of the policy: %v, %v", err, uploads)
+	}
+
+	// a redaction which can not be applied is a policy error and nothing is uploaded
+	policy.Redaction = models.TieredStorageRedaction{Type: tieredStorageRedactionRegex, Pattern: "("}
+	err = s.uploadTieredStorageBatch("acme", "orders", policy, msgs, map[string]bool{})
+	var policyErr tieredStoragePolicyError
+	if !errors.As(err, &policyErr) || uploads["fake_a"] != 2 {
+		t.Errorf("expected a policy error without uploading, got %v", err)
+	}
+
+	// the buffer holds a batch which failed on its policy until the policy is changed
+	b := &tieredStorageBuffer{tenantName: "acme", streamName: "orders", policy: policy, policyLoaded: true}
+	b.msgs = []tieredStorageBufferedMsg{{msg: msgs[0], arrivedAt: time.Now().Add(-time.Hour)}}
+	s.flushTieredStorageBuffer(b, policy, b.takeBatch(), map[string]bool{})
+	if b.policyErr == nil || len(b.failed) != 1 {
+		t.Fatalf("expected the buffer to keep the batch and the policy error")
+	}
+	b.retryAt = time.Time{}
+	if b.due(time.Now(), time.Second) {
+		t.Errorf("expected a buffer with an invalid policy not to be due")
+	}
+
+	// a buffer which fails to upload holds only the failed batch, new messages are left to the consumer redelivery
+	if !tieredStorageBuffers.Add("acme/orders", b) {
+		t.Fatalf("expected no buffer of the stream to exist")
+	}
+	defer tieredStorageBuffers.Delete("acme/orders")
+	s.storeInTieredStorageBuffer(StoredMsg{TenantName: "acme", Subject: "orders", Sequence: 3})
+	if !b.uploadFailing || len(b.msgs) != 0 {
+		t.Errorf("expected a failing buffer not to hold new messages, got %v", len(b.msgs))
+	}
+}
+
+func TestTieredStorageBufferLimits(t *testing.T) {
+	defer tieredStorageBuffers.Delete("acme/limits")
+	s := &Server{}
+	b := &tieredStorageBuffer{tenantName: "acme", streamName: "limits", policyLoaded: true}
+	tieredStorageBuffers.Add("acme/limits", b)
+	now := time.Now()
+	s.storeInTieredStorageBuffer(StoredMsg{TenantName: "acme", Subject: "limits", Data: make([]byte, tieredStorageBufferMaxBytes)})
+	if len(b.msgs) != 1 || !b.due(now, time.Hour) {
+		t.Fatalf("expected a buffer which reached its bytes limit to be due before its upload latency")
+	}
+	s.storeInTieredStorageBuffer(StoredMsg{TenantName: "acme", Subject: "limits", Data: []byte("a")})
+	if len(b.msgs) != 1 {
+		t.Errorf("expected a full buffer not to hold more messages, got %v", len(b.msgs))
+	}
+	b.takeBatch()
+	b.inflight = nil
+	for i := 0; i < tieredStorageBufferMaxMessages; i++ {
+		s.storeInTieredStorageBuffer(StoredMsg{TenantName: "acme", Subject: "limits"})
+	}
+	s.storeInTieredStorageBuffer(StoredMsg{TenantName: "acme", Subject: "limits"})
+	if len(b.msgs) != tieredStorageBufferMaxMessages || !b.due(now, time.Hour) {
+		t.Errorf("expected the buffer to hold up to %v messages and to be due, got %v", tieredStorageBufferMaxMessages, len(b.msgs))
+	}
+}
@@ -14,13 +14,13 @@ package server
 import (
 	"bytes"
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-
+	"io"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/memphisdev/memphis/db"
 	"github.com/memphisdev/memphis/models"
@@ -38,6 +38,9 @@ type TieredStorageMsg struct {
 	Buf         []byte `json:"buf"`
 	StationName string `json:"station_name"`
 	TenantName  string `json:"tenant_name"`
+	// the sequence and timestamp of the message in its original stream
+	Sequence  uint64 `json:"sequence"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 func cacheDetailsS3(keys map[string]interface{}, properties map[string]bool, tenantName string) {
@@ -53,16 +56,21 @@ func cacheDetailsS3(keys map[string]interface{}, properties map[string]bool, ten
 	s3Integration.Keys["secret_key"] = keys["secret_key"].(string)
 	s3Integration.Keys["bucket_name"] = keys["bucket_name"].(string)
 	s3Integration.Keys["region"] = keys["region"].(string)
-	if _, ok := s3Integration.Keys["url"].(string); ok {
+	if _, ok := keys["url"].(string); ok {
 		s3Integration.Keys["url"] = keys["url"].(string)
 	} else {
 		s3Integration.Keys["url"] = _EMPTY_
 	}
-	if _, ok := s3Integration.Keys["s3_path_style"].(string); ok {
+	if _, ok := keys["s3_path_style"].(string); ok {
 		s3Integration.Keys["s3_path_style"] = keys["s3_path_style"].(string)
 	} else {
 		s3Integration.Keys["s3_path_style"] = "false"
 	}
+	if _, ok := keys["archive_format"].(string); ok {
+		s3Integration.Keys["archive_format"] = keys["archive_format"].(string)
+	} else {
+		s3Integration.Keys["archive_format"] = tieredStorageFormatJson
+	}
 	s3Integration.Name = "s3"
 	if _, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
 		IntegrationsConcurrentCache.Add(tenantName, map[string]interface{}{"s3": s3Integration})
@@ -82,6 +90,7 @@ func (it IntegrationsHandler) handleCreateS3Integration(tenantName string, keys
 	}
 
 	keysMap, properties := createIntegrationsKeysAndProperties("s3", _EMPTY_, _EMPTY_, false, false, false, keys["access_key"].(string), keys["secret_key"].(string), keys["bucket_name"].(string), keys["region"].(string), keys["url"].(string), keys["s3_path_style"].(string), map[string]interface{}{}, _EMPTY_, _EMPTY_, _EMPTY_, _EMPTY_)
+	keysMap["archive_format"] = keys["archive_format"].(string)
 	s3Integration, err := createS3Integration(tenantName, keysMap, properties)
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
@@ -100,6 +109,7 @@ func (it IntegrationsHandler) handleUpdateS3Integration(tenantName string, body
 	}
 	integrationType := strings.ToLower(body.Name)
 	keysMap, properties := createIntegrationsKeysAndProperties(integrationType, _EMPTY_, _EMPTY_, false, false, false, keys["access_key"].(string), keys["secret_key"].(string), keys["bucket_name"].(string), keys["region"].(string), keys["url"].(string), keys["s3_path_style"].(string), map[string]interface{}{}, _EMPTY_, _EMPTY_, _EMPTY_, _EMPTY_)
+	keysMap["archive_format"] = keys["archive_format"].(string)
 	s3Integration, err := updateS3Integration(tenantName, keysMap, properties)
 	if err != nil {
 		return s3Integration, 500, err
@@ -143,6 +153,17 @@ func (it IntegrationsHandler) handleS3Integration(tenantName string, keys map[st
 		keys["url"] = url
 	}
 
+	if format, ok := keys["archive_format"].(string); ok && format != _EMPTY_ {
+		format = strings.ToLower(format)
+		err := validateTieredStorageFormat(format)
+		if err != nil {
+			return SHOWABLE_ERROR_STATUS_CODE, map[string]interface{}{}, err
+		}
+		keys["archive_format"] = format
+	} else {
+		keys["archive_format"] = tieredStorageFormatJson
+	}
+
 	if keys["secret_key"] == _EMPTY_ {
 		exist, integrationFromDb, err := db.GetIntegration("s3", tenantName)
 		if err != nil {
@@ -361,12 +382,158 @@ func testS3Integration(svc *s3.Client, bucketName, urlEndpointResolver string) (
 	return 0, nil
 }
 
+// Msg is the representation of a message within an object of the tiered storage, the sequence and timestamp are
+// the ones the message had in its original stream and are empty for objects which were uploaded by older versions
 type Msg struct {
-	Payload string            `json:"payload"`
-	Headers map[string]string `json:"headers"`
+	Payload   string               `json:"payload"`
+	Headers   tieredStorageHeaders `json:"headers"`
+	Sequence  uint64               `json:"sequence,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+// tieredStorageHeaders keeps every value of a header in the order the values were produced
+type tieredStorageHeaders map[string][]string
+
+// UnmarshalJSON also accepts the single valued headers of the objects which were uploaded by older versions
+func (h *tieredStorageHeaders) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	headers := make(tieredStorageHeaders, len(raw))
+	for key, value := range raw {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			var single string
+			if err := json.Unmarshal(value, &single); err != nil {
+				return fmt.Errorf("invalid value of header %v", key)
+			}
+			values = []string{single}
+		}
+		headers[key] = values
+	}
+	*h = headers
+	return nil
+}
+
+// flatten combines the values of a repeated header into a single comma separated value, the way repeated HTTP fields
+// are combined, for the places which carry a single value per header
+func (h tieredStorageHeaders) flatten() map[string]string {
+	headers := make(map[string]string, len(h))
+	for key, values := range h {
+		headers[key] = strings.Join(values, ",")
+	}
+	return headers
+}
+
+func tieredStorageTenantFolder(tenantName string) string {
+	if tenantName == _EMPTY_ || tenantName == serv.MemphisGlobalAccountString() {
+		return "global"
+	}
+	return tenantName
+}
+
+// tieredStorageObjectsPrefix returns the prefix of the objects which hold the messages of the stream
+func tieredStorageObjectsPrefix(tenantName, streamName string) string {
+	return "memphis/" + tieredStorageTenantFolder(tenantName) + "/" + strings.Replace(streamName, "#", ".", -1) + "/"
+}
+
+// parseTieredStorageHeaders keeps the headers of the message as they were produced, repeated headers keep all of their
+// values in order and only the status line and the space which follows the colon of each header are dropped
+func parseTieredStorageHeaders(header []byte) tieredStorageHeaders {
+	hdrs := tieredStorageHeaders{}
+	for i, line := range strings.Split(string(header), CR_LF) {
+		if line == _EMPTY_ || (i == 0 && strings.HasPrefix(line, "NATS/")) {
+			continue
+		}
+		keyVal := strings.SplitN(line, ":", 2)
+		if len(keyVal) != 2 {
+			continue
+		}
+		hdrs[keyVal[0]] = append(hdrs[keyVal[0]], strings.TrimPrefix(keyVal[1], " "))
+	}
+	return hdrs
+}
+
+func s3ClientFromIntegration(integration models.Integration) (*s3.Client, error) {
+	provider := credentials.NewStaticCredentialsProvider(
+		integration.Keys["access_key"].(string),
+		integration.Keys["secret_key"].(string),
+		_EMPTY_,
+	)
+	_, err := provider.Retrieve(context.Background())
+	if err != nil {
+		return nil, errors.New("invalid credentials")
+	}
+
+	region := integration.Keys["region"].(string)
+	url, _ := integration.Keys["url"].(string)
+	pathStyle := false
+	if value, ok := integration.Keys["s3_path_style"].(string); ok {
+		pathStyle, _ = strconv.ParseBool(value)
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(),
+		awsconfig.WithCredentialsProvider(provider),
+		awsconfig.WithRegion(region),
+		awsconfig.WithEndpointResolverWithOptions(getS3EndpointResolver(region, url)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = pathStyle
+	}), nil
+}
+
+type s3TieredStorageReader struct {
+	svc    *s3.Client
+	bucket string
+}
+
+func newS3TieredStorageReader(integration models.Integration) (tieredStorageReader, error) {
+	svc, err := s3ClientFromIntegration(integration)
+	if err != nil {
+		return nil, err
+	}
+	return &s3TieredStorageReader{svc: svc, bucket: integration.Keys["bucket_name"].(string)}, nil
+}
+
+func (r *s3TieredStorageReader) List(ctx context.Context, prefix string) ([]tieredStorageObjectInfo, error) {
+	objects := []tieredStorageObjectInfo{}
+	paginator := s3.NewListObjectsV2Paginator(r.svc, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, tieredStorageObjectInfo{
+				Key:          aws.ToString(obj.Key),
+				Size:         obj.Size,
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (r *s3TieredStorageReader) Read(ctx context.Context, key string) ([]byte, error) {
+	res, err := r.svc.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
 }
 
-func (s *Server) uploadToS3Storage(tenantName string, tenant map[string][]StoredMsg) error {
+func (s *Server) uploadToS3Storage(tenantName string, tenant map[string][]StoredMsg, keyPrefix string) error {
 	for k, msgs := range tenant {
 		var credentialsMap models.Integration
 		if tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName); !ok {
@@ -376,83 +543,36 @@ func (s *Server) uploadToS3Storage(tenantName string, tenant map[string][]Stored
 				continue
 			}
 		}
-		provider := credentials.NewStaticCredentialsProvider(
-			credentialsMap.Keys["access_key"].(string),
-			credentialsMap.Keys["secret_key"].(string),
-			_EMPTY_,
-		)
-
-		region := credentialsMap.Keys["region"]
-		url := credentialsMap.Keys["url"]
-		pathStyle, _ := strconv.ParseBool(credentialsMap.Keys["s3_path_style"].(string))
-
-		_, err := provider.Retrieve(context.Background())
-		if err != nil {
-			err = errors.New("uploadToS3Storage: Invalid credentials")
-			return err
-		}
-		cfg, err := awsconfig.LoadDefaultConfig(context.Background(),
-			awsconfig.WithCredentialsProvider(provider),
-			awsconfig.WithRegion(credentialsMap.Keys["region"].(string)),
-			awsconfig.WithEndpointResolverWithOptions(getS3EndpointResolver(region.(string), url.(string))),
-		)
-		svc := s3.NewFromConfig(cfg, func(o *s3.Options) {
-			o.UsePathStyle = pathStyle
-		})
+		svc, err := s3ClientFromIntegration(credentialsMap)
 		if err != nil {
 			err = errors.New("uploadToS3Storage failure " + err.Error())
 			return err
 		}
 		uploader := manager.NewUploader(svc)
 		uid := serv.memphis.nuid.Next()
-		var objectName string
+		uploads, err := buildTieredStorageUploads(tenantName, k, keyPrefix, tieredStorageFormat(credentialsMap), uid, msgs)
+		if err != nil {
+			return err
+		}
 
-		var messages []Msg
 		size := int64(0)
 		for _, msg := range msgs {
-			if tenantName == serv.MemphisGlobalAccountString() {
-				tenantName = "global"
-			}
-			objectName = "memphis/" + tenantName + "/" + k + "/" + uid + "(" + strconv.Itoa(len(msgs)) + ").json"
-			var headers string
-			hdrs := map[string]string{}
-			if len(msg.Header) > 0 {
-				headers = strings.ToLower(string(msg.Header))
-				headersSplit := strings.Split(headers, CR_LF)
-				for _, header := range headersSplit {
-					if header != _EMPTY_ && !strings.Contains(header, "nats") {
-						keyVal := strings.Split(header, ":")
-						key := strings.TrimSpace(keyVal[0])
-						value := strings.TrimSpace(keyVal[1])
-						hdrs[key] = value
-					}
-				}
-			} else {
-				headers = _EMPTY_
-			}
-
-			encodedMsg := hex.EncodeToString(msg.Data)
-			message := Msg{Payload: encodedMsg, Headers: hdrs}
-			messages = append(messages, message)
 			size += int64(len(msg.Data)) + int64(len(msg.Header))
 		}
-		// Upload the object to S3.
-		var buf bytes.Buffer
-		err = json.NewEncoder(&buf).Encode(messages)
-		if err != nil {
-			return err
-		}
-		_, err = uploader.Upload(context.Background(), &s3.PutObjectInput{
-			Bucket: aws.String(credentialsMap.Keys["bucket_name"].(string)),
-			Key:    aws.String(objectName),
-			Body:   &buf,
-		})
-		if err != nil {
-			err = errors.New("uploadToS3Storage: failed to upload object to S3: " + err.Error())
-			return err
+		for _, upload := range uploads {
+			// Upload the object to S3.
+			_, err = uploader.Upload(context.Background(), &s3.PutObjectInput{
+				Bucket: aws.String(credentialsMap.Keys["bucket_name"].(string)),
+				Key:    aws.String(upload.Key),
+				Body:   bytes.NewReader(upload.Body),
+			})
+			if err != nil {
+				err = errors.New("uploadToS3Storage: failed to upload object to S3: " + err.Error())
+				return err
+			}
+			serv.Noticef("new file has been uploaded to S3: %s", upload.Key)
 		}
-		IncrementEventCounter(tenantName, "tiered", size, int64(len(messages)), _EMPTY_, []byte{}, []byte{})
-		serv.Noticef("new file has been uploaded to S3: %s", objectName)
+		IncrementEventCounter(tieredStorageTenantFolder(tenantName), "tiered", size, int64(len(msgs)), _EMPTY_, []byte{}, []byte{})
 	}
 
 	return nil
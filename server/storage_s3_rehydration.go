@@ -0,0 +1,595 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memphisdev/memphis/analytics"
+	"github.com/memphisdev/memphis/db"
+	"github.com/memphisdev/memphis/models"
+	"github.com/memphisdev/memphis/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tieredStorageRehydrationTaskName = "tiered_storage_rehydration"
+	tieredStorageOriginalSeqHdr      = "$memphis_original_sequence"
+	tieredStorageRehydratedHdr       = "$memphis_rehydrated"
+	tieredStorageBrowseDefaultLimit  = 100
+	tieredStorageBrowseMaxLimit      = 1000
+)
+
+var tieredStorageObjectMessagesRegex = regexp.MustCompile(`\((\d+)\)\.[a-z.]+$`)
+
+// tieredStorageReadIntegrations is the order the connected integrations are read from, the messages of a station whose
+// policy does not pin an integration are uploaded to all of them
+var tieredStorageReadIntegrations = []string{"s3", filesystemIntegrationName, gcsIntegrationName, azureBlobIntegrationName}
+
+// tieredStorageStationReader returns a reader of the integration the messages of the station are uploaded to and the key
+// prefix their objects are kept under
+func tieredStorageStationReader(tenantName string, station models.Station) (tieredStorageReader, string, bool, error) {
+	tenantIntegrations, ok := IntegrationsConcurrentCache.Load(tenantName)
+	if !ok {
+		return nil, _EMPTY_, false, nil
+	}
+	exist, policy, err := db.GetTieredStoragePolicy(station.ID)
+	if err != nil {
+		return nil, _EMPTY_, false, err
+	}
+	keyPrefix := _EMPTY_
+	integrationTypes := tieredStorageReadIntegrations
+	if exist {
+		keyPrefix = tieredStorageKeyPrefix(policy.KeyPrefix)
+		if policy.Integration != _EMPTY_ {
+			integrationTypes = []string{policy.Integration}
+		}
+	}
+	for _, integrationType := range integrationTypes {
+		integration, ok := tenantIntegrations[integrationType].(models.Integration)
+		if !ok {
+			continue
+		}
+		reader, err := tieredStorageReaderFromIntegration(integrationType, integration)
+		if err != nil {
+			return nil, _EMPTY_, false, err
+		}
+		return reader, keyPrefix, true, nil
+	}
+	return nil, _EMPTY_, false, nil
+}
+
+func validateTieredStorageRange(from, to time.Time) error {
+	if !to.IsZero() && to.Before(from) {
+		return errors.New("the end of the time range must be after its start")
+	}
+	return nil
+}
+
+func inTieredStorageRange(t, from, to time.Time) bool {
+	if t.Before(from) {
+		return false
+	}
+	return to.IsZero() || !t.After(to)
+}
+
+// tieredStorageMsgTime returns the original time of the message, messages of objects uploaded by older versions are
+// matched by the upload time of their object
+func tieredStorageMsgTime(msg Msg, object models.TieredStorageObject) time.Time {
+	if msg.Timestamp.IsZero() {
+		return object.UploadedAt
+	}
+	return msg.Timestamp
+}
+
+// listTieredStorageObjects lists the objects of the station's partitions which may hold messages produced since from,
+// objects are uploaded after their messages have left the station so objects uploaded before from can not hold any of them
+func listTieredStorageObjects(ctx context.Context, reader tieredStorageReader, keyPrefix string, station models.Station, from time.Time) ([]models.TieredStorageObject, error) {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return nil, err
+	}
+	objects := []models.TieredStorageObject{}
+	for _, partition := range stationArchivePartitions(station) {
+		// objects of the json format are kept under the stream name while the archive formats are partitioned by station and partition
+		prefixes := []string{
+			keyPrefix + tieredStorageObjectsPrefix(station.TenantName, stationArchiveStreamName(sn, partition)),
+			keyPrefix + tieredStorageArchivePrefix(station.TenantName, sn.Ext(), partition),
+		}
+		for _, prefix := range prefixes {
+			listed, err := reader.List(ctx, prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range listed {
+				if obj.LastModified.Before(from) {
+					continue
+				}
+				object := models.TieredStorageObject{
+					Key:        obj.Key,
+					Partition:  partition,
+					Size:       obj.Size,
+					UploadedAt: obj.LastModified,
+				}
+				if match := tieredStorageObjectMessagesRegex.FindStringSubmatch(object.Key); match != nil {
+					object.Messages, _ = strconv.Atoi(match[1])
+				}
+				objects = append(objects, object)
+			}
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].UploadedAt.Before(objects[j].UploadedAt)
+	})
+	return objects, nil
+}
+
+func readTieredStorageObject(ctx context.Context, reader tieredStorageReader, key string) ([]Msg, error) {
+	body, err := reader.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := decodeTieredStorageObject(key, body)
+	if err != nil {
+		return nil, fmt.Errorf("object %v is not a valid tiered storage object: %v", key, err.Error())
+	}
+	return msgs, nil
+}
+
+// rehydratedMsgHeaders returns the headers a message is replayed with, the original sequence and time are kept in
+// dedicated headers and the NATS headers are dropped so the replayed message is not deduplicated, the rehydrated
+// marker keeps the replayed message from being uploaded to the tiered storage again and the values of repeated headers
+// are combined since the internal publish carries a single value per header
+func rehydratedMsgHeaders(msg Msg, msgTime time.Time) map[string]string {
+	headers := make(map[string]string, len(msg.Headers)+3)
+	for key, value := range msg.Headers.flatten() {
+		if strings.HasPrefix(strings.ToLower(key), "nats") {
+			continue
+		}
+		headers[key] = value
+	}
+	headers[stationArchiveOriginalTimeHdr] = msgTime.Format(time.RFC3339Nano)
+	if msg.Sequence != 0 {
+		headers[tieredStorageOriginalSeqHdr] = strconv.FormatUint(msg.Sequence, 10)
+	}
+	headers[tieredStorageRehydratedHdr] = "true"
+	return headers
+}
+
+// isRehydratedMsg reports whether the raw stored message, headers followed by the payload, has been replayed from the tiered storage
+func isRehydratedMsg(buf []byte) bool {
+	hdrEnd := getHdrLastIdxFromRaw(buf)
+	if hdrEnd < 0 {
+		return false
+	}
+	return getHeader(tieredStorageRehydratedHdr, buf[:hdrEnd+1]) != nil
+}
+
+// tieredStorageSource returns the station whose tiered storage is read after the user permissions have been validated,
+// along with a reader of the integration its messages are uploaded to and the key prefix of its objects
+func tieredStorageSource(user models.User, stationName string) (models.Station, tieredStorageReader, string, int, error) {
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return models.Station{}, nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	allowed, _, err := ValidateStationPermissions(user.Roles, sn.Ext(), user.TenantName, "read")
+	if err != nil {
+		return models.Station{}, nil, _EMPTY_, 500, err
+	}
+	if !allowed {
+		return models.Station{}, nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("user %v is not allowed to read from station %v", user.Username, sn.Ext())
+	}
+	exist, station, err := db.GetStationByName(sn.Ext(), user.TenantName)
+	if err != nil {
+		return models.Station{}, nil, _EMPTY_, 500, err
+	}
+	if !exist {
+		return models.Station{}, nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("Station %v does not exist", sn.Ext())
+	}
+	reader, keyPrefix, ok, err := tieredStorageStationReader(user.TenantName, station)
+	if err != nil {
+		return models.Station{}, nil, _EMPTY_, 500, err
+	}
+	if !ok {
+		return models.Station{}, nil, _EMPTY_, SHOWABLE_ERROR_STATUS_CODE, errors.New("tiered storage is not connected, connect a tiered storage integration first")
+	}
+	return station, reader, keyPrefix, 0, nil
+}
+
+func abortTieredStorageRequest(c *gin.Context, user models.User, funcName string, status int, err error) {
+	if status == SHOWABLE_ERROR_STATUS_CODE {
+		serv.Warnf("[tenant: %v][user: %v]%v: %v", user.TenantName, user.Username, funcName, err.Error())
+		c.AbortWithStatusJSON(SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	serv.Errorf("[tenant: %v][user: %v]%v: %v", user.TenantName, user.Username, funcName, err.Error())
+	c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+}
+
+func (sh StationsHandler) ListTieredStorageObjects(c *gin.Context) {
+	var body models.ListTieredStorageObjectsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ListTieredStorageObjects at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = validateTieredStorageRange(body.From, body.To)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "ListTieredStorageObjects at validateTieredStorageRange", SHOWABLE_ERROR_STATUS_CODE, err)
+		return
+	}
+	station, reader, keyPrefix, status, err := tieredStorageSource(user, body.StationName)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "ListTieredStorageObjects at tieredStorageSource", status, err)
+		return
+	}
+
+	objects, err := listTieredStorageObjects(c.Request.Context(), reader, keyPrefix, station, body.From)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "ListTieredStorageObjects at listTieredStorageObjects", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("failed listing the objects of station %v: %v", station.Name, err.Error()))
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"objects": objects})
+}
+
+// BrowseTieredStorage serves the messages of the station which have been moved to the tiered storage in the shape of the
+// message browser, the whole payload is returned since those messages can not be fetched later by their sequence
+func (sh StationsHandler) BrowseTieredStorage(c *gin.Context) {
+	var body models.BrowseTieredStorageSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("BrowseTieredStorage at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = validateTieredStorageRange(body.From, body.To)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "BrowseTieredStorage at validateTieredStorageRange", SHOWABLE_ERROR_STATUS_CODE, err)
+		return
+	}
+	limit := body.Limit
+	if limit <= 0 {
+		limit = tieredStorageBrowseDefaultLimit
+	} else if limit > tieredStorageBrowseMaxLimit {
+		limit = tieredStorageBrowseMaxLimit
+	}
+	station, reader, keyPrefix, status, err := tieredStorageSource(user, body.StationName)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "BrowseTieredStorage at tieredStorageSource", status, err)
+		return
+	}
+
+	objects, err := listTieredStorageObjects(c.Request.Context(), reader, keyPrefix, station, body.From)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "BrowseTieredStorage at listTieredStorageObjects", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("failed listing the objects of station %v: %v", station.Name, err.Error()))
+		return
+	}
+
+	var decoder *schemaPayloadDecoder
+	if station.IsNative {
+		decoder, err = loadStationSchemaDecoder(station)
+		if err != nil {
+			serv.Warnf("[tenant: %v][user: %v]BrowseTieredStorage at loadStationSchemaDecoder: station %v: %v", user.TenantName, user.Username, station.Name, err.Error())
+		}
+	}
+
+	response := models.BrowseTieredStorageResponse{Messages: []models.TieredStorageMessage{}}
+	for _, object := range objects {
+		if body.Partition != stationArchiveNoPartition && object.Partition != body.Partition {
+			continue
+		}
+		msgs, err := readTieredStorageObject(c.Request.Context(), reader, object.Key)
+		if err != nil {
+			abortTieredStorageRequest(c, user, "BrowseTieredStorage at readTieredStorageObject", SHOWABLE_ERROR_STATUS_CODE, err)
+			return
+		}
+		for _, msg := range msgs {
+			msgTime := tieredStorageMsgTime(msg, object)
+			if !inTieredStorageRange(msgTime, body.From, body.To) {
+				continue
+			}
+			if len(response.Messages) == limit {
+				response.Truncated = true
+				break
+			}
+			data, err := hex.DecodeString(msg.Payload)
+			if err != nil {
+				serv.Warnf("[tenant: %v][user: %v]BrowseTieredStorage at DecodeString: object %v: %v", user.TenantName, user.Username, object.Key, err.Error())
+				continue
+			}
+			storedMsg := StoredMsg{Sequence: msg.Sequence, Data: data, Time: msgTime}
+			messageDetails := searchResultMessageDetails(&storedMsg, msg.Headers.flatten(), object.Partition, decoder)
+			messageDetails.Data = msg.Payload
+			response.Messages = append(response.Messages, models.TieredStorageMessage{MessageDetails: messageDetails, ObjectKey: object.Key})
+		}
+		if response.Truncated {
+			break
+		}
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": station.Name}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-browse-tiered-storage")
+	}
+
+	c.IndentedJSON(200, response)
+}
+
+// RunTieredStorageRehydration replays the archived messages of the time range object by object into the target station,
+// the task status is checked between objects so a cancelled task stops before its next object
+func (s *Server) RunTieredStorageRehydration(task models.AsyncTask, source, target models.Station, reader tieredStorageReader, keyPrefix string, from, to time.Time, user models.User) {
+	go func() {
+		tenantName := target.TenantName
+		metaData := models.TieredStorageRehydrationMetaData{
+			SourceStation: source.Name,
+			From:          from,
+			To:            to,
+		}
+		targetSn, err := StationNameFromStr(target.Name)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at StationNameFromStr: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+			s.handleTieredStorageRehydrationFailure(task, source, target, user, err.Error())
+			return
+		}
+		objects, err := listTieredStorageObjects(context.Background(), reader, keyPrefix, source, from)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at listTieredStorageObjects: station %v: %v", tenantName, user.Username, source.Name, err.Error())
+			s.handleTieredStorageRehydrationFailure(task, source, target, user, err.Error())
+			return
+		}
+		metaData.TotalObjects = len(objects)
+		err = db.UpdateAsyncTaskById(task.ID, time.Now(), metaData)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at UpdateAsyncTaskById: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+		}
+
+		for _, object := range objects {
+			exist, currentTask, err := db.GetAsyncTaskById(task.ID, tenantName)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at GetAsyncTaskById: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+				s.handleTieredStorageRehydrationFailure(task, source, target, user, err.Error())
+				return
+			}
+			if !exist || currentTask.Status != "running" {
+				s.Noticef("[tenant: %v][user: %v]RunTieredStorageRehydration: rehydration of station %v into station %v has been stopped after %v messages", tenantName, user.Username, source.Name, target.Name, metaData.Replayed)
+				return
+			}
+
+			msgs, err := readTieredStorageObject(context.Background(), reader, object.Key)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at readTieredStorageObject: station %v: %v", tenantName, user.Username, source.Name, err.Error())
+				s.handleTieredStorageRehydrationFailure(task, source, target, user, err.Error())
+				return
+			}
+			partition, err := resolveArchivePartition(target.PartitionsList, object.Partition)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at resolveArchivePartition: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+				s.handleTieredStorageRehydrationFailure(task, source, target, user, err.Error())
+				return
+			}
+			subject := stationArchiveStreamName(targetSn, partition) + ".final"
+			for _, msg := range msgs {
+				msgTime := tieredStorageMsgTime(msg, object)
+				if !inTieredStorageRange(msgTime, from, to) {
+					continue
+				}
+				data, err := hex.DecodeString(msg.Payload)
+				if err == nil {
+					err = s.memphisPublishWithAck(tenantName, subject, rehydratedMsgHeaders(msg, msgTime), data)
+				}
+				if err != nil {
+					s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at memphisPublishWithAck: station %v: object %v: %v", tenantName, user.Username, target.Name, object.Key, err.Error())
+					metaData.Failed++
+					continue
+				}
+				metaData.Replayed++
+			}
+			metaData.ProcessedObjects++
+			err = db.UpdateAsyncTaskById(task.ID, time.Now(), metaData)
+			if err != nil {
+				s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at UpdateAsyncTaskById: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+			}
+		}
+
+		err = db.FinishAsyncTaskById(task.ID, "completed", _EMPTY_)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at FinishAsyncTaskById: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+			return
+		}
+
+		systemMessage := SystemMessage{
+			MessageType:    "info",
+			MessagePayload: fmt.Sprintf("Rehydration of station %s from tiered storage into station %s, triggered by user %s has been completed (%v messages, %v failed)", source.Name, target.Name, user.Username, metaData.Replayed, metaData.Failed),
+		}
+		err = s.sendSystemMessageOnWS(user, systemMessage)
+		if err != nil {
+			s.Errorf("[tenant: %v][user: %v]RunTieredStorageRehydration at sendSystemMessageOnWS: station %v: %v", tenantName, user.Username, target.Name, err.Error())
+		}
+	}()
+}
+
+func (s *Server) handleTieredStorageRehydrationFailure(task models.AsyncTask, source, target models.Station, user models.User, errMsg string) {
+	err := db.FinishAsyncTaskById(task.ID, "failed", errMsg)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleTieredStorageRehydrationFailure at FinishAsyncTaskById: station %v: %v", target.TenantName, user.Username, target.Name, err.Error())
+	}
+	systemMessage := SystemMessage{
+		MessageType:    "error",
+		MessagePayload: fmt.Sprintf("Rehydration of station %s from tiered storage into station %s, triggered by user %s has failed: %s", source.Name, target.Name, user.Username, errMsg),
+	}
+	err = s.sendSystemMessageOnWS(user, systemMessage)
+	if err != nil {
+		s.Errorf("[tenant: %v][user: %v]handleTieredStorageRehydrationFailure at sendSystemMessageOnWS: station %v: %v", target.TenantName, user.Username, target.Name, err.Error())
+	}
+}
+
+// rehydrationTarget returns the station the messages are replayed into, a missing station is created with the
+// configuration and schema of the source station
+func (s *Server) rehydrationTarget(user models.User, source models.Station, targetName string) (models.Station, bool, int, error) {
+	if targetName == _EMPTY_ || targetName == source.Name {
+		return source, false, 0, nil
+	}
+	sn, err := StationNameFromStr(targetName)
+	if err != nil {
+		return models.Station{}, false, SHOWABLE_ERROR_STATUS_CODE, err
+	}
+	allowed, _, err := ValidateStationPermissions(user.Roles, sn.Ext(), user.TenantName, "write")
+	if err != nil {
+		return models.Station{}, false, 500, err
+	}
+	if !allowed {
+		return models.Station{}, false, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("user %v is not allowed to write to station %v", user.Username, sn.Ext())
+	}
+	exist, target, err := db.GetStationByName(sn.Ext(), user.TenantName)
+	if err != nil {
+		return models.Station{}, false, 500, err
+	}
+	if exist {
+		return target, false, 0, nil
+	}
+
+	stationsCount, err := db.CountStationsByTenant(user.TenantName)
+	if err != nil {
+		return models.Station{}, false, 500, err
+	}
+	canCreate, stationsLimit := ValidataUsageLimitOfFeature(user.TenantName, "feature-stations-limitation", stationsCount+1)
+	if !canCreate {
+		return models.Station{}, false, SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("cannot create station (max amount of stations for this plan :%v)", stationsLimit)
+	}
+	target, created, err := s.createStationFromArchive(sn, user, stationArchiveConfig(source), source.SchemaName, source.SchemaVersionNumber)
+	if err != nil {
+		if IsNatsErr(err, JSInsufficientResourcesErr) {
+			return models.Station{}, false, SHOWABLE_ERROR_STATUS_CODE, errors.New("Station can not be created, probably since replicas count is larger than the cluster size")
+		}
+		return models.Station{}, false, 500, err
+	}
+	if !created {
+		// the station has been created concurrently
+		_, target, err = db.GetStationByName(sn.Ext(), user.TenantName)
+		if err != nil {
+			return models.Station{}, false, 500, err
+		}
+	}
+	return target, created, 0, nil
+}
+
+func (sh StationsHandler) RehydrateFromTieredStorage(c *gin.Context) {
+	var body models.RehydrateFromTieredStorageSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RehydrateFromTieredStorage at getUserDetailsFromMiddleware: At station %v: %v", body.StationName, err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = validateTieredStorageRange(body.From, body.To)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at validateTieredStorageRange", SHOWABLE_ERROR_STATUS_CODE, err)
+		return
+	}
+	if IsStorageLimitExceeded(user.TenantName) {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at IsStorageLimitExceeded", SHOWABLE_ERROR_STATUS_CODE, ErrUpgradePlan)
+		return
+	}
+	source, reader, keyPrefix, status, err := tieredStorageSource(user, body.StationName)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at tieredStorageSource", status, err)
+		return
+	}
+	if body.TargetStationName == _EMPTY_ || body.TargetStationName == source.Name {
+		allowed, _, err := ValidateStationPermissions(user.Roles, source.Name, user.TenantName, "write")
+		if err != nil {
+			abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at ValidateStationPermissions", 500, err)
+			return
+		}
+		if !allowed {
+			abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("user %v is not allowed to write to station %v", user.Username, source.Name))
+			return
+		}
+	}
+	target, created, status, err := sh.S.rehydrationTarget(user, source, body.TargetStationName)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at rehydrationTarget", status, err)
+		return
+	}
+	for _, partition := range stationArchivePartitions(source) {
+		_, err = resolveArchivePartition(target.PartitionsList, partition)
+		if err != nil {
+			abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at resolveArchivePartition", SHOWABLE_ERROR_STATUS_CODE, err)
+			return
+		}
+	}
+
+	created, task, err := db.CreateAsyncTaskIfNotRunning(tieredStorageRehydrationTaskName, sh.S.opts.ServerName, time.Now(), user.TenantName, target.ID, user.Username)
+	if err != nil {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage at CreateAsyncTaskIfNotRunning", 500, err)
+		return
+	}
+	if !created {
+		abortTieredStorageRequest(c, user, "RehydrateFromTieredStorage", SHOWABLE_ERROR_STATUS_CODE, fmt.Errorf("A rehydration is already running on station %v", target.Name))
+		return
+	}
+
+	sh.S.RunTieredStorageRehydration(task, source, target, reader, keyPrefix, body.From, body.To, user)
+
+	message := fmt.Sprintf("Rehydration of station %v from tiered storage into station %v has been triggered by user %v", source.Name, target.Name, user.Username)
+	if created {
+		message = fmt.Sprintf("Station %v has been created for the rehydration of station %v from tiered storage, triggered by user %v", target.Name, source.Name, user.Username)
+	}
+	serv.Noticef("[tenant: %v][user: %v]: %v", user.TenantName, user.Username, message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		StationName:       target.Name,
+		Message:           message,
+		CreatedBy:         user.ID,
+		CreatedByUsername: user.Username,
+		CreatedAt:         time.Now(),
+		TenantName:        user.TenantName,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("[tenant: %v][user: %v]RehydrateFromTieredStorage at CreateAuditLogs: At station %v: %v", user.TenantName, user.Username, target.Name, err.Error())
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analyticsParams := map[string]interface{}{"station-name": source.Name, "station-created": created}
+		analytics.SendEvent(user.TenantName, user.Username, analyticsParams, "user-rehydrate-tiered-storage")
+	}
+
+	c.IndentedJSON(200, gin.H{"async_task_id": task.ID, "station_name": target.Name, "station_created": created})
+}
@@ -0,0 +1,186 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memphisdev/memphis/models"
+)
+
+// fakeS3 is a local stand-in for an S3 compatible storage which serves path style ListObjectsV2 and GetObject requests
+type fakeS3 struct {
+	bucket  string
+	objects map[string][]byte
+	times   map[string]time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/"+f.bucket)
+	if path == "" || path == "/" {
+		type content struct {
+			Key          string
+			LastModified string
+			Size         int
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			IsTruncated bool
+			Contents    []content
+		}{Name: f.bucket, Prefix: r.URL.Query().Get("prefix")}
+		for key, body := range f.objects {
+			if strings.HasPrefix(key, result.Prefix) {
+				result.Contents = append(result.Contents, content{Key: key, LastModified: f.times[key].UTC().Format(time.RFC3339), Size: len(body)})
+			}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+		return
+	}
+	body, ok := f.objects[strings.TrimPrefix(path, "/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(body)
+}
+
+func TestTieredStorageRehydration(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	header := []byte("NATS/1.0\r\n$memphis_producedBy: producer\r\nTrace-Id: a:b\r\nNats-Msg-Id: 1\r\n\r\n")
+	msgs := []Msg{
+		{Payload: hex.EncodeToString([]byte("old")), Headers: parseTieredStorageHeaders(header), Sequence: 7, Timestamp: now.Add(-2 * time.Hour)},
+		{Payload: hex.EncodeToString([]byte("new")), Headers: parseTieredStorageHeaders(header), Sequence: 8, Timestamp: now.Add(-time.Minute)},
+	}
+	object, _ := json.Marshal(msgs)
+	fake := &fakeS3{bucket: "tiered", objects: map[string][]byte{}, times: map[string]time.Time{}}
+	for key, uploadedAt := range map[string]time.Time{
+		tieredStorageObjectsPrefix("acme", "orders$1") + "a(2).json":   now.Add(-3 * time.Hour),
+		tieredStorageObjectsPrefix("acme", "orders$1") + "b(2).json":   now,
+		tieredStorageObjectsPrefix("acme", "orders$2") + "c(2).json":   now.Add(-time.Second),
+		tieredStorageObjectsPrefix("acme", "orders2$1") + "d(2).json":  now,
+		tieredStorageObjectsPrefix("other", "orders$1") + "e(2).json":  now,
+		tieredStorageObjectsPrefix("acme", "orders#x$1") + "f(2).json": now,
+	} {
+		fake.objects[key] = object
+		fake.times[key] = uploadedAt
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	reader, err := tieredStorageReaderFromIntegration("s3", models.Integration{Keys: map[string]interface{}{
+		"bucket_name":   fake.bucket,
+		"access_key":    "test",
+		"secret_key":    "test",
+		"region":        "us-east-1",
+		"url":           ts.URL,
+		"s3_path_style": "true",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	station := models.Station{Name: "orders", TenantName: "acme", PartitionsList: []int{2, 1}}
+	from := now.Add(-time.Hour)
+	objects, err := listTieredStorageObjects(context.Background(), reader, "", station, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Partition != 2 || objects[1].Partition != 1 || objects[1].Messages != 2 {
+		t.Fatalf("unexpected objects: %+v", objects)
+	}
+
+	read, err := readTieredStorageObject(context.Background(), reader, objects[1].Key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var replayed []map[string]string
+	for _, msg := range read {
+		msgTime := tieredStorageMsgTime(msg, objects[1])
+		if inTieredStorageRange(msgTime, from, now) {
+			replayed = append(replayed, rehydratedMsgHeaders(msg, msgTime))
+		}
+	}
+	if len(replayed) != 1 {
+		t.Fatalf("expected a single message in range, got %v", len(replayed))
+	}
+	headers := replayed[0]
+	if headers["Trace-Id"] != "a:b" || headers["$memphis_producedBy"] != "producer" || headers["Nats-Msg-Id"] != "" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+	if headers[tieredStorageOriginalSeqHdr] != "8" || headers[stationArchiveOriginalTimeHdr] != now.Add(-time.Minute).Format(time.RFC3339Nano) {
+		t.Errorf("original sequence and time are not preserved: %v", headers)
+	}
+
+	raw := "NATS/1.0\r\n"
+	for key, value := range headers {
+		raw += key + ": " + value + "\r\n"
+	}
+	raw += "\r\npayload"
+	if !isRehydratedMsg([]byte(raw)) {
+		t.Errorf("expected the replayed message to be marked as rehydrated")
+	}
+	if isRehydratedMsg(append(header, []byte(tieredStorageRehydratedHdr+": true")...)) {
+		t.Errorf("expected the marker in the payload to be ignored")
+	}
+}
+
+func TestFilesystemTieredStorageReader(t *testing.T) {
+	root := t.TempDir()
+	allowedRoot := configuration.FILESYSTEM_STORAGE_ROOT
+	defer func() { configuration.FILESYSTEM_STORAGE_ROOT = allowedRoot }()
+	configuration.FILESYSTEM_STORAGE_ROOT = root
+	msgs := []StoredMsg{{Sequence: 3, Time: time.Now(), Data: []byte("fs")}}
+	uploads, err := buildTieredStorageUploads("acme", "orders$1", "archive/", tieredStorageFormatNdjsonGzip, "uid", msgs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, upload := range uploads {
+		if err := writeFileAtomically(root, filepath.Join(root, filepath.FromSlash(upload.Key)), upload.Body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := writeFileAtomically(root, filepath.Join(root, "archive", "memphis", "acme", "station=orders", "partition=2", "other(1).json"), []byte("[]")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader, err := tieredStorageReaderFromIntegration(filesystemIntegrationName, models.Integration{Keys: map[string]interface{}{"path": root}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	station := models.Station{Name: "orders", TenantName: "acme", PartitionsList: []int{1}}
+	objects, err := listTieredStorageObjects(context.Background(), reader, "archive/", station, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != len(uploads) || objects[0].Key != uploads[0].Key {
+		t.Fatalf("unexpected objects: %+v", objects)
+	}
+	read, err := readTieredStorageObject(context.Background(), reader, objects[0].Key)
+	if err != nil || len(read) != 1 || read[0].Sequence != 3 || read[0].Payload != hex.EncodeToString([]byte("fs")) {
+		t.Errorf("unexpected messages %+v: %v", read, err)
+	}
+	if _, err := reader.Read(context.Background(), "../outside"); err == nil {
+		t.Errorf("expected a key outside of the root to be rejected")
+	}
+}